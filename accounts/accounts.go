@@ -39,6 +39,7 @@ const (
 	MimetypeDataWithValidator = "data/validator"
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
+	MimetypeIBFT              = "application/x-ibft-message"
 	MimetypeTextPlain         = "text/plain"
 )
 
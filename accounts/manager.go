@@ -81,6 +81,19 @@ func NewManager(config *Config, backends ...Backend) *Manager {
 	return am
 }
 
+// AddBackend starts tracking an additional backend, making its wallets available
+// through the manager and relaying its wallet updates.
+func (am *Manager) AddBackend(backend Backend) {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+
+	am.wallets = merge(am.wallets, backend.Wallets()...)
+	am.updaters = append(am.updaters, backend.Subscribe(am.updates))
+
+	kind := reflect.TypeOf(backend)
+	am.backends[kind] = append(am.backends[kind], backend)
+}
+
 // Close terminates the account manager's internal notification processes.
 func (am *Manager) Close() error {
 	errc := make(chan error)
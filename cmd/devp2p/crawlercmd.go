@@ -0,0 +1,132 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/etddb/leveldb"
+	"github.com/crypyto-panel/go-etherdata/etddb/memorydb"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var crawlerCommand = cli.Command{
+	Name:   "crawler",
+	Usage:  "Runs a continuous network crawler",
+	Action: runCrawler,
+	Flags: []cli.Flag{
+		bootnodesFlag,
+		nodekeyFlag,
+		nodedbFlag,
+		listenAddrFlag,
+		crawlerDBFlag,
+		crawlerHTTPFlag,
+		crawlerRevalidateFlag,
+		crawlerWorkersFlag,
+		crawlerV5Flag,
+	},
+	Description: `
+The crawler discovers nodes using the discovery protocol and keeps them in a database.
+Known nodes are revalidated periodically. On every check, the crawler connects to the
+node over RLPx to learn its client version, capabilities and the etd status (network
+ID and fork ID). A summary of the network composition is served as JSON at / on the
+HTTP endpoint, the full node list is available at /nodes.`,
+}
+
+var (
+	crawlerDBFlag = cli.StringFlag{
+		Name:  "db",
+		Usage: "Crawler database location (in-memory if empty)",
+	}
+	crawlerHTTPFlag = cli.StringFlag{
+		Name:  "http",
+		Usage: "HTTP listening address of the JSON summary",
+		Value: "127.0.0.1:8080",
+	}
+	crawlerRevalidateFlag = cli.DurationFlag{
+		Name:  "revalidate",
+		Usage: "Revalidation interval of known nodes",
+		Value: 10 * time.Minute,
+	}
+	crawlerWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of concurrent node checks",
+		Value: 16,
+	}
+	crawlerV5Flag = cli.BoolFlag{
+		Name:  "v5",
+		Usage: "Use discovery v5 instead of v4",
+	}
+)
+
+func runCrawler(ctx *cli.Context) error {
+	var db etddb.KeyValueStore
+	if path := ctx.String(crawlerDBFlag.Name); path != "" {
+		ldb, err := leveldb.New(path, 16, 16, "devp2p/crawler", false)
+		if err != nil {
+			return err
+		}
+		db = ldb
+	} else {
+		db = memorydb.New()
+	}
+	defer db.Close()
+
+	var (
+		disc resolver
+		iter enode.Iterator
+	)
+	if ctx.Bool(crawlerV5Flag.Name) {
+		v5 := startV5(ctx)
+		defer v5.Close()
+		disc, iter = v5, v5.RandomNodes()
+	} else {
+		v4 := startV4(ctx)
+		defer v4.Close()
+		disc, iter = v4, v4.RandomNodes()
+	}
+
+	s, err := newCrawlService(db, disc, iter, ctx.Duration(crawlerRevalidateFlag.Name), ctx.Int(crawlerWorkersFlag.Name))
+	if err != nil {
+		return err
+	}
+	s.start()
+	defer s.stop()
+
+	listener, err := net.Listen("tcp", ctx.String(crawlerHTTPFlag.Name))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s}
+	go srv.Serve(listener)
+	defer srv.Close()
+	log.Info("Crawler HTTP server started", "url", "http://"+listener.Addr().String())
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Shutting down crawler")
+	return nil
+}
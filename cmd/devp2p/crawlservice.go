@@ -0,0 +1,470 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/cmd/devp2p/internal/etdtest"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/p2p/rlpx"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+const (
+	crawlProbeTimeout   = 10 * time.Second // timeout of the RLPx handshake and status exchange
+	crawlSchedulePeriod = time.Minute      // how often nodes are checked for revalidation
+	crawlDBPrefix       = "n"              // database key prefix of node entries
+)
+
+// crawledNode is the database entry of a node. In addition to the fields kept
+// in nodes.json, it contains the information learned from the RLPx handshake.
+type crawledNode struct {
+	nodeJSON
+
+	Client        string       `json:"client,omitempty"`
+	Caps          []string     `json:"caps,omitempty"`
+	Status        *crawlStatus `json:"status,omitempty"`
+	LastHandshake time.Time    `json:"lastHandshake,omitempty"`
+	HandshakeErr  string       `json:"handshakeError,omitempty"`
+}
+
+// crawlStatus holds the relevant fields of the etd status message.
+type crawlStatus struct {
+	ProtocolVersion uint32 `json:"protocolVersion"`
+	NetworkID       uint64 `json:"networkID"`
+	Genesis         string `json:"genesis"`
+	Head            string `json:"head"`
+	ForkHash        string `json:"forkHash"`
+	ForkNext        uint64 `json:"forkNext"`
+}
+
+// crawlSummary is the network composition served over HTTP.
+type crawlSummary struct {
+	Nodes     int            `json:"nodes"`     // known nodes
+	Reachable int            `json:"reachable"` // nodes which sent a hello message on the last check
+	Clients   map[string]int `json:"clients"`   // client name -> count
+	Versions  map[string]int `json:"versions"`  // client name and version -> count
+	Protocols map[string]int `json:"protocols"` // capability -> count
+	Networks  map[uint64]int `json:"networks"`  // network ID -> count
+	ForkIDs   map[string]int `json:"forkIDs"`   // fork hash and next fork -> count
+}
+
+// crawlService is a long-running crawler. It keeps a database of discovered nodes,
+// revalidates them periodically and probes them over RLPx to learn about the
+// client and chain they run.
+type crawlService struct {
+	db                 etddb.KeyValueStore
+	disc               resolver
+	iter               enode.Iterator
+	key                *ecdsa.PrivateKey
+	revalidateInterval time.Duration
+	workers            int
+
+	mu       sync.RWMutex
+	nodes    map[enode.ID]*crawledNode
+	inflight map[enode.ID]struct{}
+
+	queue  chan *enode.Node
+	closed chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newCrawlService(db etddb.KeyValueStore, disc resolver, iter enode.Iterator, revalidate time.Duration, workers int) (*crawlService, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	s := &crawlService{
+		db:                 db,
+		disc:               disc,
+		iter:               iter,
+		key:                key,
+		revalidateInterval: revalidate,
+		workers:            workers,
+		nodes:              make(map[enode.ID]*crawledNode),
+		inflight:           make(map[enode.ID]struct{}),
+		queue:              make(chan *enode.Node),
+		closed:             make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads all nodes from the database.
+func (s *crawlService) load() error {
+	it := s.db.NewIterator([]byte(crawlDBPrefix), nil)
+	defer it.Release()
+
+	for it.Next() {
+		var n crawledNode
+		if err := json.Unmarshal(it.Value(), &n); err != nil || n.N == nil {
+			log.Warn("Skipping invalid crawler database entry", "key", fmt.Sprintf("%x", it.Key()), "err", err)
+			continue
+		}
+		s.nodes[n.N.ID()] = &n
+	}
+	log.Info("Loaded crawler database", "nodes", len(s.nodes))
+	return it.Error()
+}
+
+// start launches the crawler goroutines.
+func (s *crawlService) start() {
+	s.wg.Add(2 + s.workers)
+	go s.discoverLoop()
+	go s.scheduleLoop()
+	for i := 0; i < s.workers; i++ {
+		go s.worker()
+	}
+}
+
+// stop terminates the crawler.
+func (s *crawlService) stop() {
+	close(s.closed)
+	s.iter.Close()
+	s.wg.Wait()
+}
+
+// discoverLoop feeds newly discovered nodes into the queue.
+func (s *crawlService) discoverLoop() {
+	defer s.wg.Done()
+	for s.iter.Next() {
+		s.enqueue(s.iter.Node())
+	}
+}
+
+// scheduleLoop periodically queues nodes which are due for revalidation.
+func (s *crawlService) scheduleLoop() {
+	defer s.wg.Done()
+
+	s.scheduleRevalidation()
+	ticker := time.NewTicker(crawlSchedulePeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.scheduleRevalidation()
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *crawlService) scheduleRevalidation() {
+	s.mu.RLock()
+	var due []*enode.Node
+	for _, n := range s.nodes {
+		if time.Since(n.LastCheck) >= s.revalidateInterval {
+			due = append(due, n.N)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, n := range due {
+		if !s.enqueue(n) {
+			return
+		}
+	}
+}
+
+// enqueue hands a node to the workers unless it was checked recently or is already
+// being checked. It returns false when the service is shutting down.
+func (s *crawlService) enqueue(n *enode.Node) bool {
+	s.mu.Lock()
+	if known, ok := s.nodes[n.ID()]; ok && time.Since(known.LastCheck) < s.revalidateInterval {
+		s.mu.Unlock()
+		return true
+	}
+	if _, ok := s.inflight[n.ID()]; ok {
+		s.mu.Unlock()
+		return true
+	}
+	s.inflight[n.ID()] = struct{}{}
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- n:
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+func (s *crawlService) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case n := <-s.queue:
+			s.check(n)
+			s.mu.Lock()
+			delete(s.inflight, n.ID())
+			s.mu.Unlock()
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// check revalidates a node and stores the result.
+func (s *crawlService) check(n *enode.Node) {
+	s.mu.RLock()
+	var node crawledNode
+	if known, ok := s.nodes[n.ID()]; ok {
+		node = *known
+	}
+	s.mu.RUnlock()
+
+	// Request the node record.
+	nn, err := s.disc.RequestENR(n)
+	node.LastCheck = truncNow()
+	if err != nil {
+		if node.Score == 0 {
+			// Node doesn't implement EIP-868.
+			log.Debug("Skipping node", "id", n.ID())
+			return
+		}
+		node.Score /= 2
+	} else {
+		node.N = nn
+		node.Seq = nn.Seq()
+		node.Score++
+		if node.FirstResponse.IsZero() {
+			node.FirstResponse = node.LastCheck
+		}
+		node.LastResponse = node.LastCheck
+
+		// Learn about the client if the node accepts connections.
+		if nn.TCP() != 0 {
+			s.probe(nn, &node)
+		}
+	}
+	if node.Score <= 0 {
+		log.Info("Removing node", "id", n.ID())
+		s.remove(n.ID())
+	} else {
+		log.Info("Updating node", "id", n.ID(), "seq", node.Seq, "score", node.Score, "client", node.Client)
+		s.store(&node)
+	}
+}
+
+// probe performs the RLPx handshake and stores the result in node.
+func (s *crawlService) probe(n *enode.Node, node *crawledNode) {
+	hello, status, err := rlpxProbe(s.key, n, crawlProbeTimeout)
+	node.LastHandshake = node.LastCheck
+	node.Client, node.Caps, node.Status, node.HandshakeErr = "", nil, nil, ""
+	if err != nil {
+		node.HandshakeErr = err.Error()
+	}
+	if hello != nil {
+		node.Client = hello.Name
+		for _, c := range hello.Caps {
+			node.Caps = append(node.Caps, c.String())
+		}
+	}
+	if status != nil {
+		node.Status = &crawlStatus{
+			ProtocolVersion: status.ProtocolVersion,
+			NetworkID:       status.NetworkID,
+			Genesis:         status.Genesis.Hex(),
+			Head:            status.Head.Hex(),
+			ForkHash:        fmt.Sprintf("%x", status.ForkID.Hash),
+			ForkNext:        status.ForkID.Next,
+		}
+	}
+}
+
+func (s *crawlService) store(node *crawledNode) {
+	enc, err := json.Marshal(node)
+	if err != nil {
+		log.Error("Can't encode crawled node", "id", node.N.ID(), "err", err)
+		return
+	}
+	id := node.N.ID()
+	if err := s.db.Put(append([]byte(crawlDBPrefix), id[:]...), enc); err != nil {
+		log.Error("Can't store crawled node", "id", id, "err", err)
+	}
+	s.mu.Lock()
+	s.nodes[id] = node
+	s.mu.Unlock()
+}
+
+func (s *crawlService) remove(id enode.ID) {
+	if err := s.db.Delete(append([]byte(crawlDBPrefix), id[:]...)); err != nil {
+		log.Error("Can't delete crawled node", "id", id, "err", err)
+	}
+	s.mu.Lock()
+	delete(s.nodes, id)
+	s.mu.Unlock()
+}
+
+// nodeList returns all known nodes, sorted by ID.
+func (s *crawlService) nodeList() []*crawledNode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*crawledNode, 0, len(s.nodes))
+	for _, n := range s.nodes {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].N.ID().Bytes(), list[j].N.ID().Bytes()) < 0
+	})
+	return list
+}
+
+// summary computes the network composition.
+func (s *crawlService) summary() *crawlSummary {
+	sum := &crawlSummary{
+		Clients:   make(map[string]int),
+		Versions:  make(map[string]int),
+		Protocols: make(map[string]int),
+		Networks:  make(map[uint64]int),
+		ForkIDs:   make(map[string]int),
+	}
+	for _, n := range s.nodeList() {
+		sum.Nodes++
+		if n.Client == "" {
+			continue
+		}
+		sum.Reachable++
+		parts := strings.Split(n.Client, "/")
+		sum.Clients[parts[0]]++
+		if len(parts) > 1 {
+			sum.Versions[parts[0]+"/"+parts[1]]++
+		} else {
+			sum.Versions[parts[0]]++
+		}
+		for _, c := range n.Caps {
+			sum.Protocols[c]++
+		}
+		if n.Status != nil {
+			sum.Networks[n.Status.NetworkID]++
+			sum.ForkIDs[fmt.Sprintf("%s/%d", n.Status.ForkHash, n.Status.ForkNext)]++
+		}
+	}
+	return sum
+}
+
+// ServeHTTP serves the summary at "/" and the node list at "/nodes".
+func (s *crawlService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var v interface{}
+	switch r.URL.Path {
+	case "/":
+		v = s.summary()
+	case "/nodes":
+		v = s.nodeList()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", jsonIndent)
+	enc.Encode(v)
+}
+
+// rlpxProbe connects to n, performs the devp2p handshake and reads the etd
+// status message if the node supports the protocol. The hello message is returned
+// even if the node disconnects later, e.g. because it has too many peers.
+func rlpxProbe(key *ecdsa.PrivateKey, n *enode.Node, timeout time.Duration) (*etdtest.Hello, *etd.StatusPacket, error) {
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()), timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fd.Close()
+	fd.SetDeadline(time.Now().Add(timeout))
+
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	if _, err := conn.Handshake(key); err != nil {
+		return nil, nil, err
+	}
+	defer conn.Write(1, rlpEncode([]p2p.DiscReason{p2p.DiscQuitting}))
+
+	// Exchange hello messages.
+	ours := &etdtest.Hello{
+		Version: 5,
+		Caps:    []p2p.Cap{{Name: "etd", Version: 64}, {Name: "etd", Version: 65}, {Name: "etd", Version: 66}},
+		ID:      crypto.FromECDSAPub(&key.PublicKey)[1:],
+	}
+	if _, err := conn.Write(0, rlpEncode(ours)); err != nil {
+		return nil, nil, err
+	}
+	hello := new(etdtest.Hello)
+	if err := readProbeMsg(conn, 0, hello); err != nil {
+		return nil, nil, err
+	}
+	conn.SetSnappy(hello.Version >= 5)
+
+	// Read the status message. The etd protocol is the only shared capability,
+	// so it starts at the first message code after the devp2p base protocol.
+	var supported bool
+	for _, c := range hello.Caps {
+		supported = supported || (c.Name == "etd" && c.Version >= 64 && c.Version <= 66)
+	}
+	if !supported {
+		return hello, nil, errors.New("no shared etd protocol version")
+	}
+	status := new(etd.StatusPacket)
+	if err := readProbeMsg(conn, 16+etd.StatusMsg, status); err != nil {
+		return hello, nil, err
+	}
+	return hello, status, nil
+}
+
+// readProbeMsg reads the next message with the given code, answering pings.
+func readProbeMsg(conn *rlpx.Conn, want uint64, val interface{}) error {
+	for {
+		code, data, _, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		switch code {
+		case want:
+			return rlp.DecodeBytes(data, val)
+		case 1:
+			var reason []p2p.DiscReason
+			if rlp.DecodeBytes(data, &reason); len(reason) > 0 {
+				return fmt.Errorf("disconnected: %v", reason[0])
+			}
+			return errors.New("disconnected")
+		case 2:
+			conn.Write(3, rlpEncode([]interface{}{}))
+		}
+	}
+}
+
+func rlpEncode(val interface{}) []byte {
+	enc, _ := rlp.EncodeToBytes(val)
+	return enc
+}
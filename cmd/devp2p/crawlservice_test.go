@@ -0,0 +1,167 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/forkid"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
+	"github.com/crypyto-panel/go-etherdata/etddb/memorydb"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/p2p/enr"
+)
+
+var crawlTestStatus = &etd.StatusPacket{
+	ProtocolVersion: 66,
+	NetworkID:       1337,
+	TD:              big.NewInt(1000),
+	Head:            common.HexToHash("0x01"),
+	Genesis:         common.HexToHash("0x02"),
+	ForkID:          forkid.ID{Hash: [4]byte{0xfc, 0x64, 0xec, 0x04}, Next: 1150000},
+}
+
+// startCrawlTestServer starts a p2p server which sends crawlTestStatus to
+// all peers speaking the etd protocol.
+func startCrawlTestServer(t *testing.T) *p2p.Server {
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{
+		Config: p2p.Config{
+			PrivateKey:  key,
+			MaxPeers:    10,
+			NoDiscovery: true,
+			ListenAddr:  "127.0.0.1:0",
+			Name:        "Getd/v1.10.2-stable/linux-amd64/go1.16",
+			Protocols: []p2p.Protocol{{
+				Name:    "etd",
+				Version: 66,
+				Length:  17,
+				Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+					if err := p2p.Send(rw, etd.StatusMsg, crawlTestStatus); err != nil {
+						return err
+					}
+					_, err := rw.ReadMsg()
+					return err
+				},
+			}},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestCrawlProbe(t *testing.T) {
+	srv := startCrawlTestServer(t)
+	defer srv.Stop()
+
+	key, _ := crypto.GenerateKey()
+	hello, status, err := rlpxProbe(key, srv.Self(), 5*time.Second)
+	if err != nil {
+		t.Fatal("probe failed:", err)
+	}
+	if hello.Name != srv.Name {
+		t.Errorf("wrong client name %q, want %q", hello.Name, srv.Name)
+	}
+	if status.NetworkID != crawlTestStatus.NetworkID || status.ForkID != crawlTestStatus.ForkID {
+		t.Errorf("wrong status %+v, want %+v", status, crawlTestStatus)
+	}
+}
+
+// crawlTestResolver returns the nodes it knows about.
+type crawlTestResolver map[enode.ID]*enode.Node
+
+func (r crawlTestResolver) RequestENR(n *enode.Node) (*enode.Node, error) {
+	if rn, ok := r[n.ID()]; ok {
+		return rn, nil
+	}
+	return nil, errors.New("timeout")
+}
+
+func TestCrawlServiceCheck(t *testing.T) {
+	srv := startCrawlTestServer(t)
+	defer srv.Stop()
+
+	var (
+		self     = srv.Self()
+		unknown  = enode.SignNull(new(enr.Record), enode.ID{1})
+		offline  = crawlTestNode(t)
+		resolver = crawlTestResolver{self.ID(): self, offline.ID(): offline}
+		db       = memorydb.New()
+	)
+	s, err := newCrawlService(db, resolver, enode.IterNodes(nil), time.Minute, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.check(self)
+	s.check(unknown)
+	s.check(offline)
+
+	// The reachable node should be counted in the summary.
+	sum := s.summary()
+	if sum.Nodes != 2 || sum.Reachable != 1 {
+		t.Fatalf("wrong node counts: nodes %d, reachable %d", sum.Nodes, sum.Reachable)
+	}
+	if sum.Clients["Getd"] != 1 || sum.Versions["Getd/v1.10.2-stable"] != 1 {
+		t.Errorf("wrong client stats: %v %v", sum.Clients, sum.Versions)
+	}
+	if sum.Networks[1337] != 1 || sum.ForkIDs["fc64ec04/1150000"] != 1 || sum.Protocols["etd/66"] != 1 {
+		t.Errorf("wrong status stats: %v %v %v", sum.Networks, sum.ForkIDs, sum.Protocols)
+	}
+
+	// Check that the nodes are loaded from the database.
+	s2, err := newCrawlService(db, resolver, enode.IterNodes(nil), time.Minute, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s2.nodeList()) != 2 {
+		t.Fatalf("wrong number of nodes loaded from db: %d", len(s2.nodeList()))
+	}
+
+	// Nodes which stop responding are removed.
+	delete(resolver, offline.ID())
+	s2.check(offline)
+	if len(s2.nodeList()) != 1 {
+		t.Fatalf("offline node not removed")
+	}
+}
+
+// crawlTestNode creates a node record pointing to a closed TCP port.
+func crawlTestNode(t *testing.T) *enode.Node {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	key, _ := crypto.GenerateKey()
+	db, _ := enode.OpenDB("")
+	ln := enode.NewLocalNode(db, key)
+	ln.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	ln.Set(enr.TCP(port))
+	ln.Set(enr.UDP(port))
+	return ln.Node()
+}
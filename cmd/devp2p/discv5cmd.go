@@ -23,6 +23,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/cmd/devp2p/internal/v5test"
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/p2p/discover"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -36,6 +37,7 @@ var (
 			discv5CrawlCommand,
 			discv5TestCommand,
 			discv5ListenCommand,
+			discv5TopicCommand,
 		},
 	}
 	discv5PingCommand = cli.Command{
@@ -77,8 +79,41 @@ var (
 			listenAddrFlag,
 		},
 	}
+	discv5TopicCommand = cli.Command{
+		Name:  "topic",
+		Usage: "Topic advertisement tools",
+		Subcommands: []cli.Command{
+			discv5TopicRegisterCommand,
+			discv5TopicSearchCommand,
+		},
+	}
+	discv5TopicRegisterCommand = cli.Command{
+		Name:      "register",
+		Usage:     "Runs a node advertising the given topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5TopicRegister,
+		Flags: []cli.Flag{
+			bootnodesFlag,
+			nodekeyFlag,
+			nodedbFlag,
+			listenAddrFlag,
+		},
+	}
+	discv5TopicSearchCommand = cli.Command{
+		Name:      "search",
+		Usage:     "Searches the DHT for nodes advertising the given topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5TopicSearch,
+		Flags:     []cli.Flag{bootnodesFlag, topicTimeoutFlag},
+	}
 )
 
+var topicTimeoutFlag = cli.DurationFlag{
+	Name:  "timeout",
+	Usage: "Time limit for the topic search",
+	Value: time.Minute,
+}
+
 func discv5Ping(ctx *cli.Context) error {
 	n := getNodeArg(ctx)
 	disc := startV5(ctx)
@@ -134,6 +169,43 @@ func discv5Listen(ctx *cli.Context) error {
 	select {}
 }
 
+func discv5TopicRegister(ctx *cli.Context) error {
+	topic := getTopicArg(ctx)
+	disc := startV5(ctx)
+	defer disc.Close()
+
+	disc.RegisterTopic(topic)
+	fmt.Println(disc.Self())
+	select {}
+}
+
+func discv5TopicSearch(ctx *cli.Context) error {
+	topic := getTopicArg(ctx)
+	disc := startV5(ctx)
+	defer disc.Close()
+
+	it := disc.TopicNodes(topic)
+	timeout := time.AfterFunc(ctx.Duration(topicTimeoutFlag.Name), it.Close)
+	defer timeout.Stop()
+
+	seen := make(map[enode.ID]bool)
+	for it.Next() {
+		if n := it.Node(); !seen[n.ID()] {
+			seen[n.ID()] = true
+			fmt.Println(n)
+		}
+	}
+	return nil
+}
+
+// getTopicArg parses the topic name argument.
+func getTopicArg(ctx *cli.Context) discover.Topic {
+	if ctx.NArg() < 1 {
+		exit("missing topic as command-line argument")
+	}
+	return discover.NewTopic(ctx.Args().First())
+}
+
 // startV5 starts an ephemeral discovery v5 node.
 func startV5(ctx *cli.Context) *discover.UDPv5 {
 	ln, config := makeDiscoveryConfig(ctx)
@@ -0,0 +1,430 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p/dnsdisc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rfc2136ServerFlag = cli.StringFlag{
+		Name:  "server",
+		Usage: "Address (host:port) of the DNS server accepting dynamic updates",
+	}
+	rfc2136ZoneFlag = cli.StringFlag{
+		Name:  "zone",
+		Usage: "DNS zone containing the tree (defaults to the tree domain)",
+	}
+	rfc2136TSIGKeyFlag = cli.StringFlag{
+		Name:  "tsig-key",
+		Usage: "Name of the TSIG key used to sign updates (optional)",
+	}
+	rfc2136TSIGSecretFlag = cli.StringFlag{
+		Name:   "tsig-secret",
+		Usage:  "Base64 encoded TSIG secret",
+		EnvVar: "DNS_TSIG_SECRET",
+	}
+	rfc2136TSIGAlgorithmFlag = cli.StringFlag{
+		Name:  "tsig-algorithm",
+		Usage: "TSIG algorithm (hmac-sha256, hmac-sha512)",
+		Value: "hmac-sha256",
+	}
+)
+
+const (
+	rfc2136DefaultTimeout = 10 * time.Second
+	rfc2136BatchSize      = 32 * 1024 // max size of an update message
+	rfc2136SyncRateLimit  = 100       // DNS queries / second when fetching the deployed tree
+	tsigFudge             = 300       // allowed clock skew in seconds
+
+	// DNS constants
+	dnsTypeSOA    = 6
+	dnsTypeTXT    = 16
+	dnsTypeTSIG   = 250
+	dnsClassIN    = 1
+	dnsClassANY   = 255
+	dnsOpUpdate   = 5
+	dnsHeaderSize = 12
+)
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+}
+
+var dnsRcodeNames = []string{
+	"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED",
+	"YXDOMAIN", "YXRRSET", "NXRRSET", "NOTAUTH", "NOTZONE",
+}
+
+type rfc2136Client struct {
+	server  string
+	zone    string
+	timeout time.Duration
+
+	tsigKey    string
+	tsigSecret []byte
+	tsigAlg    string
+}
+
+// rfc2136Change is a change to a TXT record. Changes which set a record replace all
+// existing TXT records of the name.
+type rfc2136Change struct {
+	name   string
+	delete bool
+	ttl    uint32
+	value  string
+}
+
+// newRFC2136Client sets up a dynamic DNS update client from command line flags.
+func newRFC2136Client(ctx *cli.Context) *rfc2136Client {
+	c := &rfc2136Client{
+		server:  ctx.String(rfc2136ServerFlag.Name),
+		zone:    ctx.String(rfc2136ZoneFlag.Name),
+		timeout: rfc2136DefaultTimeout,
+		tsigKey: ctx.String(rfc2136TSIGKeyFlag.Name),
+		tsigAlg: ctx.String(rfc2136TSIGAlgorithmFlag.Name),
+	}
+	if c.server == "" {
+		exit(fmt.Errorf("need DNS server address to proceed"))
+	}
+	if _, _, err := net.SplitHostPort(c.server); err != nil {
+		c.server = net.JoinHostPort(c.server, "53")
+	}
+	if ctx.IsSet(dnsTimeoutFlag.Name) {
+		c.timeout = ctx.Duration(dnsTimeoutFlag.Name)
+	}
+	if c.tsigKey != "" {
+		if tsigAlgorithms[c.tsigAlg] == nil {
+			exit(fmt.Errorf("unsupported TSIG algorithm %q", c.tsigAlg))
+		}
+		secret, err := base64.StdEncoding.DecodeString(ctx.String(rfc2136TSIGSecretFlag.Name))
+		if err != nil || len(secret) == 0 {
+			exit(fmt.Errorf("need valid base64 TSIG secret to proceed"))
+		}
+		c.tsigSecret = secret
+	}
+	return c
+}
+
+// deploy uploads the given tree to the DNS server. The tree currently deployed at
+// the URL is fetched from the server to find records which need to be removed.
+func (c *rfc2136Client) deploy(url string, t *dnsdisc.Tree) error {
+	name, _, err := dnsdisc.ParseURL(url)
+	if err != nil {
+		return err
+	}
+	zone := c.zone
+	if zone == "" {
+		zone = name
+	}
+	if name != zone && !strings.HasSuffix(name, "."+zone) {
+		return fmt.Errorf("name %q is not within zone %q", name, zone)
+	}
+
+	existing := c.collectRecords(url)
+	log.Info(fmt.Sprintf("Found %d TXT records", len(existing)))
+	changes := computeRFC2136Changes(name, t.ToTXT(name), existing)
+	if len(changes) == 0 {
+		log.Info("No changes")
+		return nil
+	}
+	batches := splitRFC2136Changes(changes, rfc2136BatchSize)
+	for i, batch := range batches {
+		log.Info(fmt.Sprintf("Submitting %d changes to %s (batch %d/%d)", len(batch), c.server, i+1, len(batches)))
+		msg, err := c.updateMessage(zone, batch)
+		if err != nil {
+			return err
+		}
+		if err := c.exchange(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectRecords fetches the currently deployed tree from the server. If the
+// tree can't be retrieved, no existing records are assumed.
+func (c *rfc2136Client) collectRecords(url string) map[string]string {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, c.server)
+		},
+	}
+	client := dnsdisc.NewClient(dnsdisc.Config{
+		Timeout:   c.timeout,
+		RateLimit: rfc2136SyncRateLimit,
+		Resolver:  resolver,
+	})
+	name, _, _ := dnsdisc.ParseURL(url)
+	log.Info(fmt.Sprintf("Retrieving existing TXT records on %s", name))
+	deployed, err := client.SyncTree(url)
+	if err != nil {
+		log.Warn("Can't retrieve deployed tree, stale records will not be deleted", "err", err)
+		return nil
+	}
+	return lowercaseRecords(deployed.ToTXT(name))
+}
+
+// computeRFC2136Changes creates DNS changes for the given set of DNS discovery
+// records. The 'existing' arg is the set of records that are currently deployed.
+// Changes are in leaf-added -> root-changed -> leaf-deleted order.
+func computeRFC2136Changes(name string, records, existing map[string]string) []rfc2136Change {
+	name = strings.ToLower(name)
+	records = lowercaseRecords(records)
+
+	var leaves, root, deletions []rfc2136Change
+	for path, value := range records {
+		if prev, ok := existing[path]; ok && prev == value {
+			log.Debug(fmt.Sprintf("Skipping %s = %q", path, value))
+			continue
+		}
+		if path == name {
+			log.Info(fmt.Sprintf("Updating %s = %q", path, value))
+			root = append(root, rfc2136Change{name: path, ttl: rootTTL, value: value})
+		} else {
+			log.Info(fmt.Sprintf("Creating %s = %q", path, value))
+			leaves = append(leaves, rfc2136Change{name: path, ttl: treeNodeTTL, value: value})
+		}
+	}
+	for path, value := range existing {
+		if _, ok := records[path]; !ok {
+			log.Info(fmt.Sprintf("Deleting %s = %q", path, value))
+			deletions = append(deletions, rfc2136Change{name: path, delete: true})
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].name < leaves[j].name })
+	sort.Slice(deletions, func(i, j int) bool { return deletions[i].name < deletions[j].name })
+
+	changes := append(leaves, root...)
+	return append(changes, deletions...)
+}
+
+// splitRFC2136Changes splits up changes such that the update message of
+// each batch stays below the given size.
+func splitRFC2136Changes(changes []rfc2136Change, sizeLimit int) [][]rfc2136Change {
+	var (
+		batches   [][]rfc2136Change
+		batchSize int
+	)
+	for _, ch := range changes {
+		size := ch.size()
+		if len(batches) == 0 || batchSize+size > sizeLimit {
+			batches = append(batches, nil)
+			batchSize = 0
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], ch)
+		batchSize += size
+	}
+	return batches
+}
+
+// size returns the approximate encoded size of the change.
+func (ch rfc2136Change) size() int {
+	size := 2 * (len(ch.name) + 12)
+	if !ch.delete {
+		size += len(ch.value) + len(ch.value)/255 + 1
+	}
+	return size
+}
+
+// updateMessage creates a signed UPDATE message containing the given changes.
+func (c *rfc2136Client) updateMessage(zone string, changes []rfc2136Change) ([]byte, error) {
+	var id [2]byte
+	crand.Read(id[:])
+	msg := make([]byte, dnsHeaderSize, rfc2136BatchSize)
+	copy(msg, id[:])
+	binary.BigEndian.PutUint16(msg[2:], dnsOpUpdate<<11)
+	binary.BigEndian.PutUint16(msg[4:], 1) // ZOCOUNT
+
+	// Zone section.
+	msg, err := appendDNSName(msg, zone)
+	if err != nil {
+		return nil, err
+	}
+	msg = appendUint16(msg, dnsTypeSOA)
+	msg = appendUint16(msg, dnsClassIN)
+
+	// Update section. Every change deletes the TXT RRset of the name, and
+	// optionally adds the new record.
+	var count uint16
+	for _, ch := range changes {
+		if msg, err = appendDNSName(msg, ch.name); err != nil {
+			return nil, err
+		}
+		msg = appendRR(msg, dnsTypeTXT, dnsClassANY, 0, nil)
+		count++
+		if ch.delete {
+			continue
+		}
+		msg, _ = appendDNSName(msg, ch.name)
+		msg = appendRR(msg, dnsTypeTXT, dnsClassIN, ch.ttl, encodeTXT(ch.value))
+		count++
+	}
+	binary.BigEndian.PutUint16(msg[8:], count) // UPCOUNT
+
+	if c.tsigKey != "" {
+		return c.sign(msg, time.Now())
+	}
+	return msg, nil
+}
+
+// sign appends a TSIG record (RFC 8945) to the message.
+func (c *rfc2136Client) sign(msg []byte, now time.Time) ([]byte, error) {
+	var (
+		key, errk = appendDNSName(nil, strings.ToLower(c.tsigKey))
+		alg, erra = appendDNSName(nil, c.tsigAlg)
+		signed    = uint64(now.Unix())
+		timeBytes = []byte{byte(signed >> 40), byte(signed >> 32), byte(signed >> 24), byte(signed >> 16), byte(signed >> 8), byte(signed)}
+	)
+	if errk != nil || erra != nil {
+		return nil, fmt.Errorf("invalid TSIG key or algorithm name")
+	}
+	// Compute the MAC over the message and the TSIG variables.
+	mac := hmac.New(tsigAlgorithms[c.tsigAlg], c.tsigSecret)
+	mac.Write(msg)
+	mac.Write(key)
+	mac.Write([]byte{0, dnsClassANY, 0, 0, 0, 0}) // class, TTL
+	mac.Write(alg)
+	mac.Write(timeBytes)
+	mac.Write([]byte{tsigFudge >> 8, tsigFudge & 0xff, 0, 0, 0, 0}) // fudge, error, other len
+	sum := mac.Sum(nil)
+
+	rdata := append(alg, timeBytes...)
+	rdata = appendUint16(rdata, tsigFudge)
+	rdata = appendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1]) // original ID
+	rdata = appendUint16(rdata, 0)        // error
+	rdata = appendUint16(rdata, 0)        // other len
+
+	msg = append(msg, key...)
+	msg = appendRR(msg, dnsTypeTSIG, dnsClassANY, 0, rdata)
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])+1) // ADCOUNT
+	return msg, nil
+}
+
+// exchange sends the message to the server over TCP and checks the response code.
+func (c *rfc2136Client) exchange(msg []byte) error {
+	conn, err := net.DialTimeout("tcp", c.server, c.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err := conn.Write(append(appendUint16(nil, uint16(len(msg))), msg...)); err != nil {
+		return err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if len(resp) < dnsHeaderSize || resp[0] != msg[0] || resp[1] != msg[1] {
+		return errors.New("invalid response from DNS server")
+	}
+	if rcode := int(resp[3] & 0xf); rcode != 0 {
+		name := fmt.Sprintf("RCODE%d", rcode)
+		if rcode < len(dnsRcodeNames) {
+			name = dnsRcodeNames[rcode]
+		}
+		return fmt.Errorf("DNS update failed: %s", name)
+	}
+	return nil
+}
+
+// appendDNSName appends the uncompressed wire encoding of a domain name.
+func appendDNSName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("name %q too long", name)
+	}
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("invalid name %q", name)
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0), nil
+}
+
+// appendRR appends the fixed fields and data of a resource record. The owner
+// name must have been appended already.
+func appendRR(b []byte, typ, class uint16, ttl uint32, rdata []byte) []byte {
+	b = appendUint16(b, typ)
+	b = appendUint16(b, class)
+	b = append(b, byte(ttl>>24), byte(ttl>>16), byte(ttl>>8), byte(ttl))
+	b = appendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// encodeTXT creates TXT record data, splitting the value into character-strings
+// of at most 255 bytes.
+func encodeTXT(value string) []byte {
+	var rdata []byte
+	for {
+		n := len(value)
+		if n > 255 {
+			n = 255
+		}
+		rdata = append(rdata, byte(n))
+		rdata = append(rdata, value[:n]...)
+		if value = value[n:]; value == "" {
+			return rdata
+		}
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// lowercaseRecords converts all record names to lowercase.
+func lowercaseRecords(records map[string]string) map[string]string {
+	lrecords := make(map[string]string, len(records))
+	for name, r := range records {
+		lrecords[strings.ToLower(name)] = r
+	}
+	return lrecords
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// This test checks that computeRFC2136Changes creates DNS changes in
+// leaf-added -> root-changed -> leaf-deleted order.
+func TestRFC2136ChangeOrder(t *testing.T) {
+	existing := map[string]string{
+		"n":                            "enrtree-root:v1 e=2KFJOGVXDQTXXUGBH7GS7NAAAI l=FDXN3SN67NA5DKA4J2GOK7BVQI seq=0 sig=v_-J",
+		"2kfjogvxdqtxxugbh7gs7naaai.n": "enr:-HW4QO1ml1DdXLeZLsUxewnthhUy8eROqkDyoMTyavfks9JlYQIlMFEUoM78PovJDPQrAkrb3LRJ",
+		"fdxn3sn67na5dka4j2gok7bvqi.n": "enrtree-branch:",
+	}
+	records := map[string]string{
+		"n":                            "enrtree-root:v1 e=JWXYDBPXYWG6FX3GMDIBFA6CJ4 l=FDXN3SN67NA5DKA4J2GOK7BVQI seq=1 sig=o908",
+		"FDXN3SN67NA5DKA4J2GOK7BVQI.n": "enrtree-branch:",
+		"JWXYDBPXYWG6FX3GMDIBFA6CJ4.n": "enrtree-branch:2XS2367YHAXJFGLZHVAWLQD4ZY",
+		"2XS2367YHAXJFGLZHVAWLQD4ZY.n": "enr:-HW4QOFzoVLaFJnNhbgMoDXPnOvcdVuj7pDpqRvh6BRDO68aVi5ZcjB3vzQRZH2IcLBGHzo8uUN3",
+	}
+	want := []rfc2136Change{
+		{name: "2xs2367yhaxjfglzhvawlqd4zy.n", ttl: treeNodeTTL, value: records["2XS2367YHAXJFGLZHVAWLQD4ZY.n"]},
+		{name: "jwxydbpxywg6fx3gmdibfa6cj4.n", ttl: treeNodeTTL, value: records["JWXYDBPXYWG6FX3GMDIBFA6CJ4.n"]},
+		{name: "n", ttl: rootTTL, value: records["n"]},
+		{name: "2kfjogvxdqtxxugbh7gs7naaai.n", delete: true},
+	}
+	changes := computeRFC2136Changes("n", records, existing)
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("wrong changes:\nhave %+v\nwant %+v", changes, want)
+	}
+
+	// Check that batches respect the size limit.
+	batches := splitRFC2136Changes(changes, changes[0].size()+changes[1].size())
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 2 {
+		t.Fatalf("wrong batches: %v", batches)
+	}
+}
+
+// This test checks that signed update messages are accepted by a server verifying TSIG.
+func TestRFC2136Update(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c := &rfc2136Client{
+		server:     l.Addr().String(),
+		timeout:    5 * time.Second,
+		tsigKey:    "Test-Key.",
+		tsigSecret: []byte("secret"),
+		tsigAlg:    "hmac-sha256",
+	}
+	changes := []rfc2136Change{
+		{name: "a.nodes.example.org", ttl: treeNodeTTL, value: string(bytes.Repeat([]byte("x"), 300))},
+		{name: "b.nodes.example.org", delete: true},
+	}
+	msg, err := c.updateMessage("example.org", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify message structure and signature.
+	if op := binary.BigEndian.Uint16(msg[2:]) >> 11; op != dnsOpUpdate {
+		t.Errorf("wrong opcode %d", op)
+	}
+	if zo, up, ad := binary.BigEndian.Uint16(msg[4:]), binary.BigEndian.Uint16(msg[8:]), binary.BigEndian.Uint16(msg[10:]); zo != 1 || up != 3 || ad != 1 {
+		t.Errorf("wrong section counts: zone %d, update %d, additional %d", zo, up, ad)
+	}
+	offset := skipDNSName(msg, dnsHeaderSize) + 4
+	for i := 0; i < 3; i++ {
+		offset = skipDNSName(msg, offset)
+		offset += 10 + int(binary.BigEndian.Uint16(msg[offset+8:]))
+	}
+	unsigned := append([]byte{}, msg[:offset]...)
+	binary.BigEndian.PutUint16(unsigned[10:], 0)
+	rdata := msg[skipDNSName(msg, offset)+10:]
+	alg := rdata[:skipDNSName(rdata, 0)]
+	timeFudge := rdata[len(alg) : len(alg)+8]
+	macSize := int(binary.BigEndian.Uint16(rdata[len(alg)+8:]))
+	gotMAC := rdata[len(alg)+10 : len(alg)+10+macSize]
+
+	mac := hmac.New(sha256.New, c.tsigSecret)
+	mac.Write(unsigned)
+	mac.Write([]byte("\x08test-key\x00"))
+	mac.Write([]byte{0, dnsClassANY, 0, 0, 0, 0})
+	mac.Write(alg)
+	mac.Write(timeFudge)
+	mac.Write([]byte{0, 0, 0, 0})
+	if !hmac.Equal(gotMAC, mac.Sum(nil)) {
+		t.Error("invalid TSIG MAC")
+	}
+
+	// Check response handling.
+	for _, rcode := range []byte{0, 5} {
+		go func(rcode byte) {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			var size [2]byte
+			io.ReadFull(conn, size[:])
+			req := make([]byte, binary.BigEndian.Uint16(size[:]))
+			io.ReadFull(conn, req)
+			resp := append([]byte{0, dnsHeaderSize}, req[:dnsHeaderSize]...)
+			resp[4] |= 0x80 // QR
+			resp[5] |= rcode
+			conn.Write(resp)
+		}(rcode)
+		err := c.exchange(msg)
+		if rcode == 0 && err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if rcode == 5 && (err == nil || err.Error() != "DNS update failed: REFUSED") {
+			t.Errorf("wrong error for REFUSED response: %v", err)
+		}
+	}
+}
+
+// skipDNSName returns the offset of the first byte after the name starting at offset.
+func skipDNSName(msg []byte, offset int) int {
+	for msg[offset] != 0 {
+		offset += int(msg[offset]) + 1
+	}
+	return offset + 1
+}
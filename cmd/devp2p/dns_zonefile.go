@@ -0,0 +1,83 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/crypyto-panel/go-etherdata/p2p/dnsdisc"
+)
+
+// makeZoneFile creates the TXT records of a tree in BIND zone file format.
+// The root record comes first, followed by all other records in name order.
+func makeZoneFile(name string, t *dnsdisc.Tree) []byte {
+	var (
+		records = lowercaseRecords(t.ToTXT(name))
+		names   = make([]string, 0, len(records))
+		buf     = new(bytes.Buffer)
+	)
+	name = strings.ToLower(name)
+	for path := range records {
+		if path != name {
+			names = append(names, path)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{name}, names...)
+
+	fmt.Fprintf(buf, "; enrtree %s seq %d\n", name, t.Seq())
+	for _, path := range names {
+		ttl := treeNodeTTL
+		if path == name {
+			ttl = rootTTL
+		}
+		fmt.Fprintf(buf, "%s.\t%d\tIN\tTXT\t%s\n", path, ttl, quoteZoneTXT(records[path]))
+	}
+	return buf.Bytes()
+}
+
+// quoteZoneTXT encodes a TXT value as quoted character-strings of at most 255 bytes.
+func quoteZoneTXT(value string) string {
+	var parts []string
+	for {
+		n := len(value)
+		if n > 255 {
+			n = 255
+		}
+		chunk := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value[:n])
+		parts = append(parts, `"`+chunk+`"`)
+		if value = value[n:]; value == "" {
+			return strings.Join(parts, " ")
+		}
+	}
+}
+
+// writeZoneFile writes a zone file to the given path, or to stdout if file is "-".
+func writeZoneFile(file string, zone []byte) {
+	if file == "-" {
+		os.Stdout.Write(zone)
+		return
+	}
+	if err := ioutil.WriteFile(file, zone, 0644); err != nil {
+		exit(err)
+	}
+}
@@ -44,6 +44,8 @@ var (
 			dnsCloudflareCommand,
 			dnsRoute53Command,
 			dnsRoute53NukeCommand,
+			dnsRFC2136Command,
+			dnsZoneFileCommand,
 		},
 	}
 	dnsSyncCommand = cli.Command{
@@ -51,7 +53,7 @@ var (
 		Usage:     "Download a DNS discovery tree",
 		ArgsUsage: "<url> [ <directory> ]",
 		Action:    dnsSync,
-		Flags:     []cli.Flag{dnsTimeoutFlag},
+		Flags:     []cli.Flag{dnsTimeoutFlag, dnsHTTPFlag},
 	}
 	dnsSignCommand = cli.Command{
 		Name:      "sign",
@@ -97,6 +99,26 @@ var (
 			route53RegionFlag,
 		},
 	}
+	dnsRFC2136Command = cli.Command{
+		Name:      "to-rfc2136",
+		Usage:     "Deploy DNS TXT records to a DNS server using dynamic updates (RFC 2136)",
+		ArgsUsage: "<tree-directory>",
+		Action:    dnsToRFC2136,
+		Flags: []cli.Flag{
+			rfc2136ServerFlag,
+			rfc2136ZoneFlag,
+			rfc2136TSIGKeyFlag,
+			rfc2136TSIGSecretFlag,
+			rfc2136TSIGAlgorithmFlag,
+			dnsTimeoutFlag,
+		},
+	}
+	dnsZoneFileCommand = cli.Command{
+		Name:      "to-zonefile",
+		Usage:     "Create a BIND zone file containing the DNS TXT records of a tree",
+		ArgsUsage: "<tree-directory> <output-file>",
+		Action:    dnsToZoneFile,
+	}
 )
 
 var (
@@ -112,6 +134,10 @@ var (
 		Name:  "seq",
 		Usage: "New sequence number of the tree",
 	}
+	dnsHTTPFlag = cli.StringFlag{
+		Name:  "http",
+		Usage: "URL of a TXT record document to fetch the tree from instead of DNS",
+	}
 )
 
 const (
@@ -241,6 +267,37 @@ func dnsToRoute53(ctx *cli.Context) error {
 	return client.deploy(domain, t)
 }
 
+// dnsToRFC2136 performs dnsRFC2136Command.
+func dnsToRFC2136(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need tree definition directory as argument")
+	}
+	dir := ctx.Args().Get(0)
+	_, t, err := loadTreeDefinitionForExport(dir)
+	if err != nil {
+		return err
+	}
+	client := newRFC2136Client(ctx)
+	return client.deploy(loadTreeDefinition(dir).Meta.URL, t)
+}
+
+// dnsToZoneFile performs dnsZoneFileCommand.
+func dnsToZoneFile(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need tree definition directory as argument")
+	}
+	output := ctx.Args().Get(1)
+	if output == "" {
+		output = "-" // default to stdout
+	}
+	domain, t, err := loadTreeDefinitionForExport(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	writeZoneFile(output, makeZoneFile(domain, t))
+	return nil
+}
+
 // dnsNukeRoute53 performs dnsRoute53NukeCommand.
 func dnsNukeRoute53(ctx *cli.Context) error {
 	if ctx.NArg() != 1 {
@@ -270,6 +327,9 @@ func dnsClient(ctx *cli.Context) *dnsdisc.Client {
 	if commandHasFlag(ctx, dnsTimeoutFlag) {
 		cfg.Timeout = ctx.Duration(dnsTimeoutFlag.Name)
 	}
+	if url := ctx.String(dnsHTTPFlag.Name); url != "" {
+		cfg.Resolver = dnsdisc.NewHTTPResolver(url, nil)
+	}
 	return dnsdisc.NewClient(cfg)
 }
 
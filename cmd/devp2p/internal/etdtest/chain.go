@@ -26,9 +26,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/forkid"
 	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
 	"github.com/crypyto-panel/go-etherdata/params"
 	"github.com/crypyto-panel/go-etherdata/rlp"
 )
@@ -103,39 +105,72 @@ func (c *Chain) GetHeaders(req GetBlockHeaders) (BlockHeaders, error) {
 	if req.Amount < 1 {
 		return nil, fmt.Errorf("no block headers requested")
 	}
-
-	headers := make(BlockHeaders, req.Amount)
+	amount := req.Amount
+	if amount > uint64(c.Len()) {
+		amount = uint64(c.Len())
+	}
+	headers := make(BlockHeaders, amount)
 	var blockNumber uint64
 
 	// range over blocks to check if our chain has the requested header
 	for _, block := range c.blocks {
-		if block.Hash() == req.Origin.Hash || block.Number().Uint64() == req.Origin.Number {
+		if req.Origin.Hash != (common.Hash{}) {
+			if block.Hash() == req.Origin.Hash {
+				headers[0] = block.Header()
+				blockNumber = block.NumberU64()
+			}
+		} else if block.NumberU64() == req.Origin.Number {
 			headers[0] = block.Header()
-			blockNumber = block.Number().Uint64()
+			blockNumber = block.NumberU64()
 		}
 	}
 	if headers[0] == nil {
 		return nil, fmt.Errorf("no headers found for given origin number %v, hash %v", req.Origin.Number, req.Origin.Hash)
 	}
 
+	// the response is cut short at either end of the chain
+	step := req.Skip + 1
 	if req.Reverse {
-		for i := 1; i < int(req.Amount); i++ {
-			blockNumber -= (1 - req.Skip)
+		for i := 1; i < len(headers); i++ {
+			if blockNumber < step {
+				return headers[:i], nil
+			}
+			blockNumber -= step
 			headers[i] = c.blocks[blockNumber].Header()
-
 		}
-
 		return headers, nil
 	}
 
-	for i := 1; i < int(req.Amount); i++ {
-		blockNumber += (1 + req.Skip)
+	for i := 1; i < len(headers); i++ {
+		blockNumber += step
+		if blockNumber >= uint64(c.Len()) {
+			return headers[:i], nil
+		}
 		headers[i] = c.blocks[blockNumber].Header()
 	}
 
 	return headers, nil
 }
 
+// GetBodies returns the bodies of the requested blocks. Unknown blocks
+// are skipped.
+func (c *Chain) GetBodies(req GetBlockBodies) BlockBodies {
+	index := make(map[common.Hash]*types.Block, c.Len())
+	for _, block := range c.blocks {
+		index[block.Hash()] = block
+	}
+	var bodies BlockBodies
+	for _, hash := range req {
+		if block, ok := index[hash]; ok {
+			bodies = append(bodies, &etd.BlockBody{
+				Transactions: block.Transactions(),
+				Uncles:       block.Uncles(),
+			})
+		}
+	}
+	return bodies
+}
+
 // loadChain takes the given chain.rlp file, and decodes and returns
 // the blocks from the file.
 func loadChain(chainfile string, genesis string) (*Chain, error) {
@@ -0,0 +1,216 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package etdtest
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/core/vm"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+// To regenerate the test chains, run this command:
+//
+//     go test -run TestWriteTestChains -write-test-chains -timeout 0
+//
+var writeTestChainsFlag = flag.Bool("write-test-chains", false, "Overwrite the test chains and genesis in testdata/")
+
+const (
+	testChainLength = 2000 // number of blocks in chain.rlp
+	halfChainLength = 999  // number of blocks in halfchain.rlp
+	reorgForkNumber = 500  // last block shared by chain.rlp and reorgchain.rlp
+	reorgLength     = 1000 // number of blocks in reorgchain.rlp after the fork point
+)
+
+// storageContract is the init code of a contract which writes three storage
+// slots and deploys code returning the first of them.
+var storageContract = common.FromHex("0x600160005560026001556003600255600b601b600039600b6000f360005460005260206000f3")
+
+// TestWriteTestChains regenerates genesis.json, chain.rlp, halfchain.rlp and
+// reorgchain.rlp. The blocks are sealed at the minimum difficulty, which needs
+// the full etdash dataset and takes a while.
+func TestWriteTestChains(t *testing.T) {
+	if !*writeTestChainsFlag {
+		t.Skip("-write-test-chains not set")
+	}
+	engine := etdash.New(etdash.Config{PowMode: etdash.ModeNormal, CachesInMem: 1, DatasetsInMem: 1}, nil, false)
+	defer engine.Close()
+
+	genesis, chain, reorg, err := makeTestChains(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(genesisFile, enc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeChainFile(t, fullchainFile, chain)
+	writeChainFile(t, halfchainFile, chain[:halfChainLength])
+	writeChainFile(t, reorgchainFile, append(chain[:reorgForkNumber:reorgForkNumber], reorg...))
+}
+
+// TestTestChains checks that the chain files in testdata are consistent with
+// each other and can be imported, verifying their seals.
+func TestTestChains(t *testing.T) {
+	chain, err := loadChain(fullchainFile, genesisFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	half, err := loadChain(halfchainFile, genesisFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if half.Head().Hash() != chain.blocks[half.Len()-1].Hash() {
+		t.Fatalf("half chain head %x not in full chain", half.Head().Hash())
+	}
+	suite := &Suite{chain: chain.Shorten(1000), fullChain: chain}
+	if err := suite.LoadReorgChain(reorgchainFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := suite.reorgForkPoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	chain.genesis.MustCommit(db)
+	engine := etdash.New(etdash.Config{PowMode: etdash.ModeNormal, CachesInMem: 1}, nil, false)
+	defer engine.Close()
+
+	bc, err := core.NewBlockChain(db, nil, chain.chainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Stop()
+	if n, err := bc.InsertChain(suite.chain.blocks[1:]); err != nil {
+		t.Fatalf("failed to import block %d: %v", n+1, err)
+	}
+	if n, err := bc.InsertChain(suite.reorgChain.blocks[reorgForkNumber+1:]); err != nil {
+		t.Fatalf("failed to import reorg block %d: %v", n+reorgForkNumber+1, err)
+	}
+	if head := bc.CurrentBlock().Hash(); head != suite.reorgChain.Head().Hash() {
+		t.Fatalf("wrong head after reorg: have %x, want %x", head, suite.reorgChain.Head().Hash())
+	}
+}
+
+// makeTestChains creates the genesis, the test chain and the blocks of the
+// reorg chain after the fork point. Every tenth block contains a transfer from
+// the faucet account, the first block deploys a contract with storage.
+func makeTestChains(engine *etdash.Ethash) (*core.Genesis, []*types.Block, []*types.Block, error) {
+	genesis := &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:             big.NewInt(19763),
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(0),
+			IstanbulBlock:       big.NewInt(0),
+			Ethash:              new(params.EthashConfig),
+		},
+		Nonce:      0xdeadbeefdeadbeef,
+		ExtraData:  make([]byte, 32),
+		GasLimit:   0x80000000,
+		Difficulty: params.MinimumDifficulty,
+		Alloc: core.GenesisAlloc{
+			crypto.PubkeyToAddress(faucetKey.PublicKey): {Balance: new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 256), common.Big1)},
+		},
+	}
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		signer = types.LatestSigner(genesis.Config)
+		faucet = crypto.PubkeyToAddress(faucetKey.PublicKey)
+	)
+	generate := func(seed byte) func(*core.BlockGen) {
+		return func(b *core.BlockGen) {
+			b.SetCoinbase(common.Address{seed})
+			var tx *types.Transaction
+			switch number := b.Number().Uint64(); {
+			case number == 1:
+				tx = types.NewContractCreation(b.TxNonce(faucet), new(big.Int), 200000, big.NewInt(params.GWei), storageContract)
+			case number%10 == 0:
+				to := common.BytesToAddress(crypto.Keccak256([]byte{seed}, b.Number().Bytes()))
+				tx = types.NewTransaction(b.TxNonce(faucet), to, big.NewInt(1), params.TxGas, big.NewInt(params.GWei), nil)
+			default:
+				return
+			}
+			signed, err := types.SignTx(tx, signer, faucetKey)
+			if err != nil {
+				panic(err)
+			}
+			b.AddTx(signed)
+		}
+	}
+	gblock := genesis.MustCommit(db)
+	chain, err := sealChain(genesis.Config, gblock, engine, db, testChainLength, generate(0))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	reorg, err := sealChain(genesis.Config, chain[reorgForkNumber-1], engine, db, reorgLength, generate(1))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return genesis, chain, reorg, nil
+}
+
+// sealChain creates n sealed blocks on top of parent. Sealing changes the hash
+// of a block, so every block has to be sealed before its child is generated.
+func sealChain(config *params.ChainConfig, parent *types.Block, engine *etdash.Ethash, db etddb.Database, n int, gen func(*core.BlockGen)) ([]*types.Block, error) {
+	blocks := make([]*types.Block, 0, n)
+	for i := 0; i < n; i++ {
+		generated, _ := core.GenerateChain(config, parent, engine, db, 1, func(_ int, b *core.BlockGen) { gen(b) })
+
+		results := make(chan *types.Block, 1)
+		if err := engine.Seal(nil, generated[0], results, nil); err != nil {
+			return nil, err
+		}
+		parent = <-results
+		blocks = append(blocks, parent)
+	}
+	return blocks, nil
+}
+
+func writeChainFile(t *testing.T, file string, blocks []*types.Block) {
+	fh, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	for _, block := range blocks {
+		if err := rlp.Encode(fh, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -129,6 +129,7 @@ func (c *Conn) handshake() error {
 			c.SetSnappy(true)
 		}
 		c.negotiateEthProtocol(msg.Caps)
+		c.negotiateSnapProtocol(msg.Caps)
 		if c.negotiatedProtoVersion == 0 {
 			return fmt.Errorf("unexpected etd protocol version")
 		}
@@ -349,6 +350,9 @@ func (c *Conn) waitForResponse(chain *Chain, timeout time.Duration, requestID ui
 		if id == requestID {
 			return msg
 		}
+		if _, ok := msg.(*Error); ok {
+			return msg
+		}
 	}
 }
 
@@ -0,0 +1,171 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package etdtest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/internal/utesting"
+)
+
+const (
+	// maxHeadersServe and maxBodiesServe are the maximum number of items a
+	// node is expected to serve in response to a single request.
+	maxHeadersServe = 1024
+	maxBodiesServe  = 1024
+
+	// maxMessageSize is the maximum size of an etd protocol message.
+	maxMessageSize = 10 * 1024 * 1024
+
+	// disconnectTimeout is the time to wait for a node to drop a misbehaving peer.
+	disconnectTimeout = 8 * time.Second
+)
+
+// etdMessageCodes are the message codes of all etd protocol messages, offset
+// by the devp2p base protocol.
+var etdMessageCodes = []int{
+	Status{}.Code(),
+	NewBlockHashes{}.Code(),
+	Transactions{}.Code(),
+	GetBlockHeaders{}.Code(),
+	BlockHeaders{}.Code(),
+	GetBlockBodies{}.Code(),
+	BlockBodies{}.Code(),
+	NewBlock{}.Code(),
+	NewPooledTransactionHashes{}.Code(),
+	GetPooledTransactions{}.Code(),
+	PooledTransactions{}.Code(),
+	GetNodeData{}.Code(),
+	NodeData{}.Code(),
+	GetReceipts{}.Code(),
+	Receipts{}.Code(),
+}
+
+// malformedPayloads returns message payloads which can't be decoded as any
+// etd protocol message.
+func malformedPayloads() [][]byte {
+	junk := make([]byte, 64)
+	rand.Read(junk)
+	return [][]byte{
+		{},                       // empty payload
+		{0x83, 0x01, 0x02, 0x03}, // string instead of list
+		{0xc8, 0x01, 0x02},       // list shorter than its size prefix
+		{0xc2, 0xc1, 0xc0},       // nested lists instead of values
+		{0xb9, 0x00, 0x01, 0x00}, // non-canonical size prefix
+		append([]byte{0xf9, 0xff, 0xff}, junk...), // random data with bogus size prefix
+	}
+}
+
+// malformedMessages sends malformed payloads for every etd message code and
+// checks that the node drops the connection each time. A message code outside
+// of the negotiated protocols is tested as well. Finally, the node must still
+// accept new peers.
+func (s *Suite) malformedMessages(t *utesting.T, isEth66 bool) error {
+	codes := append(etdMessageCodes, 0x7ff)
+	for _, code := range codes {
+		for i, payload := range malformedPayloads() {
+			t.Logf("Testing malformed message: code %d, payload %d\n", code, i)
+			conn, err := s.dialAndPeer(isEth66)
+			if err != nil {
+				return err
+			}
+			if _, err := conn.Conn.Write(uint64(code), payload); err != nil {
+				conn.Close()
+				return fmt.Errorf("could not write to connection: %v", err)
+			}
+			err = conn.waitForDisconnect(s.chain, isEth66)
+			conn.Close()
+			if err != nil {
+				return fmt.Errorf("malformed message (code %d, payload %d): %v", code, i, err)
+			}
+		}
+	}
+	conn, err := s.dialAndPeer(isEth66)
+	if err != nil {
+		return fmt.Errorf("node unusable after malformed messages: %v", err)
+	}
+	conn.Close()
+	return nil
+}
+
+// oversizedMessage sends a message exceeding the etd message size limit and
+// checks that the node drops the connection.
+func (s *Suite) oversizedMessage(isEth66 bool) error {
+	conn, err := s.dialAndPeer(isEth66)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	payload := largeBuffer(maxMessageSize/(1024*1024) + 1)
+	if _, err := conn.Conn.Write(uint64(GetBlockHeaders{}.Code()), payload); err != nil {
+		return fmt.Errorf("could not write to connection: %v", err)
+	}
+	return conn.waitForDisconnect(s.chain, isEth66)
+}
+
+// dialAndPeer dials the node and performs the protocol handshake and status
+// exchange.
+func (s *Suite) dialAndPeer(isEth66 bool) (*Conn, error) {
+	var (
+		conn *Conn
+		err  error
+	)
+	if isEth66 {
+		conn, err = s.dial66()
+	} else {
+		conn, err = s.dial()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %v", err)
+	}
+	if err := conn.peer(s.chain, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("peering failed: %v", err)
+	}
+	return conn, nil
+}
+
+// waitForDisconnect reads from the connection until the node disconnects,
+// ignoring broadcasts from previous tests. It fails if the node keeps the
+// connection open.
+func (c *Conn) waitForDisconnect(chain *Chain, isEth66 bool) error {
+	for {
+		var msg Message
+		if isEth66 {
+			_, msg = c.readAndServe66(chain, disconnectTimeout)
+		} else {
+			msg = c.readAndServe(chain, disconnectTimeout)
+		}
+		switch msg := msg.(type) {
+		case *Disconnect:
+			return nil
+		case *Error:
+			if strings.Contains(msg.Error(), "timeout") || strings.Contains(msg.Error(), "no message received") {
+				return fmt.Errorf("node did not disconnect: %v", msg)
+			}
+			// the connection was closed
+			return nil
+		case *NewBlockHashes, *NewBlock, *Transactions, *NewPooledTransactionHashes:
+			continue
+		default:
+			return fmt.Errorf("unexpected: %s, wanted disconnect", pretty.Sdump(msg))
+		}
+	}
+}
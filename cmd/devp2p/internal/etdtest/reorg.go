@@ -0,0 +1,181 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package etdtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
+	"github.com/crypyto-panel/go-etherdata/internal/utesting"
+)
+
+const (
+	reorgTimeout  = 2 * time.Minute // time limit for the node to switch to the reorg chain
+	reorgProbeID  = 0xdead          // request ID of head probes during the reorg
+	reorgProbeGap = time.Second     // interval between head probes
+)
+
+// LoadReorgChain loads a chain which forks off the test chain with a higher
+// total difficulty. It enables the reorg tests.
+func (s *Suite) LoadReorgChain(chainfile string) error {
+	genesis := s.fullChain.blocks[0]
+	blocks, err := blocksFromFile(chainfile, genesis)
+	if err != nil {
+		return err
+	}
+	if len(blocks) < 2 || blocks[1].ParentHash() != genesis.Hash() {
+		return fmt.Errorf("reorg chain does not start at genesis block %x", genesis.Hash())
+	}
+	s.reorgChain = &Chain{
+		genesis:     s.fullChain.genesis,
+		blocks:      blocks,
+		chainConfig: s.fullChain.chainConfig,
+	}
+	return nil
+}
+
+// ReorgTests returns the chain reorganization tests. They replace the chain of
+// the node, so they must run after all other tests. The list is empty unless a
+// reorg chain was loaded.
+func (s *Suite) ReorgTests() []utesting.Test {
+	if s.reorgChain == nil {
+		return nil
+	}
+	return []utesting.Test{
+		{Name: "TestLargeReorg66", Fn: s.TestLargeReorg66},
+	}
+}
+
+// TestLargeReorg66 announces the reorg chain with a status message and serves
+// it to the node. The node must sync it and switch its canonical chain, which
+// requires rolling back all blocks after the fork point.
+func (s *Suite) TestLargeReorg66(t *utesting.T) {
+	fork, err := s.reorgForkPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("reorging %d blocks at fork point %d, new head %d\n", s.chain.Len()-fork, fork, s.reorgChain.Len()-1)
+
+	conn, err := s.dial66()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.handshake(); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	status := &Status{
+		ProtocolVersion: uint32(conn.negotiatedProtoVersion),
+		NetworkID:       s.chain.chainConfig.ChainID.Uint64(),
+		TD:              s.reorgChain.TD(),
+		Head:            s.reorgChain.Head().Hash(),
+		Genesis:         s.chain.blocks[0].Hash(),
+		ForkID:          s.chain.ForkID(),
+	}
+	if _, err := conn.statusExchange(s.chain, status); err != nil {
+		t.Fatalf("status exchange failed: %v", err)
+	}
+	if err := conn.serveReorg(s.reorgChain); err != nil {
+		t.Fatalf("reorg failed: %v", err)
+	}
+	s.chain = s.reorgChain
+}
+
+// reorgForkPoint returns the number of the first block in which the reorg
+// chain differs from the test chain.
+func (s *Suite) reorgForkPoint() (int, error) {
+	for i := 1; i < s.chain.Len() && i < s.reorgChain.Len(); i++ {
+		if s.chain.blocks[i].Hash() != s.reorgChain.blocks[i].Hash() {
+			if s.reorgChain.TD().Cmp(s.chain.TD()) <= 0 {
+				return 0, fmt.Errorf("reorg chain total difficulty %v not higher than %v", s.reorgChain.TD(), s.chain.TD())
+			}
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("reorg chain does not fork off the test chain")
+}
+
+// serveReorg serves header and body requests from the given chain until the
+// node's canonical head is the head of the chain.
+func (c *Conn) serveReorg(chain *Chain) error {
+	defer c.SetReadDeadline(time.Time{})
+	var (
+		head      = chain.Head()
+		deadline  = time.Now().Add(reorgTimeout)
+		lastProbe time.Time
+	)
+	for time.Now().Before(deadline) {
+		// Query the node's canonical header at the height of the new head.
+		if time.Since(lastProbe) > reorgProbeGap {
+			probe := &etd.GetBlockHeadersPacket66{
+				RequestId: reorgProbeID,
+				GetBlockHeadersPacket: &etd.GetBlockHeadersPacket{
+					Origin: etd.HashOrNumber{Number: head.NumberU64()},
+					Amount: 1,
+				},
+			}
+			if err := c.Write66(probe, GetBlockHeaders{}.Code()); err != nil {
+				return fmt.Errorf("could not write to connection: %v", err)
+			}
+			lastProbe = time.Now()
+		}
+		c.SetReadDeadline(time.Now().Add(reorgProbeGap))
+		id, msg := c.Read66()
+		switch msg := msg.(type) {
+		case *Ping:
+			c.Write(&Pong{})
+		case GetBlockHeaders:
+			headers, err := chain.GetHeaders(msg)
+			if err != nil {
+				headers = BlockHeaders{}
+			}
+			resp := &etd.BlockHeadersPacket66{RequestId: id, BlockHeadersPacket: etd.BlockHeadersPacket(headers)}
+			if err := c.Write66(resp, BlockHeaders{}.Code()); err != nil {
+				return fmt.Errorf("could not write to connection: %v", err)
+			}
+		case GetBlockBodies:
+			resp := &etd.BlockBodiesPacket66{RequestId: id, BlockBodiesPacket: etd.BlockBodiesPacket(chain.GetBodies(msg))}
+			if err := c.Write66(resp, BlockBodies{}.Code()); err != nil {
+				return fmt.Errorf("could not write to connection: %v", err)
+			}
+		case GetReceipts:
+			// receipts are not part of the chain file, full sync doesn't need them
+			resp := &etd.ReceiptsPacket66{RequestId: id, ReceiptsPacket: etd.ReceiptsPacket{}}
+			if err := c.Write66(resp, Receipts{}.Code()); err != nil {
+				return fmt.Errorf("could not write to connection: %v", err)
+			}
+		case GetNodeData:
+			resp := &etd.NodeDataPacket66{RequestId: id, NodeDataPacket: etd.NodeDataPacket{}}
+			if err := c.Write66(resp, NodeData{}.Code()); err != nil {
+				return fmt.Errorf("could not write to connection: %v", err)
+			}
+		case BlockHeaders:
+			if id == reorgProbeID && len(msg) == 1 && msg[0].Hash() == head.Hash() {
+				return nil
+			}
+		case *Disconnect:
+			return fmt.Errorf("disconnect received: %v", msg.Reason)
+		case *Error:
+			if !strings.Contains(msg.Error(), "timeout") {
+				return msg
+			}
+		}
+	}
+	return fmt.Errorf("node did not switch to head %d (%x) within %v", head.NumberU64(), head.Hash(), reorgTimeout)
+}
@@ -0,0 +1,570 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package etdtest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/state"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etd/protocols/snap"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/internal/utesting"
+	"github.com/crypyto-panel/go-etherdata/light"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+	"github.com/crypyto-panel/go-etherdata/trie"
+)
+
+// snapRequestBytes is the response size limit used by the snap tests.
+const snapRequestBytes = 512 * 1024
+
+var (
+	zeroHash = common.Hash{}
+	ffHash   = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	midHash  = common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000")
+)
+
+// SnapTests returns the snap protocol tests. They should run after the etd
+// tests and only against nodes supporting snap/1.
+func (s *Suite) SnapTests() []utesting.Test {
+	return []utesting.Test{
+		{Name: "TestSnapStatus", Fn: s.TestSnapStatus},
+		{Name: "TestSnapGetAccountRange", Fn: s.TestSnapGetAccountRange},
+		{Name: "TestSnapGetStorageRanges", Fn: s.TestSnapGetStorageRanges},
+		{Name: "TestSnapGetByteCodes", Fn: s.TestSnapGetByteCodes},
+		{Name: "TestSnapGetTrieNodes", Fn: s.TestSnapGetTrieNodes},
+	}
+}
+
+// Is_Snap checks if the node supports the snap protocol.
+func (s *Suite) Is_Snap(t *utesting.T) {
+	conn, err := s.dialSnap()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.handshake(); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if conn.negotiatedSnapVersion == 0 {
+		t.Fail()
+	}
+}
+
+// TestSnapStatus attempts to connect to the given node and exchange
+// a status message with it, negotiating the snap protocol.
+func (s *Suite) TestSnapStatus(t *utesting.T) {
+	conn, err := s.snapPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+// TestSnapGetAccountRange tests the edge cases of account range requests. All
+// responses must be valid range proofs of the requested state root.
+func (s *Suite) TestSnapGetAccountRange(t *utesting.T) {
+	conn, err := s.snapPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	root := s.chain.Head().Root()
+	tests := []struct {
+		desc          string
+		root          common.Hash
+		origin, limit common.Hash
+		bytes         uint64
+		maxAccounts   int  // maximum number of accounts in the response, -1 if unlimited
+		empty         bool // whether the response must be empty
+	}{
+		{desc: "full range", root: root, origin: zeroHash, limit: ffHash, bytes: snapRequestBytes, maxAccounts: -1},
+		{desc: "zero byte limit", root: root, origin: zeroHash, limit: ffHash, bytes: 0, maxAccounts: 1},
+		{desc: "one byte limit", root: root, origin: zeroHash, limit: ffHash, bytes: 1, maxAccounts: 1},
+		{desc: "origin in the middle", root: root, origin: midHash, limit: ffHash, bytes: snapRequestBytes, maxAccounts: -1},
+		{desc: "limit before origin", root: root, origin: midHash, limit: zeroHash, bytes: snapRequestBytes, maxAccounts: 1},
+		{desc: "origin equals limit", root: root, origin: midHash, limit: midHash, bytes: snapRequestBytes, maxAccounts: 1},
+		{desc: "last possible origin", root: root, origin: ffHash, limit: ffHash, bytes: snapRequestBytes, maxAccounts: 1},
+		{desc: "unknown state root", root: randHash(), origin: zeroHash, limit: ffHash, bytes: snapRequestBytes, empty: true},
+	}
+	for i, tt := range tests {
+		t.Logf("Testing account range %d: %s\n", i, tt.desc)
+		req := &GetAccountRange{
+			ID:     uint64(i + 1),
+			Root:   tt.root,
+			Origin: tt.origin,
+			Limit:  tt.limit,
+			Bytes:  tt.bytes,
+		}
+		res, err := conn.accountRange(req)
+		if err != nil {
+			t.Fatalf("account range request %d failed: %v", i, err)
+		}
+		if tt.empty {
+			if len(res.Accounts) != 0 || len(res.Proof) != 0 {
+				t.Fatalf("test %d (%s): expected empty response, got %d accounts, %d proof nodes", i, tt.desc, len(res.Accounts), len(res.Proof))
+			}
+			continue
+		}
+		if tt.maxAccounts >= 0 && len(res.Accounts) > tt.maxAccounts {
+			t.Fatalf("test %d (%s): too many accounts: got %d, want at most %d", i, tt.desc, len(res.Accounts), tt.maxAccounts)
+		}
+		if _, _, err := verifyAccountRange(tt.root, tt.origin, res); err != nil {
+			t.Fatalf("test %d (%s): invalid response: %v", i, tt.desc, err)
+		}
+	}
+}
+
+// TestSnapGetStorageRanges tests storage range requests for all accounts of the
+// head state, for unknown accounts and for an unknown state root.
+func (s *Suite) TestSnapGetStorageRanges(t *utesting.T) {
+	conn, err := s.snapPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	root := s.chain.Head().Root()
+	hashes, accounts, err := conn.headAccounts(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := make([]common.Hash, len(accounts))
+	for i, acc := range accounts {
+		roots[i] = acc.Root
+	}
+	unknown := randHash()
+	tests := []struct {
+		desc     string
+		root     common.Hash
+		accounts []common.Hash
+		roots    []common.Hash // storage roots of the accounts, nil if the response must be empty
+	}{
+		{desc: "all accounts", root: root, accounts: hashes, roots: roots},
+		{desc: "unknown account", root: root, accounts: []common.Hash{unknown}, roots: []common.Hash{types.EmptyRootHash}},
+		{desc: "unknown state root", root: randHash(), accounts: hashes},
+	}
+	for i, tt := range tests {
+		t.Logf("Testing storage ranges %d: %s\n", i, tt.desc)
+		req := &GetStorageRanges{
+			ID:       uint64(i + 1),
+			Root:     tt.root,
+			Accounts: tt.accounts,
+			Bytes:    snapRequestBytes,
+		}
+		res, err := conn.storageRanges(req)
+		if err != nil {
+			t.Fatalf("storage ranges request %d failed: %v", i, err)
+		}
+		if tt.roots == nil {
+			if len(res.Slots) != 0 || len(res.Proof) != 0 {
+				t.Fatalf("test %d (%s): expected empty response, got %d slot sets, %d proof nodes", i, tt.desc, len(res.Slots), len(res.Proof))
+			}
+			continue
+		}
+		if err := verifyStorageRanges(tt.roots, res); err != nil {
+			t.Fatalf("test %d (%s): invalid response: %v", i, tt.desc, err)
+		}
+	}
+}
+
+// TestSnapGetByteCodes tests bytecode requests. The node must only return codes
+// matching the requested hashes, in request order.
+func (s *Suite) TestSnapGetByteCodes(t *utesting.T) {
+	conn, err := s.snapPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, accounts, err := conn.headAccounts(s.chain.Head().Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codeHashes []common.Hash
+	for _, acc := range accounts {
+		if hash := common.BytesToHash(acc.CodeHash); hash != emptyCodeHash {
+			codeHashes = append(codeHashes, hash)
+		}
+	}
+	tests := []struct {
+		desc   string
+		hashes []common.Hash
+		all    bool // whether all codes must be served
+	}{
+		{desc: "no hashes", all: true},
+		{desc: "unknown hashes", hashes: []common.Hash{randHash(), randHash()}},
+		{desc: "empty code", hashes: []common.Hash{emptyCodeHash}},
+		{desc: "head state codes", hashes: codeHashes, all: true},
+		{desc: "duplicate hashes", hashes: append(append([]common.Hash{}, codeHashes...), codeHashes...)},
+	}
+	for i, tt := range tests {
+		t.Logf("Testing bytecodes %d: %s\n", i, tt.desc)
+		req := &GetByteCodes{
+			ID:     uint64(i + 1),
+			Hashes: tt.hashes,
+			Bytes:  snapRequestBytes,
+		}
+		res, err := conn.byteCodes(req)
+		if err != nil {
+			t.Fatalf("bytecodes request %d failed: %v", i, err)
+		}
+		if err := verifyByteCodes(tt.hashes, res.Codes); err != nil {
+			t.Fatalf("test %d (%s): invalid response: %v", i, tt.desc, err)
+		}
+		if tt.all && len(res.Codes) != len(tt.hashes) {
+			t.Fatalf("test %d (%s): wrong number of codes: got %d, want %d", i, tt.desc, len(res.Codes), len(tt.hashes))
+		}
+	}
+}
+
+// TestSnapGetTrieNodes tests trie node requests. Requesting the root node must
+// return it, an unknown root must result in an empty response and an empty path
+// set is a protocol violation.
+func (s *Suite) TestSnapGetTrieNodes(t *utesting.T) {
+	conn, err := s.snapPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	root := s.chain.Head().Root()
+	// request the root node
+	res, err := conn.trieNodes(&GetTrieNodes{
+		ID:    1,
+		Root:  root,
+		Paths: []snap.TrieNodePathSet{{{0}}},
+		Bytes: snapRequestBytes,
+	})
+	if err != nil {
+		t.Fatalf("trie nodes request failed: %v", err)
+	}
+	if len(res.Nodes) != 1 || crypto.Keccak256Hash(res.Nodes[0]) != root {
+		t.Fatalf("wrong root node response: %s", pretty.Sdump(res))
+	}
+	// request from an unknown root
+	res, err = conn.trieNodes(&GetTrieNodes{
+		ID:    2,
+		Root:  randHash(),
+		Paths: []snap.TrieNodePathSet{{{0}}},
+		Bytes: snapRequestBytes,
+	})
+	if err != nil {
+		t.Fatalf("trie nodes request failed: %v", err)
+	}
+	if len(res.Nodes) != 0 {
+		t.Fatalf("expected empty response for unknown root, got %d nodes", len(res.Nodes))
+	}
+	// request an empty path set, the node should disconnect
+	req := &GetTrieNodes{
+		ID:    3,
+		Root:  root,
+		Paths: []snap.TrieNodePathSet{{}},
+		Bytes: snapRequestBytes,
+	}
+	if err := conn.Write(req); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(disconnectTimeout))
+	for {
+		switch msg := conn.readSnap().(type) {
+		case *Disconnect:
+			return
+		case *Error:
+			if strings.Contains(msg.Error(), "timeout") {
+				t.Fatalf("node did not disconnect after empty path set: %v", msg)
+			}
+			return
+		case *TrieNodes:
+			t.Fatalf("unexpected response to empty path set: %s", pretty.Sdump(msg))
+		}
+	}
+}
+
+// dialSnap dials the node with the etd66 and snap/1 capabilities.
+func (s *Suite) dialSnap() (*Conn, error) {
+	conn, err := s.dial66()
+	if err != nil {
+		return nil, err
+	}
+	conn.caps = append(conn.caps, p2p.Cap{Name: "snap", Version: 1})
+	return conn, nil
+}
+
+// snapPeer dials the node and peers with it, ensuring the snap protocol
+// was negotiated.
+func (s *Suite) snapPeer() (*Conn, error) {
+	conn, err := s.dialSnap()
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %v", err)
+	}
+	if err := conn.peer(s.chain, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("peering failed: %v", err)
+	}
+	if conn.negotiatedSnapVersion == 0 {
+		conn.Close()
+		return nil, fmt.Errorf("snap protocol not negotiated")
+	}
+	return conn, nil
+}
+
+// negotiateSnapProtocol sets the Conn's snap protocol version if both sides
+// support snap/1.
+func (c *Conn) negotiateSnapProtocol(caps []p2p.Cap) {
+	c.negotiatedSnapVersion = 0
+	var ours bool
+	for _, capability := range c.caps {
+		ours = ours || (capability.Name == "snap" && capability.Version == 1)
+	}
+	for _, capability := range caps {
+		if ours && capability.Name == "snap" && capability.Version == 1 {
+			c.negotiatedSnapVersion = 1
+		}
+	}
+}
+
+// readSnap reads the next snap protocol message from the connection. Pings are
+// answered and etd protocol messages are skipped.
+func (c *Conn) readSnap() Message {
+	for {
+		code, rawData, _, err := c.Conn.Read()
+		if err != nil {
+			return errorf("could not read from connection: %v", err)
+		}
+		var msg Message
+		switch int(code) {
+		case (Ping{}).Code():
+			c.Write(&Pong{})
+			continue
+		case (Disconnect{}).Code():
+			msg = new(Disconnect)
+		case (GetAccountRange{}).Code():
+			msg = new(GetAccountRange)
+		case (AccountRange{}).Code():
+			msg = new(AccountRange)
+		case (GetStorageRanges{}).Code():
+			msg = new(GetStorageRanges)
+		case (StorageRanges{}).Code():
+			msg = new(StorageRanges)
+		case (GetByteCodes{}).Code():
+			msg = new(GetByteCodes)
+		case (ByteCodes{}).Code():
+			msg = new(ByteCodes)
+		case (GetTrieNodes{}).Code():
+			msg = new(GetTrieNodes)
+		case (TrieNodes{}).Code():
+			msg = new(TrieNodes)
+		default:
+			if int(code) >= snapOffset {
+				return errorf("invalid message code: %d", code)
+			}
+			// etd protocol message, e.g. a broadcast
+			continue
+		}
+		if err := rlp.DecodeBytes(rawData, msg); err != nil {
+			return errorf("could not rlp decode message: %v", err)
+		}
+		return msg
+	}
+}
+
+// snapRequest sends a snap request and waits for the response with the given
+// request ID.
+func (c *Conn) snapRequest(req Message, id uint64) (Message, error) {
+	defer c.SetReadDeadline(time.Time{})
+	c.SetReadDeadline(time.Now().Add(timeout))
+
+	if err := c.Write(req); err != nil {
+		return nil, fmt.Errorf("could not write to connection: %v", err)
+	}
+	for {
+		switch msg := c.readSnap().(type) {
+		case *AccountRange:
+			if msg.ID == id {
+				return msg, nil
+			}
+		case *StorageRanges:
+			if msg.ID == id {
+				return msg, nil
+			}
+		case *ByteCodes:
+			if msg.ID == id {
+				return msg, nil
+			}
+		case *TrieNodes:
+			if msg.ID == id {
+				return msg, nil
+			}
+		case *Disconnect:
+			return nil, fmt.Errorf("disconnect received: %v", msg.Reason)
+		case *Error:
+			return nil, msg
+		default:
+			return nil, fmt.Errorf("unexpected: %s", pretty.Sdump(msg))
+		}
+	}
+}
+
+func (c *Conn) accountRange(req *GetAccountRange) (*AccountRange, error) {
+	msg, err := c.snapRequest(req, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*AccountRange), nil
+}
+
+func (c *Conn) storageRanges(req *GetStorageRanges) (*StorageRanges, error) {
+	msg, err := c.snapRequest(req, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*StorageRanges), nil
+}
+
+func (c *Conn) byteCodes(req *GetByteCodes) (*ByteCodes, error) {
+	msg, err := c.snapRequest(req, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*ByteCodes), nil
+}
+
+func (c *Conn) trieNodes(req *GetTrieNodes) (*TrieNodes, error) {
+	msg, err := c.snapRequest(req, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*TrieNodes), nil
+}
+
+// headAccounts retrieves and verifies the first range of accounts of the
+// given state.
+func (c *Conn) headAccounts(root common.Hash) ([]common.Hash, []*state.Account, error) {
+	res, err := c.accountRange(&GetAccountRange{
+		ID:    1000,
+		Root:  root,
+		Limit: ffHash,
+		Bytes: snapRequestBytes,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("account range request failed: %v", err)
+	}
+	hashes, blobs, err := verifyAccountRange(root, zeroHash, res)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid account range: %v", err)
+	}
+	if len(blobs) == 0 {
+		return nil, nil, fmt.Errorf("no accounts in state %x", root)
+	}
+	accounts := make([]*state.Account, len(blobs))
+	for i, blob := range blobs {
+		accounts[i] = new(state.Account)
+		if err := rlp.DecodeBytes(blob, accounts[i]); err != nil {
+			return nil, nil, fmt.Errorf("invalid account %x: %v", hashes[i], err)
+		}
+	}
+	return hashes, accounts, nil
+}
+
+// verifyAccountRange checks that the response is a valid range proof of the
+// state trie with the given root, starting at origin. It returns the accounts
+// in consensus format.
+func verifyAccountRange(root, origin common.Hash, res *AccountRange) ([]common.Hash, [][]byte, error) {
+	hashes, accounts, err := (*snap.AccountRangePacket)(res).Unpack()
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		if bytes.Compare(hash[:], origin[:]) < 0 {
+			return nil, nil, fmt.Errorf("account %x before origin %x", hash, origin)
+		}
+		keys[i] = common.CopyBytes(hash[:])
+	}
+	var end []byte
+	if len(keys) > 0 {
+		end = keys[len(keys)-1]
+	}
+	if _, err := trie.VerifyRangeProof(root, origin[:], end, keys, accounts, proofSet(res.Proof)); err != nil {
+		return nil, nil, fmt.Errorf("invalid range proof: %v", err)
+	}
+	return hashes, accounts, nil
+}
+
+// verifyStorageRanges checks the storage ranges response against the storage
+// roots of the requested accounts. Only the last range may be incomplete, in
+// which case it must come with a proof.
+func verifyStorageRanges(roots []common.Hash, res *StorageRanges) error {
+	if len(res.Slots) > len(roots) {
+		return fmt.Errorf("too many slot sets: got %d, want at most %d", len(res.Slots), len(roots))
+	}
+	hashes, slots := (*snap.StorageRangesPacket)(res).Unpack()
+	for i := range hashes {
+		keys := make([][]byte, len(hashes[i]))
+		for j, hash := range hashes[i] {
+			keys[j] = common.CopyBytes(hash[:])
+		}
+		if i < len(hashes)-1 || len(res.Proof) == 0 {
+			if _, err := trie.VerifyRangeProof(roots[i], nil, nil, keys, slots[i], nil); err != nil {
+				return fmt.Errorf("invalid storage range %d: %v", i, err)
+			}
+			continue
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("empty storage range %d with proof", i)
+		}
+		if _, err := trie.VerifyRangeProof(roots[i], zeroHash[:], keys[len(keys)-1], keys, slots[i], proofSet(res.Proof)); err != nil {
+			return fmt.Errorf("invalid storage range proof %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// verifyByteCodes checks that the codes match the requested hashes, in order.
+func verifyByteCodes(hashes []common.Hash, codes [][]byte) error {
+	next := 0
+	for i, code := range codes {
+		hash := crypto.Keccak256Hash(code)
+		for next < len(hashes) && hashes[next] != hash {
+			next++
+		}
+		if next == len(hashes) {
+			return fmt.Errorf("code %d (hash %x) was not requested or is out of order", i, hash)
+		}
+		next++
+	}
+	return nil
+}
+
+// proofSet converts a list of proof nodes into a database for proof verification.
+func proofSet(proof [][]byte) etddb.KeyValueReader {
+	nodes := make(light.NodeList, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return nodes.NodeSet()
+}
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
@@ -0,0 +1,59 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package etdtest
+
+import "github.com/crypyto-panel/go-etherdata/etd/protocols/snap"
+
+// snapOffset is the message code offset of the snap protocol. Capabilities are
+// sorted by name, so snap follows the etd protocol, which has 17 messages.
+const snapOffset = 16 + 17
+
+// GetAccountRange represents an account range query.
+type GetAccountRange snap.GetAccountRangePacket
+
+func (g GetAccountRange) Code() int { return snapOffset + snap.GetAccountRangeMsg }
+
+type AccountRange snap.AccountRangePacket
+
+func (a AccountRange) Code() int { return snapOffset + snap.AccountRangeMsg }
+
+// GetStorageRanges represents a storage slot query.
+type GetStorageRanges snap.GetStorageRangesPacket
+
+func (g GetStorageRanges) Code() int { return snapOffset + snap.GetStorageRangesMsg }
+
+type StorageRanges snap.StorageRangesPacket
+
+func (s StorageRanges) Code() int { return snapOffset + snap.StorageRangesMsg }
+
+// GetByteCodes represents a contract bytecode query.
+type GetByteCodes snap.GetByteCodesPacket
+
+func (g GetByteCodes) Code() int { return snapOffset + snap.GetByteCodesMsg }
+
+type ByteCodes snap.ByteCodesPacket
+
+func (b ByteCodes) Code() int { return snapOffset + snap.ByteCodesMsg }
+
+// GetTrieNodes represents a state trie node query.
+type GetTrieNodes snap.GetTrieNodesPacket
+
+func (g GetTrieNodes) Code() int { return snapOffset + snap.GetTrieNodesMsg }
+
+type TrieNodes snap.TrieNodesPacket
+
+func (t TrieNodes) Code() int { return snapOffset + snap.TrieNodesMsg }
@@ -17,6 +17,7 @@
 package etdtest
 
 import (
+	"math"
 	"time"
 
 	"github.com/crypyto-panel/go-etherdata/common"
@@ -30,8 +31,9 @@ import (
 type Suite struct {
 	Dest *enode.Node
 
-	chain     *Chain
-	fullChain *Chain
+	chain      *Chain
+	fullChain  *Chain
+	reorgChain *Chain
 }
 
 // NewSuite creates and returns a new etd-test suite that can
@@ -83,7 +85,18 @@ func (s *Suite) AllEthTests() []utesting.Test {
 		{Name: "TestMaliciousTx", Fn: s.TestMaliciousTx},
 		{Name: "TestMaliciousTx66", Fn: s.TestMaliciousTx66},
 		{Name: "TestLargeTxRequest66", Fn: s.TestLargeTxRequest66},
+		{Name: "TestPooledTxRetrieval66", Fn: s.TestPooledTxRetrieval66},
 		{Name: "TestNewPooledTxs66", Fn: s.TestNewPooledTxs66},
+		{Name: "TestUnknownPooledTxs66", Fn: s.TestUnknownPooledTxs66},
+		// request IDs and limits
+		{Name: "TestUnsolicitedResponse66", Fn: s.TestUnsolicitedResponse66},
+		{Name: "TestMismatchedRequestID66", Fn: s.TestMismatchedRequestID66},
+		{Name: "TestOversizedRequests66", Fn: s.TestOversizedRequests66},
+		// malformed messages
+		{Name: "TestMalformedMessages", Fn: s.TestMalformedMessages},
+		{Name: "TestMalformedMessages66", Fn: s.TestMalformedMessages66},
+		{Name: "TestOversizedMessage", Fn: s.TestOversizedMessage},
+		{Name: "TestOversizedMessage66", Fn: s.TestOversizedMessage66},
 	}
 }
 
@@ -100,6 +113,8 @@ func (s *Suite) EthTests() []utesting.Test {
 		{Name: "TestMaliciousStatus", Fn: s.TestMaliciousStatus},
 		{Name: "TestTransaction", Fn: s.TestTransaction},
 		{Name: "TestMaliciousTx", Fn: s.TestMaliciousTx},
+		{Name: "TestMalformedMessages", Fn: s.TestMalformedMessages},
+		{Name: "TestOversizedMessage", Fn: s.TestOversizedMessage},
 	}
 }
 
@@ -121,7 +136,14 @@ func (s *Suite) Eth66Tests() []utesting.Test {
 		{Name: "TestTransaction66", Fn: s.TestTransaction66},
 		{Name: "TestMaliciousTx66", Fn: s.TestMaliciousTx66},
 		{Name: "TestLargeTxRequest66", Fn: s.TestLargeTxRequest66},
+		{Name: "TestPooledTxRetrieval66", Fn: s.TestPooledTxRetrieval66},
 		{Name: "TestNewPooledTxs66", Fn: s.TestNewPooledTxs66},
+		{Name: "TestUnknownPooledTxs66", Fn: s.TestUnknownPooledTxs66},
+		{Name: "TestUnsolicitedResponse66", Fn: s.TestUnsolicitedResponse66},
+		{Name: "TestMismatchedRequestID66", Fn: s.TestMismatchedRequestID66},
+		{Name: "TestOversizedRequests66", Fn: s.TestOversizedRequests66},
+		{Name: "TestMalformedMessages66", Fn: s.TestMalformedMessages66},
+		{Name: "TestOversizedMessage66", Fn: s.TestOversizedMessage66},
 	}
 }
 
@@ -781,3 +803,232 @@ func (s *Suite) TestNewPooledTxs66(t *utesting.T) {
 		}
 	}
 }
+
+// TestMalformedMessages sends undecodable payloads for all etd message codes
+// and checks that the node drops the connection each time.
+func (s *Suite) TestMalformedMessages(t *utesting.T) {
+	if err := s.malformedMessages(t, etd65); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMalformedMessages66 sends undecodable payloads for all etd message codes
+// over the etd66 protocol and checks that the node drops the connection each time.
+func (s *Suite) TestMalformedMessages66(t *utesting.T) {
+	if err := s.malformedMessages(t, etd66); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOversizedMessage sends a message exceeding the maximum message size
+// and checks that the node drops the connection.
+func (s *Suite) TestOversizedMessage(t *utesting.T) {
+	if err := s.oversizedMessage(etd65); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOversizedMessage66 sends a message exceeding the maximum message size
+// over the etd66 protocol and checks that the node drops the connection.
+func (s *Suite) TestOversizedMessage66(t *utesting.T) {
+	if err := s.oversizedMessage(etd66); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOversizedRequests66 requests far more headers and bodies than a node
+// is allowed to serve and checks that the responses are capped and accurate.
+func (s *Suite) TestOversizedRequests66(t *utesting.T) {
+	conn, err := s.dial66()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	// request headers
+	req := &GetBlockHeaders{
+		Origin: etd.HashOrNumber{Number: 0},
+		Amount: 100 * maxHeadersServe,
+	}
+	headers, err := conn.headersRequest(req, s.chain, etd66, 77)
+	if err != nil {
+		t.Fatalf("could not get block headers: %v", err)
+	}
+	if len(headers) == 0 || len(headers) > maxHeadersServe {
+		t.Fatalf("wrong number of headers: got %d, want 1..%d", len(headers), maxHeadersServe)
+	}
+	req.Amount = uint64(len(headers))
+	expected, err := s.chain.GetHeaders(*req)
+	if err != nil {
+		t.Fatalf("failed to get expected headers: %v", err)
+	}
+	if !headersMatch(expected, headers) {
+		t.Fatalf("header mismatch: \nexpected %v \ngot %v", expected, headers)
+	}
+	// request bodies
+	hashes := make(etd.GetBlockBodiesPacket, 0, 2*maxBodiesServe)
+	for len(hashes) < 2*maxBodiesServe {
+		hashes = append(hashes, s.chain.blocks[1+len(hashes)%(s.chain.Len()-1)].Hash())
+	}
+	bodiesReq := &etd.GetBlockBodiesPacket66{
+		RequestId:            78,
+		GetBlockBodiesPacket: hashes,
+	}
+	if err := conn.Write66(bodiesReq, GetBlockBodies{}.Code()); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	msg := conn.waitForResponse(s.chain, timeout, bodiesReq.RequestId)
+	bodies, ok := msg.(BlockBodies)
+	if !ok {
+		t.Fatalf("unexpected: %s", pretty.Sdump(msg))
+	}
+	if len(bodies) == 0 || len(bodies) > maxBodiesServe {
+		t.Fatalf("wrong number of bodies: got %d, want 1..%d", len(bodies), maxBodiesServe)
+	}
+}
+
+// TestUnsolicitedResponse66 sends responses the node never requested and
+// checks that the node ignores them and keeps serving the connection.
+func (s *Suite) TestUnsolicitedResponse66(t *utesting.T) {
+	conn, err := s.dial66()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	headers := &etd.BlockHeadersPacket66{
+		RequestId:          999,
+		BlockHeadersPacket: etd.BlockHeadersPacket{s.chain.blocks[1].Header(), s.chain.blocks[2].Header()},
+	}
+	if err := conn.Write66(headers, BlockHeaders{}.Code()); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	bodies := &etd.BlockBodiesPacket66{
+		RequestId:         1000,
+		BlockBodiesPacket: etd.BlockBodiesPacket(s.chain.GetBodies(GetBlockBodies{s.chain.blocks[1].Hash()})),
+	}
+	if err := conn.Write66(bodies, BlockBodies{}.Code()); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	// the connection must still be usable
+	req := &GetBlockHeaders{
+		Origin: etd.HashOrNumber{Number: 3},
+		Amount: 2,
+	}
+	got, err := conn.headersRequest(req, s.chain, etd66, 1001)
+	if err != nil {
+		t.Fatalf("request after unsolicited responses failed: %v", err)
+	}
+	expected, err := s.chain.GetHeaders(*req)
+	if err != nil {
+		t.Fatalf("failed to get expected headers: %v", err)
+	}
+	if !headersMatch(expected, got) {
+		t.Fatalf("header mismatch: \nexpected %v \ngot %v", expected, got)
+	}
+}
+
+// TestMismatchedRequestID66 answers a header request of the node with a
+// different request ID. The node must not drop the connection over it and
+// must answer subsequent requests, including ones with large IDs, with the
+// correct IDs.
+func (s *Suite) TestMismatchedRequestID66(t *utesting.T) {
+	conn, err := s.dial66()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	// announce a block hash and wait for the node to request the header
+	nextBlock := s.fullChain.blocks[s.chain.Len()]
+	announce := &NewBlockHashes{{Hash: nextBlock.Hash(), Number: nextBlock.NumberU64()}}
+	if err := conn.Write(announce); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		id, msg := conn.Read66()
+		if req, ok := msg.(GetBlockHeaders); ok && req.Origin.Hash == nextBlock.Hash() {
+			resp := &etd.BlockHeadersPacket66{
+				RequestId:          id + 1,
+				BlockHeadersPacket: etd.BlockHeadersPacket{},
+			}
+			if err := conn.Write66(resp, BlockHeaders{}.Code()); err != nil {
+				t.Fatalf("could not write to connection: %v", err)
+			}
+			break
+		}
+		switch msg := msg.(type) {
+		case *Ping:
+			conn.Write(&Pong{})
+		case *NewPooledTransactionHashes, *Transactions, *NewBlockHashes, *NewBlock:
+			continue
+		default:
+			t.Fatalf("unexpected: %s", pretty.Sdump(msg))
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+	// the connection must still be usable
+	for _, id := range []uint64{1 << 32, math.MaxUint64} {
+		req := &GetBlockHeaders{Origin: etd.HashOrNumber{Number: 1}, Amount: 1}
+		headers, err := conn.headersRequest(req, s.chain, etd66, id)
+		if err != nil {
+			t.Fatalf("request with ID %d failed: %v", id, err)
+		}
+		if len(headers) != 1 || headers[0].Hash() != s.chain.blocks[1].Hash() {
+			t.Fatalf("wrong response to request with ID %d: %s", id, pretty.Sdump(headers))
+		}
+	}
+}
+
+// TestPooledTxRetrieval66 announces transactions with NewPooledTransactionHashes,
+// serves the node's GetPooledTransactions request and checks that the node
+// propagates and serves the retrieved transactions.
+func (s *Suite) TestPooledTxRetrieval66(t *utesting.T) {
+	// send the next block to ensure the node is no longer syncing and
+	// is able to accept txs
+	if err := s.sendNextBlock(etd66); err != nil {
+		t.Fatalf("failed to send next block: %v", err)
+	}
+	_, txs, err := generateTxs(s, 20)
+	if err != nil {
+		t.Fatalf("failed to generate transactions: %v", err)
+	}
+	if err := sendPooledTxAnnouncement(t, s, txs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestUnknownPooledTxs66 requests transactions the node doesn't know about
+// and expects an empty response with the same request ID.
+func (s *Suite) TestUnknownPooledTxs66(t *utesting.T) {
+	conn, err := s.dial66()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	req := &etd.GetPooledTransactionsPacket66{
+		RequestId:                   5678,
+		GetPooledTransactionsPacket: []common.Hash{randHash(), randHash(), randHash()},
+	}
+	if err := conn.Write66(req, GetPooledTransactions{}.Code()); err != nil {
+		t.Fatalf("could not write to conn: %v", err)
+	}
+	switch msg := conn.waitForResponse(s.chain, timeout, req.RequestId).(type) {
+	case PooledTransactions:
+		if len(msg) != 0 {
+			t.Fatalf("unexpected txs in response: %s", pretty.Sdump(msg))
+		}
+	default:
+		t.Fatalf("unexpected %s", pretty.Sdump(msg))
+	}
+}
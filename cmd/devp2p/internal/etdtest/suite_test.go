@@ -29,9 +29,10 @@ import (
 )
 
 var (
-	genesisFile   = "./testdata/genesis.json"
-	halfchainFile = "./testdata/halfchain.rlp"
-	fullchainFile = "./testdata/chain.rlp"
+	genesisFile    = "./testdata/genesis.json"
+	halfchainFile  = "./testdata/halfchain.rlp"
+	fullchainFile  = "./testdata/chain.rlp"
+	reorgchainFile = "./testdata/reorgchain.rlp"
 )
 
 func TestEthSuite(t *testing.T) {
@@ -45,7 +46,13 @@ func TestEthSuite(t *testing.T) {
 	if err != nil {
 		t.Fatalf("could not create new test suite: %v", err)
 	}
-	for _, test := range suite.AllEthTests() {
+	if err := suite.LoadReorgChain(reorgchainFile); err != nil {
+		t.Fatalf("could not load reorg chain: %v", err)
+	}
+	// The reorg test replaces the chain of the node, so it has to run last.
+	tests := append(suite.AllEthTests(), suite.SnapTests()...)
+	tests = append(tests, suite.ReorgTests()...)
+	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
 			result := utesting.RunTAP([]utesting.Test{{Name: test.Name, Fn: test.Fn}}, os.Stdout)
 			if result[0].Failed {
@@ -25,6 +25,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
 	"github.com/crypyto-panel/go-etherdata/internal/utesting"
 	"github.com/crypyto-panel/go-etherdata/params"
 )
@@ -417,3 +418,104 @@ func signWithFaucet(chainConfig *params.ChainConfig, tx *types.Transaction) *typ
 	}
 	return signedTx
 }
+
+// sendPooledTxAnnouncement announces the given transactions to the node using
+// NewPooledTransactionHashes, serves the resulting GetPooledTransactions requests
+// and waits for the node to propagate the transactions to another peer. It then
+// checks that the node serves the transactions itself.
+func sendPooledTxAnnouncement(t *utesting.T, s *Suite, txs []*types.Transaction) error {
+	sendConn, recvConn, err := s.createSendAndRecvConns(true)
+	if err != nil {
+		return err
+	}
+	defer sendConn.Close()
+	defer recvConn.Close()
+	if err = sendConn.peer(s.chain, nil); err != nil {
+		return fmt.Errorf("peering failed: %v", err)
+	}
+	if err = recvConn.peer(s.chain, nil); err != nil {
+		return fmt.Errorf("peering failed: %v", err)
+	}
+	// Announce the transactions
+	var (
+		hashes   = make([]common.Hash, len(txs))
+		txByHash = make(map[common.Hash]*types.Transaction, len(txs))
+	)
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+		txByHash[tx.Hash()] = tx
+	}
+	if err = sendConn.Write(NewPooledTransactionHashes(hashes)); err != nil {
+		return fmt.Errorf("failed to write to connection: %v", err)
+	}
+	nonce = txs[len(txs)-1].Nonce()
+
+	// Serve the node's requests until all announced transactions are retrieved
+	for served := 0; served < len(txs); {
+		id, msg := sendConn.readAndServe66(s.chain, timeout)
+		switch msg := msg.(type) {
+		case GetPooledTransactions:
+			var resp etd.PooledTransactionsPacket
+			for _, hash := range msg {
+				tx, ok := txByHash[hash]
+				if !ok {
+					return fmt.Errorf("node requested unannounced tx %v", hash)
+				}
+				resp = append(resp, tx)
+			}
+			served += len(resp)
+			packet := &etd.PooledTransactionsPacket66{RequestId: id, PooledTransactionsPacket: resp}
+			if err := sendConn.Write66(packet, PooledTransactions{}.Code()); err != nil {
+				return fmt.Errorf("failed to write to connection: %v", err)
+			}
+		case *NewPooledTransactionHashes, *Transactions, *NewBlockHashes, *NewBlock:
+			// ignore broadcasts from previous tests
+			continue
+		default:
+			return fmt.Errorf("unexpected message while waiting for tx request: %s", pretty.Sdump(msg))
+		}
+	}
+	t.Logf("served %d announced txs", len(txs))
+
+	// Wait for the transactions to be propagated
+	var recvHashes []common.Hash
+	for {
+		_, msg := recvConn.readAndServe66(s.chain, timeout)
+		switch msg := msg.(type) {
+		case *Transactions:
+			for _, tx := range *msg {
+				recvHashes = append(recvHashes, tx.Hash())
+			}
+		case *NewPooledTransactionHashes:
+			recvHashes = append(recvHashes, *msg...)
+		case *NewBlockHashes, *NewBlock:
+			continue
+		default:
+			return fmt.Errorf("unexpected message while waiting for tx propagation: %s", pretty.Sdump(msg))
+		}
+		if _, missing := compareReceivedTxs(recvHashes, txs); len(missing) == 0 {
+			break
+		}
+	}
+	// Retrieve the transactions from the node
+	req := &etd.GetPooledTransactionsPacket66{
+		RequestId:                   4321,
+		GetPooledTransactionsPacket: hashes,
+	}
+	if err = recvConn.Write66(req, GetPooledTransactions{}.Code()); err != nil {
+		return fmt.Errorf("failed to write to connection: %v", err)
+	}
+	switch msg := recvConn.waitForResponse(s.chain, timeout, req.RequestId).(type) {
+	case PooledTransactions:
+		got := make([]common.Hash, len(msg))
+		for i, tx := range msg {
+			got[i] = tx.Hash()
+		}
+		if _, missing := compareReceivedTxs(got, txs); len(missing) > 0 {
+			return fmt.Errorf("node did not serve %d of the retrieved txs", len(missing))
+		}
+	default:
+		return fmt.Errorf("unexpected %s", pretty.Sdump(msg))
+	}
+	return nil
+}
@@ -123,6 +123,24 @@ type PooledTransactions etd.PooledTransactionsPacket
 
 func (pt PooledTransactions) Code() int { return 26 }
 
+// GetNodeData represents a state trie node query.
+type GetNodeData etd.GetNodeDataPacket
+
+func (gnd GetNodeData) Code() int { return 29 }
+
+type NodeData etd.NodeDataPacket
+
+func (nd NodeData) Code() int { return 30 }
+
+// GetReceipts represents a block receipts query.
+type GetReceipts etd.GetReceiptsPacket
+
+func (gr GetReceipts) Code() int { return 31 }
+
+type Receipts etd.ReceiptsPacket
+
+func (r Receipts) Code() int { return 32 }
+
 // Conn represents an individual connection with a peer
 type Conn struct {
 	*rlpx.Conn
@@ -130,6 +148,7 @@ type Conn struct {
 	negotiatedProtoVersion uint
 	ourHighestProtoVersion uint
 	caps                   []p2p.Cap
+	negotiatedSnapVersion  uint
 }
 
 // Read reads an etd packet from the connection.
@@ -171,6 +190,14 @@ func (c *Conn) Read() Message {
 		msg = new(GetPooledTransactions)
 	case (PooledTransactions{}.Code()):
 		msg = new(PooledTransactions)
+	case (GetNodeData{}.Code()):
+		msg = new(GetNodeData)
+	case (NodeData{}.Code()):
+		msg = new(NodeData)
+	case (GetReceipts{}.Code()):
+		msg = new(GetReceipts)
+	case (Receipts{}.Code()):
+		msg = new(Receipts)
 	default:
 		return errorf("invalid message code: %d", code)
 	}
@@ -244,6 +271,30 @@ func (c *Conn) Read66() (uint64, Message) {
 			return 0, errorf("could not rlp decode message: %v", err)
 		}
 		return etdMsg.RequestId, PooledTransactions(etdMsg.PooledTransactionsPacket)
+	case (GetNodeData{}.Code()):
+		etdMsg := new(etd.GetNodeDataPacket66)
+		if err := rlp.DecodeBytes(rawData, etdMsg); err != nil {
+			return 0, errorf("could not rlp decode message: %v", err)
+		}
+		return etdMsg.RequestId, GetNodeData(etdMsg.GetNodeDataPacket)
+	case (NodeData{}.Code()):
+		etdMsg := new(etd.NodeDataPacket66)
+		if err := rlp.DecodeBytes(rawData, etdMsg); err != nil {
+			return 0, errorf("could not rlp decode message: %v", err)
+		}
+		return etdMsg.RequestId, NodeData(etdMsg.NodeDataPacket)
+	case (GetReceipts{}.Code()):
+		etdMsg := new(etd.GetReceiptsPacket66)
+		if err := rlp.DecodeBytes(rawData, etdMsg); err != nil {
+			return 0, errorf("could not rlp decode message: %v", err)
+		}
+		return etdMsg.RequestId, GetReceipts(etdMsg.GetReceiptsPacket)
+	case (Receipts{}.Code()):
+		etdMsg := new(etd.ReceiptsPacket66)
+		if err := rlp.DecodeBytes(rawData, etdMsg); err != nil {
+			return 0, errorf("could not rlp decode message: %v", err)
+		}
+		return etdMsg.RequestId, Receipts(etdMsg.ReceiptsPacket)
 	default:
 		msg = errorf("invalid message code: %d", code)
 	}
@@ -58,6 +58,7 @@ func init() {
 	// Add subcommands.
 	app.Commands = []cli.Command{
 		enrdumpCommand,
+		crawlerCommand,
 		keyCommand,
 		discv4Command,
 		discv5Command,
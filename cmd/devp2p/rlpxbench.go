@@ -0,0 +1,393 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/metrics"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/rlpx"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rlpxBenchCommand = cli.Command{
+		Name:   "bench",
+		Usage:  "Measures RLPx transport throughput and latency over loopback",
+		Action: rlpxBench,
+		Flags: []cli.Flag{
+			benchMessagesFlag,
+			benchSizeFlag,
+			benchPatternFlag,
+			benchCompressibleFlag,
+			benchSnappyFlag,
+		},
+	}
+)
+
+var (
+	benchMessagesFlag = cli.IntFlag{
+		Name:  "messages",
+		Usage: "Number of messages to send",
+		Value: 10000,
+	}
+	benchSizeFlag = cli.IntFlag{
+		Name:  "size",
+		Usage: "Message payload size in bytes (maximum size for the random pattern)",
+		Value: 1024,
+	}
+	benchPatternFlag = cli.StringFlag{
+		Name:  "pattern",
+		Usage: "Traffic pattern (stream, pingpong, random)",
+		Value: "stream",
+	}
+	benchCompressibleFlag = cli.BoolFlag{
+		Name:  "compressible",
+		Usage: "Send zero-filled instead of random payloads",
+	}
+	benchSnappyFlag = cli.BoolTFlag{
+		Name:  "snappy",
+		Usage: "Enable snappy compression of messages",
+	}
+)
+
+const (
+	benchDataMsg = 0x00 // payload sent by the benchmark driver
+	benchEchoMsg = 0x01 // payload echoed back in the pingpong pattern
+
+	benchHeaderSize  = 8 // send timestamp prepended to every payload
+	benchDialTimeout = 10 * time.Second
+)
+
+// benchConfig is the configuration of a single benchmark run.
+type benchConfig struct {
+	messages     int
+	size         int
+	pattern      string
+	compressible bool
+	snappy       bool
+}
+
+// benchResult holds the measurements of a benchmark run.
+type benchResult struct {
+	elapsed   time.Duration   // time from the first send until the last delivery
+	messages  int             // number of messages delivered
+	payload   uint64          // payload bytes delivered, in both directions
+	wire      uint64          // bytes written to the wire by both connections
+	cpu       time.Duration   // process CPU time consumed during the run
+	latencies []time.Duration // one-way latency (stream, random) or round trip time (pingpong)
+}
+
+func rlpxBench(ctx *cli.Context) error {
+	cfg := benchConfig{
+		messages:     ctx.Int(benchMessagesFlag.Name),
+		size:         ctx.Int(benchSizeFlag.Name),
+		pattern:      ctx.String(benchPatternFlag.Name),
+		compressible: ctx.Bool(benchCompressibleFlag.Name),
+		snappy:       ctx.BoolT(benchSnappyFlag.Name),
+	}
+	// Disable logging unless explicitly enabled.
+	if !ctx.GlobalIsSet("verbosity") && !ctx.GlobalIsSet("vmodule") {
+		log.Root().SetHandler(log.DiscardHandler())
+	}
+	res, err := runRLPxBench(cfg)
+	if err != nil {
+		return err
+	}
+	res.print(cfg)
+	return nil
+}
+
+// runRLPxBench connects two RLPx connections over loopback and pushes the
+// configured message pattern from the first to the second one.
+func runRLPxBench(cfg benchConfig) (*benchResult, error) {
+	if cfg.messages <= 0 {
+		return nil, errors.New("message count must be positive")
+	}
+	if cfg.size < benchHeaderSize {
+		return nil, fmt.Errorf("message size must be at least %d bytes", benchHeaderSize)
+	}
+	switch cfg.pattern {
+	case "stream", "pingpong", "random":
+	default:
+		return nil, fmt.Errorf("unknown traffic pattern %q", cfg.pattern)
+	}
+	sender, receiver, err := dialBenchConns(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer sender.conn.Close()
+	defer receiver.conn.Close()
+
+	// The receiver records one-way latencies, or echoes everything back to the
+	// sender when measuring round trips.
+	var (
+		recvErr = make(chan error, 1)
+		latency []time.Duration
+		payload uint64
+	)
+	go func() {
+		recvErr <- benchReceive(cfg, receiver, &latency, &payload)
+	}()
+
+	// Run the benchmark.
+	var cpuStart, cpuEnd metrics.CPUStats
+	metrics.ReadCPUStats(&cpuStart)
+	start := time.Now()
+
+	var rtts []time.Duration
+	if cfg.pattern == "pingpong" {
+		rtts, err = benchPingPong(cfg, sender)
+		payload += uint64(cfg.messages * cfg.size)
+	} else {
+		err = benchStream(cfg, sender)
+	}
+	if err == nil {
+		err = <-recvErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	metrics.ReadCPUStats(&cpuEnd)
+
+	res := &benchResult{
+		elapsed:   elapsed,
+		messages:  cfg.messages,
+		payload:   payload,
+		wire:      sender.wire + receiver.wire,
+		cpu:       time.Duration(cpuEnd.LocalTime-cpuStart.LocalTime) * 10 * time.Millisecond,
+		latencies: latency,
+	}
+	if cfg.pattern == "pingpong" {
+		res.latencies = rtts
+	}
+	return res, nil
+}
+
+// benchConn is an RLPx connection exchanging benchmark messages. It counts the
+// bytes it writes to the wire.
+type benchConn struct {
+	conn *rlpx.Conn
+	wbuf []byte
+	wire uint64
+}
+
+// dialBenchConns connects two RLPx connections over loopback and runs the
+// encryption handshake on them, with compression set up as configured.
+func dialBenchConns(cfg benchConfig) (*benchConn, *benchConn, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer listener.Close()
+
+	var (
+		senderKey, _   = crypto.GenerateKey()
+		receiverKey, _ = crypto.GenerateKey()
+		accepted       = make(chan *rlpx.Conn, 1)
+		acceptErr      = make(chan error, 1)
+	)
+	go func() {
+		fd, err := listener.Accept()
+		if err != nil {
+			acceptErr <- err
+			return
+		}
+		conn := rlpx.NewConn(fd, nil)
+		if _, err := conn.Handshake(receiverKey); err != nil {
+			conn.Close()
+			acceptErr <- err
+			return
+		}
+		accepted <- conn
+	}()
+	fd, err := net.DialTimeout("tcp", listener.Addr().String(), benchDialTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender := rlpx.NewConn(fd, &receiverKey.PublicKey)
+	fd.SetDeadline(time.Now().Add(benchDialTimeout))
+	if _, err := sender.Handshake(senderKey); err != nil {
+		sender.Close()
+		return nil, nil, err
+	}
+	fd.SetDeadline(time.Time{})
+
+	var receiver *rlpx.Conn
+	select {
+	case receiver = <-accepted:
+	case err := <-acceptErr:
+		sender.Close()
+		return nil, nil, err
+	}
+	sender.SetSnappy(cfg.snappy)
+	receiver.SetSnappy(cfg.snappy)
+	return &benchConn{conn: sender}, &benchConn{conn: receiver}, nil
+}
+
+// ReadMsg implements p2p.MsgReader. The payload of the returned message is only
+// valid until the next call.
+func (c *benchConn) ReadMsg() (p2p.Msg, error) {
+	code, data, _, err := c.conn.Read()
+	if err != nil {
+		return p2p.Msg{}, err
+	}
+	return p2p.Msg{Code: code, Size: uint32(len(data)), Payload: bytes.NewReader(data), ReceivedAt: time.Now()}, nil
+}
+
+// WriteMsg implements p2p.MsgWriter.
+func (c *benchConn) WriteMsg(msg p2p.Msg) error {
+	if cap(c.wbuf) < int(msg.Size) {
+		c.wbuf = make([]byte, msg.Size)
+	}
+	data := c.wbuf[:msg.Size]
+	if _, err := io.ReadFull(msg.Payload, data); err != nil {
+		return err
+	}
+	size, err := c.conn.Write(msg.Code, data)
+	c.wire += uint64(size)
+	return err
+}
+
+// benchPayload creates the message buffer of the configured maximum size.
+func benchPayload(cfg benchConfig) []byte {
+	buf := make([]byte, cfg.size)
+	if !cfg.compressible {
+		rand.Read(buf)
+	}
+	return buf
+}
+
+// benchStream sends all messages back to back without waiting for replies.
+func benchStream(cfg benchConfig, rw p2p.MsgReadWriter) error {
+	buf := benchPayload(cfg)
+	for i := 0; i < cfg.messages; i++ {
+		size := cfg.size
+		if cfg.pattern == "random" {
+			size = benchHeaderSize + mrand.Intn(cfg.size-benchHeaderSize+1)
+		}
+		if err := benchSend(rw, benchDataMsg, buf[:size]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchPingPong sends one message at a time and waits for its echo, returning
+// the measured round trip times.
+func benchPingPong(cfg benchConfig, rw p2p.MsgReadWriter) ([]time.Duration, error) {
+	var (
+		buf  = benchPayload(cfg)
+		rtts = make([]time.Duration, 0, cfg.messages)
+	)
+	for i := 0; i < cfg.messages; i++ {
+		start := time.Now()
+		if err := benchSend(rw, benchDataMsg, buf); err != nil {
+			return nil, err
+		}
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return nil, err
+		}
+		msg.Discard()
+		if msg.Code != benchEchoMsg {
+			return nil, fmt.Errorf("unexpected message code %d", msg.Code)
+		}
+		rtts = append(rtts, time.Since(start))
+	}
+	return rtts, nil
+}
+
+// benchReceive consumes the configured number of messages on the receiving side.
+func benchReceive(cfg benchConfig, rw p2p.MsgReadWriter, latency *[]time.Duration, payload *uint64) error {
+	buf := make([]byte, cfg.size)
+	for i := 0; i < cfg.messages; i++ {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Code != benchDataMsg || msg.Size < benchHeaderSize || int(msg.Size) > cfg.size {
+			msg.Discard()
+			return fmt.Errorf("invalid benchmark message (code %d, size %d)", msg.Code, msg.Size)
+		}
+		data := buf[:msg.Size]
+		if _, err := io.ReadFull(msg.Payload, data); err != nil {
+			return err
+		}
+		msg.Discard()
+		*payload += uint64(msg.Size)
+
+		if cfg.pattern == "pingpong" {
+			if err := benchSend(rw, benchEchoMsg, data); err != nil {
+				return err
+			}
+			continue
+		}
+		sent := time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+		*latency = append(*latency, time.Since(sent))
+	}
+	return nil
+}
+
+// benchSend stamps the payload with the current time and writes it.
+func benchSend(rw p2p.MsgReadWriter, code uint64, data []byte) error {
+	if code == benchDataMsg {
+		binary.BigEndian.PutUint64(data, uint64(time.Now().UnixNano()))
+	}
+	return rw.WriteMsg(p2p.Msg{Code: code, Size: uint32(len(data)), Payload: bytes.NewReader(data)})
+}
+
+// print writes the benchmark report to stdout.
+func (res *benchResult) print(cfg benchConfig) {
+	secs := res.elapsed.Seconds()
+	fmt.Printf("pattern:      %s (snappy=%t, compressible=%t, size=%d)\n", cfg.pattern, cfg.snappy, cfg.compressible, cfg.size)
+	fmt.Printf("messages:     %d in %v\n", res.messages, res.elapsed)
+	fmt.Printf("throughput:   %.0f msg/s, %.2f MB/s payload, %.2f MB/s wire\n",
+		float64(res.messages)/secs, float64(res.payload)/secs/1e6, float64(res.wire)/secs/1e6)
+	if res.payload > 0 {
+		fmt.Printf("wire ratio:   %.3f (%d wire / %d payload bytes)\n", float64(res.wire)/float64(res.payload), res.wire, res.payload)
+	}
+	kind := "latency"
+	if cfg.pattern == "pingpong" {
+		kind = "round trip"
+	}
+	if len(res.latencies) > 0 {
+		sort.Slice(res.latencies, func(i, j int) bool { return res.latencies[i] < res.latencies[j] })
+		fmt.Printf("%-13s p50=%v p90=%v p99=%v max=%v\n", kind+":",
+			percentile(res.latencies, 50), percentile(res.latencies, 90),
+			percentile(res.latencies, 99), res.latencies[len(res.latencies)-1])
+	}
+	fmt.Printf("cpu:          %v total, %v per message\n", res.cpu, res.cpu/time.Duration(res.messages))
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	return sorted[(len(sorted)-1)*p/100]
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of go-etherdata.
+//
+// go-etherdata is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-etherdata is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-etherdata. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that every traffic pattern delivers all messages and accounts for the
+// payload and wire bytes in both directions.
+func TestRLPxBench(t *testing.T) {
+	tests := []struct {
+		pattern string
+		snappy  bool
+	}{
+		{pattern: "stream", snappy: true},
+		{pattern: "stream", snappy: false},
+		{pattern: "pingpong", snappy: true},
+		{pattern: "pingpong", snappy: false},
+		{pattern: "random", snappy: true},
+	}
+	for _, tt := range tests {
+		cfg := benchConfig{messages: 50, size: 512, pattern: tt.pattern, compressible: true, snappy: tt.snappy}
+		res, err := runRLPxBench(cfg)
+		if err != nil {
+			t.Fatalf("%s (snappy=%t): benchmark failed: %v", tt.pattern, tt.snappy, err)
+		}
+		if res.messages != cfg.messages {
+			t.Errorf("%s (snappy=%t): message count mismatch: have %d, want %d", tt.pattern, tt.snappy, res.messages, cfg.messages)
+		}
+		if len(res.latencies) != cfg.messages {
+			t.Errorf("%s (snappy=%t): latency count mismatch: have %d, want %d", tt.pattern, tt.snappy, len(res.latencies), cfg.messages)
+		}
+		// Fixed size patterns carry a known payload, doubled by the echoes
+		if tt.pattern != "random" {
+			want := uint64(cfg.messages * cfg.size)
+			if tt.pattern == "pingpong" {
+				want *= 2
+			}
+			if res.payload != want {
+				t.Errorf("%s (snappy=%t): payload mismatch: have %d, want %d", tt.pattern, tt.snappy, res.payload, want)
+			}
+		}
+		// Without compression the wire carries the payload as is, with it the
+		// zero-filled payloads should shrink
+		switch {
+		case !tt.snappy && res.wire != res.payload:
+			t.Errorf("%s: uncompressed wire bytes mismatch: have %d, want %d", tt.pattern, res.wire, res.payload)
+		case tt.snappy && res.wire >= res.payload:
+			t.Errorf("%s: compressed wire bytes %d not below payload %d", tt.pattern, res.wire, res.payload)
+		}
+	}
+}
+
+// Tests that invalid benchmark configurations are rejected.
+func TestRLPxBenchConfig(t *testing.T) {
+	tests := []struct {
+		cfg  benchConfig
+		want string
+	}{
+		{cfg: benchConfig{messages: 0, size: 512, pattern: "stream"}, want: "message count"},
+		{cfg: benchConfig{messages: 1, size: benchHeaderSize - 1, pattern: "stream"}, want: "message size"},
+		{cfg: benchConfig{messages: 1, size: 512, pattern: "flood"}, want: "unknown traffic pattern"},
+	}
+	for i, tt := range tests {
+		if _, err := runRLPxBench(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.want)
+		}
+	}
+}
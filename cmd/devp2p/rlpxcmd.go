@@ -36,6 +36,7 @@ var (
 		Subcommands: []cli.Command{
 			rlpxPingCommand,
 			rlpxEthTestCommand,
+			rlpxBenchCommand,
 		},
 	}
 	rlpxPingCommand = cli.Command{
@@ -51,8 +52,13 @@ var (
 		Flags: []cli.Flag{
 			testPatternFlag,
 			testTAPFlag,
+			testReorgChainFlag,
 		},
 	}
+	testReorgChainFlag = cli.StringFlag{
+		Name:  "reorgchain",
+		Usage: "Chain file forking off <chain.rlp> with higher total difficulty, enables the reorg tests",
+	}
 )
 
 func rlpxPing(ctx *cli.Context) error {
@@ -99,10 +105,20 @@ func rlpxEthTest(ctx *cli.Context) error {
 	if err != nil {
 		exit(err)
 	}
+	if ctx.IsSet(testReorgChainFlag.Name) {
+		if err := suite.LoadReorgChain(ctx.String(testReorgChainFlag.Name)); err != nil {
+			exit(err)
+		}
+	}
 	// check if given node supports etd66, and if so, run etd66 protocol tests as well
 	is66Failed, _ := utesting.Run(utesting.Test{Name: "Is_66", Fn: suite.Is_66})
 	if is66Failed {
 		return runTests(ctx, suite.EthTests())
 	}
-	return runTests(ctx, suite.AllEthTests())
+	tests := suite.AllEthTests()
+	// run the snap protocol tests if the node supports snap
+	if isSnapFailed, _ := utesting.Run(utesting.Test{Name: "Is_Snap", Fn: suite.Is_Snap}); !isSnapFailed {
+		tests = append(tests, suite.SnapTests()...)
+	}
+	return runTests(ctx, append(tests, suite.ReorgTests()...))
 }
@@ -29,6 +29,7 @@ import (
 
 	"github.com/crypyto-panel/go-etherdata/cmd/utils"
 	"github.com/crypyto-panel/go-etherdata/etd/catalyst"
+	"github.com/crypyto-panel/go-etherdata/etd/devmode"
 	"github.com/crypyto-panel/go-etherdata/etd/etdconfig"
 	"github.com/crypyto-panel/go-etherdata/internal/etdapi"
 	"github.com/crypyto-panel/go-etherdata/metrics"
@@ -155,6 +156,12 @@ func makeFullNode(ctx *cli.Context) (*node.Node, etdapi.Backend) {
 			utils.Fatalf("%v", err)
 		}
 	}
+	// Configure developer chain control.
+	if ctx.GlobalBool(utils.DeveloperFlag.Name) && etd != nil {
+		if err := devmode.Register(stack, etd); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
 
 	// Configure GraphQL if requested
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
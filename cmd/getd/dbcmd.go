@@ -17,6 +17,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -28,9 +29,13 @@ import (
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/common/hexutil"
 	"github.com/crypyto-panel/go-etherdata/console/prompt"
+	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/params"
 	"github.com/crypyto-panel/go-etherdata/trie"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -62,6 +67,7 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbLogIndexCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -112,6 +118,25 @@ Remove blockchain and state databases`,
 		Description: `This command performs a database compaction. 
 WARNING: This operation may take a very long time to finish, and may cause database
 corruption if it is aborted during execution'!`,
+	}
+	dbLogIndexCmd = cli.Command{
+		Action: utils.MigrateFlags(dbLogIndex),
+		Name:   "logindex",
+		Usage:  "Build the persistent log index up to the current chain head",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.CalaverasFlag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+		},
+		Description: `This command backfills the address/topic log index used by --logindex
+for all the confirmed sections of the local chain, so that the node doesn't need
+to generate it while running. Indexing can be interrupted and resumed later.`,
 	}
 	dbGetCmd = cli.Command{
 		Action:    utils.MigrateFlags(dbGet),
@@ -332,6 +357,76 @@ func dbCompact(ctx *cli.Context) error {
 	return nil
 }
 
+// indexerChain is a minimal core.ChainIndexerChain over an offline database,
+// reporting the current head block without ever announcing new ones.
+type indexerChain struct {
+	db   etddb.Database
+	feed event.Feed
+}
+
+// CurrentHeader returns the header of the current head block. Note, the header
+// chain head is not used as the blocks beyond the head block have no receipts.
+func (c *indexerChain) CurrentHeader() *types.Header {
+	hash := rawdb.ReadHeadBlockHash(c.db)
+	number := rawdb.ReadHeaderNumber(c.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadHeader(c.db, hash, *number)
+}
+
+// SubscribeChainHeadEvent returns a subscription which never fires.
+func (c *indexerChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// dbLogIndex generates the persistent log index for the local chain.
+func dbLogIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	chain := &indexerChain{db: db}
+	head := chain.CurrentHeader()
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	var target uint64
+	if number := head.Number.Uint64(); number+1 >= params.BloomConfirms {
+		target = (number + 1 - params.BloomConfirms) / params.BloomBitsBlocks
+	}
+	indexer := core.NewLogIndexer(db, params.BloomBitsBlocks, params.BloomConfirms)
+	indexer.Start(chain)
+
+	var (
+		start    = time.Now()
+		ticker   = time.NewTicker(8 * time.Second)
+		progress = start
+		last     uint64
+	)
+	defer ticker.Stop()
+
+	for {
+		sections, _, _ := indexer.Sections()
+		if sections >= target {
+			log.Info("Log index generated", "sections", sections, "elapsed", common.PrettyDuration(time.Since(start)))
+			return indexer.Close()
+		}
+		// The indexer doesn't retry failed sections without a new head, bail out
+		// if it's stuck instead of waiting forever
+		if sections > last {
+			last, progress = sections, time.Now()
+		} else if time.Since(progress) > 5*time.Minute {
+			indexer.Close()
+			return fmt.Errorf("log indexing stalled at section %d", sections)
+		}
+		log.Info("Generating log index", "sections", sections, "target", target, "elapsed", common.PrettyDuration(time.Since(start)))
+		<-ticker.C
+	}
+}
+
 // dbGet shows the value of a given database key
 func dbGet(ctx *cli.Context) error {
 	if ctx.NArg() != 1 {
@@ -78,6 +78,9 @@ func (w *wizard) makeGenesis() {
 		fmt.Println("How many seconds should blocks take? (default = 15)")
 		genesis.Config.Clique.Period = uint64(w.readDefaultInt(15))
 
+		// Allow scheduling parameter changes for later on
+		w.configureCliqueTransitions(genesis.Config.Clique)
+
 		// We also need the initial list of signers
 		fmt.Println()
 		fmt.Println("Which accounts are allowed to seal? (mandatory at least one)")
@@ -243,6 +246,10 @@ func (w *wizard) manageGenesis() {
 		fmt.Printf("Which block should London come into effect? (default = %v)\n", w.conf.Genesis.Config.LondonBlock)
 		w.conf.Genesis.Config.LondonBlock = w.readDefaultBigInt(w.conf.Genesis.Config.LondonBlock)
 
+		if w.conf.Genesis.Config.Clique != nil {
+			w.configureCliqueTransitions(w.conf.Genesis.Config.Clique)
+		}
+
 		out, _ := json.MarshalIndent(w.conf.Genesis.Config, "", "  ")
 		fmt.Printf("Chain configuration updated:\n\n%s\n", out)
 
@@ -311,3 +318,68 @@ func saveGenesis(folder, network, client string, spec interface{}) {
 	}
 	log.Info("Saved genesis chain spec", "client", client, "path", path)
 }
+
+// configureCliqueTransitions queries the user for scheduled changes to the clique
+// block period, epoch length and in-turn sealing enforcement.
+func (w *wizard) configureCliqueTransitions(config *params.CliqueConfig) {
+	fmt.Println()
+	fmt.Printf("Do you want to schedule clique parameter changes? (default = no, %d scheduled)\n", len(config.Transitions))
+	if !w.readDefaultYesNo(false) {
+		return
+	}
+	// Continue the existing schedule, starting from the parameters in effect
+	// after its last transition
+	var (
+		transitions = config.Transitions
+		last        = big.NewInt(0)
+		period      = config.Period
+		epoch       = config.Epoch
+		strict      = false
+	)
+	for _, transition := range transitions {
+		if transition.Period != nil {
+			period = *transition.Period
+		}
+		if transition.Epoch != nil {
+			epoch = *transition.Epoch
+		}
+		if transition.StrictTurn != nil {
+			strict = *transition.StrictTurn
+		}
+		last = transition.Block
+	}
+	for {
+		fmt.Println()
+		fmt.Printf("Which block should the next change come into effect? (above %v, empty to finish)\n", last)
+		block := w.readDefaultBigInt(nil)
+		if block == nil {
+			break
+		}
+		if block.Cmp(last) <= 0 {
+			log.Error("Invalid transition block, must be above the previous one", "block", block, "previous", last)
+			continue
+		}
+		transition := params.CliqueTransition{Block: block}
+
+		fmt.Println()
+		fmt.Printf("How many seconds should blocks take from then on? (default = %d)\n", period)
+		if next := uint64(w.readDefaultInt(int(period))); next != period {
+			period = next
+			transition.Period = &next
+		}
+		fmt.Println()
+		fmt.Printf("How many blocks should an epoch span from then on? (default = %d)\n", epoch)
+		if next := uint64(w.readDefaultInt(int(epoch))); next != epoch && next > 0 {
+			epoch = next
+			transition.Epoch = &next
+		}
+		fmt.Println()
+		fmt.Printf("Should only in-turn signers be allowed to seal from then on? (default = %s)\n", map[bool]string{true: "yes", false: "no"}[strict])
+		if next := w.readDefaultYesNo(strict); next != strict {
+			strict = next
+			transition.StrictTurn = &next
+		}
+		transitions, last = append(transitions, transition), block
+	}
+	config.Transitions = transitions
+}
@@ -230,6 +230,18 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: etdconfig.Defaults.TxLookupLimit,
 	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain a persistent address/topic log index for fast log filtering",
+	}
+	SnapServeRateFlag = cli.IntFlag{
+		Name:  "snap.serverate",
+		Usage: "Maximum rate of snap sync data served to all peers combined in KB/s (0 = unlimited)",
+	}
+	SnapPeerServeRateFlag = cli.IntFlag{
+		Name:  "snap.peerserverate",
+		Usage: "Maximum rate of snap sync data served to a single peer in KB/s (0 = unlimited)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -477,6 +489,15 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: "Transaction ordering strategy used to fill blocks (" + strings.Join(miner.Orderings(), ", ") + ")",
+		Value: miner.OrderingPrice,
+	}
+	MinerOrderingPluginFlag = cli.StringFlag{
+		Name:  "miner.ordering.plugin",
+		Usage: "Go plugin exporting a custom transaction ordering strategy (overrides --miner.ordering)",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -610,6 +631,33 @@ var (
 		Usage: "HTTP path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in an HTTP or WS-RPC batch (0=unlimited)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size in bytes of the results of an HTTP or WS-RPC request or batch (0=unlimited)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Request cost units refilled per second for each HTTP or WS-RPC client (0=unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.rateburst",
+		Usage: "Maximum request cost units an HTTP or WS-RPC client may spend at once",
+		Value: 100,
+	}
+	RPCMethodCostsFlag = cli.StringFlag{
+		Name:  "rpc.methodcosts",
+		Usage: "Comma separated list of method=cost pairs weighting rate limited requests (e.g. etd_getLogs=10)",
+		Value: "",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded secret for authenticating HTTP and WS-RPC requests with HS256 bearer tokens (generated if missing)",
+		Value: "",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -634,6 +682,14 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: node.DefaultConfig.P2P.MaxPendingPeers,
 	}
+	MaxUploadFlag = cli.IntFlag{
+		Name:  "maxupload",
+		Usage: "Maximum upload bandwidth of all peer connections in KB/s (0 = unlimited)",
+	}
+	MaxDownloadFlag = cli.IntFlag{
+		Name:  "maxdownload",
+		Usage: "Maximum download bandwidth of all peer connections in KB/s (0 = unlimited)",
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -1017,6 +1073,48 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setJWTSecret configures the secret authenticating HTTP and WebSocket RPC
+// requests from the set command line flags.
+func setJWTSecret(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+}
+
+// setRPCLimits configures the limits imposed on HTTP and WebSocket RPC clients
+// from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCLimits.ResponseBytes = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.Rate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) || cfg.RPCLimits.Burst == 0 {
+		cfg.RPCLimits.Burst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodCostsFlag.Name) {
+		cfg.RPCLimits.MethodCosts = make(map[string]int)
+		for _, entry := range SplitAndTrim(ctx.GlobalString(RPCMethodCostsFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid method cost %q, want method=cost", entry)
+			}
+			cost, err := strconv.Atoi(parts[1])
+			if err != nil || cost < 0 {
+				Fatalf("Invalid cost of method %s: %q", parts[0], parts[1])
+			}
+			if cost > cfg.RPCLimits.Burst {
+				Fatalf("Cost %d of method %s exceeds rate limit burst %d", cost, parts[0], cfg.RPCLimits.Burst)
+			}
+			cfg.RPCLimits.MethodCosts[parts[0]] = cost
+		}
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1184,6 +1282,12 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(MaxUploadFlag.Name) {
+		cfg.MaxUploadRate = ctx.GlobalInt(MaxUploadFlag.Name) * 1024
+	}
+	if ctx.GlobalIsSet(MaxDownloadFlag.Name) {
+		cfg.MaxDownloadRate = ctx.GlobalInt(MaxDownloadFlag.Name) * 1024
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -1223,6 +1327,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setJWTSecret(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1412,6 +1518,15 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.GlobalString(MinerOrderingFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerOrderingPluginFlag.Name) {
+		cfg.OrderingPlugin = ctx.GlobalString(MinerOrderingPluginFlag.Name)
+	}
+	if _, err := miner.LoadOrdering(cfg); err != nil {
+		Fatalf("Invalid transaction ordering: %v", err)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *etdconfig.Config) {
@@ -1555,6 +1670,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *etdconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapServeRateFlag.Name) {
+		cfg.SnapServeRate = ctx.GlobalInt(SnapServeRateFlag.Name) * 1024
+	}
+	if ctx.GlobalIsSet(SnapPeerServeRateFlag.Name) {
+		cfg.SnapPeerServeRate = ctx.GlobalInt(SnapPeerServeRateFlag.Name) * 1024
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
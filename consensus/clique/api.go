@@ -17,14 +17,26 @@
 package clique
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/consensus"
+	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/event"
+	"github.com/crypyto-panel/go-etherdata/log"
 	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
+// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+const chainHeadChanSize = 10
+
+// errNoChainHeadEvents is returned when subscribing to the signer set changes of
+// a chain which doesn't announce its new heads.
+var errNoChainHeadEvents = errors.New("chain head events not supported")
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the proof-of-authority scheme.
 type API struct {
@@ -32,6 +44,22 @@ type API struct {
 	clique *Clique
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *API) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"getSnapshot":       {Summary: "Returns the signer snapshot at a block.", Params: []string{"number"}},
+		"getSnapshotAtHash": {Summary: "Returns the signer snapshot at a block, given by hash.", Params: []string{"hash"}},
+		"getSigners":        {Summary: "Returns the authorized signers at a block.", Params: []string{"number"}},
+		"getSignersAtHash":  {Summary: "Returns the authorized signers at a block, given by hash.", Params: []string{"hash"}},
+		"proposals":         {Summary: "Returns the signer votes the node casts when sealing."},
+		"propose":           {Summary: "Casts a vote to add or remove a signer when sealing.", Params: []string{"address", "auth"}},
+		"discard":           {Summary: "Drops a signer vote.", Params: []string{"address"}},
+		"status":            {Summary: "Returns the sealing activity of the signers over the recent blocks."},
+		"getHistory":        {Summary: "Returns the signer set changes over a range of blocks.", Params: []string{"from", "to"}},
+		"signerChanges":     {Summary: "Subscribes to the changes of the signer set."},
+	}
+}
+
 // GetSnapshot retrieves the state snapshot at a given block.
 func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 	// Retrieve the requested block number (or current if none requested)
@@ -175,3 +203,109 @@ func (api *API) Status() (*status, error) {
 		NumBlocks:     numBlocks,
 	}, nil
 }
+
+// resolveNumber returns the block number referenced by the given specifier,
+// defaulting to the current head if nil or symbolic.
+func (api *API) resolveNumber(number *rpc.BlockNumber) uint64 {
+	if number == nil || *number < 0 {
+		return api.chain.CurrentHeader().Number.Uint64()
+	}
+	return uint64(number.Int64())
+}
+
+// GetHistory retrieves all the votes cast and the signer set changes within the
+// given range of blocks (both inclusive). The range ends at the current head if
+// no end is given, and covers the most blocks a single request may span if no
+// start is given.
+func (api *API) GetHistory(from *rpc.BlockNumber, to *rpc.BlockNumber) (*History, error) {
+	end := api.resolveNumber(to)
+	if from == nil {
+		start := uint64(0)
+		if end >= maxHistoryRange {
+			start = end - maxHistoryRange + 1
+		}
+		return api.clique.history(api.chain, start, end)
+	}
+	return api.clique.history(api.chain, api.resolveNumber(from), end)
+}
+
+// chainHeadSubscriber is implemented by the chains announcing their new heads,
+// needed for tracking the signer set changes.
+type chainHeadSubscriber interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// SignerChanges creates a subscription that fires whenever a vote passes on the
+// canonical chain, modifying the set of authorized signers.
+func (api *API) SignerChanges(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	chain, ok := api.chain.(chainHeadSubscriber)
+	if !ok {
+		return &rpc.Subscription{}, errNoChainHeadEvents
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	headSub := chain.SubscribeChainHeadEvent(heads)
+	last := api.chain.CurrentHeader()
+
+	go func() {
+		defer headSub.Unsubscribe()
+
+		for {
+			select {
+			case <-heads:
+				// Events may be stale by the time they are processed, so track
+				// the current head instead of the announced one.
+				last = api.notifySignerChanges(notifier, rpcSub.ID, last, api.chain.CurrentHeader())
+
+			case <-headSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// notifySignerChanges sends the signer set changes in the canonical blocks after
+// the last processed one up to the given head, returning the last block processed.
+func (api *API) notifySignerChanges(notifier *rpc.Notifier, id rpc.ID, last, head *types.Header) *types.Header {
+	for last.Hash() != head.Hash() {
+		// Resume from the last processed block still canonical
+		number, err := canonicalAncestor(api.chain, last)
+		if err != nil {
+			log.Warn("Failed to track clique signer changes", "err", err)
+			return head
+		}
+		// Replay at most maxHistoryRange blocks at once
+		from, to := number+1, head.Number.Uint64()
+		if to >= from+maxHistoryRange {
+			to = from + maxHistoryRange - 1
+		}
+		end := head
+		if to != head.Number.Uint64() {
+			if end = api.chain.GetHeaderByNumber(to); end == nil {
+				log.Warn("Failed to track clique signer changes", "number", to, "err", "missing header")
+				return last
+			}
+		}
+		history, err := api.clique.history(api.chain, from, to)
+		if err != nil {
+			log.Warn("Failed to track clique signer changes", "from", from, "to", to, "err", err)
+			return last
+		}
+		for _, change := range history.Changes {
+			notifier.Notify(id, change)
+		}
+		last = end
+	}
+	return last
+}
@@ -17,9 +17,12 @@
 package clique
 
 import (
+	"bytes"
 	"math/big"
+	"sort"
 	"testing"
 
+	"github.com/crypyto-panel/go-etherdata/accounts"
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/rawdb"
@@ -112,3 +115,115 @@ func TestReimportMirroredState(t *testing.T) {
 		t.Fatalf("chain head mismatch: have %d, want %d", head, 3)
 	}
 }
+
+// Tests that once in-turn sealing is enforced by a transition, the engine only
+// accepts and seals blocks of the in-turn signer, while out-of-turn blocks are
+// still valid before the transition.
+func TestStrictTurn(t *testing.T) {
+	// Create the genesis block with A, B and C as the initial signers, ordering
+	// them by address to know whose turn each block is
+	pool := newTesterAccountPool()
+	names := []string{"A", "B", "C"}
+	sort.Slice(names, func(i, j int) bool {
+		return bytes.Compare(pool.address(names[i]).Bytes(), pool.address(names[j]).Bytes()) < 0
+	})
+	inturn := func(number uint64) string { return names[number%uint64(len(names))] }
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(names)+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	pool.checkpoint(&types.Header{Extra: genesis.ExtraData}, names)
+
+	db := rawdb.NewMemoryDatabase()
+	genesis.Commit(db)
+
+	strict := true
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{
+		Period:      1,
+		Epoch:       30000,
+		Transitions: []params.CliqueTransition{{Block: big.NewInt(3), StrictTurn: &strict}},
+	}
+	engine := New(config.Clique, db)
+
+	// Generate a chain with every block sealed by the in-turn signer, plus one
+	// more block to attempt sealing on top
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, 5, nil)
+	sign := func(header *types.Header, signer string) *types.Header {
+		header = types.CopyHeader(header)
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+		if signer != inturn(header.Number.Uint64()) {
+			header.Difficulty = diffNoTurn
+		}
+		pool.sign(header, signer)
+		return header
+	}
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		blocks[i] = block.WithSeal(sign(header, inturn(header.Number.Uint64())))
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:4]); err != nil {
+		t.Fatalf("failed to import in-turn chain: %v", err)
+	}
+	// Out-of-turn blocks must be accepted before the transition, but rejected
+	// after it. The first signer sealed neither block 1 nor block 4, so it is
+	// not prevented from sealing blocks 2 and 5 by the recent signer limit.
+	if err := engine.VerifyHeader(chain, sign(blocks[1].Header(), names[0]), true); err != nil {
+		t.Errorf("out-of-turn block before transition rejected: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, sign(blocks[4].Header(), names[0]), true); err != errOutOfTurnSigner {
+		t.Errorf("out-of-turn block after transition error mismatch: have %v, want %v", err, errOutOfTurnSigner)
+	}
+	// Out-of-turn signers must not even attempt to seal after the transition
+	signFn := func(signer string, signed *bool) SignerFn {
+		return func(account accounts.Account, mimeType string, message []byte) ([]byte, error) {
+			*signed = true
+			return crypto.Sign(crypto.Keccak256(message), pool.accounts[signer])
+		}
+	}
+	var (
+		results = make(chan *types.Block, 1)
+		stop    = make(chan struct{})
+		signed  bool
+	)
+	defer close(stop)
+
+	pending := types.CopyHeader(blocks[4].Header())
+	pending.Extra = make([]byte, extraVanity+extraSeal)
+
+	pending.Difficulty = diffNoTurn
+	engine.Authorize(pool.address(names[0]), signFn(names[0], &signed))
+	if err := engine.Seal(chain, blocks[4].WithSeal(pending), results, stop); err != nil {
+		t.Fatalf("failed to attempt out-of-turn sealing: %v", err)
+	}
+	if signed {
+		t.Fatalf("out-of-turn signer sealed block after transition")
+	}
+	// The in-turn signer should seal a block the engine accepts
+	pending.Difficulty = diffInTurn
+	engine.Authorize(pool.address(inturn(5)), signFn(inturn(5), &signed))
+	if err := engine.Seal(chain, blocks[4].WithSeal(pending), results, stop); err != nil {
+		t.Fatalf("failed to seal in-turn block: %v", err)
+	}
+	if !signed {
+		t.Fatalf("in-turn signer did not seal block")
+	}
+	sealed := <-results
+	if err := engine.VerifyHeader(chain, sealed.Header(), true); err != nil {
+		t.Fatalf("sealed in-turn block rejected: %v", err)
+	}
+	if _, err := chain.InsertChain(types.Blocks{sealed}); err != nil {
+		t.Fatalf("failed to import sealed block: %v", err)
+	}
+}
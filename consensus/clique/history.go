@@ -0,0 +1,170 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+)
+
+// maxHistoryRange is the maximum number of blocks the vote history may be
+// reconstructed for in a single request.
+const maxHistoryRange = 8192
+
+var (
+	// errHistoryRangeTooLarge is returned if the vote history is requested for
+	// more blocks than maxHistoryRange.
+	errHistoryRangeTooLarge = fmt.Errorf("block range exceeds limit of %d blocks", maxHistoryRange)
+
+	// errReorgTooDeep is returned by the signer change tracker if it can't find
+	// the last processed block's ancestor on the canonical chain.
+	errReorgTooDeep = errors.New("reorg too deep")
+)
+
+// VoteRecord is a single authorization vote cast in a block header. Unlike the
+// pending votes tracked by a Snapshot, records are kept even if the vote was
+// later discarded or superseded.
+type VoteRecord struct {
+	Block     uint64         `json:"block"`     // Block number the vote was cast in
+	Hash      common.Hash    `json:"hash"`      // Hash of the block the vote was cast in
+	Signer    common.Address `json:"signer"`    // Authorized signer that cast this vote
+	Address   common.Address `json:"address"`   // Account being voted on to change its authorization
+	Authorize bool           `json:"authorize"` // Whether to authorize or deauthorize the voted account
+}
+
+// SignerChange is a modification of the authorized signer set, caused by a
+// vote passing in the given block.
+type SignerChange struct {
+	Block      uint64           `json:"block"`      // Block number the signer set changed in
+	Hash       common.Hash      `json:"hash"`       // Hash of the block the signer set changed in
+	Address    common.Address   `json:"address"`    // Account that was authorized or deauthorized
+	Authorized bool             `json:"authorized"` // Whether the account was added to or removed from the signers
+	Signers    []common.Address `json:"signers"`    // Set of authorized signers after the change
+}
+
+// History is the record of all votes cast and signer set changes within a
+// range of canonical blocks.
+type History struct {
+	From    uint64          `json:"from"`    // First block of the range (inclusive)
+	To      uint64          `json:"to"`      // Last block of the range (inclusive)
+	Votes   []*VoteRecord   `json:"votes"`   // Votes cast in chronological order
+	Changes []*SignerChange `json:"changes"` // Signer set changes in chronological order
+}
+
+// history reconstructs the votes and signer set changes within the canonical
+// block range [from, to] by replaying the headers on top of the snapshot of
+// the block preceding the range.
+func (c *Clique) history(chain consensus.ChainHeaderReader, from, to uint64) (*History, error) {
+	// The genesis block can't contain votes, start the replay after it
+	if from == 0 {
+		from = 1
+	}
+	if from > to {
+		return &History{From: from, To: to}, nil
+	}
+	if to-from >= maxHistoryRange {
+		return nil, errHistoryRangeTooLarge
+	}
+	parent := chain.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	history := &History{From: from, To: to, Votes: []*VoteRecord{}, Changes: []*SignerChange{}}
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		// Bail out if the canonical chain was reorged during the replay
+		if header.ParentHash != snap.Hash {
+			return nil, errInvalidVotingChain
+		}
+		next, err := snap.apply([]*types.Header{header})
+		if err != nil {
+			return nil, err
+		}
+		if header.Coinbase != (common.Address{}) {
+			signer, err := ecrecover(header, c.signatures)
+			if err != nil {
+				return nil, err
+			}
+			history.Votes = append(history.Votes, &VoteRecord{
+				Block:     number,
+				Hash:      header.Hash(),
+				Signer:    signer,
+				Address:   header.Coinbase,
+				Authorize: bytes.Equal(header.Nonce[:], nonceAuthVote),
+			})
+		}
+		history.Changes = append(history.Changes, signerChanges(snap, next, header)...)
+		snap = next
+	}
+	return history, nil
+}
+
+// signerChanges returns the differences between the signer sets of two
+// consecutive snapshots.
+func signerChanges(prev, next *Snapshot, header *types.Header) []*SignerChange {
+	var changes []*SignerChange
+	for signer := range next.Signers {
+		if _, ok := prev.Signers[signer]; !ok {
+			changes = append(changes, &SignerChange{Address: signer, Authorized: true})
+		}
+	}
+	for signer := range prev.Signers {
+		if _, ok := next.Signers[signer]; !ok {
+			changes = append(changes, &SignerChange{Address: signer, Authorized: false})
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	signers := next.signers()
+	for _, change := range changes {
+		change.Block = header.Number.Uint64()
+		change.Hash = header.Hash()
+		change.Signers = signers
+	}
+	return changes
+}
+
+// canonicalAncestor walks back from the given header until it finds a block
+// which is part of the canonical chain, returning its number.
+func canonicalAncestor(chain consensus.ChainHeaderReader, header *types.Header) (uint64, error) {
+	for i := 0; i < maxHistoryRange; i++ {
+		number := header.Number.Uint64()
+		if canon := chain.GetHeaderByNumber(number); canon != nil && canon.Hash() == header.Hash() {
+			return number, nil
+		}
+		if number == 0 {
+			break
+		}
+		if header = chain.GetHeader(header.ParentHash, number-1); header == nil {
+			break
+		}
+	}
+	return 0, errReorgTooDeep
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/core/vm"
+	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+// Tests that the vote history and signer set changes are correctly
+// reconstructed from the canonical chain.
+func TestHistory(t *testing.T) {
+	accounts := newTesterAccountPool()
+	votes := []testerVote{
+		{signer: "A", voted: "C", auth: true},
+		{signer: "B", voted: "C", auth: true},
+		{signer: "C"},
+		{signer: "A", voted: "B"},
+		{signer: "C", voted: "B"},
+		{signer: "A"},
+	}
+	// Create the genesis block with A and B as the initial signers
+	signers := []common.Address{accounts.address("A"), accounts.address("B")}
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	accounts.checkpoint(&types.Header{Extra: genesis.ExtraData}, []string{"A", "B"})
+
+	db := rawdb.NewMemoryDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(votes), func(j int, gen *core.BlockGen) {
+		gen.SetCoinbase(accounts.address(votes[j].voted))
+		if votes[j].auth {
+			var nonce types.BlockNonce
+			copy(nonce[:], nonceAuthVote)
+			gen.SetNonce(nonce)
+		}
+	})
+	for j, block := range blocks {
+		header := block.Header()
+		if j > 0 {
+			header.ParentHash = blocks[j-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, votes[j].signer)
+		blocks[j] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Track the signer set changes while importing the chain
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("clique", &API{chain: chain, clique: engine}); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	changes := make(chan *SignerChange, 2)
+	sub, err := client.Subscribe(context.Background(), "clique", changes, "signerChanges")
+	if err != nil {
+		t.Fatalf("failed to subscribe to signer changes: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import test chain: %v", err)
+	}
+	for _, number := range []uint64{2, 5} {
+		select {
+		case change := <-changes:
+			if change.Block != number || change.Hash != blocks[number-1].Hash() {
+				t.Errorf("signer change mismatch: have block %d, want %d", change.Block, number)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("signer change subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("signer change in block %d not notified", number)
+		}
+	}
+	// The whole chain is covered if no range is given
+	history, err := (&API{chain: chain, clique: engine}).GetHistory(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	}
+	// Verify the recorded votes
+	want := []VoteRecord{
+		{Block: 1, Signer: accounts.address("A"), Address: accounts.address("C"), Authorize: true},
+		{Block: 2, Signer: accounts.address("B"), Address: accounts.address("C"), Authorize: true},
+		{Block: 4, Signer: accounts.address("A"), Address: accounts.address("B"), Authorize: false},
+		{Block: 5, Signer: accounts.address("C"), Address: accounts.address("B"), Authorize: false},
+	}
+	if len(history.Votes) != len(want) {
+		t.Fatalf("vote count mismatch: have %d, want %d", len(history.Votes), len(want))
+	}
+	for i, vote := range history.Votes {
+		want[i].Hash = blocks[want[i].Block-1].Hash()
+		if *vote != want[i] {
+			t.Errorf("vote %d: mismatch: have %+v, want %+v", i, vote, want[i])
+		}
+	}
+	// Verify the signer set changes
+	if len(history.Changes) != 2 {
+		t.Fatalf("change count mismatch: have %d, want %d", len(history.Changes), 2)
+	}
+	if change := history.Changes[0]; change.Block != 2 || change.Address != accounts.address("C") || !change.Authorized || len(change.Signers) != 3 {
+		t.Errorf("change 0: mismatch: have %+v", change)
+	}
+	if change := history.Changes[1]; change.Block != 5 || change.Address != accounts.address("B") || change.Authorized || len(change.Signers) != 2 {
+		t.Errorf("change 1: mismatch: have %+v", change)
+	}
+	// Verify that a partial range only reports the changes within it
+	if history, err = engine.history(chain, 3, 5); err != nil {
+		t.Fatalf("failed to retrieve partial history: %v", err)
+	}
+	if len(history.Votes) != 2 || len(history.Changes) != 1 {
+		t.Errorf("partial history mismatch: have %d votes and %d changes, want 2 and 1", len(history.Votes), len(history.Changes))
+	}
+	if _, err := engine.history(chain, 1, maxHistoryRange+1); err != errHistoryRangeTooLarge {
+		t.Errorf("oversized range error mismatch: have %v, want %v", err, errHistoryRangeTooLarge)
+	}
+}
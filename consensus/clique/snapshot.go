@@ -206,7 +206,7 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	for i, header := range headers {
 		// Remove any votes on checkpoint blocks
 		number := header.Number.Uint64()
-		if number%s.config.Epoch == 0 {
+		if s.config.IsCheckpoint(number) {
 			snap.Votes = nil
 			snap.Tally = make(map[common.Address]Tally)
 		}
@@ -22,6 +22,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/common/hexutil"
 	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
 var errEthashStopped = errors.New("etdash stopped")
@@ -31,6 +32,16 @@ type API struct {
 	etdash *Ethash
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *API) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"getWork":        {Summary: "Returns the work package for external miners."},
+		"submitWork":     {Summary: "Submits a proof-of-work solution of an external miner.", Params: []string{"nonce", "hash", "digest"}},
+		"submitHashrate": {Summary: "Submits the hash rate of an external miner.", Params: []string{"rate", "id"}},
+		"getHashrate":    {Summary: "Returns the total hash rate of the local and external miners."},
+	}
+}
+
 // GetWork returns a work package for external miner.
 //
 // The work package consists of 3 strings:
@@ -0,0 +1,747 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/event"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// maxBacklog is the maximum number of messages for the next height to keep
+	// around while the current one is still being decided.
+	maxBacklog = 1024
+
+	// maxFutureRounds is the number of rounds ahead of the local one for which
+	// messages are retained.
+	maxFutureRounds = 64
+
+	// maxTimeoutShift caps the exponential growth of the round timeouts.
+	maxTimeoutShift = 8
+)
+
+// Chain is the blockchain the consensus loop follows and inserts finalized
+// blocks into. It is satisfied by core.BlockChain.
+type Chain interface {
+	consensus.ChainHeaderReader
+
+	// Validator returns the block validator to check proposals with.
+	Validator() core.Validator
+
+	// InsertChain imports a batch of blocks into the chain.
+	InsertChain(chain types.Blocks) (int, error)
+
+	// SubscribeChainHeadEvent subscribes to new canonical chain heads.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// sealTask is a block handed over by the miner to be proposed once the local
+// validator's turn comes.
+type sealTask struct {
+	block   *types.Block
+	results chan<- *types.Block
+	stop    <-chan struct{}
+}
+
+// roundState is the progress of consensus on a single block height.
+type roundState struct {
+	height uint64        // Number of the block being decided on
+	round  uint32        // Current consensus round
+	parent *types.Header // Parent of the block being decided on
+	snap   *Snapshot     // Validator set deciding on the block
+
+	proposal  *types.Block // Proposal accepted in the current round
+	digest    common.Hash  // Proposal hash of the accepted proposal
+	committed bool         // Whether a commit was sent in the current round
+	final     bool         // Whether consensus was reached on the height
+
+	locked       *types.Block // Last proposal a quorum prepared in, if any
+	lockedDigest common.Hash  // Proposal hash of the locked proposal
+	lockedRound  uint32       // Round the locked proposal was prepared in
+	lockedSeals  [][]byte     // Prepare signatures proving the lock
+
+	blocks   map[common.Hash]*types.Block              // Accepted proposals by proposal hash
+	prepares map[uint32]map[common.Address]*message    // Prepare messages by round and validator
+	commits  map[common.Hash]map[common.Address][]byte // Committed seals by proposal hash and validator
+	changes  map[uint32]map[common.Address]*message    // Round changes by round and validator
+	pending  map[uint32]*message                       // Proposals received ahead of their round
+}
+
+// newRoundState creates the consensus state of the block following parent.
+func newRoundState(parent *types.Header, snap *Snapshot) *roundState {
+	return &roundState{
+		height:   parent.Number.Uint64() + 1,
+		parent:   parent,
+		snap:     snap,
+		blocks:   make(map[common.Hash]*types.Block),
+		prepares: make(map[uint32]map[common.Address]*message),
+		commits:  make(map[common.Hash]map[common.Address][]byte),
+		changes:  make(map[uint32]map[common.Address]*message),
+		pending:  make(map[uint32]*message),
+	}
+}
+
+// stateMachine runs the consensus protocol of the local validator, tracking
+// the chain head and driving each height through its rounds.
+type stateMachine struct {
+	engine *IBFT
+	chain  Chain
+
+	state     *roundState // Consensus state of the current height
+	candidate *sealTask   // Block the local miner asked to propose
+	backlog   []*message  // Messages received for the next height
+
+	roundTimer   *time.Timer // Fires when the current round times out
+	proposeTimer *time.Timer // Fires when the local candidate may be proposed
+}
+
+// Start launches the consensus loop on top of the given chain, allowing the
+// engine to take part in consensus once authorized. It is a no-op if the loop
+// is already running.
+func (c *IBFT) Start(chain Chain) {
+	c.start.Do(func() {
+		sm := &stateMachine{
+			engine:       c,
+			chain:        chain,
+			roundTimer:   time.NewTimer(0),
+			proposeTimer: time.NewTimer(0),
+		}
+		stopTimer(sm.roundTimer)
+		stopTimer(sm.proposeTimer)
+
+		atomic.StoreInt32(&c.running, 1)
+		c.wg.Add(1)
+		go sm.loop()
+	})
+}
+
+// loop is the main event loop of the consensus state machine.
+func (sm *stateMachine) loop() {
+	defer sm.engine.wg.Done()
+	defer sm.roundTimer.Stop()
+	defer sm.proposeTimer.Stop()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := sm.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	sm.newHeight(sm.chain.CurrentHeader())
+	for {
+		select {
+		case ev := <-heads:
+			sm.newHeight(ev.Block.Header())
+
+		case msg := <-sm.engine.msgCh:
+			sm.handleMessage(msg)
+
+		case task := <-sm.engine.reqCh:
+			sm.candidate = task
+			sm.propose()
+
+		case <-sm.roundTimer.C:
+			sm.timeout()
+
+		case <-sm.proposeTimer.C:
+			sm.propose()
+
+		case <-sub.Err():
+			return
+		case <-sm.engine.quit:
+			return
+		}
+	}
+}
+
+// newHeight starts consensus on the block following the given head.
+func (sm *stateMachine) newHeight(head *types.Header) {
+	if sm.state != nil && head.Number.Uint64() < sm.state.height {
+		return
+	}
+	snap, err := sm.engine.snapshot(sm.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		log.Error("Failed to retrieve validator set", "number", head.Number, "hash", head.Hash(), "err", err)
+		return
+	}
+	sm.state = newRoundState(head, snap)
+	if sm.candidate != nil && sm.candidate.block.ParentHash() != head.Hash() {
+		sm.candidate = nil
+	}
+	sm.startRound(0)
+
+	// Process any messages that arrived ahead of the new height
+	backlog := sm.backlog
+	sm.backlog = nil
+	for _, msg := range backlog {
+		if msg.Height >= sm.state.height {
+			sm.handleMessage(msg)
+		}
+	}
+}
+
+// startRound moves consensus on the current height into the given round.
+func (sm *stateMachine) startRound(round uint32) {
+	s := sm.state
+	s.round = round
+	s.proposal, s.digest, s.committed = nil, common.Hash{}, false
+	stopTimer(sm.proposeTimer)
+
+	// The first round only starts once the parent is old enough, timeouts
+	// double every round to give slow validators a chance to catch up
+	start := time.Now()
+	if round == 0 {
+		if earliest := time.Unix(int64(s.parent.Time+sm.engine.config.Period), 0); earliest.After(start) {
+			start = earliest
+		}
+	}
+	shift := round
+	if shift > maxTimeoutShift {
+		shift = maxTimeoutShift
+	}
+	timeout := time.Duration(sm.engine.config.RequestTimeout) * time.Millisecond << shift
+	resetTimer(sm.roundTimer, time.Until(start.Add(timeout)))
+
+	if round > 0 {
+		log.Debug("Starting new consensus round", "number", s.height, "round", round)
+		sm.send(&message{
+			Code:          msgRoundChange,
+			Height:        s.height,
+			Round:         round,
+			Digest:        s.lockedDigest,
+			Proposal:      s.locked,
+			PreparedRound: s.lockedRound,
+			PreparedSeals: s.lockedSeals,
+		})
+	}
+	if msg := s.pending[round]; msg != nil {
+		delete(s.pending, round)
+		sm.handlePreprepare(msg)
+	}
+	sm.propose()
+}
+
+// timeout moves on to the next round if the current one failed to reach
+// consensus in time.
+func (sm *stateMachine) timeout() {
+	if sm.state == nil || sm.state.final {
+		return
+	}
+	sm.startRound(sm.state.round + 1)
+}
+
+// propose broadcasts the proposal of the current round if the local validator
+// is its proposer and has something to propose.
+func (sm *stateMachine) propose() {
+	s := sm.state
+	if s == nil || s.final || s.proposal != nil {
+		return
+	}
+	if s.snap.proposer(s.height, s.round) != sm.engine.signerAddress() {
+		return
+	}
+	// Later rounds need a quorum of validators to have given up on the previous
+	// one, and must re-propose the latest block any of them prepared
+	var block *types.Block
+	if s.round > 0 {
+		changes := s.changes[s.round]
+		if len(changes) < s.snap.quorum() {
+			return
+		}
+		var best *message
+		for _, msg := range changes {
+			if msg.Proposal != nil && (best == nil || msg.PreparedRound > best.PreparedRound) {
+				best = msg
+			}
+		}
+		if best != nil {
+			block = best.Proposal
+		}
+	}
+	if block == nil {
+		task := sm.candidate
+		if task == nil || task.block.ParentHash() != s.parent.Hash() {
+			return
+		}
+		select {
+		case <-task.stop:
+			sm.candidate = nil
+			return
+		default:
+		}
+		// Don't propose before the block's timestamp is reached
+		if wait := time.Until(time.Unix(int64(task.block.Time()), 0)); wait > 0 {
+			resetTimer(sm.proposeTimer, wait)
+			return
+		}
+		sealed, err := sm.engine.sealProposal(task.block, s.round)
+		if err != nil {
+			log.Warn("Failed to seal proposal", "number", s.height, "round", s.round, "err", err)
+			return
+		}
+		block = sealed
+	}
+	digest, err := proposalHash(block.Header())
+	if err != nil {
+		log.Warn("Failed to hash proposal", "number", s.height, "round", s.round, "err", err)
+		return
+	}
+	log.Info("Proposing block", "number", s.height, "round", s.round, "txs", len(block.Transactions()), "hash", digest)
+	sm.send(&message{
+		Code:     msgPreprepare,
+		Height:   s.height,
+		Round:    s.round,
+		Digest:   digest,
+		Proposal: block,
+	})
+}
+
+// sealProposal signs a block as the proposer of the given round.
+func (c *IBFT) sealProposal(block *types.Block, round uint32) (*types.Block, error) {
+	header := block.Header()
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	extra.Round, extra.Seal, extra.CommittedSeals = round, nil, nil
+	if err := writeExtra(header, extra); err != nil {
+		return nil, err
+	}
+	sighash, err := sigHash(header)
+	if err != nil {
+		return nil, err
+	}
+	if extra.Seal, err = c.sign(sighash.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writeExtra(header, extra); err != nil {
+		return nil, err
+	}
+	return block.WithSeal(header), nil
+}
+
+// send signs a consensus message, gossips it to the network and processes it
+// locally. Nothing is sent if the local node is not a validator.
+func (sm *stateMachine) send(msg *message) {
+	signer := sm.engine.signerAddress()
+	if !sm.state.snap.isValidator(signer) {
+		return
+	}
+	data, err := msg.signingData()
+	if err != nil {
+		log.Error("Failed to encode consensus message", "err", err)
+		return
+	}
+	if msg.Signature, err = sm.engine.sign(data); err != nil {
+		log.Warn("Failed to sign consensus message", "err", err)
+		return
+	}
+	msg.sender = signer
+
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		log.Error("Failed to encode consensus message", "err", err)
+		return
+	}
+	sm.engine.broadcast(payload)
+	sm.handleMessage(msg)
+}
+
+// handleMessage dispatches a consensus message from a validator.
+func (sm *stateMachine) handleMessage(msg *message) {
+	s := sm.state
+	if s == nil {
+		return
+	}
+	switch {
+	case msg.Height == s.height+1:
+		if len(sm.backlog) < maxBacklog && sm.nextValidator(msg.sender) {
+			sm.backlog = append(sm.backlog, msg)
+		}
+		return
+	case msg.Height != s.height:
+		return
+	case msg.Round > s.round+maxFutureRounds:
+		return
+	case !s.snap.isValidator(msg.sender):
+		return
+	}
+	// The message is relevant to the current height, relay it to the network.
+	// Final blocks are only relayed once their committed seals are verified.
+	if msg.Code != msgFinal {
+		sm.relay(msg)
+	}
+	switch msg.Code {
+	case msgPreprepare:
+		sm.handlePreprepare(msg)
+	case msgPrepare:
+		sm.handlePrepare(msg)
+	case msgCommit:
+		sm.handleCommit(msg)
+	case msgRoundChange:
+		sm.handleRoundChange(msg)
+	case msgFinal:
+		sm.handleFinal(msg)
+	}
+}
+
+// nextValidator returns whether the address may be a validator of the height
+// following the current one. Proposals carry the validator set in effect after
+// them, so the sets of the proposals accepted so far are checked besides the
+// current one, which stays in effect unless a vote passes.
+func (sm *stateMachine) nextValidator(address common.Address) bool {
+	s := sm.state
+	if s.snap.isValidator(address) {
+		return true
+	}
+	for _, block := range s.blocks {
+		extra, err := ExtractExtra(block.Header())
+		if err != nil {
+			continue
+		}
+		for _, validator := range extra.Validators {
+			if validator == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// relay gossips a message received from the network to the peers not yet
+// knowing about it.
+func (sm *stateMachine) relay(msg *message) {
+	if msg.payload != nil {
+		sm.engine.broadcast(msg.payload)
+	}
+}
+
+// handlePreprepare accepts the proposal of a round, preparing it.
+func (sm *stateMachine) handlePreprepare(msg *message) {
+	s := sm.state
+	if s.final || msg.Round < s.round || msg.Proposal == nil {
+		return
+	}
+	if msg.sender != s.snap.proposer(s.height, msg.Round) {
+		log.Debug("Ignoring proposal from non-proposer", "number", s.height, "round", msg.Round, "sender", msg.sender)
+		return
+	}
+	if msg.Round > s.round {
+		s.pending[msg.Round] = msg
+		return
+	}
+	if s.proposal != nil {
+		return
+	}
+	if err := sm.verifyProposal(msg.Proposal, msg.Digest, msg.Round); err != nil {
+		log.Warn("Rejected consensus proposal", "number", s.height, "round", msg.Round, "proposer", msg.sender, "err", err)
+		return
+	}
+	// Validators that prepared a block before may only prepare it again
+	if s.locked != nil && s.lockedDigest != msg.Digest {
+		log.Debug("Ignoring proposal conflicting with lock", "number", s.height, "round", msg.Round, "locked", s.lockedDigest)
+		return
+	}
+	s.proposal, s.digest = msg.Proposal, msg.Digest
+	s.blocks[msg.Digest] = msg.Proposal
+	stopTimer(sm.proposeTimer)
+
+	sm.send(&message{
+		Code:   msgPrepare,
+		Height: s.height,
+		Round:  s.round,
+		Digest: msg.Digest,
+	})
+	sm.checkPrepared()
+	sm.checkCommitted()
+}
+
+// verifyProposal checks that a block is a valid proposal for the given round
+// of the current height, hashing to the given digest.
+func (sm *stateMachine) verifyProposal(block *types.Block, digest common.Hash, round uint32) error {
+	s := sm.state
+	header := block.Header()
+	if header.Number.Uint64() != s.height || header.ParentHash != s.parent.Hash() {
+		return consensus.ErrUnknownAncestor
+	}
+	if hash, err := proposalHash(header); err != nil || hash != digest {
+		return errInvalidMessage
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return err
+	}
+	if extra.Round > round {
+		return errInvalidMessage
+	}
+	if err := sm.engine.verifyHeader(sm.chain, header, nil, false); err != nil {
+		return err
+	}
+	if err := sm.chain.Validator().ValidateBody(block); err != nil && err != core.ErrKnownBlock {
+		return err
+	}
+	return nil
+}
+
+// handlePrepare tallies a validator preparing a proposal.
+func (sm *stateMachine) handlePrepare(msg *message) {
+	s := sm.state
+	if s.prepares[msg.Round] == nil {
+		s.prepares[msg.Round] = make(map[common.Address]*message)
+	}
+	s.prepares[msg.Round][msg.sender] = msg
+	sm.checkPrepared()
+}
+
+// checkPrepared locks on the accepted proposal and commits to it once a quorum
+// of validators prepared it in the current round.
+func (sm *stateMachine) checkPrepared() {
+	s := sm.state
+	if s.final || s.proposal == nil || s.committed {
+		return
+	}
+	var seals [][]byte
+	for _, prepare := range s.prepares[s.round] {
+		if prepare.Digest == s.digest {
+			seals = append(seals, prepare.Signature)
+		}
+	}
+	if len(seals) < s.snap.quorum() {
+		return
+	}
+	s.locked, s.lockedDigest, s.lockedRound, s.lockedSeals = s.proposal, s.digest, s.round, seals
+	s.committed = true
+
+	seal, err := sm.engine.sign(commitData(s.digest))
+	if err != nil {
+		log.Warn("Failed to sign committed seal", "err", err)
+		return
+	}
+	sm.send(&message{
+		Code:          msgCommit,
+		Height:        s.height,
+		Round:         s.round,
+		Digest:        s.digest,
+		CommittedSeal: seal,
+	})
+}
+
+// handleCommit tallies a validator committing to a proposal.
+func (sm *stateMachine) handleCommit(msg *message) {
+	s := sm.state
+	committer, err := recoverSigner(commitData(msg.Digest), msg.CommittedSeal)
+	if err != nil || committer != msg.sender {
+		return
+	}
+	if s.commits[msg.Digest] == nil {
+		s.commits[msg.Digest] = make(map[common.Address][]byte)
+	}
+	s.commits[msg.Digest][msg.sender] = msg.CommittedSeal
+	sm.checkCommitted()
+}
+
+// checkCommitted finalizes the proposal of the current round once a quorum of
+// validators committed to it, regardless of the round they did so in. Only the
+// proposer of the round finalizes, so that every node ends up with the same
+// set of committed seals, and thus the same block hash.
+func (sm *stateMachine) checkCommitted() {
+	s := sm.state
+	if s.final || s.proposal == nil || s.snap.proposer(s.height, s.round) != sm.engine.signerAddress() {
+		return
+	}
+	if seals := s.commits[s.digest]; len(seals) >= s.snap.quorum() {
+		sm.finalize(s.proposal, seals)
+	}
+}
+
+// finalize attaches the committed seals to a proposal, announces the sealed
+// block to the other validators and imports it.
+func (sm *stateMachine) finalize(block *types.Block, seals map[common.Address][]byte) {
+	s := sm.state
+
+	committers := make([]common.Address, 0, len(seals))
+	for committer := range seals {
+		committers = append(committers, committer)
+	}
+	sort.Sort(addressesAscending(committers))
+
+	header := block.Header()
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return
+	}
+	extra.CommittedSeals = make([][]byte, len(committers))
+	for i, committer := range committers {
+		extra.CommittedSeals[i] = seals[committer]
+	}
+	if err := writeExtra(header, extra); err != nil {
+		return
+	}
+	sealed := block.WithSeal(header)
+	log.Info("Consensus reached", "number", s.height, "round", s.round, "hash", sealed.Hash(), "committers", len(committers))
+
+	sm.importBlock(sealed)
+	sm.send(&message{
+		Code:     msgFinal,
+		Height:   s.height,
+		Round:    s.round,
+		Digest:   s.digest,
+		Proposal: sealed,
+	})
+}
+
+// handleFinal imports a block the proposer of a round finalized, once its
+// committed seals are verified, and relays it to the rest of the network.
+func (sm *stateMachine) handleFinal(msg *message) {
+	s := sm.state
+	if s.final || msg.Proposal == nil {
+		return
+	}
+	header := msg.Proposal.Header()
+	if header.Number.Uint64() != s.height || header.ParentHash != s.parent.Hash() {
+		return
+	}
+	if err := sm.engine.verifyHeader(sm.chain, header, nil, true); err != nil {
+		log.Warn("Rejected finalized block", "number", s.height, "round", msg.Round, "sender", msg.sender, "err", err)
+		return
+	}
+	if err := sm.chain.Validator().ValidateBody(msg.Proposal); err != nil && err != core.ErrKnownBlock {
+		log.Warn("Rejected finalized block", "number", s.height, "round", msg.Round, "sender", msg.sender, "err", err)
+		return
+	}
+	sm.relay(msg)
+	sm.importBlock(msg.Proposal)
+}
+
+// importBlock marks the current height decided and imports the sealed block.
+func (sm *stateMachine) importBlock(sealed *types.Block) {
+	s := sm.state
+	s.final = true
+	stopTimer(sm.roundTimer)
+	stopTimer(sm.proposeTimer)
+
+	header := sealed.Header()
+
+	// Hand the block back to the local miner if it built it, so the sealing
+	// work doesn't need to be redone and the block gets broadcast
+	if task := sm.candidate; task != nil && SealHash(task.block.Header()) == SealHash(header) {
+		sm.candidate = nil
+		select {
+		case <-task.stop:
+		default:
+			select {
+			case task.results <- sealed:
+				return
+			default:
+			}
+		}
+	}
+	sm.engine.wg.Add(1)
+	go func() {
+		defer sm.engine.wg.Done()
+		if _, err := sm.chain.InsertChain(types.Blocks{sealed}); err != nil {
+			log.Error("Failed to import finalized block", "number", sealed.Number(), "hash", sealed.Hash(), "err", err)
+		}
+	}()
+}
+
+// handleRoundChange tallies a validator moving to a new round, catching up
+// with the rest of the validators if the local node fell behind.
+func (sm *stateMachine) handleRoundChange(msg *message) {
+	s := sm.state
+	if s.final || msg.Round == 0 || msg.Round < s.round {
+		return
+	}
+	// Drop the prepared block if it's bogus or a quorum of validators didn't
+	// prepare it, the round change still stands
+	if msg.Proposal != nil {
+		if msg.PreparedRound >= msg.Round || !sm.verifyPrepared(msg) || sm.verifyProposal(msg.Proposal, msg.Digest, msg.PreparedRound) != nil {
+			msg.Proposal = nil
+		}
+	}
+	if s.changes[msg.Round] == nil {
+		s.changes[msg.Round] = make(map[common.Address]*message)
+	}
+	s.changes[msg.Round][msg.sender] = msg
+
+	// If enough validators moved past the local round for at least one of them
+	// to be honest, skip ahead to the highest round that many agree on
+	highest := make(map[common.Address]uint32)
+	for round, changes := range s.changes {
+		if round <= s.round {
+			continue
+		}
+		for validator := range changes {
+			if round > highest[validator] {
+				highest[validator] = round
+			}
+		}
+	}
+	if len(highest) > s.snap.faulty() {
+		rounds := make([]uint32, 0, len(highest))
+		for _, round := range highest {
+			rounds = append(rounds, round)
+		}
+		sort.Slice(rounds, func(i, j int) bool { return rounds[i] > rounds[j] })
+		sm.startRound(rounds[s.snap.faulty()])
+		return
+	}
+	if msg.Round == s.round {
+		sm.propose()
+	}
+}
+
+// verifyPrepared checks that the prepared seals of a round change prove that a
+// quorum of validators prepared its locked proposal.
+func (sm *stateMachine) verifyPrepared(msg *message) bool {
+	s := sm.state
+	preparers := make(map[common.Address]struct{})
+	for _, sig := range msg.PreparedSeals {
+		preparer, err := preparedSender(s.height, msg.PreparedRound, msg.Digest, sig)
+		if err != nil || !s.snap.isValidator(preparer) {
+			return false
+		}
+		preparers[preparer] = struct{}{}
+	}
+	return len(preparers) >= s.snap.quorum()
+}
+
+// stopTimer stops a timer and drains its channel if it already fired.
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+// resetTimer reschedules a timer to fire after the given duration.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	stopTimer(timer)
+	timer.Reset(d)
+}
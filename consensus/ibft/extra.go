@@ -0,0 +1,149 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+// ExtraVanity is the fixed number of extra-data prefix bytes of IBFT headers
+// reserved for proposer vanity, preceding the RLP encoded consensus fields.
+const ExtraVanity = 32
+
+var (
+	// MixDigest is the mix digest of headers sealed by the IBFT consensus engine.
+	MixDigest = common.HexToHash("0x63746963616c2062797a616e74696e65206661756c7420746f6c6572616e6365")
+
+	// errInvalidExtra is returned if a header's extra-data doesn't contain the
+	// IBFT consensus fields.
+	errInvalidExtra = errors.New("invalid ibft extra-data")
+)
+
+// Vote is a proposal to add an account to, or remove it from, the validator
+// set, cast by the proposer of a block.
+type Vote struct {
+	Address   common.Address // Account being voted on to change its authorization
+	Authorize bool           // Whether to authorize or deauthorize the voted account
+}
+
+// Extra is the consensus data embedded into the extra-data of IBFT headers,
+// following the vanity bytes.
+type Extra struct {
+	Validators     []common.Address // Validator set in effect after this block, in ascending order
+	Vote           *Vote            `rlp:"nil"` // Validator governance vote cast by the proposer, if any
+	Round          uint32           // Consensus round the block was proposed in
+	Seal           []byte           // Proposer signature over the header
+	CommittedSeals [][]byte         // Validator signatures committing to the proposal
+}
+
+// ExtractExtra decodes the IBFT consensus fields from the extra-data of a header.
+func ExtractExtra(header *types.Header) (*Extra, error) {
+	if len(header.Extra) < ExtraVanity {
+		return nil, errInvalidExtra
+	}
+	extra := new(Extra)
+	if err := rlp.DecodeBytes(header.Extra[ExtraVanity:], extra); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// writeExtra replaces the consensus fields in the extra-data of a header,
+// retaining the vanity. The vanity is zero padded if too short.
+func writeExtra(header *types.Header, extra *Extra) error {
+	payload, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		return err
+	}
+	vanity := make([]byte, ExtraVanity)
+	copy(vanity, header.Extra)
+
+	header.Extra = append(vanity, payload...)
+	return nil
+}
+
+// GenesisExtra assembles the extra-data of a genesis block authorizing the
+// given initial validators.
+func GenesisExtra(vanity []byte, validators []common.Address) ([]byte, error) {
+	header := &types.Header{Extra: vanity}
+	if err := writeExtra(header, &Extra{Validators: sortedAddresses(validators)}); err != nil {
+		return nil, err
+	}
+	return header.Extra, nil
+}
+
+// filteredHash calculates the hash of a header with some of its consensus
+// fields cleared. Committed seals are always cleared, as they are only added
+// once consensus was reached on the rest of the header.
+func filteredHash(header *types.Header, keepRound, keepSeal bool) (common.Hash, error) {
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !keepRound {
+		extra.Round = 0
+	}
+	if !keepSeal {
+		extra.Seal = nil
+	}
+	extra.CommittedSeals = nil
+
+	cpy := types.CopyHeader(header)
+	if err := writeExtra(cpy, extra); err != nil {
+		return common.Hash{}, err
+	}
+	return cpy.Hash(), nil
+}
+
+// sigHash returns the hash the proposer of a block signs.
+func sigHash(header *types.Header) (common.Hash, error) {
+	return filteredHash(header, true, false)
+}
+
+// proposalHash returns the hash validators prepare and commit to, covering the
+// entire header apart from the committed seals.
+func proposalHash(header *types.Header) (common.Hash, error) {
+	return filteredHash(header, true, true)
+}
+
+// commitData returns the data a validator signs to commit to a proposal.
+func commitData(digest common.Hash) []byte {
+	return append(digest.Bytes(), byte(msgCommit))
+}
+
+// recoverSigner returns the address which produced the signature over the
+// keccak256 hash of the given data.
+func recoverSigner(data []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// addressesAscending implements the sort interface to allow sorting a list of addresses.
+type addressesAscending []common.Address
+
+func (s addressesAscending) Len() int           { return len(s) }
+func (s addressesAscending) Less(i, j int) bool { return bytes.Compare(s[i][:], s[j][:]) < 0 }
+func (s addressesAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,607 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ibft implements a Byzantine fault tolerant proof-of-authority
+// consensus engine with immediate finality.
+//
+// Blocks are agreed upon by a set of validators in rounds. The proposer of each
+// round, rotating across the validators, broadcasts a block which validators
+// prepare and then commit to once a quorum of them accepted it. A block is only
+// valid if it carries the committed seals of a quorum of validators, so any two
+// valid blocks at the same height are impossible without more than a third of
+// the validators misbehaving, and blocks are final as soon as they are sealed.
+package ibft
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/accounts"
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus"
+	"github.com/crypyto-panel/go-etherdata/consensus/misc"
+	"github.com/crypyto-panel/go-etherdata/core/state"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+	"github.com/crypyto-panel/go-etherdata/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	inmemorySnapshots  = 128  // Number of recent validator snapshots to keep in memory
+	inmemorySignatures = 4096 // Number of recent block proposers to keep in memory
+
+	defaultEpoch          = 30000 // Default number of blocks after which to reset the pending votes
+	defaultRequestTimeout = 10000 // Default timeout of the first round in milliseconds
+)
+
+var (
+	// defaultDifficulty is the difficulty of every block. Forks are impossible
+	// with committed seals, so there's no need to weigh chains.
+	defaultDifficulty = big.NewInt(1)
+
+	// uncleHash is always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+	uncleHash = types.CalcUncleHash(nil)
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidCheckpointVote is returned if a checkpoint block contains a vote.
+	errInvalidCheckpointVote = errors.New("vote in checkpoint block")
+
+	// errInvalidMixDigest is returned if a block's mix digest is not the IBFT one.
+	errInvalidMixDigest = errors.New("invalid mix digest")
+
+	// errInvalidNonce is returned if a block's nonce is non-zero.
+	errInvalidNonce = errors.New("non-zero nonce")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is not 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errInvalidProposer is returned if a block is not sealed by the validator
+	// entitled to propose it.
+	errInvalidProposer = errors.New("invalid proposer")
+
+	// errMismatchingValidators is returned if the validator set embedded in a
+	// block doesn't match the outcome of the governance votes.
+	errMismatchingValidators = errors.New("mismatching validator set")
+
+	// errInvalidCommittedSeals is returned if a block contains a committed seal
+	// not made by a validator, or made over the wrong proposal.
+	errInvalidCommittedSeals = errors.New("invalid committed seals")
+
+	// errInsufficientCommittedSeals is returned if a block doesn't contain the
+	// committed seals of a quorum of validators.
+	errInsufficientCommittedSeals = errors.New("insufficient committed seals")
+
+	// errUnauthorizedValidator is returned if the local signer is asked to seal
+	// a block while not being a validator.
+	errUnauthorizedValidator = errors.New("unauthorized validator")
+
+	// errNotStarted is returned if a block is to be sealed while the consensus
+	// loop is not running.
+	errNotStarted = errors.New("consensus not started")
+)
+
+// SignerFn hashes and signs the data to be signed by a backing account.
+type SignerFn func(signer accounts.Account, mimeType string, message []byte) ([]byte, error)
+
+// IBFT is the Byzantine fault tolerant proof-of-authority consensus engine.
+type IBFT struct {
+	config *params.IBFTConfig // Consensus engine configuration parameters
+
+	recents    *lru.ARCCache // Snapshots for recent blocks to speed up reorgs
+	signatures *lru.ARCCache // Proposers of recent blocks to speed up verification
+
+	proposals map[common.Address]bool // Current list of proposals we are pushing
+
+	signer common.Address // Etherdata address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer and proposals fields
+
+	peers   *peerSet       // Peers connected via the consensus protocol
+	known   *lru.Cache     // Hashes of consensus messages already seen
+	msgCh   chan *message  // Decoded consensus messages to process
+	reqCh   chan *sealTask // Blocks handed over for proposal by the miner
+	running int32          // Whether the consensus loop is running (atomic)
+	quit    chan struct{}  // Termination channel for the consensus loop
+	wg      sync.WaitGroup // Tracks the consensus loop and block imports
+	start   sync.Once      // Ensures the consensus loop is only started once
+	close   sync.Once      // Ensures the engine is only closed once
+}
+
+// New creates an IBFT consensus engine with the given configuration. The
+// consensus loop needs to be started separately for the engine to take part
+// in consensus as a validator.
+func New(config *params.IBFTConfig) *IBFT {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = defaultEpoch
+	}
+	if conf.RequestTimeout == 0 {
+		conf.RequestTimeout = defaultRequestTimeout
+	}
+	recents, _ := lru.NewARC(inmemorySnapshots)
+	signatures, _ := lru.NewARC(inmemorySignatures)
+	known, _ := lru.New(knownMessages)
+
+	return &IBFT{
+		config:     &conf,
+		recents:    recents,
+		signatures: signatures,
+		proposals:  make(map[common.Address]bool),
+		peers:      newPeerSet(),
+		known:      known,
+		msgCh:      make(chan *message, messageQueueSize),
+		reqCh:      make(chan *sealTask),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Author implements consensus.Engine, returning the Etherdata address of the
+// validator that proposed the block.
+func (c *IBFT) Author(header *types.Header) (common.Address, error) {
+	hash := header.Hash()
+	if address, known := c.signatures.Get(hash); known {
+		return address.(common.Address), nil
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sighash, err := sigHash(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	proposer, err := recoverSigner(sighash.Bytes(), extra.Seal)
+	if err != nil {
+		return common.Address{}, err
+	}
+	c.signatures.Add(hash, proposer)
+	return proposer, nil
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules. The
+// committed seals are only verified if seal is set.
+func (c *IBFT) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return c.verifyHeader(chain, header, nil, seal)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (c *IBFT) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := c.verifyHeader(chain, header, headers[:i], seals[i])
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database. Committed seals are only checked if
+// requested, allowing proposals to be verified before consensus is reached.
+func (c *IBFT) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, committed bool) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time > uint64(time.Now().Unix()) {
+		return consensus.ErrFutureBlock
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return err
+	}
+	if number%c.config.Epoch == 0 && extra.Vote != nil {
+		return errInvalidCheckpointVote
+	}
+	if header.MixDigest != MixDigest {
+		return errInvalidMixDigest
+	}
+	if header.Nonce != (types.BlockNonce{}) {
+		return errInvalidNonce
+	}
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	if number > 0 && (header.Difficulty == nil || header.Difficulty.Cmp(defaultDifficulty) != 0) {
+		return errInvalidDifficulty
+	}
+	// Verify that the gas limit is <= 2^63-1
+	cap := uint64(0x7fffffffffffffff)
+	if header.GasLimit > cap {
+		return fmt.Errorf("invalid gasLimit: have %v, max %v", header.GasLimit, cap)
+	}
+	// All basic checks passed, verify cascading fields
+	return c.verifyCascadingFields(chain, header, extra, parents, committed)
+}
+
+// verifyCascadingFields verifies all the header fields that are not standalone,
+// rather depend on a batch of previous headers.
+func (c *IBFT) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, extra *Extra, parents []*types.Header, committed bool) error {
+	// The genesis block is the always valid dead-end
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time+c.config.Period > header.Time {
+		return errInvalidTimestamp
+	}
+	// Verify that the gasUsed is <= gasLimit
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if !chain.Config().IsLondon(header.Number) {
+		// Verify BaseFee not present before EIP-1559 fork.
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %d, want <nil>", header.BaseFee)
+		}
+		if err := misc.VerifyGaslimit(parent.GasLimit, header.GasLimit); err != nil {
+			return err
+		}
+	} else if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		// Verify the header's EIP-1559 attributes.
+		return err
+	}
+	// Retrieve the validator set of the parent and verify the proposer
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	proposer, err := c.Author(header)
+	if err != nil {
+		return err
+	}
+	if proposer != snap.proposer(number, extra.Round) {
+		return errInvalidProposer
+	}
+	// Ensure the validator set change matches the outcome of the votes
+	next := snap.copy()
+	next.apply(number, proposer, extra.Vote)
+	if !validatorsEqual(next.Validators, extra.Validators) {
+		return errMismatchingValidators
+	}
+	if !committed {
+		return nil
+	}
+	return verifyCommittedSeals(snap, header, extra)
+}
+
+// verifyCommittedSeals checks that a quorum of the validators in the given
+// snapshot committed to the header.
+func verifyCommittedSeals(snap *Snapshot, header *types.Header, extra *Extra) error {
+	digest, err := proposalHash(header)
+	if err != nil {
+		return err
+	}
+	data := commitData(digest)
+
+	committers := make(map[common.Address]struct{})
+	for _, seal := range extra.CommittedSeals {
+		committer, err := recoverSigner(data, seal)
+		if err != nil {
+			return errInvalidCommittedSeals
+		}
+		if !snap.isValidator(committer) {
+			return errInvalidCommittedSeals
+		}
+		if _, ok := committers[committer]; ok {
+			return errInvalidCommittedSeals
+		}
+		committers[committer] = struct{}{}
+	}
+	if len(committers) < snap.quorum() {
+		return errInsufficientCommittedSeals
+	}
+	return nil
+}
+
+// snapshot retrieves the validator snapshot at a given point in time.
+func (c *IBFT) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
+	// Search for a snapshot in memory or a checkpoint to start from
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for snap == nil {
+		// If an in-memory snapshot was found, use that
+		if s, ok := c.recents.Get(hash); ok {
+			snap = s.(*Snapshot)
+			break
+		}
+		// No snapshot for this header, gather the header and move backward
+		var header *types.Header
+		if len(parents) > 0 {
+			// If we have explicit parents, pick from there (enforced)
+			header = parents[len(parents)-1]
+			if header.Hash() != hash || header.Number.Uint64() != number {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			parents = parents[:len(parents)-1]
+		} else {
+			// No explicit parents (or no more left), reach out to the database
+			header = chain.GetHeader(hash, number)
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+		// Every block embeds the validator set in effect after it, but pending
+		// votes need to be replayed from the last checkpoint on
+		if number%c.config.Epoch == 0 {
+			extra, err := ExtractExtra(header)
+			if err != nil {
+				return nil, err
+			}
+			snap = newSnapshot(c.config.Epoch, number, hash, extra.Validators)
+			break
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+	// Previous snapshot found, apply any pending headers on top of it
+	if len(headers) > 0 {
+		snap = snap.copy()
+		for i := len(headers) - 1; i >= 0; i-- {
+			header := headers[i]
+			extra, err := ExtractExtra(header)
+			if err != nil {
+				return nil, err
+			}
+			proposer, err := c.Author(header)
+			if err != nil {
+				return nil, err
+			}
+			snap.apply(header.Number.Uint64(), proposer, extra.Vote)
+			snap.Hash = header.Hash()
+		}
+	}
+	c.recents.Add(snap.Hash, snap)
+	return snap, nil
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+func (c *IBFT) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return nil
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (c *IBFT) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	header.Nonce = types.BlockNonce{}
+	header.MixDigest = MixDigest
+	header.Difficulty = new(big.Int).Set(defaultDifficulty)
+
+	number := header.Number.Uint64()
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	// Cast a random vote on the pending proposals (good enough for now)
+	c.lock.RLock()
+	var vote *Vote
+	if number%c.config.Epoch != 0 {
+		addresses := make([]common.Address, 0, len(c.proposals))
+		for address, authorize := range c.proposals {
+			if snap.validVote(address, authorize) {
+				addresses = append(addresses, address)
+			}
+		}
+		if len(addresses) > 0 {
+			address := addresses[rand.Intn(len(addresses))]
+			vote = &Vote{Address: address, Authorize: c.proposals[address]}
+		}
+	}
+	signer := c.signer
+	c.lock.RUnlock()
+
+	// Embed the validator set resulting from the vote
+	next := snap.copy()
+	next.apply(number, signer, vote)
+	if err := writeExtra(header, &Extra{Validators: next.Validators, Vote: vote}); err != nil {
+		return err
+	}
+	// Ensure the timestamp has the correct delay
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Time = parent.Time + c.config.Period
+	if header.Time < uint64(time.Now().Unix()) {
+		header.Time = uint64(time.Now().Unix())
+	}
+	return nil
+}
+
+// Finalize implements consensus.Engine, ensuring no uncles are set, nor block
+// rewards given.
+func (c *IBFT) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	// No block rewards in PoA, so the state remains as is and uncles are dropped
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	header.UncleHash = types.CalcUncleHash(nil)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
+// nor block rewards given, and returns the final block.
+func (c *IBFT) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// Finalize block
+	c.Finalize(chain, header, state, txs, uncles)
+
+	// Assemble and return the final block for sealing
+	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
+}
+
+// Authorize injects a private key into the consensus engine to propose and
+// validate blocks with.
+func (c *IBFT) Authorize(signer common.Address, signFn SignerFn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.signer = signer
+	c.signFn = signFn
+}
+
+// signerAddress returns the address of the local validator key.
+func (c *IBFT) signerAddress() common.Address {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.signer
+}
+
+// sign signs the given data with the local validator key.
+func (c *IBFT) sign(data []byte) ([]byte, error) {
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errUnauthorizedValidator
+	}
+	return signFn(accounts.Account{Address: signer}, accounts.MimetypeIBFT, data)
+}
+
+// Seal implements consensus.Engine, handing the block over to the consensus
+// loop to be proposed once the local validator's turn comes. The sealed block
+// is only returned once a quorum of validators committed to it.
+func (c *IBFT) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if !snap.isValidator(c.signerAddress()) {
+		return errUnauthorizedValidator
+	}
+	if atomic.LoadInt32(&c.running) == 0 {
+		return errNotStarted
+	}
+	select {
+	case c.reqCh <- &sealTask{block: block, results: results, stop: stop}:
+	case <-stop:
+	case <-c.quit:
+		return errNotStarted
+	}
+	return nil
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+func (c *IBFT) SealHash(header *types.Header) common.Hash {
+	return SealHash(header)
+}
+
+// SealHash returns the hash of a block prior to it being proposed, excluding
+// the round it is proposed in and all signatures.
+func SealHash(header *types.Header) common.Hash {
+	hash, err := filteredHash(header, false, false)
+	if err != nil {
+		return header.Hash()
+	}
+	return hash
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It always returns 1,
+// as committed seals rule out forks.
+func (c *IBFT) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(defaultDifficulty)
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to allow
+// controlling the validator voting mechanisms.
+func (c *IBFT) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "ibft",
+		Version:   "1.0",
+		Service:   &API{chain: chain, ibft: c},
+		Public:    false,
+	}}
+}
+
+// Close implements consensus.Engine, terminating the consensus loop.
+func (c *IBFT) Close() error {
+	c.close.Do(func() {
+		close(c.quit)
+		atomic.StoreInt32(&c.running, 0)
+		c.wg.Wait()
+	})
+	return nil
+}
+
+// validatorsEqual returns whether two sorted validator lists are identical.
+func validatorsEqual(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
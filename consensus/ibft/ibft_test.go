@@ -0,0 +1,589 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/accounts"
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus/misc"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/core/vm"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+// testNode is a validator of an in-process test network, running its own
+// chain and consensus engine, and proposing empty blocks when asked to.
+type testNode struct {
+	key    *ecdsa.PrivateKey
+	addr   common.Address
+	engine *IBFT
+	chain  *core.BlockChain
+	quit   chan struct{}
+}
+
+// testNetwork is a set of validators connected via in-memory pipes.
+type testNetwork struct {
+	nodes []*testNode
+	pipes []*p2p.MsgPipeRW
+	wg    sync.WaitGroup
+}
+
+// newTestNetwork creates a network of the given number of validators, starting
+// only the first online ones of them.
+func newTestNetwork(t *testing.T, validators int, online int) *testNetwork {
+	t.Helper()
+
+	keys := make([]*ecdsa.PrivateKey, validators)
+	addrs := make([]common.Address, validators)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.IBFT = &params.IBFTConfig{Period: 0, Epoch: 30000, RequestTimeout: 500}
+
+	extra, err := GenesisExtra(nil, addrs)
+	if err != nil {
+		t.Fatalf("failed to create genesis extra-data: %v", err)
+	}
+	genesis := &core.Genesis{
+		Config:     &config,
+		ExtraData:  extra,
+		GasLimit:   params.GenesisGasLimit,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: big.NewInt(1),
+		Alloc:      core.GenesisAlloc{},
+	}
+	network := new(testNetwork)
+	for i := 0; i < online; i++ {
+		db := rawdb.NewMemoryDatabase()
+		genesis.MustCommit(db)
+
+		engine := New(config.IBFT)
+		chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create blockchain: %v", err)
+		}
+		key := keys[i]
+		engine.Authorize(addrs[i], func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(data), key)
+		})
+		network.nodes = append(network.nodes, &testNode{
+			key:    key,
+			addr:   addrs[i],
+			engine: engine,
+			chain:  chain,
+			quit:   make(chan struct{}),
+		})
+	}
+	// Connect all the online nodes with each other
+	for i, a := range network.nodes {
+		for j, b := range network.nodes[i+1:] {
+			rwa, rwb := p2p.MsgPipe()
+			network.pipes = append(network.pipes, rwa, rwb)
+
+			go a.engine.runPeer(newPeer(p2p.NewPeer(enode.ID{byte(i + j + 1)}, "", nil), rwa))
+			go b.engine.runPeer(newPeer(p2p.NewPeer(enode.ID{byte(i)}, "", nil), rwb))
+		}
+	}
+	for _, node := range network.nodes {
+		node.engine.Start(node.chain)
+
+		network.wg.Add(1)
+		go func(node *testNode) {
+			defer network.wg.Done()
+			node.seal(t)
+		}(node)
+	}
+	return network
+}
+
+// close tears down all the nodes of the network.
+func (n *testNetwork) close() {
+	for _, node := range n.nodes {
+		close(node.quit)
+	}
+	n.wg.Wait()
+
+	for _, pipe := range n.pipes {
+		pipe.Close()
+	}
+	for _, node := range n.nodes {
+		node.engine.Close()
+		node.chain.Stop()
+	}
+}
+
+// waitHeight waits until all nodes reached the given height.
+func (n *testNetwork) waitHeight(t *testing.T, height uint64, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for _, node := range n.nodes {
+		for node.chain.CurrentBlock().NumberU64() < height {
+			if time.Now().After(deadline) {
+				t.Fatalf("validator %x stuck at height %d, want %d", node.addr, node.chain.CurrentBlock().NumberU64(), height)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// seal acts as the miner of a node, building an empty block on top of every new
+// head and handing it to the engine for proposal.
+func (node *testNode) seal(t *testing.T) {
+	heads := make(chan core.ChainHeadEvent, 10)
+	sub := node.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	var (
+		results = make(chan *types.Block, 1)
+		stop    = make(chan struct{})
+		head    = node.chain.CurrentBlock()
+	)
+	for {
+		block, err := node.candidate(head)
+		if err == nil {
+			err = node.engine.Seal(node.chain, block, results, stop)
+		}
+		if err != nil {
+			t.Errorf("failed to seal candidate: %v", err)
+			return
+		}
+		select {
+		case ev := <-heads:
+			head = ev.Block
+		case block := <-results:
+			if _, err := node.chain.InsertChain(types.Blocks{block}); err != nil {
+				t.Errorf("failed to insert sealed block: %v", err)
+				return
+			}
+			head = block
+		case <-node.quit:
+			close(stop)
+			return
+		}
+		close(stop)
+		stop = make(chan struct{})
+	}
+}
+
+// candidate creates an empty block to propose on top of the given parent.
+func (node *testNode) candidate(parent *types.Block) (*types.Block, error) {
+	config := node.chain.Config()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent.Header())
+	}
+	if err := node.engine.Prepare(node.chain, header); err != nil {
+		return nil, err
+	}
+	statedb, err := node.chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	return node.engine.FinalizeAndAssemble(node.chain, header, statedb, nil, nil, nil)
+}
+
+// checkAgreement verifies that all nodes have the same chain up to a height.
+func (n *testNetwork) checkAgreement(t *testing.T, height uint64) {
+	t.Helper()
+
+	for number := uint64(1); number <= height; number++ {
+		want := n.nodes[0].chain.GetHeaderByNumber(number).Hash()
+		for _, node := range n.nodes[1:] {
+			if have := node.chain.GetHeaderByNumber(number).Hash(); have != want {
+				t.Fatalf("block %d mismatch: validator %x has %x, want %x", number, node.addr, have, want)
+			}
+		}
+	}
+}
+
+// Tests that a fully online validator set reaches consensus on new blocks.
+func TestConsensus(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	defer network.close()
+
+	network.waitHeight(t, 8, 30*time.Second)
+	network.checkAgreement(t, 8)
+
+	// Every block should carry the committed seals of a quorum
+	node := network.nodes[0]
+	for number := uint64(1); number <= 8; number++ {
+		header := node.chain.GetHeaderByNumber(number)
+		extra, err := ExtractExtra(header)
+		if err != nil {
+			t.Fatalf("block %d: failed to decode extra-data: %v", number, err)
+		}
+		if len(extra.CommittedSeals) < 3 {
+			t.Errorf("block %d: committed seal count mismatch: have %d, want >= 3", number, len(extra.CommittedSeals))
+		}
+	}
+}
+
+// Tests that consensus is reached even if a faulty validator is offline, with
+// the rounds it should propose in timing out.
+func TestConsensusFaultTolerance(t *testing.T) {
+	network := newTestNetwork(t, 4, 3)
+	defer network.close()
+
+	network.waitHeight(t, 6, 60*time.Second)
+	network.checkAgreement(t, 6)
+
+	var changes int
+	for number := uint64(1); number <= 6; number++ {
+		extra, err := ExtractExtra(network.nodes[0].chain.GetHeaderByNumber(number))
+		if err != nil {
+			t.Fatalf("block %d: failed to decode extra-data: %v", number, err)
+		}
+		if extra.Round > 0 {
+			changes++
+		}
+	}
+	if changes == 0 {
+		t.Errorf("no round changes despite offline proposer")
+	}
+}
+
+// Tests that blocks without the committed seals of a quorum are rejected.
+func TestVerifyCommittedSeals(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	network.waitHeight(t, 1, 30*time.Second)
+	network.close()
+
+	node := network.nodes[0]
+	header := node.chain.GetHeaderByNumber(1)
+	if err := node.engine.VerifyHeader(node.chain, header, true); err != nil {
+		t.Fatalf("failed to verify sealed header: %v", err)
+	}
+	tests := []struct {
+		seals func([][]byte) [][]byte
+		err   error
+	}{
+		{func(seals [][]byte) [][]byte { return seals[:2] }, errInsufficientCommittedSeals},
+		{func(seals [][]byte) [][]byte { return append(seals[:2], seals[0]) }, errInvalidCommittedSeals},
+		{func(seals [][]byte) [][]byte { return [][]byte{{1, 2, 3}} }, errInvalidCommittedSeals},
+	}
+	for i, tt := range tests {
+		extra, _ := ExtractExtra(header)
+		extra.CommittedSeals = tt.seals(extra.CommittedSeals)
+
+		cpy := types.CopyHeader(header)
+		if err := writeExtra(cpy, extra); err != nil {
+			t.Fatalf("test %d: failed to write extra-data: %v", i, err)
+		}
+		engine := New(node.chain.Config().IBFT)
+		if err := engine.VerifyHeader(node.chain, cpy, true); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the proposal hash validators commit to doesn't depend on the
+// committed seals, while the block hash does.
+func TestProposalHash(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), MixDigest: MixDigest}
+	extra := &Extra{Validators: []common.Address{{1}, {2}}, Seal: []byte{1}}
+	if err := writeExtra(header, extra); err != nil {
+		t.Fatalf("failed to write extra-data: %v", err)
+	}
+	digest, err := proposalHash(header)
+	if err != nil {
+		t.Fatalf("failed to hash proposal: %v", err)
+	}
+	sealed := types.CopyHeader(header)
+	extra.CommittedSeals = [][]byte{{1}, {2}, {3}}
+	if err := writeExtra(sealed, extra); err != nil {
+		t.Fatalf("failed to write extra-data: %v", err)
+	}
+	if have, _ := proposalHash(sealed); have != digest {
+		t.Errorf("committed seals changed the proposal hash: have %x, want %x", have, digest)
+	}
+	if sealed.Hash() == header.Hash() {
+		t.Errorf("committed seals didn't change the block hash")
+	}
+	extra.Round = 1
+	if err := writeExtra(sealed, extra); err != nil {
+		t.Fatalf("failed to write extra-data: %v", err)
+	}
+	if have, _ := proposalHash(sealed); have == digest {
+		t.Errorf("round change didn't change the proposal hash")
+	}
+}
+
+// Tests that headers are only checked for committed seals if requested.
+func TestVerifyHeaderSeal(t *testing.T) {
+	network := newTestNetwork(t, 4, 4)
+	network.waitHeight(t, 1, 30*time.Second)
+	network.close()
+
+	node := network.nodes[0]
+	header := types.CopyHeader(node.chain.GetHeaderByNumber(1))
+	extra, _ := ExtractExtra(header)
+	extra.CommittedSeals = nil
+	if err := writeExtra(header, extra); err != nil {
+		t.Fatalf("failed to write extra-data: %v", err)
+	}
+	engine := New(node.chain.Config().IBFT)
+	if err := engine.VerifyHeader(node.chain, header, false); err != nil {
+		t.Errorf("unsealed header rejected without seal check: %v", err)
+	}
+	if err := engine.VerifyHeader(node.chain, header, true); err != errInsufficientCommittedSeals {
+		t.Errorf("unsealed header error mismatch: have %v, want %v", err, errInsufficientCommittedSeals)
+	}
+	_, results := engine.VerifyHeaders(node.chain, []*types.Header{header}, []bool{false})
+	if err := <-results; err != nil {
+		t.Errorf("unsealed header batch rejected without seal check: %v", err)
+	}
+}
+
+// newTestStateMachine creates a consensus state machine for the given
+// validators without starting its loop, deciding on the block after genesis.
+func newTestStateMachine(t *testing.T, keys []*ecdsa.PrivateKey) *stateMachine {
+	t.Helper()
+
+	addrs := make([]common.Address, len(keys))
+	for i, key := range keys {
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.IBFT = &params.IBFTConfig{Period: 0, Epoch: 30000, RequestTimeout: 500}
+
+	extra, err := GenesisExtra(nil, addrs)
+	if err != nil {
+		t.Fatalf("failed to create genesis extra-data: %v", err)
+	}
+	genesis := &core.Genesis{Config: &config, ExtraData: extra, BaseFee: big.NewInt(params.InitialBaseFee), Difficulty: big.NewInt(1)}
+
+	db := rawdb.NewMemoryDatabase()
+	genesis.MustCommit(db)
+
+	engine := New(config.IBFT)
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+
+	sm := &stateMachine{engine: engine, chain: chain, roundTimer: time.NewTimer(time.Hour), proposeTimer: time.NewTimer(time.Hour)}
+	t.Cleanup(func() {
+		sm.roundTimer.Stop()
+		sm.proposeTimer.Stop()
+	})
+	sm.newHeight(chain.CurrentHeader())
+	return sm
+}
+
+// signTestMessage signs a consensus message with the given key and encodes it
+// as it is sent over the network.
+func signTestMessage(t *testing.T, msg *message, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+
+	data, err := msg.signingData()
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	if msg.Signature, err = crypto.Sign(crypto.Keccak256(data), key); err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	return payload
+}
+
+// Tests that consensus messages are only relayed once they were validated
+// against the validator set, height and round of the consensus state.
+func TestRelayValidation(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sm := newTestStateMachine(t, keys)
+
+	// Connect two peers, one sending the messages and one to relay them to
+	src, dst := newPeer(p2p.NewPeer(enode.ID{1}, "", nil), nil), newPeer(p2p.NewPeer(enode.ID{2}, "", nil), nil)
+	for _, p := range []*peer{src, dst} {
+		if err := sm.engine.peers.register(p); err != nil {
+			t.Fatalf("failed to register peer: %v", err)
+		}
+	}
+	outsider, _ := crypto.GenerateKey()
+	tests := []struct {
+		msg   *message
+		key   *ecdsa.PrivateKey
+		relay bool
+	}{
+		{&message{Code: msgPrepare, Height: 1}, keys[0], true},
+		{&message{Code: msgPrepare, Height: 1}, outsider, false},
+		{&message{Code: msgPrepare, Height: 0}, keys[1], false},
+		{&message{Code: msgPrepare, Height: 3}, keys[1], false},
+		{&message{Code: msgPrepare, Height: 1, Round: maxFutureRounds + 1}, keys[1], false},
+		{&message{Code: msgRoundChange, Height: 1, Round: 1}, keys[2], true},
+	}
+	for i, tt := range tests {
+		if err := sm.engine.handlePayload(src, signTestMessage(t, tt.msg, tt.key)); err != nil {
+			t.Fatalf("test %d: failed to handle message: %v", i, err)
+		}
+		if len(dst.queue) != 0 {
+			t.Fatalf("test %d: message relayed before validation", i)
+		}
+		sm.handleMessage(<-sm.engine.msgCh)
+		if relayed := len(dst.queue) == 1; relayed != tt.relay {
+			t.Errorf("test %d: relay mismatch: have %v, want %v", i, relayed, tt.relay)
+		}
+		for len(dst.queue) > 0 {
+			<-dst.queue
+		}
+	}
+}
+
+// Tests that round changes only carry a locked proposal if it comes with the
+// prepare signatures of a quorum of validators.
+func TestRoundChangeCertificate(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sm := newTestStateMachine(t, keys)
+	digest := common.Hash{0x01}
+
+	prepared := func(signers ...*ecdsa.PrivateKey) [][]byte {
+		var seals [][]byte
+		for _, key := range signers {
+			prepare := &message{Code: msgPrepare, Height: 1, Round: 0, Digest: digest}
+			signTestMessage(t, prepare, key)
+			seals = append(seals, prepare.Signature)
+		}
+		return seals
+	}
+	outsider, _ := crypto.GenerateKey()
+	tests := []struct {
+		seals [][]byte
+		valid bool
+	}{
+		{prepared(keys[0], keys[1], keys[2]), true},
+		{prepared(keys[0], keys[1]), false},
+		{prepared(keys[0], keys[1], keys[1]), false},
+		{prepared(keys[0], keys[1], outsider), false},
+		{nil, false},
+	}
+	for i, tt := range tests {
+		msg := &message{Code: msgRoundChange, Height: 1, Round: 1, Digest: digest, PreparedSeals: tt.seals}
+		if valid := sm.verifyPrepared(msg); valid != tt.valid {
+			t.Errorf("test %d: certificate validity mismatch: have %v, want %v", i, valid, tt.valid)
+		}
+	}
+	// A different prepared round must invalidate the certificate
+	msg := &message{Code: msgRoundChange, Height: 1, Round: 2, Digest: digest, PreparedRound: 1, PreparedSeals: tests[0].seals}
+	if sm.verifyPrepared(msg) {
+		t.Errorf("certificate accepted for wrong round")
+	}
+}
+
+// Tests that messages for the next height are only kept around if they come
+// from validators of that height.
+func TestBacklogValidators(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sm := newTestStateMachine(t, keys)
+	outsider, _ := crypto.GenerateKey()
+
+	backlog := func(key *ecdsa.PrivateKey) bool {
+		msg, err := decodeMessage(signTestMessage(t, &message{Code: msgPrepare, Height: 2}, key))
+		if err != nil {
+			t.Fatalf("failed to decode message: %v", err)
+		}
+		prev := len(sm.backlog)
+		sm.handleMessage(msg)
+		return len(sm.backlog) > prev
+	}
+	if !backlog(keys[0]) {
+		t.Errorf("message of current validator not backlogged")
+	}
+	if backlog(outsider) {
+		t.Errorf("message of non-validator backlogged")
+	}
+	// Accept a proposal authorizing the outsider and ensure it's backlogged
+	validators := make([]common.Address, 0, len(keys)+1)
+	for _, key := range append(keys, outsider) {
+		validators = append(validators, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	extra, err := GenesisExtra(nil, sortedAddresses(validators))
+	if err != nil {
+		t.Fatalf("failed to create extra-data: %v", err)
+	}
+	sm.state.blocks[common.Hash{0x01}] = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Extra: extra})
+	if !backlog(outsider) {
+		t.Errorf("message of next height validator not backlogged")
+	}
+}
+
+// Tests that a message dropped because the consensus queue is full is accepted
+// again once it's re-gossiped.
+func TestHandlePayloadQueueFull(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sm := newTestStateMachine(t, keys)
+	src := newPeer(p2p.NewPeer(enode.ID{1}, "", nil), nil)
+
+	for len(sm.engine.msgCh) < cap(sm.engine.msgCh) {
+		sm.engine.msgCh <- new(message)
+	}
+	payload := signTestMessage(t, &message{Code: msgPrepare, Height: 1}, keys[0])
+	if err := sm.engine.handlePayload(src, payload); err != nil {
+		t.Fatalf("failed to handle message: %v", err)
+	}
+	<-sm.engine.msgCh
+	if err := sm.engine.handlePayload(src, payload); err != nil {
+		t.Fatalf("failed to handle message: %v", err)
+	}
+	if len(sm.engine.msgCh) != cap(sm.engine.msgCh) {
+		t.Fatalf("dropped message not accepted again")
+	}
+	// Once queued, the message must not be accepted a second time
+	<-sm.engine.msgCh
+	if err := sm.engine.handlePayload(src, payload); err != nil {
+		t.Fatalf("failed to handle message: %v", err)
+	}
+	if len(sm.engine.msgCh) == cap(sm.engine.msgCh) {
+		t.Fatalf("queued message accepted twice")
+	}
+}
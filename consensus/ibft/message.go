@@ -0,0 +1,94 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"errors"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+// Consensus message codes exchanged between validators.
+const (
+	msgPreprepare  uint64 = iota // Proposer announcing the block of a round
+	msgPrepare                   // Validator accepting the proposal of a round
+	msgCommit                    // Validator committing to a prepared proposal
+	msgRoundChange               // Validator moving on to a new round
+	msgFinal                     // Proposer announcing the committed block of a round
+)
+
+// errInvalidMessage is returned if a consensus message is malformed.
+var errInvalidMessage = errors.New("invalid consensus message")
+
+// message is a signed consensus message, as gossiped between validators.
+type message struct {
+	Code          uint64       // Message type
+	Height        uint64       // Block number consensus is being reached on
+	Round         uint32       // Round the message belongs to
+	Digest        common.Hash  // Hash of the proposal being prepared or committed to
+	Proposal      *types.Block `rlp:"nil"` // Proposed (preprepare), locked (round change) or committed (final) block
+	PreparedRound uint32       // Round the locked block was prepared in (round change)
+	PreparedSeals [][]byte     // Signatures of the quorum of prepares for the locked block (round change)
+	CommittedSeal []byte       // Signature over the commit data of the digest (commit)
+	Signature     []byte       // Signature of the sender over the rest of the message
+
+	sender  common.Address // Recovered sender of the message (not transmitted)
+	payload []byte         // Encoded message as received from the network (not transmitted)
+}
+
+// signingData returns the RLP encoding of the message without its signature,
+// which is what the sender signs.
+func (m *message) signingData() ([]byte, error) {
+	cpy := *m
+	cpy.Signature = nil
+	return rlp.EncodeToBytes(&cpy)
+}
+
+// decodeMessage parses a consensus message and recovers its sender.
+func decodeMessage(payload []byte) (*message, error) {
+	msg := new(message)
+	if err := rlp.DecodeBytes(payload, msg); err != nil {
+		return nil, err
+	}
+	if msg.Code > msgFinal {
+		return nil, errInvalidMessage
+	}
+	data, err := msg.signingData()
+	if err != nil {
+		return nil, err
+	}
+	if msg.sender, err = recoverSigner(data, msg.Signature); err != nil {
+		return nil, err
+	}
+	msg.payload = payload
+	return msg, nil
+}
+
+// preparedSender recovers the validator which prepared the given proposal in a
+// round, from the signature of its prepare message. Prepare messages carry no
+// other fields, so their signatures suffice to prove that a proposal was
+// prepared.
+func preparedSender(height uint64, round uint32, digest common.Hash, sig []byte) (common.Address, error) {
+	prepare := &message{Code: msgPrepare, Height: height, Round: round, Digest: digest}
+	data, err := prepare.signingData()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverSigner(data, sig)
+}
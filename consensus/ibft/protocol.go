@@ -0,0 +1,240 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// ProtocolName is the official short name of the consensus protocol used
+	// during devp2p capability negotiation.
+	ProtocolName = "ibft"
+
+	// ProtocolVersion is the version of the consensus protocol.
+	ProtocolVersion = 1
+
+	// consensusMsg is the only message code of the protocol, carrying a signed
+	// consensus message.
+	consensusMsg = 0x00
+
+	// maxMessageSize is the maximum cap on the size of a protocol message.
+	maxMessageSize = 10 * 1024 * 1024
+
+	// knownMessages is the number of recently seen message hashes to track
+	// globally and per peer to avoid gossiping them around in circles.
+	knownMessages = 4096
+
+	// messageQueueSize is the number of consensus messages to queue up for
+	// processing or sending before dropping new ones.
+	messageQueueSize = 256
+)
+
+// errMessageTooLarge is returned if a peer sends a message over the size limit.
+var errMessageTooLarge = errors.New("message too large")
+
+// Protocols returns the devp2p protocol validators exchange consensus messages
+// over. Messages are gossiped, so nodes relay them even if not validators.
+func (c *IBFT) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    ProtocolName,
+		Version: ProtocolVersion,
+		Length:  1,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			return c.runPeer(newPeer(p, rw))
+		},
+	}}
+}
+
+// runPeer registers a peer for the duration of its connection and handles the
+// messages it sends.
+func (c *IBFT) runPeer(p *peer) error {
+	if err := c.peers.register(p); err != nil {
+		return err
+	}
+	defer c.peers.unregister(p)
+
+	go p.broadcastLoop()
+	defer p.close()
+
+	for {
+		msg, err := p.rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > maxMessageSize {
+			msg.Discard()
+			return errMessageTooLarge
+		}
+		if msg.Code != consensusMsg {
+			msg.Discard()
+			return errInvalidMessage
+		}
+		var payload []byte
+		if err := msg.Decode(&payload); err != nil {
+			return err
+		}
+		if err := c.handlePayload(p, payload); err != nil {
+			p.Log().Debug("Invalid consensus message", "err", err)
+			return err
+		}
+	}
+}
+
+// handlePayload processes a consensus message received from a peer, queueing
+// it up for the consensus loop. Messages are only relayed to the rest of the
+// network once the consensus loop validated them. A message dropped on a full
+// queue is not marked known, so it's accepted again if re-gossiped.
+func (c *IBFT) handlePayload(p *peer, payload []byte) error {
+	hash := crypto.Keccak256Hash(payload)
+	p.markKnown(hash)
+	if c.known.Contains(hash) {
+		return nil
+	}
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		return err
+	}
+	select {
+	case c.msgCh <- msg:
+		c.known.Add(hash, struct{}{})
+	default:
+		log.Debug("Dropping consensus message, queue full", "code", msg.Code, "number", msg.Height, "round", msg.Round)
+	}
+	return nil
+}
+
+// broadcast gossips a consensus message to all peers not yet knowing about it.
+func (c *IBFT) broadcast(payload []byte) {
+	hash := crypto.Keccak256Hash(payload)
+	c.known.Add(hash, struct{}{})
+
+	for _, p := range c.peers.peersWithoutMessage(hash) {
+		p.send(hash, payload)
+	}
+}
+
+// peer is a remote node connected via the consensus protocol.
+type peer struct {
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	id    string
+	known *lru.Cache    // Hashes of messages the peer is known to have
+	queue chan []byte   // Messages queued up for sending
+	term  chan struct{} // Termination channel to stop the broadcaster
+}
+
+// newPeer wraps a devp2p peer for the consensus protocol.
+func newPeer(p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
+	known, _ := lru.New(knownMessages)
+	return &peer{
+		Peer:  p,
+		rw:    rw,
+		id:    p.ID().String(),
+		known: known,
+		queue: make(chan []byte, messageQueueSize),
+		term:  make(chan struct{}),
+	}
+}
+
+// markKnown marks a message as known to the peer, so it's never sent back.
+func (p *peer) markKnown(hash common.Hash) {
+	p.known.Add(hash, struct{}{})
+}
+
+// send queues a message for sending to the peer, dropping it if the peer is
+// too slow to keep up.
+func (p *peer) send(hash common.Hash, payload []byte) {
+	p.markKnown(hash)
+	select {
+	case p.queue <- payload:
+	default:
+		p.Log().Debug("Dropping consensus message, peer queue full")
+	}
+}
+
+// broadcastLoop sends the queued messages to the remote peer.
+func (p *peer) broadcastLoop() {
+	for {
+		select {
+		case payload := <-p.queue:
+			if err := p2p.Send(p.rw, consensusMsg, payload); err != nil {
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// close signals the broadcast goroutine to terminate.
+func (p *peer) close() {
+	close(p.term)
+}
+
+// peerSet is the set of peers connected via the consensus protocol.
+type peerSet struct {
+	peers map[string]*peer
+	lock  sync.RWMutex
+}
+
+// newPeerSet creates an empty peer set.
+func newPeerSet() *peerSet {
+	return &peerSet{peers: make(map[string]*peer)}
+}
+
+// register adds a new peer to the set.
+func (ps *peerSet) register(p *peer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[p.id]; ok {
+		return p2p.DiscAlreadyConnected
+	}
+	ps.peers[p.id] = p
+	return nil
+}
+
+// unregister removes a peer from the set.
+func (ps *peerSet) unregister(p *peer) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	delete(ps.peers, p.id)
+}
+
+// peersWithoutMessage retrieves the peers not yet knowing about a message.
+func (ps *peerSet) peersWithoutMessage(hash common.Hash) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if !p.known.Contains(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
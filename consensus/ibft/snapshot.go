@@ -0,0 +1,168 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"sort"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+)
+
+// tallyVote is a single governance vote a validator cast, pending until the
+// voted change gathers a majority or the epoch ends.
+type tallyVote struct {
+	Validator common.Address `json:"validator"` // Validator that cast this vote
+	Block     uint64         `json:"block"`     // Block number the vote was cast in
+	Address   common.Address `json:"address"`   // Account being voted on to change its authorization
+	Authorize bool           `json:"authorize"` // Whether to authorize or deauthorize the voted account
+}
+
+// Snapshot is the state of the validator set and governance voting at a given
+// point in time.
+type Snapshot struct {
+	epoch uint64 // Number of blocks after which to reset the pending votes
+
+	Number     uint64           `json:"number"`     // Block number where the snapshot was created
+	Hash       common.Hash      `json:"hash"`       // Block hash where the snapshot was created
+	Validators []common.Address `json:"validators"` // Set of validators at this moment, in ascending order
+	Votes      []*tallyVote     `json:"votes"`      // List of pending votes cast in chronological order
+}
+
+// newSnapshot creates a new snapshot with the specified validator set and no
+// pending votes. This should only be used for genesis and checkpoint blocks.
+func newSnapshot(epoch uint64, number uint64, hash common.Hash, validators []common.Address) *Snapshot {
+	return &Snapshot{
+		epoch:      epoch,
+		Number:     number,
+		Hash:       hash,
+		Validators: sortedAddresses(validators),
+	}
+}
+
+// copy creates a deep copy of the snapshot, though not the individual votes.
+func (s *Snapshot) copy() *Snapshot {
+	cpy := &Snapshot{
+		epoch:      s.epoch,
+		Number:     s.Number,
+		Hash:       s.Hash,
+		Validators: make([]common.Address, len(s.Validators)),
+		Votes:      make([]*tallyVote, len(s.Votes)),
+	}
+	copy(cpy.Validators, s.Validators)
+	copy(cpy.Votes, s.Votes)
+	return cpy
+}
+
+// isValidator returns whether the given account is a member of the validator set.
+func (s *Snapshot) isValidator(address common.Address) bool {
+	for _, validator := range s.Validators {
+		if validator == address {
+			return true
+		}
+	}
+	return false
+}
+
+// proposer returns the validator entitled to propose the block with the given
+// number in the given round.
+func (s *Snapshot) proposer(number uint64, round uint32) common.Address {
+	return s.Validators[(number+uint64(round))%uint64(len(s.Validators))]
+}
+
+// faulty returns the maximum number of faulty validators the set tolerates.
+func (s *Snapshot) faulty() int {
+	return (len(s.Validators) - 1) / 3
+}
+
+// quorum returns the number of validators that need to agree on a proposal.
+func (s *Snapshot) quorum() int {
+	return (2*len(s.Validators) + 2) / 3
+}
+
+// validVote returns whether it makes sense to cast the specified vote in the
+// given snapshot context (e.g. don't try to add an already authorized validator).
+func (s *Snapshot) validVote(address common.Address, authorize bool) bool {
+	validator := s.isValidator(address)
+	return (validator && !authorize) || (!validator && authorize)
+}
+
+// apply advances the snapshot by a block with the given number, proposed by
+// the given validator and carrying the given vote. The hash of the snapshot is
+// left for the caller to update.
+func (s *Snapshot) apply(number uint64, proposer common.Address, vote *Vote) {
+	// Remove any pending votes on checkpoint blocks
+	if number%s.epoch == 0 {
+		s.Votes = nil
+	}
+	s.Number = number
+
+	if vote == nil || !s.validVote(vote.Address, vote.Authorize) {
+		return
+	}
+	// Discard any previous vote from the proposer on the same account
+	for i, v := range s.Votes {
+		if v.Validator == proposer && v.Address == vote.Address {
+			s.Votes = append(s.Votes[:i:i], s.Votes[i+1:]...)
+			break
+		}
+	}
+	s.Votes = append(s.Votes, &tallyVote{
+		Validator: proposer,
+		Block:     number,
+		Address:   vote.Address,
+		Authorize: vote.Authorize,
+	})
+	// Tally up the votes on the account and bail out if there's no majority
+	count := 0
+	for _, v := range s.Votes {
+		if v.Address == vote.Address && v.Authorize == vote.Authorize {
+			count++
+		}
+	}
+	if count <= len(s.Validators)/2 {
+		return
+	}
+	// Vote passed, update the validator set unless it would become empty
+	if vote.Authorize {
+		s.Validators = sortedAddresses(append(s.Validators, vote.Address))
+	} else if len(s.Validators) > 1 {
+		validators := make([]common.Address, 0, len(s.Validators)-1)
+		for _, validator := range s.Validators {
+			if validator != vote.Address {
+				validators = append(validators, validator)
+			}
+		}
+		s.Validators = validators
+	}
+	// Discard all votes around the changed account, and the ones it cast
+	votes := make([]*tallyVote, 0, len(s.Votes))
+	for _, v := range s.Votes {
+		if v.Address == vote.Address || (!vote.Authorize && v.Validator == vote.Address) {
+			continue
+		}
+		votes = append(votes, v)
+	}
+	s.Votes = votes
+}
+
+// sortedAddresses returns a sorted copy of the given addresses.
+func sortedAddresses(addresses []common.Address) []common.Address {
+	sorted := make([]common.Address, len(addresses))
+	copy(sorted, addresses)
+	sort.Sort(addressesAscending(sorted))
+	return sorted
+}
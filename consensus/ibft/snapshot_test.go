@@ -0,0 +1,137 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package ibft
+
+import (
+	"testing"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+)
+
+// Tests that validator governance votes are tallied correctly.
+func TestSnapshotVoting(t *testing.T) {
+	addr := func(b byte) common.Address { return common.Address{b} }
+
+	type testVote struct {
+		proposer  byte
+		address   byte
+		authorize bool
+	}
+	tests := []struct {
+		validators []byte
+		votes      []testVote
+		results    []byte
+	}{
+		{
+			// Single validator, no votes cast
+			validators: []byte{1},
+			results:    []byte{1},
+		}, {
+			// Single validator, voting to add another
+			validators: []byte{1},
+			votes:      []testVote{{1, 2, true}},
+			results:    []byte{1, 2},
+		}, {
+			// Single validator, voting itself out is refused
+			validators: []byte{1},
+			votes:      []testVote{{1, 1, false}},
+			results:    []byte{1},
+		}, {
+			// Four validators, a single vote doesn't pass
+			validators: []byte{1, 2, 3, 4},
+			votes:      []testVote{{1, 5, true}},
+			results:    []byte{1, 2, 3, 4},
+		}, {
+			// Four validators, votes from the same validator count once
+			validators: []byte{1, 2, 3, 4},
+			votes:      []testVote{{1, 5, true}, {1, 5, true}, {1, 5, true}},
+			results:    []byte{1, 2, 3, 4},
+		}, {
+			// Four validators, three votes pass
+			validators: []byte{1, 2, 3, 4},
+			votes:      []testVote{{1, 5, true}, {2, 5, true}, {3, 5, true}},
+			results:    []byte{1, 2, 3, 4, 5},
+		}, {
+			// Four validators, kicking one out needs a strict majority
+			validators: []byte{1, 2, 3, 4},
+			votes:      []testVote{{1, 4, false}, {2, 4, false}, {3, 4, false}},
+			results:    []byte{1, 2, 3},
+		}, {
+			// Votes for invalid changes are ignored
+			validators: []byte{1, 2},
+			votes:      []testVote{{1, 2, true}, {2, 3, false}},
+			results:    []byte{1, 2},
+		}, {
+			// Votes of a removed validator are discarded
+			validators: []byte{1, 2, 3},
+			votes:      []testVote{{3, 4, true}, {1, 3, false}, {2, 3, false}, {1, 4, true}},
+			results:    []byte{1, 2},
+		},
+	}
+	for i, tt := range tests {
+		validators := make([]common.Address, len(tt.validators))
+		for j, v := range tt.validators {
+			validators[j] = addr(v)
+		}
+		snap := newSnapshot(defaultEpoch, 0, common.Hash{}, validators)
+		for j, v := range tt.votes {
+			snap.apply(uint64(j+1), addr(v.proposer), &Vote{Address: addr(v.address), Authorize: v.authorize})
+		}
+		results := make([]common.Address, len(tt.results))
+		for j, v := range tt.results {
+			results[j] = addr(v)
+		}
+		if !validatorsEqual(snap.Validators, results) {
+			t.Errorf("test %d: validator set mismatch: have %x, want %x", i, snap.Validators, results)
+		}
+	}
+}
+
+// Tests that pending votes are discarded at checkpoints.
+func TestSnapshotCheckpoint(t *testing.T) {
+	validators := []common.Address{{1}, {2}, {3}}
+	snap := newSnapshot(2, 0, common.Hash{}, validators)
+
+	snap.apply(1, common.Address{1}, &Vote{Address: common.Address{4}, Authorize: true})
+	snap.apply(2, common.Address{2}, nil)
+	snap.apply(3, common.Address{2}, &Vote{Address: common.Address{4}, Authorize: true})
+	if len(snap.Validators) != 3 {
+		t.Fatalf("vote passed across checkpoint: have %d validators", len(snap.Validators))
+	}
+	if len(snap.Votes) != 1 {
+		t.Fatalf("pending votes mismatch: have %d, want 1", len(snap.Votes))
+	}
+}
+
+// Tests proposer rotation and the fault tolerance thresholds.
+func TestSnapshotProposer(t *testing.T) {
+	validators := []common.Address{{1}, {2}, {3}, {4}}
+	snap := newSnapshot(defaultEpoch, 0, common.Hash{}, validators)
+
+	if have := snap.proposer(1, 0); have != validators[1] {
+		t.Errorf("proposer mismatch: have %x, want %x", have, validators[1])
+	}
+	if have := snap.proposer(1, 3); have != validators[0] {
+		t.Errorf("proposer mismatch after round changes: have %x, want %x", have, validators[0])
+	}
+	for n, want := range map[int][2]int{1: {0, 1}, 3: {0, 2}, 4: {1, 3}, 6: {1, 4}, 7: {2, 5}, 10: {3, 7}} {
+		snap := newSnapshot(defaultEpoch, 0, common.Hash{}, make([]common.Address, n))
+		if f, q := snap.faulty(), snap.quorum(); f != want[0] || q != want[1] {
+			t.Errorf("%d validators: have faulty %d quorum %d, want %d and %d", n, f, q, want[0], want[1])
+		}
+	}
+}
@@ -476,8 +476,15 @@ func (bc *BlockChain) loadLastState() error {
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
 func (bc *BlockChain) SetHead(head uint64) error {
-	_, err := bc.SetHeadBeyondRoot(head, common.Hash{})
-	return err
+	prev := bc.CurrentBlock().Hash()
+	if _, err := bc.SetHeadBeyondRoot(head, common.Hash{}); err != nil {
+		return err
+	}
+	// Send chain head event to update the transaction pool and the miner
+	if current := bc.CurrentBlock(); current.Hash() != prev {
+		bc.chainHeadFeed.Send(ChainHeadEvent{Block: current})
+	}
+	return nil
 }
 
 // SetHeadBeyondRoot rewinds the local chain to a new head with the extra condition
@@ -924,10 +931,22 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	if number == nil {
 		return nil
 	}
-	receipts := rawdb.ReadReceipts(bc.db, hash, *number, bc.chainConfig)
+	// Read the raw receipt blob (from the freezer if already migrated) and
+	// derive the metadata fields against the block, which is usually served
+	// from the block cache instead of decoding the body a second time.
+	receipts := rawdb.ReadRawReceipts(bc.db, hash, *number)
 	if receipts == nil {
 		return nil
 	}
+	block := bc.GetBlock(hash, *number)
+	if block == nil {
+		log.Error("Missing body but have receipt", "hash", hash, "number", *number)
+		return nil
+	}
+	if err := receipts.DeriveFields(bc.chainConfig, hash, *number, block.Transactions()); err != nil {
+		log.Error("Failed to derive block receipts fields", "hash", hash, "number", *number, "err", err)
+		return nil
+	}
 	bc.receiptsCache.Add(hash, receipts)
 	return receipts
 }
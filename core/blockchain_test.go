@@ -3275,3 +3275,37 @@ func TestEIP1559Transition(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that rewinding the chain head announces the new head, but setting the
+// head to the current one doesn't.
+func TestSetHeadEvent(t *testing.T) {
+	_, chain, err := newCanonical(etdash.NewFaker(), 4, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer chain.Stop()
+
+	heads := make(chan ChainHeadEvent, 2)
+	sub := chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	if err := chain.SetHead(4); err != nil {
+		t.Fatalf("failed to set head: %v", err)
+	}
+	select {
+	case ev := <-heads:
+		t.Fatalf("unchanged head announced: #%d", ev.Block.NumberU64())
+	default:
+	}
+	if err := chain.SetHead(2); err != nil {
+		t.Fatalf("failed to rewind head: %v", err)
+	}
+	select {
+	case ev := <-heads:
+		if ev.Block.NumberU64() != 2 {
+			t.Fatalf("announced head mismatch: have #%d, want #2", ev.Block.NumberU64())
+		}
+	default:
+		t.Fatalf("rewound head not announced")
+	}
+}
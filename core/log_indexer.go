@@ -0,0 +1,263 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+)
+
+const (
+	// logIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	logIndexThrottling = 100 * time.Millisecond
+)
+
+// errCorruptLogIndex is returned if a stored log index entry cannot be decoded.
+var errCorruptLogIndex = errors.New("corrupt log index entry")
+
+// LogIndexer implements a core.ChainIndexer, building up an inverted index from
+// log addresses and positional topics to the blocks containing them, permitting
+// exact log filtering without false positive block lookups.
+type LogIndexer struct {
+	size    uint64              // section size to generate the log index for
+	db      etddb.Database      // database instance to write index data and metadata into
+	terms   map[string][]uint64 // block offsets of each address and topic term in the current section
+	section uint64              // Section is the section number being processed currently
+	head    common.Hash         // Head is the hash of the last header processed
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain for fast logs filtering.
+func NewLogIndexer(db etddb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, logIndexThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (b *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.terms, b.section, b.head = make(map[string][]uint64), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header
+// into the index.
+func (b *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	receipts := rawdb.ReadRawReceipts(b.db, hash, number)
+	if receipts == nil && header.ReceiptHash != types.EmptyRootHash {
+		return fmt.Errorf("missing receipts for block #%d [%x]", number, hash[:4])
+	}
+	offset := number - b.section*b.size
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			b.add(logIndexAddressTerm(log.Address), offset)
+			for i, topic := range log.Topics {
+				b.add(logIndexTopicTerm(i, topic), offset)
+			}
+		}
+	}
+	b.head = hash
+	return nil
+}
+
+// add appends a block offset to the list of a term, unless already present.
+func (b *LogIndexer) add(term []byte, offset uint64) {
+	offsets := b.terms[string(term)]
+	if len(offsets) > 0 && offsets[len(offsets)-1] == offset {
+		return
+	}
+	b.terms[string(term)] = append(offsets, offset)
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database. Any index data left behind for the same
+// section by a previous, reorged canonical chain is dropped.
+func (b *LogIndexer) Commit() error {
+	rawdb.DeleteStaleLogIndex(b.db, b.section, b.head)
+
+	batch := b.db.NewBatch()
+	for term, offsets := range b.terms {
+		rawdb.WriteLogIndex(batch, b.section, b.head, []byte(term), encodeLogIndexOffsets(offsets))
+		if batch.ValueSize() > etddb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	rawdb.WriteLogIndexSection(batch, b.section, b.head)
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// LogIndexMatches returns the numbers of the blocks within an indexed section
+// which contain logs emitted by any of the given addresses and carrying any of
+// the given topics at each position. Blocks are only guaranteed to contain the
+// individual terms, not necessarily within the same log.
+//
+// The returned flag is false if the section isn't indexed for the given section
+// head, or if the criteria don't restrict the result at all.
+func LogIndexMatches(db etddb.KeyValueReader, size, section uint64, head common.Hash, addresses []common.Address, topics [][]common.Hash) ([]uint64, bool, error) {
+	// Gather the alternative terms of each restricting clause
+	var clauses [][][]byte
+	if len(addresses) > 0 {
+		clause := make([][]byte, len(addresses))
+		for i, address := range addresses {
+			clause[i] = logIndexAddressTerm(address)
+		}
+		clauses = append(clauses, clause)
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue // wildcard
+		}
+		clause := make([][]byte, len(sub))
+		for j, topic := range sub {
+			clause[j] = logIndexTopicTerm(i, topic)
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 0 || !rawdb.HasLogIndexSection(db, section, head) {
+		return nil, false, nil
+	}
+	// Union the alternatives of each clause and intersect the clauses
+	var matches []uint64
+	for i, clause := range clauses {
+		var union []uint64
+		for _, term := range clause {
+			blob, _ := rawdb.ReadLogIndex(db, section, head, term)
+			if len(blob) == 0 {
+				continue
+			}
+			offsets, err := decodeLogIndexOffsets(blob)
+			if err != nil {
+				return nil, false, err
+			}
+			union = mergeOffsets(union, offsets)
+		}
+		if i == 0 {
+			matches = union
+		} else {
+			matches = intersectOffsets(matches, union)
+		}
+		if len(matches) == 0 {
+			return nil, true, nil
+		}
+	}
+	for i := range matches {
+		matches[i] += section * size
+	}
+	return matches, true, nil
+}
+
+// logIndexAddressTerm returns the log index term of a log address.
+func logIndexAddressTerm(address common.Address) []byte {
+	return append([]byte{0}, address[:]...)
+}
+
+// logIndexTopicTerm returns the log index term of a topic at a given position.
+func logIndexTopicTerm(pos int, topic common.Hash) []byte {
+	return append([]byte{byte(pos + 1)}, topic[:]...)
+}
+
+// encodeLogIndexOffsets compresses an ascending list of block offsets into
+// varint encoded deltas.
+func encodeLogIndexOffsets(offsets []uint64) []byte {
+	var (
+		blob = make([]byte, 0, len(offsets)*2)
+		buf  = make([]byte, binary.MaxVarintLen64)
+		last uint64
+	)
+	for _, offset := range offsets {
+		n := binary.PutUvarint(buf, offset-last)
+		blob = append(blob, buf[:n]...)
+		last = offset
+	}
+	return blob
+}
+
+// decodeLogIndexOffsets is the inverse of encodeLogIndexOffsets.
+func decodeLogIndexOffsets(blob []byte) ([]uint64, error) {
+	var (
+		offsets []uint64
+		last    uint64
+	)
+	for len(blob) > 0 {
+		delta, n := binary.Uvarint(blob)
+		if n <= 0 {
+			return nil, errCorruptLogIndex
+		}
+		last += delta
+		offsets = append(offsets, last)
+		blob = blob[n:]
+	}
+	return offsets, nil
+}
+
+// mergeOffsets returns the sorted union of two ascending offset lists.
+func mergeOffsets(a, b []uint64) []uint64 {
+	merged := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case a[0] > b[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// intersectOffsets returns the sorted intersection of two ascending offset lists.
+func intersectOffsets(a, b []uint64) []uint64 {
+	var shared []uint64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			shared, a, b = append(shared, a[0]), a[1:], b[1:]
+		}
+	}
+	return shared
+}
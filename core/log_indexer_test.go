@@ -0,0 +1,184 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
+	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/params"
+)
+
+// makeLogChain generates a chain of blocks, emitting the logs returned by the
+// callback for each block, and stores them along with their receipts.
+func makeLogChain(db etddb.Database, genesis *types.Block, n int, seed byte, logs func(i int) []*types.Log) []*types.Block {
+	blocks, receipts := GenerateChain(params.TestChainConfig, genesis, etdash.NewFaker(), db, n, func(i int, gen *BlockGen) {
+		gen.SetExtra([]byte{seed})
+		if found := logs(i); len(found) > 0 {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = found
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, gen.BaseFee(), nil))
+		}
+	})
+	for i, block := range blocks {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	return blocks
+}
+
+// indexLogSection runs the log indexer over a single section of headers.
+func indexLogSection(t *testing.T, indexer *LogIndexer, section uint64, headers []*types.Header) {
+	t.Helper()
+
+	if err := indexer.Reset(context.Background(), section, common.Hash{}); err != nil {
+		t.Fatalf("section %d: failed to reset indexer: %v", section, err)
+	}
+	for _, header := range headers {
+		if err := indexer.Process(context.Background(), header); err != nil {
+			t.Fatalf("section %d: failed to process header #%d: %v", section, header.Number, err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("section %d: failed to commit: %v", section, err)
+	}
+}
+
+// Tests that the log index resolves address and positional topic criteria into
+// the exact set of blocks containing them, and drops reorged sections.
+func TestLogIndexer(t *testing.T) {
+	const size = 16
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+
+		addr1  = common.HexToAddress("0x01")
+		addr2  = common.HexToAddress("0x02")
+		topic1 = common.HexToHash("0x01")
+		topic2 = common.HexToHash("0x02")
+	)
+	// Create a chain of two sections, with a log in every third block. Block
+	// numbers start at one as the genesis isn't generated.
+	blocks := makeLogChain(db, genesis, 2*size, 0, func(i int) []*types.Log {
+		switch i % 3 {
+		case 0:
+			return []*types.Log{{Address: addr1, Topics: []common.Hash{topic1}}}
+		case 1:
+			return []*types.Log{{Address: addr2, Topics: []common.Hash{topic2, topic1}}}
+		}
+		return nil
+	})
+	headers := []*types.Header{genesis.Header()}
+	for _, block := range blocks {
+		headers = append(headers, block.Header())
+	}
+	indexer := &LogIndexer{db: db, size: size}
+	indexLogSection(t, indexer, 0, headers[:size])
+	indexLogSection(t, indexer, 1, headers[size:2*size])
+
+	tests := []struct {
+		section   uint64
+		addresses []common.Address
+		topics    [][]common.Hash
+		want      []uint64
+		ok        bool
+	}{
+		// Criteria-less filters cannot be served
+		{0, nil, nil, nil, false},
+		{0, nil, [][]common.Hash{{}, {}}, nil, false},
+
+		// Single clauses, alternatives and positions
+		{0, []common.Address{addr1}, nil, []uint64{1, 4, 7, 10, 13}, true},
+		{1, []common.Address{addr1}, nil, []uint64{16, 19, 22, 25, 28, 31}, true},
+		{0, []common.Address{addr1, addr2}, nil, []uint64{1, 2, 4, 5, 7, 8, 10, 11, 13, 14}, true},
+		{0, nil, [][]common.Hash{{topic1}}, []uint64{1, 4, 7, 10, 13}, true},
+		{0, nil, [][]common.Hash{{}, {topic1}}, []uint64{2, 5, 8, 11, 14}, true},
+
+		// Intersections across clauses
+		{0, []common.Address{addr2}, [][]common.Hash{{topic2}, {topic1}}, []uint64{2, 5, 8, 11, 14}, true},
+		{0, []common.Address{addr1}, [][]common.Hash{{topic2}}, nil, true},
+		{0, []common.Address{common.HexToAddress("0x03")}, nil, nil, true},
+
+		// Unindexed sections
+		{2, []common.Address{addr1}, nil, nil, false},
+	}
+	for i, tt := range tests {
+		head := common.Hash{}
+		if tt.section < 2 {
+			head = headers[(tt.section+1)*size-1].Hash()
+		}
+		matches, ok, err := LogIndexMatches(db, size, tt.section, head, tt.addresses, tt.topics)
+		if err != nil {
+			t.Fatalf("test %d: failed to match: %v", i, err)
+		}
+		if ok != tt.ok {
+			t.Errorf("test %d: served mismatch: have %v, want %v", i, ok, tt.ok)
+		}
+		if !reflect.DeepEqual(matches, tt.want) {
+			t.Errorf("test %d: matches mismatch: have %v, want %v", i, matches, tt.want)
+		}
+	}
+	// Reorg the second section and ensure the stale entries get dropped
+	fork := makeLogChain(db, blocks[size-2], size, 1, func(i int) []*types.Log {
+		return []*types.Log{{Address: addr2}}
+	})
+	forked := append([]*types.Header{}, headers[:size]...)
+	for _, block := range fork {
+		forked = append(forked, block.Header())
+	}
+	oldHead, newHead := headers[2*size-1].Hash(), forked[2*size-1].Hash()
+	indexLogSection(t, indexer, 1, forked[size:2*size])
+
+	if rawdb.HasLogIndexSection(db, 1, oldHead) {
+		t.Errorf("stale section still marked indexed")
+	}
+	if _, ok, _ := LogIndexMatches(db, size, 1, oldHead, []common.Address{addr1}, nil); ok {
+		t.Errorf("stale section still served")
+	}
+	matches, ok, err := LogIndexMatches(db, size, 1, newHead, []common.Address{addr2}, nil)
+	if err != nil || !ok {
+		t.Fatalf("reorged section not served: ok %v, err %v", ok, err)
+	}
+	if len(matches) != size {
+		t.Errorf("reorged section matches mismatch: have %v, want %d blocks", matches, size)
+	}
+}
+
+// Tests that block offsets survive an encoding roundtrip.
+func TestLogIndexOffsetEncoding(t *testing.T) {
+	for _, offsets := range [][]uint64{nil, {0}, {1, 2, 3}, {0, 127, 128, 16383, 16384}} {
+		decoded, err := decodeLogIndexOffsets(encodeLogIndexOffsets(offsets))
+		if err != nil {
+			t.Fatalf("failed to decode %v: %v", offsets, err)
+		}
+		if len(decoded) != len(offsets) || (len(offsets) > 0 && !reflect.DeepEqual(decoded, offsets)) {
+			t.Errorf("roundtrip mismatch: have %v, want %v", decoded, offsets)
+		}
+	}
+	if _, err := decodeLogIndexOffsets([]byte{0x80}); err != errCorruptLogIndex {
+		t.Errorf("corrupt entry error mismatch: have %v, want %v", err, errCorruptLogIndex)
+	}
+}
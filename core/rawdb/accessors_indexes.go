@@ -175,3 +175,56 @@ func DeleteBloombits(db etddb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// HasLogIndexSection checks whether the log index of the given section has been
+// generated for the specified section head.
+func HasLogIndexSection(db etddb.KeyValueReader, section uint64, head common.Hash) bool {
+	ok, _ := db.Has(logIndexSectionKey(section, head))
+	return ok
+}
+
+// WriteLogIndexSection marks the log index of the given section as complete for
+// the specified section head.
+func WriteLogIndexSection(db etddb.KeyValueWriter, section uint64, head common.Hash) {
+	if err := db.Put(logIndexSectionKey(section, head), []byte{}); err != nil {
+		log.Crit("Failed to store log index marker", "err", err)
+	}
+}
+
+// ReadLogIndex retrieves the compressed block offsets belonging to the given
+// section and address or topic term.
+func ReadLogIndex(db etddb.KeyValueReader, section uint64, head common.Hash, term []byte) ([]byte, error) {
+	return db.Get(logIndexKey(section, head, term))
+}
+
+// WriteLogIndex stores the compressed block offsets belonging to the given
+// section and address or topic term.
+func WriteLogIndex(db etddb.KeyValueWriter, section uint64, head common.Hash, term []byte, offsets []byte) {
+	if err := db.Put(logIndexKey(section, head, term), offsets); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
+// DeleteStaleLogIndex removes all log index entries of the given section that
+// were generated for a section head other than the specified one, i.e. the ones
+// left behind by a chain reorg.
+func DeleteStaleLogIndex(db etddb.Database, section uint64, head common.Hash) {
+	prefix := logIndexSectionKey(section, common.Hash{})[:len(logIndexPrefix)+8]
+
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) < len(prefix)+common.HashLength {
+			continue
+		}
+		if bytes.Equal(key[len(prefix):len(prefix)+common.HashLength], head[:]) {
+			continue
+		}
+		db.Delete(key)
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete stale log index", "err", it.Error())
+	}
+}
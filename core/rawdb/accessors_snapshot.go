@@ -216,3 +216,27 @@ func DeleteSnapshotSyncStatus(db etddb.KeyValueWriter) {
 		log.Crit("Failed to remove snapshot sync status", "err", err)
 	}
 }
+
+// ReadSnapshotSyncHealNodes retrieves a chunk of the serialized trie nodes
+// downloaded but not yet committed by the snap sync healer, saved at the last
+// checkpoint.
+func ReadSnapshotSyncHealNodes(db etddb.KeyValueReader, chunk uint16) []byte {
+	data, _ := db.Get(snapshotSyncHealNodesKey(chunk))
+	return data
+}
+
+// WriteSnapshotSyncHealNodes stores a chunk of the serialized trie nodes
+// downloaded but not yet committed by the snap sync healer.
+func WriteSnapshotSyncHealNodes(db etddb.KeyValueWriter, chunk uint16, nodes []byte) {
+	if err := db.Put(snapshotSyncHealNodesKey(chunk), nodes); err != nil {
+		log.Crit("Failed to store snapshot sync heal nodes", "err", err)
+	}
+}
+
+// DeleteSnapshotSyncHealNodes deletes a chunk of the serialized trie nodes of
+// the snap sync healer saved at the last checkpoint.
+func DeleteSnapshotSyncHealNodes(db etddb.KeyValueWriter, chunk uint16) {
+	if err := db.Delete(snapshotSyncHealNodesKey(chunk)); err != nil {
+		log.Crit("Failed to remove snapshot sync heal nodes", "err", err)
+	}
+}
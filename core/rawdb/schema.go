@@ -63,6 +63,10 @@ var (
 	// snapshotSyncStatusKey tracks the snapshot sync status across restarts.
 	snapshotSyncStatusKey = []byte("SnapshotSyncStatus")
 
+	// snapshotSyncHealNodesPrefix tracks the snapshot sync healer's retrieved but
+	// uncommitted trie nodes across restarts, split up into chunks.
+	snapshotSyncHealNodesPrefix = []byte("SnapshotSyncHealNodes") // snapshotSyncHealNodesPrefix + chunk (uint16 big endian) -> trie nodes
+
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
@@ -86,6 +90,7 @@ var (
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	logIndexPrefix        = []byte("E") // logIndexPrefix + section (uint64 big endian) + hash + term -> compressed block offsets
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
@@ -95,6 +100,7 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexIndexPrefix  = []byte("iL") // LogIndexIndexPrefix is the data table of the log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -197,6 +203,14 @@ func storageSnapshotsKey(accountHash common.Hash) []byte {
 	return append(SnapshotStoragePrefix, accountHash.Bytes()...)
 }
 
+// snapshotSyncHealNodesKey = snapshotSyncHealNodesPrefix + chunk (uint16 big endian)
+func snapshotSyncHealNodesKey(chunk uint16) []byte {
+	key := make([]byte, len(snapshotSyncHealNodesPrefix)+2)
+	copy(key, snapshotSyncHealNodesPrefix)
+	binary.BigEndian.PutUint16(key[len(snapshotSyncHealNodesPrefix):], chunk)
+	return key
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
@@ -207,6 +221,20 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexSectionKey = logIndexPrefix + section (uint64 big endian) + hash
+func logIndexSectionKey(section uint64, hash common.Hash) []byte {
+	key := append(append(logIndexPrefix, make([]byte, 8)...), hash.Bytes()...)
+
+	binary.BigEndian.PutUint64(key[1:], section)
+
+	return key
+}
+
+// logIndexKey = logIndexPrefix + section (uint64 big endian) + hash + term
+func logIndexKey(section uint64, hash common.Hash, term []byte) []byte {
+	return append(logIndexSectionKey(section, hash), term...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
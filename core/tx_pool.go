@@ -943,6 +943,29 @@ func (pool *TxPool) Has(hash common.Hash) bool {
 	return pool.all.Get(hash) != nil
 }
 
+// Clear drops all the transactions from the pool and resets its state to the
+// current chain head. It's meant for development chains rewound at will.
+func (pool *TxPool) Clear() {
+	pool.mu.Lock()
+	var hashes []common.Hash
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		hashes = append(hashes, hash)
+		return true
+	}, true, true)
+	for _, hash := range hashes {
+		pool.removeTx(hash, true)
+	}
+	pool.mu.Unlock()
+
+	pool.Sync()
+}
+
+// Sync resets the state of the pool to the current chain head, returning after
+// the reset completed, without waiting for the chain head event to arrive.
+func (pool *TxPool) Sync() {
+	<-pool.requestReset(nil, pool.chain.CurrentBlock().Header())
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -51,6 +51,15 @@ func NewPublicEtherdataAPI(e *Etherdata) *PublicEtherdataAPI {
 	return &PublicEtherdataAPI{e}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicEtherdataAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"etherbase": {Summary: "Returns the address mining rewards are sent to."},
+		"coinbase":  {Summary: "Alias of etherbase."},
+		"hashrate":  {Summary: "Returns the hash rate of the local miner."},
+	}
+}
+
 // Etherbase is the address that mining rewards will be send to
 func (api *PublicEtherdataAPI) Etherbase() (common.Address, error) {
 	return api.e.Etherbase()
@@ -77,6 +86,13 @@ func NewPublicMinerAPI(e *Etherdata) *PublicMinerAPI {
 	return &PublicMinerAPI{e}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicMinerAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"mining": {Summary: "Returns whether the node is mining."},
+	}
+}
+
 // Mining returns an indication if this node is currently mining.
 func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
@@ -93,6 +109,18 @@ func NewPrivateMinerAPI(e *Etherdata) *PrivateMinerAPI {
 	return &PrivateMinerAPI{e: e}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateMinerAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"start":               {Summary: "Starts mining with the given number of threads.", Params: []string{"threads"}},
+		"stop":                {Summary: "Stops mining."},
+		"setExtra":            {Summary: "Sets the extra data of the blocks mined.", Params: []string{"extra"}},
+		"setGasPrice":         {Summary: "Sets the minimum gas price of the transactions mined.", Params: []string{"gasPrice"}},
+		"setEtherbase":        {Summary: "Sets the address mining rewards are sent to.", Params: []string{"etherbase"}},
+		"setRecommitInterval": {Summary: "Sets the interval of recreating the block being mined, in milliseconds.", Params: []string{"interval"}},
+	}
+}
+
 // Start starts the miner with the given number of threads. If threads is nil,
 // the number of workers started is equal to the number of logical CPUs that are
 // usable by this process. If mining is already running, this method adjust the
@@ -152,6 +180,14 @@ func NewPrivateAdminAPI(etd *Etherdata) *PrivateAdminAPI {
 	return &PrivateAdminAPI{etd: etd}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateAdminAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"exportChain": {Summary: "Exports the blocks of the chain into a file.", Params: []string{"file", "first", "last"}},
+		"importChain": {Summary: "Imports the blocks of a file into the chain.", Params: []string{"file"}},
+	}
+}
+
 // ExportChain exports the current blockchain into a local file,
 // or a range of blocks if first and last are non-nil
 func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64) (bool, error) {
@@ -262,6 +298,14 @@ func NewPublicDebugAPI(etd *Etherdata) *PublicDebugAPI {
 	return &PublicDebugAPI{etd: etd}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicDebugAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"dumpBlock":    {Summary: "Returns the state of all accounts at a block.", Params: []string{"blockNr"}},
+		"accountRange": {Summary: "Returns a range of the accounts in the state of a block.", Params: []string{"blockNrOrHash", "start", "maxResults", "nocode", "nostorage", "incompletes"}},
+	}
+}
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
 	opts := &state.DumpConfig{
@@ -303,6 +347,17 @@ func NewPrivateDebugAPI(etd *Etherdata) *PrivateDebugAPI {
 	return &PrivateDebugAPI{etd: etd}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateDebugAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"preimage":                    {Summary: "Returns the preimage of a hash stored in the database.", Params: []string{"hash"}},
+		"getBadBlocks":                {Summary: "Returns the recently rejected blocks."},
+		"storageRangeAt":              {Summary: "Returns a range of the storage of a contract after a transaction of a block.", Params: []string{"blockHash", "txIndex", "contractAddress", "keyStart", "maxResult"}},
+		"getModifiedAccountsByNumber": {Summary: "Returns the accounts modified between two blocks, given by number.", Params: []string{"startNum", "endNum"}},
+		"getModifiedAccountsByHash":   {Summary: "Returns the accounts modified between two blocks, given by hash.", Params: []string{"startHash", "endHash"}},
+	}
+}
+
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
 func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	if preimage := rawdb.ReadPreimage(api.etd.ChainDb(), hash); preimage != nil {
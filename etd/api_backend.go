@@ -31,6 +31,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/core/vm"
 	"github.com/crypyto-panel/go-etherdata/etd/downloader"
+	"github.com/crypyto-panel/go-etherdata/etd/filters"
 	"github.com/crypyto-panel/go-etherdata/etd/gasprice"
 	"github.com/crypyto-panel/go-etherdata/etddb"
 	"github.com/crypyto-panel/go-etherdata/event"
@@ -279,10 +280,24 @@ func (b *EthAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 	return b.gpo.SuggestTipCap(ctx)
 }
 
+func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
+	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
+func (b *EthAPIBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	return b.etd.miner.PendingBlockAndReceipts()
+}
+
 func (b *EthAPIBackend) ChainDb() etddb.Database {
 	return b.etd.ChainDb()
 }
 
+// EventSystem returns the event system shared by the filter API and any other
+// service subscribing to chain events, such as GraphQL.
+func (b *EthAPIBackend) EventSystem() *filters.EventSystem {
+	return b.etd.eventSystem
+}
+
 func (b *EthAPIBackend) EventMux() *event.TypeMux {
 	return b.etd.EventMux()
 }
@@ -312,6 +327,16 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+// LogIndexStatus returns the section size and the number of sections covered by
+// the persistent log index, or zero sections if the index is disabled.
+func (b *EthAPIBackend) LogIndexStatus() (uint64, uint64) {
+	if b.etd.logIndexer == nil {
+		return params.BloomBitsBlocks, 0
+	}
+	sections, _, _ := b.etd.logIndexer.Sections()
+	return params.BloomBitsBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.etd.bloomRequests)
@@ -31,6 +31,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/common/hexutil"
 	"github.com/crypyto-panel/go-etherdata/consensus"
 	"github.com/crypyto-panel/go-etherdata/consensus/clique"
+	"github.com/crypyto-panel/go-etherdata/consensus/ibft"
 	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/bloombits"
 	"github.com/crypyto-panel/go-etherdata/core/rawdb"
@@ -82,8 +83,10 @@ type Etherdata struct {
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
+	logIndexer        *core.ChainIndexer // Optional log index operating during block imports
 
-	APIBackend *EthAPIBackend
+	APIBackend  *EthAPIBackend
+	eventSystem *filters.EventSystem // Chain and pool events shared by all log and head subscribers
 
 	miner     *miner.Miner
 	gasPrice  *big.Int
@@ -201,6 +204,10 @@ func New(stack *node.Node, config *etdconfig.Config) (*Etherdata, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	etd.bloomIndexer.Start(etd.blockchain)
+	if config.LogIndex {
+		etd.logIndexer = core.NewLogIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+		etd.logIndexer.Start(etd.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -239,6 +246,7 @@ func New(stack *node.Node, config *etdconfig.Config) (*Etherdata, error) {
 		gpoParams.Default = config.Miner.GasPrice
 	}
 	etd.APIBackend.gpo = gasprice.NewOracle(etd.APIBackend, gpoParams)
+	etd.eventSystem = filters.NewEventSystem(etd.APIBackend, false)
 
 	// Setup DNS discovery iterators.
 	dnsclient := dnsdisc.NewClient(dnsdisc.Config{})
@@ -257,6 +265,7 @@ func New(stack *node.Node, config *etdconfig.Config) (*Etherdata, error) {
 	// Register the backend on the node
 	stack.RegisterAPIs(etd.APIs())
 	stack.RegisterProtocols(etd.Protocols())
+	stack.RegisterDNSFilter(etd.NodeFilter())
 	stack.RegisterLifecycle(etd)
 	// Check for unclean shutdown
 	if uncleanShutdowns, discards, err := rawdb.PushUncleanShutdownMarker(chainDb); err != nil {
@@ -324,7 +333,7 @@ func (s *Etherdata) APIs() []rpc.API {
 		}, {
 			Namespace: "etd",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, s.eventSystem, 5*time.Minute),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -426,6 +435,10 @@ func (s *Etherdata) shouldPreserve(block *types.Block) bool {
 	if _, ok := s.engine.(*clique.Clique); ok {
 		return false
 	}
+	// Blocks are final under BFT consensus, there's never anything to preserve.
+	if _, ok := s.engine.(*ibft.IBFT); ok {
+		return false
+	}
 	return s.isLocalBlock(block)
 }
 
@@ -475,6 +488,14 @@ func (s *Etherdata) StartMining(threads int) error {
 			}
 			clique.Authorize(eb, wallet.SignData)
 		}
+		if ibft, ok := s.engine.(*ibft.IBFT); ok {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("validator missing: %v", err)
+			}
+			ibft.Authorize(eb, wallet.SignData)
+		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
 		atomic.StoreUint32(&s.handler.acceptTxs, 1)
@@ -518,11 +539,20 @@ func (s *Etherdata) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer
 func (s *Etherdata) Protocols() []p2p.Protocol {
 	protos := etd.MakeProtocols((*etdHandler)(s.handler), s.networkID, s.etdDialCandidates)
 	if s.config.SnapshotCache > 0 {
-		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
+		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), snap.ServeConfig{Rate: s.config.SnapServeRate, PeerRate: s.config.SnapPeerServeRate}, s.snapDialCandidates)...)
+	}
+	if engine, ok := s.engine.(*ibft.IBFT); ok {
+		protos = append(protos, engine.Protocols()...)
 	}
 	return protos
 }
 
+// NodeFilter returns a filter accepting only the nodes which advertise an `etd`
+// fork ID compatible with the local chain.
+func (s *Etherdata) NodeFilter() func(*enode.Node) bool {
+	return etd.NewNodeFilter(s.blockchain)
+}
+
 // Start implements node.Lifecycle, starting all internal goroutines needed by the
 // Etherdata protocol implementation.
 func (s *Etherdata) Start() error {
@@ -531,6 +561,11 @@ func (s *Etherdata) Start() error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
+	// Start following the chain to take part in BFT consensus
+	if engine, ok := s.engine.(*ibft.IBFT); ok {
+		engine.Start(s.blockchain)
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
@@ -554,6 +589,9 @@ func (s *Etherdata) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Stop()
@@ -65,6 +65,16 @@ func newConsensusAPI(etd *etd.Etherdata) *consensusAPI {
 	return &consensusAPI{etd: etd}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *consensusAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"assembleBlock": {Summary: "Creates a new block on top of a parent.", Params: []string{"params"}},
+		"newBlock":      {Summary: "Executes and imports a block without making it the head.", Params: []string{"params"}},
+		"finalizeBlock": {Summary: "Marks a block as final.", Params: []string{"blockHash"}},
+		"setHead":       {Summary: "Makes a block the head of the chain.", Params: []string{"newHead"}},
+	}
+}
+
 // blockExecutionEnv gathers all the data required to execute
 // a block, either when assembling it or when inserting it.
 type blockExecutionEnv struct {
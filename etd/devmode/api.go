@@ -0,0 +1,356 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package devmode implements the RPC API controlling developer chains, giving
+// test suites full control over the chain head, time and accounts.
+package devmode
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/hexutil"
+	"github.com/crypyto-panel/go-etherdata/consensus/clique"
+	"github.com/crypyto-panel/go-etherdata/consensus/misc"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/etd"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/node"
+	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+var (
+	// errNotClique is returned if the dev API is registered on a chain not
+	// sealed by clique.
+	errNotClique = errors.New("developer chain control requires clique consensus")
+
+	// errTimestampTooLow is returned if the next block timestamp is set to a
+	// time not later than the current head.
+	errTimestampTooLow = errors.New("timestamp not after the current head")
+
+	// errHeadMoved is returned if a block mined on demand didn't become the new
+	// head, as another one was sealed concurrently.
+	errHeadMoved = errors.New("chain head moved while mining")
+)
+
+// Register adds the developer chain control APIs to the node, taking over the
+// clock of the clique engine and adding a wallet backend for impersonation.
+func Register(stack *node.Node, backend *etd.Etherdata) error {
+	api, err := NewAPI(backend)
+	if err != nil {
+		return err
+	}
+	stack.AccountManager().AddBackend(api.impersonator)
+
+	log.Warn("Developer chain control enabled")
+	stack.RegisterAPIs([]rpc.API{
+		{
+			Namespace: "dev",
+			Version:   "1.0",
+			Service:   api,
+		},
+	})
+	return nil
+}
+
+// snapshot is a saved chain head the chain can be reverted to.
+type snapshot struct {
+	number uint64               // Number of the head block
+	hash   common.Hash          // Hash of the head block
+	offset int64                // Clock offset in seconds
+	txs    []*types.Transaction // Transactions in the pool
+}
+
+// API is the developer chain control API, allowing to snapshot and revert the
+// chain, warp time, mine blocks on demand and impersonate accounts.
+type API struct {
+	etd          *etd.Etherdata
+	engine       *clique.Clique
+	impersonator *impersonator
+
+	offset int64 // Seconds the clock is ahead of the system time (atomic access)
+
+	snapshots []*snapshot // Snapshots taken, indexed by their id
+	next      uint64      // Exact timestamp of the next block mined on demand, if set
+	lock      sync.Mutex  // Serializes chain control operations
+}
+
+// NewAPI creates the developer chain control API on top of a clique chain,
+// installing its warpable clock into the engine.
+func NewAPI(backend *etd.Etherdata) (*API, error) {
+	engine, ok := backend.Engine().(*clique.Clique)
+	if !ok {
+		return nil, errNotClique
+	}
+	api := &API{
+		etd:          backend,
+		engine:       engine,
+		impersonator: newImpersonator(),
+	}
+	engine.SetClock(api.now)
+	return api, nil
+}
+
+// RPCDescriptions implements rpc.Describer.
+func (api *API) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"snapshot":                 {Summary: "Saves the chain head, pending transactions and clock, returning an id to revert to."},
+		"revert":                   {Summary: "Reverts the chain to a snapshot, dropping it and all later ones.", Params: []string{"id"}},
+		"setNextBlockTimestamp":    {Summary: "Sets the exact timestamp of the next block mined on demand.", Params: []string{"timestamp"}},
+		"increaseTime":             {Summary: "Moves the clock forward, returning the total offset in seconds.", Params: []string{"seconds"}},
+		"mine":                     {Summary: "Mines blocks on demand, returning their hashes.", Params: []string{"blocks"}},
+		"impersonateAccount":       {Summary: "Allows sending transactions from an account without its key.", Params: []string{"address"}},
+		"stopImpersonatingAccount": {Summary: "Stops impersonating an account.", Params: []string{"address"}},
+	}
+}
+
+// now returns the current time of the warped clock.
+func (api *API) now() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&api.offset)) * time.Second)
+}
+
+// Snapshot saves the current chain head, the pending transactions and the clock,
+// returning an id to revert to it later.
+func (api *API) Snapshot() hexutil.Uint64 {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	head := api.etd.BlockChain().CurrentBlock()
+	snap := &snapshot{
+		number: head.NumberU64(),
+		hash:   head.Hash(),
+		offset: atomic.LoadInt64(&api.offset),
+	}
+	pending, queued := api.etd.TxPool().Content()
+	for _, txs := range pending {
+		snap.txs = append(snap.txs, txs...)
+	}
+	for _, txs := range queued {
+		snap.txs = append(snap.txs, txs...)
+	}
+	api.snapshots = append(api.snapshots, snap)
+	return hexutil.Uint64(len(api.snapshots) - 1)
+}
+
+// Revert rewinds the chain, the transaction pool and the clock to a snapshot.
+// The snapshot and all later ones are discarded. False is returned if there's
+// no such snapshot.
+func (api *API) Revert(id hexutil.Uint64) (bool, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	if uint64(id) >= uint64(len(api.snapshots)) {
+		return false, nil
+	}
+	snap := api.snapshots[id]
+
+	chain := api.etd.BlockChain()
+	if chain.GetCanonicalHash(snap.number) != snap.hash {
+		return false, fmt.Errorf("snapshot block %d no longer canonical", snap.number)
+	}
+	if err := chain.SetHead(snap.number); err != nil {
+		return false, err
+	}
+	pool := api.etd.TxPool()
+	pool.Clear()
+	for i, err := range pool.AddLocals(snap.txs) {
+		if err != nil {
+			log.Debug("Dropped transaction on revert", "hash", snap.txs[i].Hash(), "err", err)
+		}
+	}
+	atomic.StoreInt64(&api.offset, snap.offset)
+	api.snapshots = api.snapshots[:id]
+	return true, nil
+}
+
+// SetNextBlockTimestamp warps the clock to the given time, which the next block
+// will be stamped with.
+func (api *API) SetNextBlockTimestamp(timestamp hexutil.Uint64) error {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	if uint64(timestamp) <= api.etd.BlockChain().CurrentBlock().Time() {
+		return errTimestampTooLow
+	}
+	atomic.StoreInt64(&api.offset, int64(timestamp)-time.Now().Unix())
+	api.next = uint64(timestamp)
+	return nil
+}
+
+// IncreaseTime moves the clock forward by the given number of seconds, returning
+// the total number of seconds the clock is ahead of the system time.
+func (api *API) IncreaseTime(seconds hexutil.Uint64) hexutil.Uint64 {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	offset := atomic.AddInt64(&api.offset, int64(seconds))
+	if offset < 0 {
+		return 0
+	}
+	return hexutil.Uint64(offset)
+}
+
+// Mine seals the given number of blocks (one if unspecified) right away, with
+// the pending transactions included, returning their hashes.
+func (api *API) Mine(blocks *hexutil.Uint64) ([]common.Hash, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	count := uint64(1)
+	if blocks != nil {
+		count = uint64(*blocks)
+	}
+	// Keep the miner from sealing competing blocks in the meantime
+	if api.etd.IsMining() {
+		coinbase, err := api.etd.Etherbase()
+		if err != nil {
+			return nil, err
+		}
+		api.etd.Miner().Stop()
+		defer api.etd.Miner().Start(coinbase)
+	}
+	hashes := make([]common.Hash, 0, count)
+	for i := uint64(0); i < count; i++ {
+		block, err := api.mineBlock()
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, block.Hash())
+	}
+	// Make the pool accept transactions on top of the new head right away
+	api.etd.TxPool().Sync()
+	return hashes, nil
+}
+
+// mineBlock assembles, seals and writes a block on top of the current head.
+func (api *API) mineBlock() (*types.Block, error) {
+	var (
+		chain  = api.etd.BlockChain()
+		config = chain.Config()
+		parent = chain.CurrentBlock()
+	)
+	coinbase, err := api.etd.Etherbase()
+	if err != nil {
+		return nil, err
+	}
+	num := parent.Number()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     num.Add(num, common.Big1),
+		Coinbase:   coinbase,
+		GasLimit:   parent.GasLimit(),
+		Time:       uint64(api.now().Unix()),
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent.Header())
+	}
+	if err := api.engine.Prepare(chain, header); err != nil {
+		return nil, err
+	}
+	if api.next > parent.Time() {
+		header.Time = api.next
+	}
+	api.next = 0
+
+	// Execute all the pending transactions that fit
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	pending, err := api.etd.TxPool().Pending(true)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer   = types.MakeSigner(config, header.Number)
+		txset    = types.NewTransactionsByPriceAndNonce(signer, pending, header.BaseFee)
+		gaspool  = new(core.GasPool).AddGas(header.GasLimit)
+		txs      []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for gaspool.Gas() >= params.TxGas {
+		tx := txset.Peek()
+		if tx == nil {
+			break
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(txs))
+
+		snap := statedb.Snapshot()
+		receipt, err := core.ApplyTransaction(config, chain, &header.Coinbase, gaspool, statedb, header, tx, &header.GasUsed, *chain.GetVMConfig())
+		switch {
+		case err == nil:
+			txs = append(txs, tx)
+			receipts = append(receipts, receipt)
+			txset.Shift()
+		case errors.Is(err, core.ErrNonceTooLow):
+			statedb.RevertToSnapshot(snap)
+			txset.Shift()
+		default:
+			statedb.RevertToSnapshot(snap)
+			log.Debug("Skipping transaction on demand mining", "hash", tx.Hash(), "err", err)
+			txset.Pop()
+		}
+	}
+	block, err := api.engine.FinalizeAndAssemble(chain, header, statedb, txs, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	sealed := block.Header()
+	if err := api.engine.SignHeader(sealed); err != nil {
+		return nil, err
+	}
+	block = block.WithSeal(sealed)
+
+	// Update the receipts and logs with the final block hash
+	var logs []*types.Log
+	for i, receipt := range receipts {
+		receipt.BlockHash = block.Hash()
+		receipt.BlockNumber = block.Number()
+		receipt.TransactionIndex = uint(i)
+		for _, log := range receipt.Logs {
+			log.BlockHash = block.Hash()
+		}
+		logs = append(logs, receipt.Logs...)
+	}
+	status, err := chain.WriteBlockWithState(block, receipts, logs, statedb, true)
+	if err != nil {
+		return nil, err
+	}
+	if status != core.CanonStatTy {
+		return nil, errHeadMoved
+	}
+	log.Info("Mined block on demand", "number", block.Number(), "hash", block.Hash(), "txs", len(txs))
+	api.etd.EventMux().Post(core.NewMinedBlockEvent{Block: block})
+	return block, nil
+}
+
+// ImpersonateAccount allows sending transactions from the given account through
+// eth_sendTransaction without its key.
+func (api *API) ImpersonateAccount(address common.Address) {
+	api.impersonator.add(address)
+}
+
+// StopImpersonatingAccount stops allowing to send transactions from the given
+// account without its key.
+func (api *API) StopImpersonatingAccount(address common.Address) {
+	api.impersonator.remove(address)
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package devmode
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/accounts"
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/hexutil"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etd"
+	"github.com/crypyto-panel/go-etherdata/etd/etdconfig"
+	"github.com/crypyto-panel/go-etherdata/node"
+	"github.com/crypyto-panel/go-etherdata/params"
+)
+
+var (
+	// testKey is a private key of the clique signer and funded tester account.
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+	// testAddr is the Etherdata address of the tester account.
+	testAddr = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// startDevService starts a node running a developer chain sealed by the tester
+// account, with the dev API attached.
+func startDevService(t *testing.T) (*node.Node, *etd.Etherdata, *API) {
+	t.Helper()
+
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatal("can't create node:", err)
+	}
+	genesis := core.DeveloperGenesisBlock(0, testAddr)
+	genesis.GasLimit = 30000000
+
+	etdservice, err := etd.New(n, &etdconfig.Config{Genesis: genesis, Miner: etdconfig.Defaults.Miner, TxPool: core.DefaultTxPoolConfig})
+	if err != nil {
+		t.Fatal("can't create etd service:", err)
+	}
+	api, err := NewAPI(etdservice)
+	if err != nil {
+		t.Fatal("can't create dev API:", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal("can't start node:", err)
+	}
+	etdservice.SetEtherbase(testAddr)
+	api.engine.Authorize(testAddr, func(signer accounts.Account, mimeType string, message []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(message), testKey)
+	})
+	return n, etdservice, api
+}
+
+// transfer creates a signed value transfer from the tester account.
+func transfer(t *testing.T, etdservice *etd.Etherdata, nonce uint64, to common.Address, amount *big.Int) *types.Transaction {
+	t.Helper()
+
+	signer := types.LatestSigner(etdservice.BlockChain().Config())
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, amount, params.TxGas, big.NewInt(2*params.InitialBaseFee), nil), signer, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+func TestMineAndRevert(t *testing.T) {
+	n, etdservice, api := startDevService(t)
+	defer n.Close()
+
+	chain := etdservice.BlockChain()
+	id := api.Snapshot()
+
+	// Mine a block with a transfer, plus an empty one
+	recipient := common.Address{0x01}
+	if err := etdservice.TxPool().AddLocal(transfer(t, etdservice, 0, recipient, big.NewInt(1000))); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	count := hexutil.Uint64(2)
+	hashes, err := api.Mine(&count)
+	if err != nil {
+		t.Fatalf("failed to mine blocks: %v", err)
+	}
+	if len(hashes) != 2 {
+		t.Fatalf("mined block count mismatch: have %d, want 2", len(hashes))
+	}
+	if head := chain.CurrentBlock(); head.NumberU64() != 2 || head.Hash() != hashes[1] {
+		t.Fatalf("head mismatch: have #%d [%x], want #2 [%x]", head.NumberU64(), head.Hash(), hashes[1])
+	}
+	if txs := chain.GetBlockByHash(hashes[0]).Transactions(); len(txs) != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want 1", len(txs))
+	}
+	state, _ := chain.State()
+	if balance := state.GetBalance(recipient); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("recipient balance mismatch: have %v, want 1000", balance)
+	}
+	// Revert to the genesis and check the chain was rewound
+	if ok, err := api.Revert(id); !ok || err != nil {
+		t.Fatalf("failed to revert: %v %v", ok, err)
+	}
+	if head := chain.CurrentBlock(); head.NumberU64() != 0 {
+		t.Fatalf("head not reverted: have #%d", head.NumberU64())
+	}
+	state, _ = chain.State()
+	if balance := state.GetBalance(recipient); balance.Sign() != 0 {
+		t.Fatalf("recipient balance not reverted: have %v", balance)
+	}
+	// The snapshot was consumed by the revert
+	if ok, _ := api.Revert(id); ok {
+		t.Fatalf("reverted to consumed snapshot")
+	}
+}
+
+func TestTimeWarp(t *testing.T) {
+	n, etdservice, api := startDevService(t)
+	defer n.Close()
+
+	// Increase the time and check the mined block follows it
+	if offset := api.IncreaseTime(3600); offset != 3600 {
+		t.Fatalf("offset mismatch: have %d, want 3600", offset)
+	}
+	if _, err := api.Mine(nil); err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+	head := etdservice.BlockChain().CurrentBlock()
+	if min := uint64(time.Now().Unix()) + 3600; head.Time() < min {
+		t.Fatalf("block time not warped: have %d, want >= %d", head.Time(), min)
+	}
+	// Set an exact timestamp for the next block
+	if err := api.SetNextBlockTimestamp(hexutil.Uint64(head.Time())); err != errTimestampTooLow {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTimestampTooLow)
+	}
+	next := head.Time() + 86400
+	if err := api.SetNextBlockTimestamp(hexutil.Uint64(next)); err != nil {
+		t.Fatalf("failed to set timestamp: %v", err)
+	}
+	if _, err := api.Mine(nil); err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+	if head := etdservice.BlockChain().CurrentBlock(); head.Time() != next {
+		t.Fatalf("block time mismatch: have %d, want %d", head.Time(), next)
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	n, etdservice, api := startDevService(t)
+	defer n.Close()
+
+	// Fund the account to impersonate
+	var (
+		whale     = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		recipient = common.Address{0x02}
+	)
+	if err := etdservice.TxPool().AddLocal(transfer(t, etdservice, 0, whale, big.NewInt(params.Ether))); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if _, err := api.Mine(nil); err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+	// Signing is refused until the account is impersonated
+	var (
+		account = accounts.Account{Address: whale}
+		chainID = etdservice.BlockChain().Config().ChainID
+		tx      = types.NewTransaction(0, recipient, big.NewInt(1000), params.TxGas, big.NewInt(2*params.InitialBaseFee), nil)
+	)
+	if _, err := api.impersonator.SignTx(account, tx, chainID); err != accounts.ErrUnknownAccount {
+		t.Fatalf("error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+	api.ImpersonateAccount(whale)
+	signed, err := api.impersonator.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign impersonated transaction: %v", err)
+	}
+	if err := etdservice.TxPool().AddLocal(signed); err != nil {
+		t.Fatalf("failed to add impersonated transaction: %v", err)
+	}
+	if _, err := api.Mine(nil); err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+	if txs := etdservice.BlockChain().CurrentBlock().Transactions(); len(txs) != 1 || txs[0].Hash() != signed.Hash() {
+		t.Fatalf("impersonated transaction not included")
+	}
+	state, _ := etdservice.BlockChain().State()
+	if balance := state.GetBalance(recipient); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("recipient balance mismatch: have %v, want 1000", balance)
+	}
+	api.StopImpersonatingAccount(whale)
+	if api.impersonator.Contains(account) {
+		t.Fatalf("account still impersonated")
+	}
+}
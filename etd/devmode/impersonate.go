@@ -0,0 +1,190 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package devmode
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	etherdata "github.com/crypyto-panel/go-etherdata"
+	"github.com/crypyto-panel/go-etherdata/accounts"
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/event"
+)
+
+// errImpersonationOnly is returned for any wallet operation other than signing
+// transactions with an impersonated account.
+var errImpersonationOnly = errors.New("impersonated accounts can only send transactions")
+
+// impersonator is an account backend holding a single wallet, which signs
+// transactions of impersonated accounts without their keys.
+type impersonator struct {
+	accounts map[common.Address]struct{}
+	feed     event.Feed // Wallet arrivals, never fired as the wallet is static
+	lock     sync.RWMutex
+}
+
+// newImpersonator creates a backend impersonating no accounts.
+func newImpersonator() *impersonator {
+	return &impersonator{accounts: make(map[common.Address]struct{})}
+}
+
+// add starts impersonating an account.
+func (w *impersonator) add(address common.Address) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.accounts[address] = struct{}{}
+}
+
+// remove stops impersonating an account.
+func (w *impersonator) remove(address common.Address) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	delete(w.accounts, address)
+}
+
+// Wallets implements accounts.Backend, returning the impersonating wallet.
+func (w *impersonator) Wallets() []accounts.Wallet {
+	return []accounts.Wallet{w}
+}
+
+// Subscribe implements accounts.Backend.
+func (w *impersonator) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return w.feed.Subscribe(sink)
+}
+
+// URL implements accounts.Wallet.
+func (w *impersonator) URL() accounts.URL {
+	return accounts.URL{Scheme: "impersonate"}
+}
+
+// Status implements accounts.Wallet.
+func (w *impersonator) Status() (string, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return fmt.Sprintf("Impersonating %d accounts", len(w.accounts)), nil
+}
+
+// Open implements accounts.Wallet, the wallet is always open.
+func (w *impersonator) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, the wallet is always open.
+func (w *impersonator) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the impersonated accounts.
+func (w *impersonator) Accounts() []accounts.Account {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	list := make([]accounts.Account, 0, len(w.accounts))
+	for address := range w.accounts {
+		list = append(list, accounts.Account{Address: address, URL: w.URL()})
+	}
+	return list
+}
+
+// Contains implements accounts.Wallet, returning whether an account is
+// impersonated.
+func (w *impersonator) Contains(account accounts.Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, ok := w.accounts[account.Address]
+	return ok
+}
+
+// Derive implements accounts.Wallet, but is not supported.
+func (w *impersonator) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop.
+func (w *impersonator) SelfDerive(bases []accounts.DerivationPath, chain etherdata.ChainStateReader) {
+}
+
+// SignData implements accounts.Wallet, but is not supported.
+func (w *impersonator) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return nil, errImpersonationOnly
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, but is not supported.
+func (w *impersonator) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return nil, errImpersonationOnly
+}
+
+// SignText implements accounts.Wallet, but is not supported.
+func (w *impersonator) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return nil, errImpersonationOnly
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, but is not supported.
+func (w *impersonator) SignTextWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, errImpersonationOnly
+}
+
+// SignTx implements accounts.Wallet, attaching a placeholder signature to the
+// transaction which is accepted as coming from the impersonated account.
+//
+// The sender is cached in the returned transaction object, which the transaction
+// pool, the miner and the block import all pass along. It is lost if the
+// transaction is decoded anew, e.g. from the database after a restart.
+func (w *impersonator) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	// Derive the placeholder from the account, so that the same transaction
+	// impersonated for different accounts has different hashes
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig[12:32], account.Address[:])
+	sig[63] = 1
+
+	signer := types.LatestSignerForChainID(chainID)
+	signed, err := tx.WithSignature(signer, sig)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := types.Sender(&impersonatedSigner{Signer: signer, from: account.Address}, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *impersonator) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// impersonatedSigner is a transaction signer attributing transactions to a fixed
+// account. Deriving the sender of a transaction with it caches the account in
+// the transaction, and as it equals the signer it wraps, the chain then accepts
+// the cached account as the sender.
+type impersonatedSigner struct {
+	types.Signer
+	from common.Address
+}
+
+// Sender returns the impersonated account, regardless of the signature.
+func (s *impersonatedSigner) Sender(tx *types.Transaction) (common.Address, error) {
+	return s.from, nil
+}
@@ -51,6 +51,13 @@ func NewPublicDownloaderAPI(d *Downloader, m *event.TypeMux) *PublicDownloaderAP
 	return api
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicDownloaderAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"syncing": {Summary: "Subscribes to the synchronisation status of the node."},
+	}
+}
+
 // eventLoop runs a loop until the event mux closes. It will install and uninstall new
 // sync subscriptions and broadcasts sync status updates to the installed sync subscriptions.
 func (api *PublicDownloaderAPI) eventLoop() {
@@ -36,6 +36,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/log"
 	"github.com/crypyto-panel/go-etherdata/metrics"
+	"github.com/crypyto-panel/go-etherdata/p2p"
 	"github.com/crypyto-panel/go-etherdata/params"
 	"github.com/crypyto-panel/go-etherdata/trie"
 )
@@ -242,8 +243,9 @@ func New(checkpoint uint64, stateDb etddb.Database, stateBloom *trie.SyncBloom,
 // or header sync is currently at; and the latest known block which the sync targets.
 //
 // In addition, during the state download phase of fast synchronisation the number
-// of processed and the total number of known states are also returned. Otherwise
-// these are zero.
+// of processed and the total number of known states are also returned, and during
+// snap synchronisation the state download and healing statistics. Otherwise these
+// are zero.
 func (d *Downloader) Progress() etherdata.SyncProgress {
 	// Lock the current stats and return the progress
 	d.syncStatsLock.RLock()
@@ -261,13 +263,28 @@ func (d *Downloader) Progress() etherdata.SyncProgress {
 	default:
 		log.Error("Unknown downloader chain/mode combo", "light", d.lightchain != nil, "full", d.blockchain != nil, "mode", mode)
 	}
-	return etherdata.SyncProgress{
+	progress := etherdata.SyncProgress{
 		StartingBlock: d.syncStatsChainOrigin,
 		CurrentBlock:  current,
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
 	}
+	if snap := d.SnapSyncer.Progress(); snap != nil {
+		progress.SyncedAccounts = snap.AccountSynced
+		progress.SyncedAccountBytes = uint64(snap.AccountBytes)
+		progress.SyncedBytecodes = snap.BytecodeSynced
+		progress.SyncedBytecodeBytes = uint64(snap.BytecodeBytes)
+		progress.SyncedStorage = snap.StorageSynced
+		progress.SyncedStorageBytes = uint64(snap.StorageBytes)
+		progress.HealedTrienodes = snap.TrienodeHealSynced
+		progress.HealedTrienodeBytes = uint64(snap.TrienodeHealBytes)
+		progress.HealedBytecodes = snap.BytecodeHealSynced
+		progress.HealedBytecodeBytes = uint64(snap.BytecodeHealBytes)
+		progress.HealingStates = snap.HealPending
+		progress.StateETA = uint64(snap.Remaining.Seconds())
+	}
+	return progress
 }
 
 // Synchronising returns whether the downloader is currently retrieving blocks.
@@ -329,20 +346,29 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	case nil, errBusy, errCanceled:
 		return err
 	}
-	if errors.Is(err, errInvalidChain) || errors.Is(err, errBadPeer) || errors.Is(err, errTimeout) ||
-		errors.Is(err, errStallingPeer) || errors.Is(err, errUnsyncedPeer) || errors.Is(err, errEmptyHeaderSet) ||
-		errors.Is(err, errPeersUnavailable) || errors.Is(err, errTooOld) || errors.Is(err, errInvalidAncestor) {
-		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
-		if d.dropPeer == nil {
-			// The dropPeer method is nil when `--copydb` is used for a local copy.
-			// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
-			log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", id)
-		} else {
-			d.dropPeer(id)
-		}
+	// Drop the peer if it's to blame, recording whether it misbehaved or is only
+	// of no use to us
+	var reason p2p.ReputationEvent
+	switch {
+	case errors.Is(err, errInvalidChain) || errors.Is(err, errBadPeer) || errors.Is(err, errEmptyHeaderSet) ||
+		errors.Is(err, errInvalidAncestor):
+		reason = p2p.InvalidData
+	case errors.Is(err, errTimeout) || errors.Is(err, errStallingPeer):
+		reason = p2p.RequestTimeout
+	case errors.Is(err, errUnsyncedPeer) || errors.Is(err, errPeersUnavailable) || errors.Is(err, errTooOld):
+		reason = p2p.UselessPeer
+	default:
+		log.Warn("Synchronisation failed, retrying", "err", err)
 		return err
 	}
-	log.Warn("Synchronisation failed, retrying", "err", err)
+	log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
+	if d.dropPeer == nil {
+		// The dropPeer method is nil when `--copydb` is used for a local copy.
+		// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
+		log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", id)
+	} else {
+		d.dropPeer(id, reason)
+	}
 	return err
 }
 
@@ -1204,7 +1230,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64) error {
 			// Header retrieval timed out, consider the peer bad and drop
 			p.log.Debug("Header request timed out", "elapsed", ttl)
 			headerTimeoutMeter.Mark(1)
-			d.dropPeer(p.id)
+			d.dropPeer(p.id, p2p.RequestTimeout)
 
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh} {
@@ -1246,8 +1272,8 @@ func (d *Downloader) fillHeaderSkeleton(from uint64, skeleton []*types.Header) (
 		}
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchHeaders(req.From, MaxHeaderFetch) }
 		capacity = func(p *peerConnection) int { return p.HeaderCapacity(d.peers.rates.TargetRoundTrip()) }
-		setIdle  = func(p *peerConnection, accepted int, deliveryTime time.Time) {
-			p.SetHeadersIdle(accepted, deliveryTime)
+		setIdle  = func(p *peerConnection, accepted int, deliveryTime time.Time, timeout bool) {
+			p.SetHeadersIdle(accepted, deliveryTime, timeout)
 		}
 	)
 	err := d.fetchParts(d.headerCh, deliver, d.queue.headerContCh, expire,
@@ -1274,7 +1300,9 @@ func (d *Downloader) fetchBodies(from uint64) error {
 		expire   = func() map[string]int { return d.queue.ExpireBodies(d.peers.rates.TargetTimeout()) }
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchBodies(req) }
 		capacity = func(p *peerConnection) int { return p.BlockCapacity(d.peers.rates.TargetRoundTrip()) }
-		setIdle  = func(p *peerConnection, accepted int, deliveryTime time.Time) { p.SetBodiesIdle(accepted, deliveryTime) }
+		setIdle  = func(p *peerConnection, accepted int, deliveryTime time.Time, timeout bool) {
+			p.SetBodiesIdle(accepted, deliveryTime, timeout)
+		}
 	)
 	err := d.fetchParts(d.bodyCh, deliver, d.bodyWakeCh, expire,
 		d.queue.PendingBlocks, d.queue.InFlightBlocks, d.queue.ReserveBodies,
@@ -1298,8 +1326,8 @@ func (d *Downloader) fetchReceipts(from uint64) error {
 		expire   = func() map[string]int { return d.queue.ExpireReceipts(d.peers.rates.TargetTimeout()) }
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchReceipts(req) }
 		capacity = func(p *peerConnection) int { return p.ReceiptCapacity(d.peers.rates.TargetRoundTrip()) }
-		setIdle  = func(p *peerConnection, accepted int, deliveryTime time.Time) {
-			p.SetReceiptsIdle(accepted, deliveryTime)
+		setIdle  = func(p *peerConnection, accepted int, deliveryTime time.Time, timeout bool) {
+			p.SetReceiptsIdle(accepted, deliveryTime, timeout)
 		}
 	)
 	err := d.fetchParts(d.receiptCh, deliver, d.receiptWakeCh, expire,
@@ -1338,7 +1366,7 @@ func (d *Downloader) fetchReceipts(from uint64) error {
 func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack) (int, error), wakeCh chan bool,
 	expire func() map[string]int, pending func() int, inFlight func() bool, reserve func(*peerConnection, int) (*fetchRequest, bool, bool),
 	fetchHook func([]*types.Header), fetch func(*peerConnection, *fetchRequest) error, cancel func(*fetchRequest), capacity func(*peerConnection) int,
-	idle func() ([]*peerConnection, int), setIdle func(*peerConnection, int, time.Time), kind string) error {
+	idle func() ([]*peerConnection, int), setIdle func(*peerConnection, int, time.Time, bool), kind string) error {
 
 	// Create a ticker to detect expired retrieval tasks
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -1367,7 +1395,7 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 				// caused by a timed out request which came through in the end), set it to
 				// idle. If the delivery's stale, the peer should have already been idled.
 				if !errors.Is(err, errStaleDelivery) {
-					setIdle(peer, accepted, deliveryTime)
+					setIdle(peer, accepted, deliveryTime, false)
 				}
 				// Issue a log to the user to see what's going on
 				switch {
@@ -1420,7 +1448,7 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 					// how response times reacts, to it always requests one more than the minimum (i.e. min 2).
 					if fails > 2 {
 						peer.log.Trace("Data delivery timed out", "type", kind)
-						setIdle(peer, 0, time.Now())
+						setIdle(peer, 0, time.Now(), true)
 					} else {
 						peer.log.Debug("Stalling delivery, dropping", "type", kind)
 
@@ -1429,7 +1457,7 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 							// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
 							peer.log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", pid)
 						} else {
-							d.dropPeer(pid)
+							d.dropPeer(pid, p2p.RequestTimeout)
 
 							// If this peer was the master peer, abort sync immediately
 							d.cancelLock.RLock()
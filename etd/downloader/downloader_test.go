@@ -34,6 +34,8 @@ import (
 	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
 	"github.com/crypyto-panel/go-etherdata/etddb"
 	"github.com/crypyto-panel/go-etherdata/event"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p"
 	"github.com/crypyto-panel/go-etherdata/trie"
 )
 
@@ -402,7 +404,7 @@ func (dl *downloadTester) newPeer(id string, version uint, chain *testChain) err
 }
 
 // dropPeer simulates a hard peer removal from the connection pool.
-func (dl *downloadTester) dropPeer(id string) {
+func (dl *downloadTester) dropPeer(id string, reason p2p.ReputationEvent) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
@@ -1711,3 +1713,39 @@ func testCheckpointEnforcement(t *testing.T, protocol uint, mode SyncMode) {
 		assertOwnChain(t, tester, chain.len())
 	}
 }
+
+// reportingPeer is a download tester peer which records the reputation events
+// reported against it.
+type reportingPeer struct {
+	*downloadTesterPeer
+	events     []p2p.ReputationEvent
+	deliveries int
+}
+
+func (p *reportingPeer) Report(ev p2p.ReputationEvent) { p.events = append(p.events, ev) }
+
+func (p *reportingPeer) ReportDelivery(elapsed, target time.Duration) { p.deliveries++ }
+
+// Tests that only expired requests are reported as timeouts against a peer's
+// reputation, while empty deliveries are neither rewarded nor penalised.
+func TestPeerReputationReports(t *testing.T) {
+	peer := &reportingPeer{downloadTesterPeer: &downloadTesterPeer{id: "peer"}}
+	conn := newPeerConnection("peer", etd.ETD66, peer, log.New())
+
+	peers := newPeerSet()
+	if err := peers.Register(conn); err != nil {
+		t.Fatalf("failed to register peer: %v", err)
+	}
+	conn.SetBodiesIdle(0, time.Now(), false)
+	if len(peer.events) != 0 || peer.deliveries != 0 {
+		t.Fatalf("empty delivery reported: events %v, deliveries %d", peer.events, peer.deliveries)
+	}
+	conn.SetBodiesIdle(4, time.Now(), false)
+	if len(peer.events) != 0 || peer.deliveries != 1 {
+		t.Fatalf("useful delivery mismatch: events %v, deliveries %d", peer.events, peer.deliveries)
+	}
+	conn.SetBodiesIdle(0, time.Now(), true)
+	if len(peer.events) != 1 || peer.events[0] != p2p.RequestTimeout {
+		t.Fatalf("timeout not reported: events %v", peer.events)
+	}
+}
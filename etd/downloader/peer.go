@@ -31,6 +31,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/etd/protocols/etd"
 	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p"
 	"github.com/crypyto-panel/go-etherdata/p2p/msgrate"
 )
 
@@ -59,6 +60,7 @@ type peerConnection struct {
 	stateStarted   time.Time // Time instance when the last node data fetch was started
 
 	rates   *msgrate.Tracker         // Tracker to hone in on the number of items retrievable per second
+	target  func() time.Duration     // Target round trip time to measure delivery latency against
 	lacking map[common.Hash]struct{} // Set of hashes not to request (didn't have previously)
 
 	peer Peer
@@ -199,35 +201,62 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
-func (p *peerConnection) SetHeadersIdle(delivered int, deliveryTime time.Time) {
+func (p *peerConnection) SetHeadersIdle(delivered int, deliveryTime time.Time, timeout bool) {
 	p.rates.Update(etd.BlockHeadersMsg, deliveryTime.Sub(p.headerStarted), delivered)
+	p.report(delivered, deliveryTime.Sub(p.headerStarted), timeout)
 	atomic.StoreInt32(&p.headerIdle, 0)
 }
 
 // SetBodiesIdle sets the peer to idle, allowing it to execute block body retrieval
 // requests. Its estimated body retrieval throughput is updated with that measured
 // just now.
-func (p *peerConnection) SetBodiesIdle(delivered int, deliveryTime time.Time) {
+func (p *peerConnection) SetBodiesIdle(delivered int, deliveryTime time.Time, timeout bool) {
 	p.rates.Update(etd.BlockBodiesMsg, deliveryTime.Sub(p.blockStarted), delivered)
+	p.report(delivered, deliveryTime.Sub(p.blockStarted), timeout)
 	atomic.StoreInt32(&p.blockIdle, 0)
 }
 
 // SetReceiptsIdle sets the peer to idle, allowing it to execute new receipt
 // retrieval requests. Its estimated receipt retrieval throughput is updated
 // with that measured just now.
-func (p *peerConnection) SetReceiptsIdle(delivered int, deliveryTime time.Time) {
+func (p *peerConnection) SetReceiptsIdle(delivered int, deliveryTime time.Time, timeout bool) {
 	p.rates.Update(etd.ReceiptsMsg, deliveryTime.Sub(p.receiptStarted), delivered)
+	p.report(delivered, deliveryTime.Sub(p.receiptStarted), timeout)
 	atomic.StoreInt32(&p.receiptIdle, 0)
 }
 
 // SetNodeDataIdle sets the peer to idle, allowing it to execute new state trie
 // data retrieval requests. Its estimated state retrieval throughput is updated
 // with that measured just now.
-func (p *peerConnection) SetNodeDataIdle(delivered int, deliveryTime time.Time) {
+func (p *peerConnection) SetNodeDataIdle(delivered int, deliveryTime time.Time, timeout bool) {
 	p.rates.Update(etd.NodeDataMsg, deliveryTime.Sub(p.stateStarted), delivered)
+	p.report(delivered, deliveryTime.Sub(p.stateStarted), timeout)
 	atomic.StoreInt32(&p.stateIdle, 0)
 }
 
+// report feeds the outcome of a retrieval into the reputation of the remote
+// peer, if the underlying connection tracks one. Expired requests count against
+// the peer, while empty deliveries (the peer not having the requested data) are
+// neither rewarded nor penalised.
+func (p *peerConnection) report(delivered int, elapsed time.Duration, timeout bool) {
+	rep, ok := p.peer.(p2p.ReputationReporter)
+	if !ok {
+		return
+	}
+	if timeout {
+		rep.Report(p2p.RequestTimeout)
+		return
+	}
+	if delivered == 0 {
+		return
+	}
+	var target time.Duration
+	if p.target != nil {
+		target = p.target()
+	}
+	rep.ReportDelivery(elapsed, target)
+}
+
 // HeaderCapacity retrieves the peers header download allowance based on its
 // previously discovered throughput.
 func (p *peerConnection) HeaderCapacity(targetRTT time.Duration) int {
@@ -349,6 +378,7 @@ func (ps *peerSet) Register(p *peerConnection) error {
 		return errAlreadyRegistered
 	}
 	p.rates = msgrate.NewTracker(ps.rates.MeanCapacities(), ps.rates.MedianRoundTrip())
+	p.target = ps.rates.TargetRoundTrip
 	if err := ps.rates.Track(p.id, p.rates); err != nil {
 		return err
 	}
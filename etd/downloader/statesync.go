@@ -27,6 +27,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/crypto"
 	"github.com/crypyto-panel/go-etherdata/etddb"
 	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p"
 	"github.com/crypyto-panel/go-etherdata/trie"
 	"golang.org/x/crypto/sha3"
 )
@@ -108,7 +109,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 		// available for the next sync.
 		for _, req := range active {
 			req.timer.Stop()
-			req.peer.SetNodeDataIdle(int(req.nItems), time.Now())
+			req.peer.SetNodeDataIdle(int(req.nItems), time.Now(), false)
 		}
 	}()
 	go s.run()
@@ -245,13 +246,13 @@ func (d *Downloader) spindownStateSync(active map[string]*stateReq, finished []*
 		req.peer.log.Trace("State peer marked idle (spindown)", "req.items", int(req.nItems), "reason", reason)
 		req.timer.Stop()
 		delete(active, req.peer.id)
-		req.peer.SetNodeDataIdle(int(req.nItems), time.Now())
+		req.peer.SetNodeDataIdle(int(req.nItems), time.Now(), false)
 	}
 	// The 'finished' set contains deliveries that we were going to pass to processing.
 	// Those are now moot, but we still need to set those peers as idle, which would
 	// otherwise have been done after processing
 	for _, req := range finished {
-		req.peer.SetNodeDataIdle(int(req.nItems), time.Now())
+		req.peer.SetNodeDataIdle(int(req.nItems), time.Now(), false)
 	}
 }
 
@@ -383,7 +384,7 @@ func (s *stateSync) loop() (err error) {
 					// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
 					req.peer.log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", req.peer.id)
 				} else {
-					s.d.dropPeer(req.peer.id)
+					s.d.dropPeer(req.peer.id, p2p.RequestTimeout)
 
 					// If this peer was the master peer, abort sync immediately
 					s.d.cancelLock.RLock()
@@ -398,7 +399,7 @@ func (s *stateSync) loop() (err error) {
 			}
 			// Process all the received blobs and check for stale delivery
 			delivered, err := s.process(req)
-			req.peer.SetNodeDataIdle(delivered, req.delivered)
+			req.peer.SetNodeDataIdle(delivered, req.delivered, !req.dropped && req.timedOut())
 			if err != nil {
 				log.Warn("Node data write error", "err", err)
 				return err
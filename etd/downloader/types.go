@@ -20,10 +20,12 @@ import (
 	"fmt"
 
 	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/p2p"
 )
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer detected as malicious or
+// useless, along with the behaviour that led to it.
+type peerDropFn func(id string, reason p2p.ReputationEvent)
 
 // dataPack is a data message returned by a peer for some query.
 type dataPack interface {
@@ -29,6 +29,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/consensus"
 	"github.com/crypyto-panel/go-etherdata/consensus/clique"
 	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
+	"github.com/crypyto-panel/go-etherdata/consensus/ibft"
 	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/etd/downloader"
 	"github.com/crypyto-panel/go-etherdata/etd/gasprice"
@@ -41,18 +42,22 @@ import (
 
 // FullNodeGPO contains default gasprice oracle settings for full node.
 var FullNodeGPO = gasprice.Config{
-	Blocks:      20,
-	Percentile:  60,
-	MaxPrice:    gasprice.DefaultMaxPrice,
-	IgnorePrice: gasprice.DefaultIgnorePrice,
+	Blocks:           20,
+	Percentile:       60,
+	MaxHeaderHistory: 1024,
+	MaxBlockHistory:  1024,
+	MaxPrice:         gasprice.DefaultMaxPrice,
+	IgnorePrice:      gasprice.DefaultIgnorePrice,
 }
 
 // LightClientGPO contains default gasprice oracle settings for light client.
 var LightClientGPO = gasprice.Config{
-	Blocks:      2,
-	Percentile:  60,
-	MaxPrice:    gasprice.DefaultMaxPrice,
-	IgnorePrice: gasprice.DefaultIgnorePrice,
+	Blocks:           2,
+	Percentile:       60,
+	MaxHeaderHistory: 300,
+	MaxBlockHistory:  5,
+	MaxPrice:         gasprice.DefaultMaxPrice,
+	IgnorePrice:      gasprice.DefaultIgnorePrice,
 }
 
 // Defaults contains default settings for use on the Etherdata main net.
@@ -132,6 +137,11 @@ type Config struct {
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	LogIndex      bool   `toml:",omitempty"` // Whether to maintain the persistent address/topic log index
+
+	// Snap serving options
+	SnapServeRate     int `toml:",omitempty"` // Bytes per second served to all snap peers combined (0 = unlimited)
+	SnapPeerServeRate int `toml:",omitempty"` // Bytes per second served to a single snap peer (0 = unlimited)
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
@@ -211,6 +221,10 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, co
 	if chainConfig.Clique != nil {
 		return clique.New(chainConfig.Clique, db)
 	}
+	// If Byzantine fault tolerance is requested, set it up
+	if chainConfig.IBFT != nil {
+		return ibft.New(chainConfig.IBFT)
+	}
 	// Otherwise assume proof-of-work
 	switch config.PowMode {
 	case etdash.ModeFake:
@@ -17,6 +17,7 @@
 package filters
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -31,6 +32,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/etddb"
 	"github.com/crypyto-panel/go-etherdata/event"
+	"github.com/crypyto-panel/go-etherdata/log"
 	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
@@ -58,12 +60,13 @@ type PublicFilterAPI struct {
 	timeout   time.Duration
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance serving the filters
+// and subscriptions of the given event system.
+func NewPublicFilterAPI(backend Backend, events *EventSystem, timeout time.Duration) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend: backend,
 		chainDb: backend.ChainDb(),
-		events:  NewEventSystem(backend, lightMode),
+		events:  events,
 		filters: make(map[rpc.ID]*filter),
 		timeout: timeout,
 	}
@@ -72,6 +75,22 @@ func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration)
 	return api
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicFilterAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"newPendingTransactionFilter": {Summary: "Creates a filter collecting the hashes of transactions entering the pool."},
+		"newPendingTransactions":      {Summary: "Subscribes to the transactions entering the pool.", Params: []string{"fullTx", "criteria"}},
+		"newBlockFilter":              {Summary: "Creates a filter collecting the hashes of new canonical blocks."},
+		"newHeads":                    {Summary: "Subscribes to the headers of new canonical blocks."},
+		"logs":                        {Summary: "Subscribes to the logs matching the criteria, optionally resumable.", Params: []string{"criteria", "options"}},
+		"newFilter":                   {Summary: "Creates a filter collecting the logs matching the criteria.", Params: []string{"criteria"}},
+		"getLogs":                     {Summary: "Returns the logs matching the criteria.", Params: []string{"criteria"}},
+		"uninstallFilter":             {Summary: "Removes the filter with the given ID.", Params: []string{"id"}},
+		"getFilterLogs":               {Summary: "Returns all the logs matching the filter with the given ID.", Params: []string{"id"}},
+		"getFilterChanges":            {Summary: "Returns the results of a filter collected since the last poll.", Params: []string{"id"}},
+	}
+}
+
 // timeoutLoop runs at the interval set by 'timeout' and deletes filters
 // that have not been recently used. It is started when the API is created.
 func (api *PublicFilterAPI) timeoutLoop(timeout time.Duration) {
@@ -142,11 +161,20 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+//
+// If fullTx is set, the signed transactions are notified instead of their hashes.
+// The optional criteria restrict the transactions notified.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool, crit *TransactionCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	if (fullTx != nil && *fullTx) || crit != nil {
+		if crit == nil {
+			crit = new(TransactionCriteria)
+		}
+		return api.filteredPendingTransactions(notifier, fullTx != nil && *fullTx, *crit)
+	}
 
 	rpcSub := notifier.CreateSubscription()
 
@@ -175,6 +203,46 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// filteredPendingTransactions creates a subscription for the pending transactions
+// matching the given criteria, notifying either their hashes or full objects.
+func (api *PublicFilterAPI) filteredPendingTransactions(notifier *rpc.Notifier, fullTx bool, crit TransactionCriteria) (*rpc.Subscription, error) {
+	var (
+		txHashes = make(chan []common.Hash, 128)
+		txs      chan []*types.Transaction
+	)
+	if fullTx {
+		txs = make(chan []*types.Transaction, 128)
+	}
+	pendingTxSub, err := api.events.SubscribeFilteredPendingTxs(crit, txHashes, txs)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		for {
+			select {
+			case hashes := <-txHashes:
+				for _, h := range hashes {
+					notifier.Notify(rpcSub.ID, h)
+				}
+			case batch := <-txs:
+				for _, tx := range batch {
+					notifier.Notify(rpcSub.ID, tx)
+				}
+			case <-rpcSub.Err():
+				pendingTxSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				pendingTxSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with etd_getFilterChanges.
 //
@@ -241,11 +309,18 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// If options are given, the subscription is resumable: it first backfills the
+// logs from the fromBlock of the criteria (or the resume cursor), then follows
+// the chain, notifying LogEvents with explicit removals for reorged logs.
+func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria, opts *LogsOptions) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	if opts != nil {
+		return api.resumableLogs(ctx, notifier, crit, opts)
+	}
 
 	var (
 		rpcSub      = notifier.CreateSubscription()
@@ -282,6 +357,116 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 // Same as etherdata.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria etherdata.FilterQuery
 
+// TransactionCriteria restricts the pending transactions delivered by a
+// subscription. Empty fields match any transaction, multiple values within a
+// field are alternatives.
+type TransactionCriteria struct {
+	From        []common.Address `json:"from"`        // Senders of the transaction
+	To          []common.Address `json:"to"`          // Recipients, contract creations never match
+	Selectors   []hexutil.Bytes  `json:"selector"`    // 4 byte method selectors of the call data
+	MinGasPrice *hexutil.Big     `json:"minGasPrice"` // Minimum gas price, or fee cap for dynamic fee transactions
+}
+
+// validate checks the criteria for malformed fields.
+func (crit *TransactionCriteria) validate() error {
+	for _, selector := range crit.Selectors {
+		if len(selector) != 4 {
+			return fmt.Errorf("invalid method selector %v, want 4 bytes", selector)
+		}
+	}
+	return nil
+}
+
+// matches reports whether a transaction satisfies all the criteria.
+func (crit *TransactionCriteria) matches(tx *types.Transaction) bool {
+	if crit.MinGasPrice != nil && tx.GasFeeCap().Cmp(crit.MinGasPrice.ToInt()) < 0 {
+		return false
+	}
+	if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+		return false
+	}
+	if len(crit.Selectors) > 0 {
+		data := tx.Data()
+		if len(data) < 4 {
+			return false
+		}
+		var found bool
+		for _, selector := range crit.Selectors {
+			if bytes.Equal(data[:4], selector) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(crit.From) > 0 {
+		// Only recover the sender when filtering on it, it's cached by the pool
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil || !includes(crit.From, from) {
+			return false
+		}
+	}
+	return true
+}
+
+// resumableLogs creates a log subscription delivering the logs matching the
+// criteria from a starting block or cursor onwards, exactly once along the
+// canonical chain.
+func (api *PublicFilterAPI) resumableLogs(ctx context.Context, notifier *rpc.Notifier, crit FilterCriteria, opts *LogsOptions) (*rpc.Subscription, error) {
+	rpcSub := notifier.CreateSubscription()
+
+	stream, err := newLogStream(ctx, api.backend, crit, opts, func(event *LogEvent) error {
+		return notifier.Notify(rpcSub.ID, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		headers = make(chan *types.Header)
+		wakeup  = make(chan struct{}, 1)
+		headSub = api.events.SubscribeNewHeads(headers)
+	)
+	go func() {
+		defer headSub.Unsubscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Collapse head events while a backfill is running, so the event system
+		// is never blocked on a slow subscriber
+		go func() {
+			defer cancel()
+			for {
+				select {
+				case <-headers:
+					select {
+					case wakeup <- struct{}{}:
+					default:
+					}
+				case <-rpcSub.Err(): // client send an unsubscribe request
+					return
+				case <-notifier.Closed(): // connection dropped
+					return
+				}
+			}
+		}()
+		for {
+			if err := stream.advance(ctx); err != nil && ctx.Err() == nil {
+				log.Debug("Failed to advance log subscription", "id", rpcSub.ID, "err", err)
+			}
+			select {
+			case <-wakeup:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewFilter creates a new filter and returns the filter id. It can be
 // used to retrieve logs when the state changes. This method cannot be
 // used to fetch logs that are already stored in the state.
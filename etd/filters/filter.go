@@ -30,6 +30,10 @@ import (
 	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
+// errResponseBudget is returned if the logs found can't fit into the response
+// size limit of the RPC server the query was issued through.
+var errResponseBudget = errors.New("query results exceed the response size limit")
+
 type Backend interface {
 	ChainDb() etddb.Database
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
@@ -47,6 +51,12 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// logIndexBackend is implemented by backends maintaining a persistent log index,
+// which is preferred over the bloom bits for all the sections it covers.
+type logIndexBackend interface {
+	LogIndexStatus() (uint64, uint64)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -59,6 +69,8 @@ type Filter struct {
 	begin, end int64       // Range interval if filtering multiple blocks
 
 	matcher *bloombits.Matcher
+
+	size int // Lower bound of the JSON encoded size of the logs found
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -144,16 +156,32 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
-		logs []*types.Log
-		err  error
+		logs  []*types.Log
+		found []*types.Log
+		err   error
 	)
+	if backend, ok := f.backend.(logIndexBackend); ok {
+		size, sections := backend.LogIndexStatus()
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			if indexed > end {
+				found, err = f.logIndexLogs(ctx, size, end)
+			} else {
+				found, err = f.logIndexLogs(ctx, size, indexed-1)
+			}
+			logs = append(logs, found...)
+			if err != nil {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			found, err = f.indexedLogs(ctx, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			found, err = f.indexedLogs(ctx, indexed-1)
 		}
+		logs = append(logs, found...)
 		if err != nil {
 			return logs, err
 		}
@@ -163,6 +191,53 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	return logs, err
 }
 
+// logIndexLogs returns the logs matching the filter criteria based on the
+// persistent log index. It stops at the first section which cannot be served
+// from the index, leaving the rest of the range to the bloom bits.
+func (f *Filter) logIndexLogs(ctx context.Context, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for f.begin <= int64(end) {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		// Look up the candidate blocks of the section on the canonical chain
+		section := uint64(f.begin) / size
+		head, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber((section+1)*size-1))
+		if head == nil || err != nil {
+			return logs, err
+		}
+		matches, ok, err := core.LogIndexMatches(f.db, size, section, head.Hash(), f.addresses, f.topics)
+		if err != nil || !ok {
+			return logs, err
+		}
+		for _, number := range matches {
+			if number < uint64(f.begin) {
+				continue
+			}
+			if number > end {
+				break
+			}
+			// Retrieve the matching block and pull the truly matching logs
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+		}
+		if next := (section + 1) * size; next <= end {
+			f.begin = int64(next)
+		} else {
+			f.begin = int64(end) + 1
+		}
+	}
+	return logs, nil
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -267,11 +342,26 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 			}
 			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
 		}
+		// Give up early if the logs can't be returned to an RPC caller anyway
+		if budget, ok := rpc.ResponseBudget(ctx); ok {
+			for _, log := range logs {
+				f.size += logJSONSize(log)
+			}
+			if f.size > budget {
+				return nil, errResponseBudget
+			}
+		}
 		return logs, nil
 	}
 	return nil, nil
 }
 
+// logJSONSize returns a lower bound of the size of the JSON encoding of a log,
+// counting its hex encoded fields and the field names.
+func logJSONSize(log *types.Log) int {
+	return 300 + len(log.Topics)*(2*common.HashLength+5) + 2*len(log.Data)
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
@@ -346,3 +436,14 @@ func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]commo
 	}
 	return true
 }
+
+// filterTxs creates a slice of transactions matching the given criteria.
+func filterTxs(txs []*types.Transaction, crit *TransactionCriteria) []*types.Transaction {
+	var ret []*types.Transaction
+	for _, tx := range txs {
+		if crit.matches(tx) {
+			ret = append(ret, tx)
+		}
+	}
+	return ret
+}
@@ -73,8 +73,10 @@ type subscription struct {
 	typ       Type
 	created   time.Time
 	logsCrit  etherdata.FilterQuery
+	txsCrit   *TransactionCriteria
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
+	txs       chan []*types.Transaction
 	headers   chan *types.Header
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
@@ -166,6 +168,7 @@ func (sub *Subscription) Unsubscribe() {
 				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
+			case <-sub.f.txs:
 			case <-sub.f.headers:
 			}
 		}
@@ -306,6 +309,31 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 	return es.subscribe(sub)
 }
 
+// SubscribeFilteredPendingTxs creates a subscription for the transactions that
+// enter the transaction pool and match the given criteria. Matches are written
+// in full to txs if given, or as hashes to the hashes channel otherwise.
+func (es *EventSystem) SubscribeFilteredPendingTxs(crit TransactionCriteria, hashes chan []common.Hash, txs chan []*types.Transaction) (*Subscription, error) {
+	if err := crit.validate(); err != nil {
+		return nil, err
+	}
+	if hashes == nil {
+		hashes = make(chan []common.Hash)
+	}
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingTransactionsSubscription,
+		created:   time.Now(),
+		txsCrit:   &crit,
+		logs:      make(chan []*types.Log),
+		hashes:    hashes,
+		txs:       txs,
+		headers:   make(chan *types.Header),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub), nil
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 func (es *EventSystem) handleLogs(filters filterIndex, ev []*types.Log) {
@@ -347,7 +375,23 @@ func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent)
 		hashes = append(hashes, tx.Hash())
 	}
 	for _, f := range filters[PendingTransactionsSubscription] {
-		f.hashes <- hashes
+		if f.txsCrit == nil {
+			f.hashes <- hashes
+			continue
+		}
+		matched := filterTxs(ev.Txs, f.txsCrit)
+		if len(matched) == 0 {
+			continue
+		}
+		if f.txs != nil {
+			f.txs <- matched
+			continue
+		}
+		matchedHashes := make([]common.Hash, 0, len(matched))
+		for _, tx := range matched {
+			matchedHashes = append(matchedHashes, tx.Hash())
+		}
+		f.hashes <- matchedHashes
 	}
 }
 
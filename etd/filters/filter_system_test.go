@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -28,11 +29,13 @@ import (
 
 	"github.com/crypyto-panel/go-etherdata"
 	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/hexutil"
 	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
 	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/bloombits"
 	"github.com/crypyto-panel/go-etherdata/core/rawdb"
 	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/crypto"
 	"github.com/crypyto-panel/go-etherdata/etddb"
 	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/params"
@@ -168,7 +171,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		db          = rawdb.NewMemoryDatabase()
 		backend     = &testBackend{db: db}
-		api         = NewPublicFilterAPI(backend, false, deadline)
+		api         = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 		genesis     = (&core.Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, etdash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -220,7 +223,7 @@ func TestPendingTxFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -271,11 +274,85 @@ func TestPendingTxFilter(t *testing.T) {
 
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
+// TestFilteredPendingTxSubscription tests that pending transactions are matched
+// against the subscription criteria, and delivered in full or as hashes.
+func TestFilteredPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		es      = NewEventSystem(backend, false)
+
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key1.PublicKey)
+		to      = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		other   = common.HexToAddress("0x01")
+		signer  = types.LatestSigner(params.TestChainConfig)
+	)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to *common.Address, price int64, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: to, GasPrice: big.NewInt(price), Gas: 21000, Data: data})
+	}
+	transactions := []*types.Transaction{
+		sign(key1, 0, &to, 10, []byte{0xa9, 0x05, 0x9c, 0xbb, 0x01}), // match
+		sign(key2, 0, &to, 10, []byte{0xa9, 0x05, 0x9c, 0xbb}),       // wrong sender
+		sign(key1, 1, &other, 10, []byte{0xa9, 0x05, 0x9c, 0xbb}),    // wrong recipient
+		sign(key1, 2, nil, 10, []byte{0xa9, 0x05, 0x9c, 0xbb}),       // contract creation
+		sign(key1, 3, &to, 10, []byte{0x09, 0x5e, 0xa7, 0xb3}),       // wrong selector
+		sign(key1, 4, &to, 10, []byte{0xa9, 0x05}),                   // short call data
+		sign(key1, 5, &to, 5, []byte{0xa9, 0x05, 0x9c, 0xbb}),        // too cheap
+		sign(key1, 6, &to, 20, []byte{0x09, 0x5e, 0xa7, 0xb3}),       // match
+	}
+	crit := TransactionCriteria{
+		From:        []common.Address{from},
+		To:          []common.Address{to},
+		Selectors:   []hexutil.Bytes{{0xa9, 0x05, 0x9c, 0xbb}},
+		MinGasPrice: (*hexutil.Big)(big.NewInt(10)),
+	}
+	full := make(chan []*types.Transaction)
+	fullSub, err := es.SubscribeFilteredPendingTxs(crit, nil, full)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer fullSub.Unsubscribe()
+
+	crit.Selectors = []hexutil.Bytes{{0x09, 0x5e, 0xa7, 0xb3}}
+	crit.MinGasPrice = (*hexutil.Big)(big.NewInt(15))
+	hashes := make(chan []common.Hash)
+	hashSub, err := es.SubscribeFilteredPendingTxs(crit, hashes, nil)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer hashSub.Unsubscribe()
+
+	go backend.txFeed.Send(core.NewTxsEvent{Txs: transactions})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case txs := <-full:
+			if len(txs) != 1 || txs[0] != transactions[0] {
+				t.Errorf("full transactions mismatch: have %v, want [%x]", txs, transactions[0].Hash())
+			}
+		case have := <-hashes:
+			if len(have) != 1 || have[0] != transactions[7].Hash() {
+				t.Errorf("transaction hashes mismatch: have %x, want [%x]", have, transactions[7].Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("pending transactions not delivered")
+		}
+	}
+	// Malformed criteria are rejected
+	if _, err := es.SubscribeFilteredPendingTxs(TransactionCriteria{Selectors: []hexutil.Bytes{{0x01}}}, nil, full); err == nil {
+		t.Errorf("invalid selector accepted")
+	}
+}
+
 func TestLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -319,7 +396,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 	)
 
 	// different situations where log filter creation should fail.
@@ -341,7 +418,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	var (
 		db        = rawdb.NewMemoryDatabase()
 		backend   = &testBackend{db: db}
-		api       = NewPublicFilterAPI(backend, false, deadline)
+		api       = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 		blockHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -366,7 +443,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -480,7 +557,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, NewEventSystem(backend, false), deadline)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -616,7 +693,7 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, timeout)
+		api     = NewPublicFilterAPI(backend, NewEventSystem(backend, false), timeout)
 		done    = make(chan struct{})
 	)
 
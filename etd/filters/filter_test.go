@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
@@ -29,6 +30,8 @@ import (
 	"github.com/crypyto-panel/go-etherdata/core/rawdb"
 	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/params"
 )
 
@@ -223,3 +226,115 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// logIndexTestBackend extends the test backend with a persistent log index.
+type logIndexTestBackend struct {
+	*testBackend
+	indexer *core.ChainIndexer
+	size    uint64
+}
+
+func (b *logIndexTestBackend) LogIndexStatus() (uint64, uint64) {
+	sections, _, _ := b.indexer.Sections()
+	return b.size, sections
+}
+
+// logIndexTestChain is a static core.ChainIndexerChain over the test database.
+type logIndexTestChain struct {
+	db   etddb.Database
+	feed event.Feed
+}
+
+func (c *logIndexTestChain) CurrentHeader() *types.Header {
+	return rawdb.ReadHeadHeader(c.db)
+}
+
+func (c *logIndexTestChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// Tests that range filters are served from the persistent log index where it is
+// available, falling back to block iteration for the rest of the range.
+func TestLogIndexFilters(t *testing.T) {
+	const size = 100
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key1.PublicKey)
+		hash1   = common.BytesToHash([]byte("topic1"))
+		hash2   = common.BytesToHash([]byte("topic2"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, etdash.NewFaker(), db, 250, func(i int, gen *core.BlockGen) {
+		var topic common.Hash
+		switch i {
+		case 4, 239:
+			topic = hash1
+		case 149:
+			topic = hash2
+		default:
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic}}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteHeadHeaderHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the first two sections, leaving the chain head unindexed
+	indexer := core.NewLogIndexer(db, size, 0)
+	defer indexer.Close()
+	indexer.Start(&logIndexTestChain{db: db})
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := indexer.Sections(); sections == 2 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("log index not generated")
+		}
+	}
+	backend := &logIndexTestBackend{testBackend: &testBackend{db: db}, indexer: indexer, size: size}
+
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		want       []uint64
+	}{
+		{0, -1, []common.Address{addr}, nil, []uint64{5, 150, 240}},
+		{0, -1, nil, [][]common.Hash{{hash1}}, []uint64{5, 240}},
+		{0, -1, nil, [][]common.Hash{{hash1, hash2}}, []uint64{5, 150, 240}},
+		{100, 199, []common.Address{addr}, nil, []uint64{150}},
+		{6, 150, nil, [][]common.Hash{{hash1, hash2}}, []uint64{150}},
+		{0, -1, nil, nil, []uint64{5, 150, 240}},
+		{0, -1, []common.Address{common.BytesToAddress([]byte("failmenow"))}, nil, nil},
+	}
+	for i, tt := range tests {
+		logs, err := NewRangeFilter(backend, tt.begin, tt.end, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: filtering failed: %v", i, err)
+		}
+		var have []uint64
+		for _, log := range logs {
+			have = append(have, log.BlockNumber)
+		}
+		if len(have) != len(tt.want) {
+			t.Errorf("test %d: log count mismatch: have %v, want %v", i, have, tt.want)
+			continue
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, have, tt.want)
+				break
+			}
+		}
+	}
+}
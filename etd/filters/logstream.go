@@ -0,0 +1,335 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/hexutil"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+const (
+	// logStreamChunk is the maximum number of blocks a resumable log subscription
+	// backfills in one go, before rechecking the chain for reorgs.
+	logStreamChunk = 2048
+
+	// logStreamRetries is the number of times a resumable log subscription retries
+	// a backfill interrupted by reorgs, before waiting for the next chain head.
+	logStreamRetries = 8
+
+	// cursorComplete marks a cursor whose block has been fully delivered.
+	cursorComplete = math.MaxUint32
+)
+
+var (
+	errInvalidCursor  = errors.New("invalid resume cursor")
+	errUnknownCursor  = errors.New("resume cursor refers to unknown block")
+	errPendingResume  = errors.New("pending logs cannot be resumed")
+	errCursorAndStart = errors.New("cannot specify both resume cursor and fromBlock")
+	errStreamReorged  = errors.New("chain reorged during backfill")
+)
+
+// LogsOptions turns a log subscription into a resumable one, which backfills
+// historical logs before following the chain, and notifies LogEvents carrying
+// a cursor to resume from after a disconnect.
+type LogsOptions struct {
+	// Resume is the cursor of the last event received by the client. Logs are
+	// delivered from right after it, with logs reorged out since then delivered
+	// as removed first. The filter criteria must be the same as originally.
+	Resume hexutil.Bytes `json:"resume"`
+}
+
+// LogEvent is a notification of a resumable log subscription. Reorged logs are
+// notified again with the removed flag set, in reverse order.
+type LogEvent struct {
+	Log    *types.Log    `json:"log"`
+	Cursor hexutil.Bytes `json:"cursor"`
+}
+
+// logCursor is the position of a resumable log subscription within the chain:
+// all the matching logs up to the given block have been delivered, along with
+// the ones within the block with an index lower than seen.
+type logCursor struct {
+	number uint64
+	hash   common.Hash
+	seen   uint32
+}
+
+// encode serializes the cursor into an opaque token for the client.
+func (c logCursor) encode() hexutil.Bytes {
+	blob := make([]byte, 8+common.HashLength+4)
+	binary.BigEndian.PutUint64(blob, c.number)
+	copy(blob[8:], c.hash[:])
+	binary.BigEndian.PutUint32(blob[8+common.HashLength:], c.seen)
+	return blob
+}
+
+// decodeLogCursor parses a cursor token created by encode.
+func decodeLogCursor(blob []byte) (logCursor, error) {
+	if len(blob) != 8+common.HashLength+4 {
+		return logCursor{}, errInvalidCursor
+	}
+	return logCursor{
+		number: binary.BigEndian.Uint64(blob),
+		hash:   common.BytesToHash(blob[8 : 8+common.HashLength]),
+		seen:   binary.BigEndian.Uint32(blob[8+common.HashLength:]),
+	}, nil
+}
+
+// logStream delivers the logs matching a filter along the canonical chain from
+// a cursor onwards, exactly once, unwinding the cursor with removal events if
+// the blocks it went through get reorged out.
+type logStream struct {
+	backend Backend
+	crit    FilterCriteria
+	cursor  logCursor
+	notify  func(*LogEvent) error
+}
+
+// newLogStream creates a log stream starting from the resume cursor if given,
+// or from the first block of the filter criteria otherwise.
+func newLogStream(ctx context.Context, backend Backend, crit FilterCriteria, opts *LogsOptions, notify func(*LogEvent) error) (*logStream, error) {
+	if crit.BlockHash != nil {
+		return nil, errors.New("cannot subscribe to the logs of a single block")
+	}
+	if (crit.FromBlock != nil && crit.FromBlock.Int64() == rpc.PendingBlockNumber.Int64()) ||
+		(crit.ToBlock != nil && crit.ToBlock.Int64() == rpc.PendingBlockNumber.Int64()) {
+		return nil, errPendingResume
+	}
+	stream := &logStream{backend: backend, crit: crit, notify: notify}
+
+	// Resume from the client cursor if one was given
+	if len(opts.Resume) > 0 {
+		if crit.FromBlock != nil && crit.FromBlock.Int64() != rpc.LatestBlockNumber.Int64() {
+			return nil, errCursorAndStart
+		}
+		cursor, err := decodeLogCursor(opts.Resume)
+		if err != nil {
+			return nil, err
+		}
+		header, err := backend.HeaderByHash(ctx, cursor.hash)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.Number.Uint64() != cursor.number {
+			return nil, errUnknownCursor
+		}
+		stream.cursor = cursor
+		return stream, nil
+	}
+	// Otherwise start at the beginning of the first requested block
+	number := rpc.LatestBlockNumber
+	if crit.FromBlock != nil {
+		number = rpc.BlockNumber(crit.FromBlock.Int64())
+	}
+	header, err := backend.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("unknown starting block")
+	}
+	stream.cursor = logCursor{number: header.Number.Uint64(), hash: header.Hash()}
+	if number == rpc.LatestBlockNumber {
+		stream.cursor.seen = cursorComplete // only deliver logs of future blocks
+	}
+	return stream, nil
+}
+
+// advance delivers all the events between the cursor and the current chain head,
+// retrying until the chain stays put for long enough.
+func (s *logStream) advance(ctx context.Context) error {
+	for i := 0; ; i++ {
+		if err := s.rewind(ctx); err != nil {
+			return err
+		}
+		err := s.forward(ctx)
+		if err != errStreamReorged || i == logStreamRetries {
+			return err
+		}
+	}
+}
+
+// rewind walks the cursor back to the canonical chain, delivering removal events
+// for all the logs delivered from blocks since reorged out.
+func (s *logStream) rewind(ctx context.Context) error {
+	for {
+		header, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(s.cursor.number))
+		if err != nil {
+			return err
+		}
+		if header != nil && header.Hash() == s.cursor.hash {
+			return nil
+		}
+		// Cursor block reorged out, remove its delivered logs in reverse order
+		logs, err := s.blockLogs(ctx, s.cursor.hash)
+		if err != nil {
+			return err
+		}
+		for i := len(logs) - 1; i >= 0; i-- {
+			if uint64(logs[i].Index) >= uint64(s.cursor.seen) {
+				continue
+			}
+			removed := *logs[i]
+			removed.Removed = true
+
+			s.cursor.seen = uint32(removed.Index)
+			if err := s.emit(&removed); err != nil {
+				return err
+			}
+		}
+		// Step the cursor back to the end of the parent block
+		orphan, err := s.backend.HeaderByHash(ctx, s.cursor.hash)
+		if err != nil {
+			return err
+		}
+		if orphan == nil || s.cursor.number == 0 {
+			return errUnknownCursor
+		}
+		s.cursor = logCursor{number: s.cursor.number - 1, hash: orphan.ParentHash, seen: cursorComplete}
+	}
+}
+
+// forward delivers the logs of the canonical chain from the cursor up to the
+// current head, or the end of the filter range. It returns errStreamReorged if
+// the chain changed under it, without delivering inconsistent logs.
+func (s *logStream) forward(ctx context.Context) error {
+	head, err := s.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || head == nil {
+		return err
+	}
+	end := head.Number.Uint64()
+	if s.crit.ToBlock != nil && s.crit.ToBlock.Int64() >= 0 && s.crit.ToBlock.Uint64() < end {
+		end = s.crit.ToBlock.Uint64()
+	}
+	// Finish off the cursor block if it was delivered partially
+	if s.cursor.seen != cursorComplete && s.cursor.number <= end {
+		logs, err := s.blockLogs(ctx, s.cursor.hash)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if uint64(log.Index) < uint64(s.cursor.seen) {
+				continue
+			}
+			s.cursor.seen = uint32(log.Index) + 1
+			if err := s.emit(log); err != nil {
+				return err
+			}
+		}
+		s.cursor.seen = cursorComplete
+	}
+	// Deliver the following blocks in chunks, verifying each against reorgs
+	for s.cursor.number < end {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from, to := s.cursor.number+1, s.cursor.number+logStreamChunk
+		if to > end {
+			to = end
+		}
+		// Pin the end of the chunk before filtering, any reorg of the chunk
+		// while it's being filtered is detected by it changing afterwards
+		last, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(to))
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			return errStreamReorged
+		}
+		filter := NewRangeFilter(s.backend, int64(from), int64(to), s.crit.Addresses, s.crit.Topics)
+		logs, err := filter.Logs(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.checkCanonical(ctx, from, last, logs); err != nil {
+			return err
+		}
+		for _, log := range logs {
+			s.cursor = logCursor{number: log.BlockNumber, hash: log.BlockHash, seen: uint32(log.Index) + 1}
+			if err := s.emit(log); err != nil {
+				return err
+			}
+		}
+		s.cursor = logCursor{number: to, hash: last.Hash(), seen: cursorComplete}
+	}
+	return nil
+}
+
+// checkCanonical verifies that the chain segment from the given block number up
+// to the pinned last header attaches to the cursor, that every log filtered
+// belongs to a block of the segment, and that the segment is still canonical.
+func (s *logStream) checkCanonical(ctx context.Context, from uint64, last *types.Header, logs []*types.Log) error {
+	// Collect the hashes of the segment by walking it back from its end
+	hashes := make(map[uint64]common.Hash)
+	for header := last; ; {
+		number := header.Number.Uint64()
+		hashes[number] = header.Hash()
+		if number == from {
+			if header.ParentHash != s.cursor.hash {
+				return errStreamReorged
+			}
+			break
+		}
+		parent, err := s.backend.HeaderByHash(ctx, header.ParentHash)
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			return errStreamReorged
+		}
+		header = parent
+	}
+	for _, log := range logs {
+		if hashes[log.BlockNumber] != log.BlockHash {
+			return errStreamReorged
+		}
+	}
+	// Ensure the segment wasn't reorged out while it was being filtered
+	head, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(last.Number.Uint64()))
+	if err != nil {
+		return err
+	}
+	if head == nil || head.Hash() != last.Hash() {
+		return errStreamReorged
+	}
+	return nil
+}
+
+// blockLogs returns the logs of a block matching the filter criteria.
+func (s *logStream) blockLogs(ctx context.Context, hash common.Hash) ([]*types.Log, error) {
+	logsList, err := s.backend.GetLogs(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	var unfiltered []*types.Log
+	for _, logs := range logsList {
+		unfiltered = append(unfiltered, logs...)
+	}
+	return filterLogs(unfiltered, nil, nil, s.crit.Addresses, s.crit.Topics), nil
+}
+
+// emit notifies the client of a log along with the cursor right after it.
+func (s *logStream) emit(log *types.Log) error {
+	return s.notify(&LogEvent{Log: log, Cursor: s.cursor.encode()})
+}
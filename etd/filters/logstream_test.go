@@ -0,0 +1,208 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/rawdb"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/etddb"
+	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+// streamedLog is a summary of a log event for test comparisons.
+type streamedLog struct {
+	number  uint64
+	topic   common.Hash
+	removed bool
+}
+
+// makeStreamChain generates a chain emitting logs with the topics given for
+// each block index, and makes it the canonical one.
+func makeStreamChain(db etddb.Database, parent *types.Block, n int, seed byte, topics map[int][]common.Hash) []*types.Block {
+	addr := common.HexToAddress("0x01")
+	blocks, receipts := core.GenerateChain(params.TestChainConfig, parent, etdash.NewFaker(), db, n, func(i int, gen *core.BlockGen) {
+		gen.SetExtra([]byte{seed})
+		for j, topic := range topics[i] {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic}}}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i*10+j), common.Address{}, big.NewInt(0), 0, gen.BaseFee(), nil))
+		}
+	})
+	for i, block := range blocks {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	return blocks
+}
+
+// collectingStream creates a log stream recording all its events.
+func collectingStream(t *testing.T, backend Backend, crit FilterCriteria, opts *LogsOptions) (*logStream, *[]*LogEvent) {
+	t.Helper()
+
+	events := new([]*LogEvent)
+	stream, err := newLogStream(context.Background(), backend, crit, opts, func(event *LogEvent) error {
+		*events = append(*events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to create log stream: %v", err)
+	}
+	return stream, events
+}
+
+func checkStreamedLogs(t *testing.T, events []*LogEvent, want []streamedLog) {
+	t.Helper()
+
+	if len(events) != len(want) {
+		t.Fatalf("event count mismatch: have %d, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		have := streamedLog{event.Log.BlockNumber, event.Log.Topics[0], event.Log.Removed}
+		if have != want[i] {
+			t.Errorf("event %d: mismatch: have %+v, want %+v", i, have, want[i])
+		}
+	}
+}
+
+// Tests that resumable log streams backfill history, resume from cursors exactly
+// where they left off and unwind reorged logs explicitly.
+func TestLogStream(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		genesis = core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+
+		topicA = common.HexToHash("0x0a")
+		topicB = common.HexToHash("0x0b")
+		topicC = common.HexToHash("0x0c")
+	)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	// Block numbers are one higher than the generator indices
+	blocks := makeStreamChain(db, genesis, 10, 0, map[int][]common.Hash{
+		1: {topicA},
+		4: {topicA, topicB},
+		7: {topicB},
+	})
+	// Backfill everything from the genesis
+	crit := FilterCriteria{FromBlock: big.NewInt(0)}
+	stream, events := collectingStream(t, backend, crit, &LogsOptions{})
+	if err := stream.advance(context.Background()); err != nil {
+		t.Fatalf("failed to advance stream: %v", err)
+	}
+	checkStreamedLogs(t, *events, []streamedLog{
+		{2, topicA, false}, {5, topicA, false}, {5, topicB, false}, {8, topicB, false},
+	})
+	// Resume from right within block 5 and ensure nothing is lost or duplicated
+	stream, resumed := collectingStream(t, backend, FilterCriteria{}, &LogsOptions{Resume: (*events)[1].Cursor})
+	if err := stream.advance(context.Background()); err != nil {
+		t.Fatalf("failed to advance resumed stream: %v", err)
+	}
+	checkStreamedLogs(t, *resumed, []streamedLog{
+		{5, topicB, false}, {8, topicB, false},
+	})
+	// Reorg the chain from block 4 and ensure the stale logs are removed, in
+	// reverse order, before the new ones are delivered
+	makeStreamChain(db, blocks[2], 8, 1, map[int][]common.Hash{
+		2: {topicC},
+	})
+	*resumed = nil
+	if err := stream.advance(context.Background()); err != nil {
+		t.Fatalf("failed to advance reorged stream: %v", err)
+	}
+	checkStreamedLogs(t, *resumed, []streamedLog{
+		{8, topicB, true}, {5, topicB, true}, {5, topicA, true}, {6, topicC, false},
+	})
+	// Resume from a cursor within the reorged blocks
+	stream, reorged := collectingStream(t, backend, FilterCriteria{}, &LogsOptions{Resume: (*events)[2].Cursor})
+	if err := stream.advance(context.Background()); err != nil {
+		t.Fatalf("failed to advance stale stream: %v", err)
+	}
+	checkStreamedLogs(t, *reorged, []streamedLog{
+		{5, topicB, true}, {5, topicA, true}, {6, topicC, false},
+	})
+	// Filter criteria are applied to backfills, reorgs and resumes alike
+	crit = FilterCriteria{FromBlock: big.NewInt(3), Topics: [][]common.Hash{{topicC}}}
+	stream, filtered := collectingStream(t, backend, crit, &LogsOptions{})
+	if err := stream.advance(context.Background()); err != nil {
+		t.Fatalf("failed to advance filtered stream: %v", err)
+	}
+	checkStreamedLogs(t, *filtered, []streamedLog{{6, topicC, false}})
+
+	// Invalid cursors and conflicting options are rejected
+	if _, err := newLogStream(context.Background(), backend, FilterCriteria{}, &LogsOptions{Resume: []byte{1, 2, 3}}, nil); err != errInvalidCursor {
+		t.Errorf("invalid cursor error mismatch: have %v, want %v", err, errInvalidCursor)
+	}
+	unknown := logCursor{number: 5, hash: common.HexToHash("0xdead")}.encode()
+	if _, err := newLogStream(context.Background(), backend, FilterCriteria{}, &LogsOptions{Resume: unknown}, nil); err != errUnknownCursor {
+		t.Errorf("unknown cursor error mismatch: have %v, want %v", err, errUnknownCursor)
+	}
+	if _, err := newLogStream(context.Background(), backend, FilterCriteria{FromBlock: big.NewInt(1)}, &LogsOptions{Resume: (*events)[0].Cursor}, nil); err != errCursorAndStart {
+		t.Errorf("conflicting options error mismatch: have %v, want %v", err, errCursorAndStart)
+	}
+}
+
+// reorgingBackend is a test backend running a hook the first time the header of
+// a given block number is requested.
+type reorgingBackend struct {
+	*testBackend
+	number rpc.BlockNumber
+	hook   func()
+}
+
+func (b *reorgingBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == b.number && b.hook != nil {
+		hook := b.hook
+		b.hook = nil
+		hook()
+	}
+	return b.testBackend.HeaderByNumber(ctx, number)
+}
+
+// Tests that reorgs happening while a chunk is being filtered are detected even
+// if they only touch blocks without any logs on the original chain.
+func TestLogStreamReorgDuringFilter(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+		topic   = common.HexToHash("0x0a")
+	)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	blocks := makeStreamChain(db, genesis, 10, 0, nil)
+
+	// Swap in a chain with a log in block 4 once the filter went past it
+	backend := &reorgingBackend{testBackend: &testBackend{db: db}, number: 8}
+	backend.hook = func() {
+		makeStreamChain(db, blocks[1], 9, 1, map[int][]common.Hash{1: {topic}})
+	}
+	stream, events := collectingStream(t, backend, FilterCriteria{FromBlock: big.NewInt(0)}, &LogsOptions{})
+	if err := stream.advance(context.Background()); err != nil {
+		t.Fatalf("failed to advance stream: %v", err)
+	}
+	checkStreamedLogs(t, *events, []streamedLog{{4, topic, false}})
+}
@@ -219,7 +219,11 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return n, err
 	}
-	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.removePeer)
+	// The block fetcher only drops peers propagating blocks failing validation
+	dropper := func(id string) {
+		h.removePeer(id, p2p.InvalidData)
+	}
+	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, dropper)
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -319,7 +323,7 @@ func (h *handler) runEthPeer(peer *etd.Peer, handler etd.Handler) error {
 		// Start a timer to disconnect if the peer doesn't reply in time
 		p.syncDrop = time.AfterFunc(syncChallengeTimeout, func() {
 			peer.Log().Warn("Checkpoint challenge timed out, dropping", "addr", peer.RemoteAddr(), "type", peer.Name())
-			h.removePeer(peer.ID())
+			h.removePeer(peer.ID(), p2p.RequestTimeout)
 		})
 		// Make sure it's cleaned up if the peer dies off
 		defer func() {
@@ -354,10 +358,12 @@ func (h *handler) runSnapExtension(peer *snap.Peer, handler snap.Handler) error
 	return handler(peer)
 }
 
-// removePeer requests disconnection of a peer.
-func (h *handler) removePeer(id string) {
+// removePeer requests disconnection of a peer, recording the behaviour that led
+// to it against the peer's reputation.
+func (h *handler) removePeer(id string, reason p2p.ReputationEvent) {
 	peer := h.peers.peer(id)
 	if peer != nil {
+		peer.Peer.Report(reason)
 		peer.Peer.Disconnect(p2p.DiscUselessPeer)
 	}
 }
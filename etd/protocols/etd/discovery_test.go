@@ -0,0 +1,62 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package etd
+
+import (
+	"testing"
+
+	"github.com/crypyto-panel/go-etherdata/core/forkid"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/p2p/enr"
+)
+
+// Tests that the node filter only accepts nodes advertising a fork ID which is
+// compatible with the local chain.
+func TestNodeFilter(t *testing.T) {
+	backend := newTestBackend(0)
+	defer backend.close()
+
+	filter := NewNodeFilter(backend.chain)
+
+	tests := []struct {
+		entry *enrEntry
+		want  bool
+	}{
+		{entry: currentENREntry(backend.chain), want: true},
+		{entry: &enrEntry{ForkID: forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}}, want: false},
+		{entry: nil, want: false},
+	}
+	for i, tt := range tests {
+		key, _ := crypto.GenerateKey()
+
+		var r enr.Record
+		if tt.entry != nil {
+			r.Set(tt.entry)
+		}
+		if err := enode.SignV4(&r, key); err != nil {
+			t.Fatalf("test %d: failed to sign record: %v", i, err)
+		}
+		node, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			t.Fatalf("test %d: failed to create node: %v", i, err)
+		}
+		if have := filter(node); have != tt.want {
+			t.Errorf("test %d: filter mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
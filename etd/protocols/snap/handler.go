@@ -36,6 +36,8 @@ import (
 
 const (
 	// softResponseLimit is the target maximum size of replies to data retrievals.
+	// Replies are further scaled down to the throughput measured towards the
+	// requesting peer, see serve.go.
 	softResponseLimit = 2 * 1024 * 1024
 
 	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
@@ -82,14 +84,17 @@ type Backend interface {
 	Handle(peer *Peer, packet Packet) error
 }
 
-// MakeProtocols constructs the P2P protocol definitions for `snap`.
-func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
+// MakeProtocols constructs the P2P protocol definitions for `snap`. Serving
+// remote requests is subject to the given limits, shared across all peers.
+func MakeProtocols(backend Backend, config ServeConfig, dnsdisc enode.Iterator) []p2p.Protocol {
 	// Filter the discovery iterator for nodes advertising snap support.
 	dnsdisc = enode.Filter(dnsdisc, func(n *enode.Node) bool {
 		var snap enrEntry
 		return n.Load(&snap) == nil
 	})
 
+	srv := newServer(config)
+
 	protocols := make([]p2p.Protocol, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		version := version // Closure
@@ -99,8 +104,8 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 			Version: version,
 			Length:  protocolLengths[version],
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
-				return backend.RunPeer(newPeer(version, p, rw), func(peer *Peer) error {
-					return handle(backend, peer)
+				return backend.RunPeer(srv.newPeer(version, p, rw), func(peer *Peer) error {
+					return handle(backend, srv, peer)
 				})
 			},
 			NodeInfo: func() interface{} {
@@ -118,9 +123,9 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 
 // handle is the callback invoked to manage the life cycle of a `snap` peer.
 // When this function terminates, the peer is disconnected.
-func handle(backend Backend, peer *Peer) error {
+func handle(backend Backend, srv *server, peer *Peer) error {
 	for {
-		if err := handleMessage(backend, peer); err != nil {
+		if err := handleMessage(backend, srv, peer); err != nil {
 			peer.Log().Debug("Message handling failed in `snap`", "err", err)
 			return err
 		}
@@ -130,7 +135,7 @@ func handle(backend Backend, peer *Peer) error {
 // handleMessage is invoked whenever an inbound message is received from a
 // remote peer on the `snap` protocol. The remote connection is torn down upon
 // returning any error.
-func handleMessage(backend Backend, peer *Peer) error {
+func handleMessage(backend Backend, srv *server, peer *Peer) error {
 	// Read the next message from the remote peer, and ensure it's fully consumed
 	msg, err := peer.rw.ReadMsg()
 	if err != nil {
@@ -141,6 +146,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 	}
 	defer msg.Discard()
 	start := time.Now()
+	srv.received(peer, msg.Code, start)
+
 	// Track the emount of time it takes to serve the request and run the handler
 	if metrics.Enabled {
 		h := fmt.Sprintf("%s/%s/%d/%#02x", p2p.HandleHistName, ProtocolName, peer.Version(), msg.Code)
@@ -161,9 +168,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
-		if req.Bytes > softResponseLimit {
-			req.Bytes = softResponseLimit
-		}
+		req.Bytes = srv.responseLimit(peer, AccountRangeMsg, req.Bytes)
+
 		// Retrieve the requested state and bail out if non existent
 		tr, err := trie.New(req.Root, backend.Chain().StateCache().TrieDB())
 		if err != nil {
@@ -179,7 +185,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 			size     uint64
 			last     common.Hash
 		)
-		for it.Next() && size < req.Bytes {
+		for it.Next() {
 			hash, account := it.Hash(), common.CopyBytes(it.Account())
 
 			// Track the returned interval for the Merkle proofs
@@ -191,10 +197,14 @@ func handleMessage(backend Backend, peer *Peer) error {
 				Hash: hash,
 				Body: account,
 			})
-			// If we've exceeded the request threshold, abort
+			// If we've exceeded the request threshold, abort. At least one account
+			// is always served to guarantee progress, even for tiny limits.
 			if bytes.Compare(hash[:], req.Limit[:]) >= 0 {
 				break
 			}
+			if size >= req.Bytes {
+				break
+			}
 		}
 		it.Release()
 
@@ -215,7 +225,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 			proofs = append(proofs, blob)
 		}
 		// Send back anything accumulated
-		return p2p.Send(peer.rw, AccountRangeMsg, &AccountRangePacket{
+		return srv.reply(peer, AccountRangeMsg, accountRangeServeMeter, &AccountRangePacket{
 			ID:       req.ID,
 			Accounts: accounts,
 			Proof:    proofs,
@@ -243,9 +253,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
-		if req.Bytes > softResponseLimit {
-			req.Bytes = softResponseLimit
-		}
+		req.Bytes = srv.responseLimit(peer, StorageRangesMsg, req.Bytes)
+
 		// TODO(karalabe): Do we want to enforce > 0 accounts and 1 account if origin is set?
 		// TODO(karalabe):   - Logging locally is not ideal as remote faulst annoy the local user
 		// TODO(karalabe):   - Dropping the remote peer is less flexible wrt client bugs (slow is better than non-functional)
@@ -348,7 +357,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 			}
 		}
 		// Send back anything accumulated
-		return p2p.Send(peer.rw, StorageRangesMsg, &StorageRangesPacket{
+		return srv.reply(peer, StorageRangesMsg, storageRangeServeMeter, &StorageRangesPacket{
 			ID:    req.ID,
 			Slots: slots,
 			Proof: proofs,
@@ -378,9 +387,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
-		if req.Bytes > softResponseLimit {
-			req.Bytes = softResponseLimit
-		}
+		req.Bytes = srv.responseLimit(peer, ByteCodesMsg, req.Bytes)
+
 		if len(req.Hashes) > maxCodeLookups {
 			req.Hashes = req.Hashes[:maxCodeLookups]
 		}
@@ -403,7 +411,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 			}
 		}
 		// Send back anything accumulated
-		return p2p.Send(peer.rw, ByteCodesMsg, &ByteCodesPacket{
+		return srv.reply(peer, ByteCodesMsg, byteCodeServeMeter, &ByteCodesPacket{
 			ID:    req.ID,
 			Codes: codes,
 		})
@@ -424,9 +432,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
-		if req.Bytes > softResponseLimit {
-			req.Bytes = softResponseLimit
-		}
+		req.Bytes = srv.responseLimit(peer, TrieNodesMsg, req.Bytes)
+
 		// Make sure we have the state associated with the request
 		triedb := backend.Chain().StateCache().TrieDB()
 
@@ -498,7 +505,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 			}
 		}
 		// Send back anything accumulated
-		return p2p.Send(peer.rw, TrieNodesMsg, &TrieNodesPacket{
+		return srv.reply(peer, TrieNodesMsg, trieNodeServeMeter, &TrieNodesPacket{
 			ID:    req.ID,
 			Nodes: nodes,
 		})
@@ -0,0 +1,32 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"github.com/crypyto-panel/go-etherdata/metrics"
+)
+
+var (
+	// Bytes served in reply to the individual request types
+	accountRangeServeMeter = metrics.NewRegisteredMeter("snap/serve/accounts", nil)
+	storageRangeServeMeter = metrics.NewRegisteredMeter("snap/serve/storage", nil)
+	byteCodeServeMeter     = metrics.NewRegisteredMeter("snap/serve/bytecodes", nil)
+	trieNodeServeMeter     = metrics.NewRegisteredMeter("snap/serve/trienodes", nil)
+
+	// Time replies were held back to honour the serving budgets
+	serveThrottleTimer = metrics.NewRegisteredTimer("snap/serve/throttle", nil)
+)
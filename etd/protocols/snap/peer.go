@@ -17,9 +17,13 @@
 package snap
 
 import (
+	"time"
+
 	"github.com/crypyto-panel/go-etherdata/common"
 	"github.com/crypyto-panel/go-etherdata/log"
 	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/msgrate"
+	"github.com/crypyto-panel/go-etherdata/p2p/rlpx"
 )
 
 // Peer is a collection of relevant information we have about a `snap` peer.
@@ -30,6 +34,11 @@ type Peer struct {
 	rw        p2p.MsgReadWriter // Input/output streams for snap
 	version   uint              // Protocol version negotiated
 
+	serveRate   *msgrate.Tracker // Throughput measured when serving replies to the peer
+	serveBudget *rlpx.Limiter    // Bytes per second allowed to be served to the peer, nil if unlimited
+	requested   time.Time        // Time the request currently being served arrived
+	lastReply   *servedReply     // Last large reply sent, awaiting the next request
+
 	logger log.Logger // Contextual logger with the peer id injected
 }
 
@@ -37,12 +46,20 @@ type Peer struct {
 // version.
 func newPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
 	id := p.ID().String()
+
+	// Start out assuming the peer can download full sized replies, the serving
+	// measurements will scale it down if not
+	caps := make(map[uint64]float64)
+	for _, code := range []uint64{AccountRangeMsg, StorageRangesMsg, ByteCodesMsg, TrieNodesMsg} {
+		caps[code] = softResponseLimit / serveTargetRTT.Seconds()
+	}
 	return &Peer{
-		id:      id,
-		Peer:    p,
-		rw:      rw,
-		version: version,
-		logger:  log.New("peer", id[:8]),
+		id:        id,
+		Peer:      p,
+		rw:        rw,
+		version:   version,
+		serveRate: msgrate.NewTracker(caps, serveTargetRTT),
+		logger:    log.New("peer", id[:8]),
 	}
 }
 
@@ -0,0 +1,149 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/metrics"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/rlpx"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+const (
+	// serveTargetRTT is the time a reply should take to be delivered to the
+	// remote peer. Replies are sized to fit the throughput measured towards the
+	// peer so that slow peers don't tie up the disk with lookups they can't
+	// download in a timely fashion anyway.
+	serveTargetRTT = time.Second
+
+	// minResponseLimit is the lower bound of the adaptive response sizing. It
+	// ensures that even the slowest peers make meaningful progress. Replies
+	// smaller than this are also not used to measure peer throughput, as their
+	// delivery time is dominated by latency rather than bandwidth.
+	minResponseLimit = 64 * 1024
+
+	// serveIdleTimeout is the time after which the gap between a reply and the
+	// peer's next request is attributed to the peer being idle rather than to
+	// it downloading the reply, and is not used to measure its throughput.
+	serveIdleTimeout = 10 * serveTargetRTT
+)
+
+// ServeConfig contains the limits imposed on serving `snap` requests.
+type ServeConfig struct {
+	Rate     int // Bytes per second allowed to be served to all peers combined (0 = unlimited)
+	PeerRate int // Bytes per second allowed to be served to a single peer (0 = unlimited)
+}
+
+// server maintains the serving budgets shared across all `snap` peers.
+type server struct {
+	config ServeConfig
+	budget *rlpx.Limiter // Global serving budget, nil if unlimited
+}
+
+// newServer creates the shared serving state for the given limits.
+func newServer(config ServeConfig) *server {
+	s := &server{config: config}
+	if config.Rate > 0 {
+		s.budget = rlpx.NewLimiter(config.Rate)
+	}
+	return s
+}
+
+// servedReply is the last reply sent to a peer, kept until the peer's next
+// request arrives to measure how long the peer took to receive it.
+type servedReply struct {
+	code     uint64    // Message code of the reply
+	size     int       // Encoded size of the reply
+	received time.Time // Time the request answered by the reply arrived
+}
+
+// newPeer wraps a network connection into a `snap` peer with its own serving
+// budget and throughput tracker.
+func (s *server) newPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	peer := newPeer(version, p, rw)
+	if s.config.PeerRate > 0 {
+		peer.serveBudget = rlpx.NewLimiter(s.config.PeerRate)
+	}
+	return peer
+}
+
+// responseLimit calculates the number of bytes to serve in reply to a request
+// of the given type. The requested amount is capped by the soft response limit,
+// by the throughput measured towards the peer and by the serving budgets, but
+// never below minResponseLimit.
+func (s *server) responseLimit(peer *Peer, kind uint64, requested uint64) uint64 {
+	limit := uint64(peer.serveRate.Capacity(kind, serveTargetRTT))
+	for _, rate := range []int{s.config.PeerRate, s.config.Rate} {
+		if budget := uint64(float64(rate) * serveTargetRTT.Seconds()); rate > 0 && limit > budget {
+			limit = budget
+		}
+	}
+	if limit > softResponseLimit {
+		limit = softResponseLimit
+	}
+	if limit < minResponseLimit {
+		limit = minResponseLimit
+	}
+	if requested < limit {
+		return requested
+	}
+	return limit
+}
+
+// received is called when a message arrives from the peer. A new request means
+// that the peer is done with the previous reply, so the time between the two
+// requests is fed into the peer's throughput estimate. Timing the local write
+// instead would only measure how fast the reply is handed to the connection.
+func (s *server) received(peer *Peer, code uint64, now time.Time) {
+	switch code {
+	case GetAccountRangeMsg, GetStorageRangesMsg, GetByteCodesMsg, GetTrieNodesMsg:
+	default:
+		return
+	}
+	if last := peer.lastReply; last != nil {
+		if elapsed := now.Sub(last.received); elapsed < serveIdleTimeout {
+			peer.serveRate.Update(last.code, elapsed, last.size)
+		}
+		peer.lastReply = nil
+	}
+	peer.requested = now
+}
+
+// reply sends a response to the peer once the serving budgets permit. Large
+// replies are remembered to measure the peer's throughput on its next request.
+func (s *server) reply(peer *Peer, code uint64, meter metrics.Meter, data interface{}) error {
+	size, r, err := rlp.EncodeToReader(data)
+	if err != nil {
+		return err
+	}
+	// Hold the reply back until both the peer's and the global budget allow it.
+	// Since the peer's next request is only read after this one is answered,
+	// this also limits the database lookups done on the peer's behalf.
+	if wait := peer.serveBudget.Wait(size) + s.budget.Wait(size); wait > 0 {
+		serveThrottleTimer.Update(wait)
+	}
+	if err := peer.rw.WriteMsg(p2p.Msg{Code: code, Size: uint32(size), Payload: r}); err != nil {
+		return err
+	}
+	if size >= minResponseLimit {
+		peer.lastReply = &servedReply{code: code, size: size, received: peer.requested}
+	}
+	meter.Mark(int64(size))
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/metrics"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+)
+
+// newServeTestPeer creates a `snap` peer served by srv, returning the remote
+// end of the connection.
+func newServeTestPeer(srv *server) (*Peer, *p2p.MsgPipeRW) {
+	local, remote := p2p.MsgPipe()
+	peer := srv.newPeer(snap1, p2p.NewPeer(enode.ID{1}, "test", nil), local)
+	return peer, remote
+}
+
+// Tests that response sizes are capped by the request, the soft limit and the
+// serving budgets, and that they adapt to the throughput measured for a peer.
+func TestServeResponseLimit(t *testing.T) {
+	srv := newServer(ServeConfig{})
+	peer, _ := newServeTestPeer(srv)
+
+	if limit := srv.responseLimit(peer, AccountRangeMsg, 1000); limit != 1000 {
+		t.Errorf("small request: limit mismatch: have %d, want %d", limit, 1000)
+	}
+	if limit := srv.responseLimit(peer, AccountRangeMsg, 1<<30); limit != softResponseLimit {
+		t.Errorf("large request: limit mismatch: have %d, want %d", limit, softResponseLimit)
+	}
+	// Feed a slow measurement into the tracker and ensure replies shrink, but
+	// not below the minimum and not for other request types
+	for i := 0; i < 100; i++ {
+		peer.serveRate.Update(AccountRangeMsg, 10*time.Second, minResponseLimit)
+	}
+	if limit := srv.responseLimit(peer, AccountRangeMsg, 1<<30); limit != minResponseLimit {
+		t.Errorf("slow peer: limit mismatch: have %d, want %d", limit, minResponseLimit)
+	}
+	if limit := srv.responseLimit(peer, StorageRangesMsg, 1<<30); limit != softResponseLimit {
+		t.Errorf("slow peer, other type: limit mismatch: have %d, want %d", limit, softResponseLimit)
+	}
+	// Ensure the serving budgets cap the replies to one round trip worth of data
+	srv = newServer(ServeConfig{Rate: 512 * 1024, PeerRate: 256 * 1024})
+	peer, _ = newServeTestPeer(srv)
+	if limit := srv.responseLimit(peer, TrieNodesMsg, 1<<30); limit != 256*1024 {
+		t.Errorf("budgeted peer: limit mismatch: have %d, want %d", limit, 256*1024)
+	}
+}
+
+// Tests that replies are held back once a peer exhausts its serving budget.
+func TestServeReplyThrottle(t *testing.T) {
+	srv := newServer(ServeConfig{PeerRate: 64 * 1024})
+	peer, remote := newServeTestPeer(srv)
+
+	go func() {
+		for {
+			msg, err := remote.ReadMsg()
+			if err != nil {
+				return
+			}
+			msg.Discard()
+		}
+	}()
+	defer remote.Close()
+
+	reply := &ByteCodesPacket{Codes: [][]byte{make([]byte, 40*1024)}}
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := srv.reply(peer, ByteCodesMsg, metrics.NilMeter{}, reply); err != nil {
+			t.Fatalf("reply %d failed: %v", i, err)
+		}
+	}
+	// The first reply fits the initial budget, the second has to wait for the
+	// missing 16KB to be refilled
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("replies not throttled: took %v", elapsed)
+	}
+}
+
+// Tests that the throughput towards a peer is measured between its requests
+// instead of by the time it takes to hand a reply to the connection.
+func TestServeRateMeasurement(t *testing.T) {
+	srv := newServer(ServeConfig{})
+	peer, remote := newServeTestPeer(srv)
+
+	go func() {
+		for {
+			msg, err := remote.ReadMsg()
+			if err != nil {
+				return
+			}
+			msg.Discard()
+		}
+	}()
+	defer remote.Close()
+
+	// Answer requests with full sized replies, the next request arriving only
+	// after the peer needed 4 seconds to download each of them
+	var (
+		reply = &ByteCodesPacket{Codes: [][]byte{make([]byte, softResponseLimit)}}
+		now   = time.Now()
+	)
+	for i := 0; i < 100; i++ {
+		srv.received(peer, GetByteCodesMsg, now)
+		if err := srv.reply(peer, ByteCodesMsg, metrics.NilMeter{}, reply); err != nil {
+			t.Fatalf("reply %d failed: %v", i, err)
+		}
+		now = now.Add(4 * serveTargetRTT)
+	}
+	srv.received(peer, GetByteCodesMsg, now)
+
+	limit := srv.responseLimit(peer, ByteCodesMsg, 1<<30)
+	if want := uint64(softResponseLimit / 4); limit < want*9/10 || limit > want*11/10 {
+		t.Errorf("limit mismatch: have %d, want about %d", limit, want)
+	}
+	// Ensure that requests arriving after the peer idled are not measured
+	if err := srv.reply(peer, ByteCodesMsg, metrics.NilMeter{}, reply); err != nil {
+		t.Fatalf("reply failed: %v", err)
+	}
+	srv.received(peer, GetByteCodesMsg, now.Add(serveIdleTimeout))
+	if have := srv.responseLimit(peer, ByteCodesMsg, 1<<30); have != limit {
+		t.Errorf("idle peer: limit mismatch: have %d, want %d", have, limit)
+	}
+}
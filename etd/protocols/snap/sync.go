@@ -37,6 +37,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/light"
 	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/p2p"
 	"github.com/crypyto-panel/go-etherdata/p2p/msgrate"
 	"github.com/crypyto-panel/go-etherdata/rlp"
 	"github.com/crypyto-panel/go-etherdata/trie"
@@ -77,6 +78,19 @@ const (
 	// and waste round trip times. If it's too high, we're capping responses and
 	// waste bandwidth.
 	maxTrieRequestCount = maxRequestSize / 512
+
+	// syncStatusInterval is the time interval between checkpointing the sync
+	// progress to disk, limiting the work lost if the node crashes mid-sync.
+	syncStatusInterval = time.Minute
+
+	// healNodesChunkSize is the maximum size of the trie nodes stored in one
+	// database entry when checkpointing the healing progress.
+	healNodesChunkSize = 1024 * 1024
+
+	// maxHealNodesChunks is the maximum number of database entries used to
+	// checkpoint the healing progress. Trie nodes beyond this limit are not
+	// saved and need to be retrieved again after a restart.
+	maxHealNodesChunks = 16
 )
 
 var (
@@ -349,6 +363,26 @@ type syncProgress struct {
 	BytecodeHealNops   uint64             // Number of bytecodes not requested
 }
 
+// Progress is a summary of the snapshot sync statistics, meant for reporting
+// to the user.
+type Progress struct {
+	// Status report during syncing phase
+	AccountSynced  uint64             // Number of accounts downloaded
+	AccountBytes   common.StorageSize // Number of account trie bytes persisted to disk
+	BytecodeSynced uint64             // Number of bytecodes downloaded
+	BytecodeBytes  common.StorageSize // Number of bytecode bytes downloaded
+	StorageSynced  uint64             // Number of storage slots downloaded
+	StorageBytes   common.StorageSize // Number of storage trie bytes persisted to disk
+	Remaining      time.Duration      // Estimated time left to download the state ranges (0 = unknown)
+
+	// Status report during healing phase
+	TrienodeHealSynced uint64             // Number of state trie nodes downloaded
+	TrienodeHealBytes  common.StorageSize // Number of state trie bytes persisted to disk
+	BytecodeHealSynced uint64             // Number of bytecodes downloaded
+	BytecodeHealBytes  common.StorageSize // Number of bytecodes persisted to disk
+	HealPending        uint64             // Number of trie nodes and bytecodes pending healing
+}
+
 // SyncPeer abstracts out the methods required for a peer to be synced against
 // with the goal of allowing the construction of mock peers without the full
 // blown networking.
@@ -376,6 +410,22 @@ type SyncPeer interface {
 	Log() log.Logger
 }
 
+// reportTimeout penalises the reputation of a peer failing to answer a request
+// in time, if the peer tracks one.
+func reportTimeout(peer SyncPeer) {
+	if rep, ok := peer.(p2p.ReputationReporter); ok {
+		rep.Report(p2p.RequestTimeout)
+	}
+}
+
+// reportDelivery rewards the reputation of a peer answering a request, if the
+// peer tracks one.
+func reportDelivery(peer SyncPeer, elapsed, target time.Duration) {
+	if rep, ok := peer.(p2p.ReputationReporter); ok {
+		rep.ReportDelivery(elapsed, target)
+	}
+}
+
 // Syncer is an Etherdata account and storage trie syncer based on snapshots and
 // the  snap protocol. It's purpose is to download all the accounts and storage
 // slots from remote peers and reassemble chunks of the state trie, on top of
@@ -440,8 +490,12 @@ type Syncer struct {
 	storageHealed      uint64             // Number of storage slots downloaded during the healing stage
 	storageHealedBytes common.StorageSize // Number of raw storage bytes persisted to disk during the healing stage
 
-	startTime time.Time // Time instance when snapshot sync started
-	logTime   time.Time // Time instance when status was last reported
+	startTime   time.Time          // Time instance when snapshot sync started
+	startSynced common.StorageSize // Number of bytes already synced when snapshot sync started
+	logTime     time.Time          // Time instance when status was last reported
+	saveTime    time.Time          // Time instance when status was last persisted
+
+	progress *Progress // Latest sync statistics for user reporting
 
 	pend sync.WaitGroup // Tracks network request goroutines for graceful shutdown
 	lock sync.RWMutex   // Protects fields that can change outside of sync (peers, reqs, root)
@@ -549,11 +603,14 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 	s.statelessPeers = make(map[string]struct{})
 	s.lock.Unlock()
 
+	// Retrieve the previous sync status from LevelDB and abort if already synced
+	s.loadSyncStatus()
 	if s.startTime == (time.Time{}) {
 		s.startTime = time.Now()
+		s.startSynced = s.accountBytes + s.bytecodeBytes + s.storageBytes
 	}
-	// Retrieve the previous sync status from LevelDB and abort if already synced
-	s.loadSyncStatus()
+	s.saveTime = time.Now()
+
 	if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
 		log.Debug("Snapshot sync already completed")
 		return nil
@@ -664,6 +721,11 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		}
 		// Report stats if sometding meaningful happened
 		s.report(false)
+
+		// Checkpoint the progress every now and again to survive crashes
+		if time.Since(s.saveTime) > syncStatusInterval {
+			s.saveSyncStatus()
+		}
 	}
 }
 
@@ -714,6 +776,8 @@ func (s *Syncer) loadSyncStatus() {
 			s.trienodeHealBytes = progress.TrienodeHealBytes
 			s.bytecodeHealSynced = progress.BytecodeHealSynced
 			s.bytecodeHealBytes = progress.BytecodeHealBytes
+
+			s.restoreHealer()
 			return
 		}
 	}
@@ -758,18 +822,81 @@ func (s *Syncer) loadSyncStatus() {
 	}
 }
 
-// saveSyncStatus marshals the remaining sync tasks into leveldb.
+// restoreHealer feeds the trie nodes retrieved, but not yet committed by a
+// previous heal cycle into the current one, so they don't need to be downloaded
+// again. Nodes not belonging to the currently synced state trie are discarded.
+func (s *Syncer) restoreHealer() {
+	retrieved := make(map[common.Hash][]byte)
+	for i := uint16(0); i < maxHealNodesChunks; i++ {
+		blob := rawdb.ReadSnapshotSyncHealNodes(s.db, i)
+		if len(blob) == 0 {
+			break
+		}
+		var nodes [][]byte
+		if err := rlp.DecodeBytes(blob, &nodes); err != nil {
+			log.Error("Failed to decode snap sync heal nodes", "chunk", i, "err", err)
+			break
+		}
+		for _, node := range nodes {
+			retrieved[crypto.Keccak256Hash(node)] = node
+		}
+	}
+	if len(retrieved) == 0 {
+		return
+	}
+	// Replay the nodes as if they were just delivered, walking down the trie as
+	// long as the scheduler requests any of them. Anything else requested is
+	// queued up for retrieval as usual.
+	var restored int
+	for {
+		var (
+			nodes, paths, codes = s.healer.scheduler.Missing(0)
+			progress            bool
+		)
+		for i, hash := range nodes {
+			if node, ok := retrieved[hash]; ok {
+				delete(retrieved, hash)
+				if err := s.healer.scheduler.Process(trie.SyncResult{Hash: hash, Data: node}); err == nil {
+					restored++
+					progress = true
+					continue
+				}
+			}
+			s.healer.trieTasks[hash] = paths[i]
+		}
+		for _, hash := range codes {
+			s.healer.codeTasks[hash] = struct{}{}
+		}
+		if !progress {
+			break
+		}
+	}
+	batch := s.db.NewBatch()
+	if err := s.healer.scheduler.Commit(batch); err != nil {
+		log.Error("Failed to commit restored healing data", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to persist restored healing data", "err", err)
+	}
+	log.Debug("Restored state heal progress", "nodes", restored, "dropped", len(retrieved))
+}
+
+// saveSyncStatus marshals the remaining sync tasks and the healing progress
+// into leveldb.
 func (s *Syncer) saveSyncStatus() {
 	// Serialize any partial progress to disk before spinning down
 	for _, task := range s.tasks {
 		if err := task.genBatch.Write(); err != nil {
 			log.Error("Failed to persist account slots", "err", err)
 		}
+		task.genBatch.Reset()
+
 		for _, subtasks := range task.SubTasks {
 			for _, subtask := range subtasks {
 				if err := subtask.genBatch.Write(); err != nil {
 					log.Error("Failed to persist storage slots", "err", err)
 				}
+				subtask.genBatch.Reset()
 			}
 		}
 	}
@@ -791,7 +918,56 @@ func (s *Syncer) saveSyncStatus() {
 	if err != nil {
 		panic(err) // This can only fail during implementation
 	}
-	rawdb.WriteSnapshotSyncStatus(s.db, status)
+	// Store the trie nodes the healer is holding on to, waiting for children
+	var chunks [][]byte
+	if s.healer != nil {
+		chunks = chunkHealNodes(s.healer.scheduler.Retrieved())
+	}
+	batch := s.db.NewBatch()
+	rawdb.WriteSnapshotSyncStatus(batch, status)
+	for i, chunk := range chunks {
+		rawdb.WriteSnapshotSyncHealNodes(batch, uint16(i), chunk)
+	}
+	for i := len(chunks); i < maxHealNodesChunks; i++ {
+		rawdb.DeleteSnapshotSyncHealNodes(batch, uint16(i))
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to persist snap sync status", "err", err)
+	}
+	s.saveTime = time.Now()
+}
+
+// chunkHealNodes splits up the trie nodes retrieved by the healer into RLP lists
+// of at most healNodesChunkSize bytes each. Nodes not fitting into the first
+// maxHealNodesChunks chunks are dropped.
+func chunkHealNodes(nodes [][]byte) [][]byte {
+	var (
+		chunks [][]byte
+		chunk  [][]byte
+		size   int
+	)
+	flush := func() {
+		blob, err := rlp.EncodeToBytes(chunk)
+		if err != nil {
+			panic(err) // This can only fail during implementation
+		}
+		chunks = append(chunks, blob)
+		chunk, size = nil, 0
+	}
+	for _, node := range nodes {
+		if len(chunk) > 0 && size+len(node) > healNodesChunkSize {
+			flush()
+			if len(chunks) == maxHealNodesChunks {
+				return chunks
+			}
+		}
+		chunk = append(chunk, node)
+		size += len(node)
+	}
+	if len(chunk) > 0 {
+		flush()
+	}
+	return chunks
 }
 
 // cleanAccountTasks removes account range retrieval tasks that have already been
@@ -923,6 +1099,7 @@ func (s *Syncer) assignAccountTasks(success chan *accountResponse, fail chan *ac
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Account range request timed out", "reqid", reqid)
 			s.rates.Update(idle, AccountRangeMsg, 0, 0)
+			reportTimeout(peer)
 			s.scheduleRevertAccountRequest(req)
 		})
 		s.accountReqs[reqid] = req
@@ -1034,6 +1211,7 @@ func (s *Syncer) assignBytecodeTasks(success chan *bytecodeResponse, fail chan *
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Bytecode request timed out", "reqid", reqid)
 			s.rates.Update(idle, ByteCodesMsg, 0, 0)
+			reportTimeout(peer)
 			s.scheduleRevertBytecodeRequest(req)
 		})
 		s.bytecodeReqs[reqid] = req
@@ -1181,6 +1359,7 @@ func (s *Syncer) assignStorageTasks(success chan *storageResponse, fail chan *st
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Storage request timed out", "reqid", reqid)
 			s.rates.Update(idle, StorageRangesMsg, 0, 0)
+			reportTimeout(peer)
 			s.scheduleRevertStorageRequest(req)
 		})
 		s.storageReqs[reqid] = req
@@ -1314,6 +1493,7 @@ func (s *Syncer) assignTrienodeHealTasks(success chan *trienodeHealResponse, fai
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Trienode heal request timed out", "reqid", reqid)
 			s.rates.Update(idle, TrieNodesMsg, 0, 0)
+			reportTimeout(peer)
 			s.scheduleRevertTrienodeHealRequest(req)
 		})
 		s.trienodeHealReqs[reqid] = req
@@ -1430,6 +1610,7 @@ func (s *Syncer) assignBytecodeHealTasks(success chan *bytecodeHealResponse, fai
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Bytecode heal request timed out", "reqid", reqid)
 			s.rates.Update(idle, ByteCodesMsg, 0, 0)
+			reportTimeout(peer)
 			s.scheduleRevertBytecodeHealRequest(req)
 		})
 		s.bytecodeHealReqs[reqid] = req
@@ -2228,6 +2409,7 @@ func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, acco
 	}
 	delete(s.accountReqs, id)
 	s.rates.Update(peer.ID(), AccountRangeMsg, time.Since(req.time), int(size))
+	reportDelivery(peer, time.Since(req.time), s.rates.TargetRoundTrip())
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2340,6 +2522,7 @@ func (s *Syncer) onByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error
 	}
 	delete(s.bytecodeReqs, id)
 	s.rates.Update(peer.ID(), ByteCodesMsg, time.Since(req.time), len(bytecodes))
+	reportDelivery(peer, time.Since(req.time), s.rates.TargetRoundTrip())
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2449,6 +2632,7 @@ func (s *Syncer) OnStorage(peer SyncPeer, id uint64, hashes [][]common.Hash, slo
 	}
 	delete(s.storageReqs, id)
 	s.rates.Update(peer.ID(), StorageRangesMsg, time.Since(req.time), int(size))
+	reportDelivery(peer, time.Since(req.time), s.rates.TargetRoundTrip())
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2576,6 +2760,7 @@ func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error
 	}
 	delete(s.trienodeHealReqs, id)
 	s.rates.Update(peer.ID(), TrieNodesMsg, time.Since(req.time), len(trienodes))
+	reportDelivery(peer, time.Since(req.time), s.rates.TargetRoundTrip())
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2671,6 +2856,7 @@ func (s *Syncer) onHealByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) e
 	}
 	delete(s.bytecodeHealReqs, id)
 	s.rates.Update(peer.ID(), ByteCodesMsg, time.Since(req.time), len(bytecodes))
+	reportDelivery(peer, time.Since(req.time), s.rates.TargetRoundTrip())
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -2764,8 +2950,19 @@ func (s *Syncer) onHealState(paths [][]byte, value []byte) error {
 // hashSpace is the total size of the 256 bit hash space for accounts.
 var hashSpace = new(big.Int).Exp(common.Big2, common.Big256, nil)
 
+// Progress returns the latest snapshot sync statistics, or nil if no sync cycle
+// was run yet.
+func (s *Syncer) Progress() *Progress {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.progress
+}
+
 // report calculates various status reports and provides it to the user.
 func (s *Syncer) report(force bool) {
+	s.updateProgress()
+
 	if len(s.tasks) > 0 {
 		s.reportSyncProgress(force)
 		return
@@ -2780,9 +2977,31 @@ func (s *Syncer) reportSyncProgress(force bool) {
 		return
 	}
 	// Don't report anything until we have a meaningful progress
+	estBytes := s.estimateStateSize()
+	if estBytes == 0 {
+		return
+	}
+	s.logTime = time.Now()
+	synced := s.accountBytes + s.bytecodeBytes + s.storageBytes
+
+	// Create a mega progress report
+	var (
+		progress = fmt.Sprintf("%.2f%%", float64(synced)*100/estBytes)
+		accounts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.accountSynced), s.accountBytes.TerminalString())
+		storage  = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.storageSynced), s.storageBytes.TerminalString())
+		bytecode = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.bytecodeSynced), s.bytecodeBytes.TerminalString())
+	)
+	log.Info("State sync in progress", "synced", progress, "state", synced,
+		"accounts", accounts, "slots", storage, "codes", bytecode, "eta", common.PrettyDuration(s.estimateRemainingTime(estBytes)))
+}
+
+// estimateStateSize extrapolates the total size of the state being synced from
+// the portion of the account hash space already covered. Zero is returned if no
+// meaningful progress has been made yet.
+func (s *Syncer) estimateStateSize() float64 {
 	synced := s.accountBytes + s.bytecodeBytes + s.storageBytes
 	if synced == 0 {
-		return
+		return 0
 	}
 	accountGaps := new(big.Int)
 	for _, task := range s.tasks {
@@ -2790,26 +3009,50 @@ func (s *Syncer) reportSyncProgress(force bool) {
 	}
 	accountFills := new(big.Int).Sub(hashSpace, accountGaps)
 	if accountFills.BitLen() == 0 {
-		return
+		return 0
 	}
-	s.logTime = time.Now()
-	estBytes := float64(new(big.Int).Div(
+	return float64(new(big.Int).Div(
 		new(big.Int).Mul(new(big.Int).SetUint64(uint64(synced)), hashSpace),
 		accountFills,
 	).Uint64())
+}
 
-	elapsed := time.Since(s.startTime)
-	estTime := elapsed / time.Duration(synced) * time.Duration(estBytes)
+// estimateRemainingTime estimates how long it takes to download the rest of the
+// state, based on the throughput achieved since the syncer was started. Data
+// synced before a restart is not counted towards the throughput.
+func (s *Syncer) estimateRemainingTime(estBytes float64) time.Duration {
+	synced := s.accountBytes + s.bytecodeBytes + s.storageBytes
+	if synced <= s.startSynced || float64(synced) >= estBytes {
+		return 0
+	}
+	elapsed := float64(time.Since(s.startTime))
+	return time.Duration(elapsed / float64(synced-s.startSynced) * (estBytes - float64(synced)))
+}
 
-	// Create a mega progress report
-	var (
-		progress = fmt.Sprintf("%.2f%%", float64(synced)*100/estBytes)
-		accounts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.accountSynced), s.accountBytes.TerminalString())
-		storage  = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.storageSynced), s.storageBytes.TerminalString())
-		bytecode = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.bytecodeSynced), s.bytecodeBytes.TerminalString())
-	)
-	log.Info("State sync in progress", "synced", progress, "state", synced,
-		"accounts", accounts, "slots", storage, "codes", bytecode, "eta", common.PrettyDuration(estTime-elapsed))
+// updateProgress refreshes the sync statistics exposed for user reporting.
+func (s *Syncer) updateProgress() {
+	progress := &Progress{
+		AccountSynced:      s.accountSynced,
+		AccountBytes:       s.accountBytes,
+		BytecodeSynced:     s.bytecodeSynced,
+		BytecodeBytes:      s.bytecodeBytes,
+		StorageSynced:      s.storageSynced,
+		StorageBytes:       s.storageBytes,
+		TrienodeHealSynced: s.trienodeHealSynced,
+		TrienodeHealBytes:  s.trienodeHealBytes,
+		BytecodeHealSynced: s.bytecodeHealSynced,
+		BytecodeHealBytes:  s.bytecodeHealBytes,
+	}
+	if len(s.tasks) > 0 {
+		if estBytes := s.estimateStateSize(); estBytes > 0 {
+			progress.Remaining = s.estimateRemainingTime(estBytes)
+		}
+	} else {
+		progress.HealPending = uint64(s.healer.scheduler.Pending())
+	}
+	s.lock.Lock()
+	s.progress = progress
+	s.lock.Unlock()
 }
 
 // reportHealProgress calculates various status reports and provides it to the user.
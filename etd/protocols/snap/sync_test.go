@@ -1712,3 +1712,113 @@ func TestSlotEstimation(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a snap sync suspended during healing resumes with the trie nodes
+// already retrieved, instead of downloading them again.
+func TestSyncHealResume(t *testing.T) {
+	t.Parallel()
+
+	var (
+		once   sync.Once
+		cancel = make(chan struct{})
+		term   = func() {
+			once.Do(func() {
+				close(cancel)
+			})
+		}
+	)
+	sourceAccountTrie, elems := makeAccountTrieNoStorage(100)
+	root := sourceAccountTrie.Hash()
+
+	newHealer := func(syncer *Syncer) *healTask {
+		return &healTask{
+			scheduler: state.NewStateSync(root, syncer.db, nil, syncer.onHealState),
+			trieTasks: make(map[common.Hash]trie.SyncPath),
+			codeTasks: make(map[common.Hash]struct{}),
+		}
+	}
+	// Retrieve the root node through the healer and suspend the sync
+	db := rawdb.NewMemoryDatabase()
+	syncer := NewSyncer(db)
+	syncer.healer = newHealer(syncer)
+
+	nodes, paths, _ := syncer.healer.scheduler.Missing(0)
+	if len(nodes) != 1 || nodes[0] != root {
+		t.Fatalf("unexpected heal tasks: have %x, want [%x]", nodes, root)
+	}
+	blob, _, err := sourceAccountTrie.TryGetNode(paths[0][0])
+	if err != nil {
+		t.Fatalf("failed to retrieve root node: %v", err)
+	}
+	if err := syncer.healer.scheduler.Process(trie.SyncResult{Hash: root, Data: blob}); err != nil {
+		t.Fatalf("failed to process root node: %v", err)
+	}
+	syncer.saveSyncStatus()
+
+	// Restore the healer in a fresh syncer and ensure the root is not requested
+	syncer = NewSyncer(db)
+	syncer.healer = newHealer(syncer)
+	syncer.restoreHealer()
+
+	if _, ok := syncer.healer.trieTasks[root]; ok {
+		t.Fatalf("restored root node scheduled for retrieval")
+	}
+	if len(syncer.healer.trieTasks) == 0 {
+		t.Fatalf("children of restored root node not scheduled")
+	}
+	// Finish the sync and ensure the state is complete
+	source := newTestPeer("source", t, term)
+	source.accountTrie = sourceAccountTrie
+	source.accountValues = elems
+
+	syncer = NewSyncer(db)
+	syncer.Register(source)
+	source.remote = syncer
+
+	if err := syncer.Sync(root, cancel); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	verifyTrie(syncer.db, root, t)
+}
+
+// Tests that the retrieved trie nodes of the healer are checkpointed in size
+// limited chunks, and that the number of chunks is capped.
+func TestChunkHealNodes(t *testing.T) {
+	node := make([]byte, healNodesChunkSize/4)
+
+	for _, tt := range []struct {
+		nodes  int
+		chunks int
+		stored int
+	}{
+		{nodes: 0, chunks: 0, stored: 0},
+		{nodes: 1, chunks: 1, stored: 1},
+		{nodes: 4, chunks: 1, stored: 4},
+		{nodes: 5, chunks: 2, stored: 5},
+		{nodes: 4 * maxHealNodesChunks, chunks: maxHealNodesChunks, stored: 4 * maxHealNodesChunks},
+		{nodes: 4*maxHealNodesChunks + 1, chunks: maxHealNodesChunks, stored: 4 * maxHealNodesChunks},
+	} {
+		nodes := make([][]byte, tt.nodes)
+		for i := range nodes {
+			nodes[i] = node
+		}
+		chunks := chunkHealNodes(nodes)
+		if len(chunks) != tt.chunks {
+			t.Errorf("%d nodes: chunk count mismatch: have %d, want %d", tt.nodes, len(chunks), tt.chunks)
+		}
+		var stored int
+		for i, chunk := range chunks {
+			var list [][]byte
+			if err := rlp.DecodeBytes(chunk, &list); err != nil {
+				t.Fatalf("%d nodes: failed to decode chunk %d: %v", tt.nodes, i, err)
+			}
+			if size := len(list) * len(node); size > healNodesChunkSize {
+				t.Errorf("%d nodes: chunk %d too large: %d bytes", tt.nodes, i, size)
+			}
+			stored += len(list)
+		}
+		if stored != tt.stored {
+			t.Errorf("%d nodes: stored node count mismatch: have %d, want %d", tt.nodes, stored, tt.stored)
+		}
+	}
+}
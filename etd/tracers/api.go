@@ -81,6 +81,22 @@ func NewAPI(backend Backend) *API {
 	return &API{backend: backend}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *API) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"traceChain":                  {Summary: "Subscribes to the traces of the transactions of a range of blocks.", Params: []string{"start", "end", "config"}},
+		"traceBlockByNumber":          {Summary: "Returns the traces of the transactions of a block, given by number.", Params: []string{"number", "config"}},
+		"traceBlockByHash":            {Summary: "Returns the traces of the transactions of a block, given by hash.", Params: []string{"hash", "config"}},
+		"traceBlock":                  {Summary: "Returns the traces of the transactions of an RLP encoded block.", Params: []string{"blob", "config"}},
+		"traceBlockFromFile":          {Summary: "Returns the traces of the transactions of an RLP encoded block read from a file.", Params: []string{"file", "config"}},
+		"traceBadBlock":               {Summary: "Returns the traces of the transactions of a rejected block.", Params: []string{"hash", "config"}},
+		"standardTraceBlockToFile":    {Summary: "Writes the standard traces of the transactions of a block into files.", Params: []string{"hash", "config"}},
+		"standardTraceBadBlockToFile": {Summary: "Writes the standard traces of the transactions of a rejected block into files.", Params: []string{"hash", "config"}},
+		"traceTransaction":            {Summary: "Returns the trace of a transaction.", Params: []string{"hash", "config"}},
+		"traceCall":                   {Summary: "Returns the trace of a call executed on top of a block.", Params: []string{"args", "blockNrOrHash", "config"}},
+	}
+}
+
 type chainContext struct {
 	api *API
 	ctx context.Context
@@ -51,3 +51,10 @@ func tracer(name string) (string, bool) {
 	}
 	return "", false
 }
+
+// IsBuiltin reports whether name refers to one of the JavaScript tracers shipped
+// with go-etherdata, as opposed to custom tracer code.
+func IsBuiltin(name string) bool {
+	_, ok := all[name]
+	return ok
+}
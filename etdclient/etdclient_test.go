@@ -561,6 +561,50 @@ func testAtFunctions(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func TestBlockReceipts(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	ec := NewClient(client)
+	if err := sendTransaction(ec); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	// Wait for the transaction to be included in the pending block
+	var pending []map[string]interface{}
+	for i := 0; i < 50; i++ {
+		if err := client.Call(&pending, "etd_getBlockReceipts", "pending"); err != nil {
+			t.Fatalf("failed to retrieve pending receipts: %v", err)
+		}
+		if len(pending) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("pending receipt count mismatch: have %d, want 1", len(pending))
+	}
+	if from := common.HexToAddress(pending[0]["from"].(string)); from != testAddr {
+		t.Errorf("pending receipt sender mismatch: have %x, want %x", from, testAddr)
+	}
+	// Mined blocks without transactions return an empty list, unknown ones null
+	var mined []map[string]interface{}
+	if err := client.Call(&mined, "etd_getBlockReceipts", "0x1"); err != nil {
+		t.Fatalf("failed to retrieve block receipts: %v", err)
+	}
+	if mined == nil || len(mined) != 0 {
+		t.Errorf("block receipts mismatch: have %v, want empty list", mined)
+	}
+	var unknown []map[string]interface{}
+	if err := client.Call(&unknown, "etd_getBlockReceipts", "0x10"); err != nil {
+		t.Fatalf("failed to retrieve unknown block receipts: %v", err)
+	}
+	if unknown != nil {
+		t.Errorf("unknown block receipts mismatch: have %v, want nil", unknown)
+	}
+}
+
 func sendTransaction(ec *Client) error {
 	// Retrieve chainID
 	chainID, err := ec.ChainID(context.Background())
@@ -0,0 +1,84 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+const (
+	maxQueryDepth = 20   // Maximum nesting depth of a query
+	maxQueryCost  = 1000 // Cost budget of a query, or of a subscription event
+
+	costBlock           = 1   // Cost of each block returned by a block range
+	costLog             = 1   // Cost of each log returned by a log query
+	costLogWindow       = 10  // Cost of filtering a window of logPageWindow blocks for logs
+	costCall            = 10  // Cost of executing a call or gas estimation
+	costTrace           = 100 // Cost of tracing a transaction
+	costTouchedAccounts = 100 // Cost of collecting the accounts touched by a block
+	costStateDiff       = 100 // Cost of diffing the state changes of a block
+)
+
+// errQueryTooComplex is returned if a query exceeds its cost budget.
+var errQueryTooComplex = errors.New("query too complex")
+
+type costBudgetKey struct{}
+
+// costBudget tracks the remaining cost a query may spend on expensive fields.
+type costBudget struct {
+	remaining int64
+}
+
+// withCostBudget returns a context limiting the queries run with it to the
+// given cost.
+func withCostBudget(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, costBudgetKey{}, &costBudget{remaining: limit})
+}
+
+// chargeCost deducts the cost of resolving a field from the budget of the
+// query, failing if there's not enough left. Queries without a budget are
+// not limited.
+func chargeCost(ctx context.Context, cost int64) error {
+	budget, ok := ctx.Value(costBudgetKey{}).(*costBudget)
+	if !ok {
+		return nil
+	}
+	if atomic.AddInt64(&budget.remaining, -cost) < 0 {
+		return errQueryTooComplex
+	}
+	return nil
+}
+
+// chargeLogRange charges the cost of filtering the logs of the blocks between
+// begin and end, a costLogWindow for every logPageWindow blocks started.
+func chargeLogRange(ctx context.Context, begin, end uint64) error {
+	if begin > end {
+		return nil
+	}
+	windows := (end-begin)/logPageWindow + 1
+	return chargeCost(ctx, int64(windows)*costLogWindow)
+}
+
+// refillCost restores the full budget of a subscription before resolving the
+// next event, so long running subscriptions are limited per event.
+func refillCost(ctx context.Context) {
+	if budget, ok := ctx.Value(costBudgetKey{}).(*costBudget); ok {
+		atomic.StoreInt64(&budget.remaining, maxQueryCost)
+	}
+}
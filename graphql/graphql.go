@@ -18,7 +18,9 @@
 package graphql
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -32,14 +34,20 @@ import (
 	"github.com/crypyto-panel/go-etherdata/core/state"
 	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/etd/filters"
+	"github.com/crypyto-panel/go-etherdata/etd/tracers"
 	"github.com/crypyto-panel/go-etherdata/internal/etdapi"
+	"github.com/crypyto-panel/go-etherdata/rlp"
 	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
 var (
-	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errBlockInvariant     = errors.New("block objects must be instantiated with at least one of num or hash")
+	errTracingUnsupported = errors.New("tracing not supported by the backend")
 )
 
+// defaultTracer is the tracer used to trace transactions if none is requested.
+const defaultTracer = "callTracer"
+
 type Long int64
 
 // ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
@@ -71,6 +79,32 @@ func (b *Long) UnmarshalGraphQL(input interface{}) error {
 	return err
 }
 
+// JSON is an arbitrary JSON value, such as the output of a tracer.
+type JSON struct {
+	raw json.RawMessage
+}
+
+// ImplementsGraphQLType returns true if JSON implements the provided GraphQL type.
+func (j JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	j.raw = raw
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j.raw == nil {
+		return []byte("null"), nil
+	}
+	return j.raw, nil
+}
+
 // Account represents an Etherdata account at a particular block.
 type Account struct {
 	backend       etdapi.Backend
@@ -131,6 +165,10 @@ type Log struct {
 	log         *types.Log
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 func (l *Log) Transaction(ctx context.Context) *Transaction {
 	return l.transaction
 }
@@ -412,6 +450,9 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	if err != nil || receipt == nil {
 		return nil, err
 	}
+	if err := chargeCost(ctx, int64(len(receipt.Logs))*costLog); err != nil {
+		return nil, err
+	}
 	ret := make([]*Log, 0, len(receipt.Logs))
 	for _, log := range receipt.Logs {
 		ret = append(ret, &Log{
@@ -448,6 +489,56 @@ func (t *Transaction) AccessList(ctx context.Context) (*[]*AccessTuple, error) {
 	return &ret, nil
 }
 
+func (t *Transaction) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return tx.MarshalBinary()
+}
+
+func (t *Transaction) RawReceipt(ctx context.Context) (*hexutil.Bytes, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	types.Receipts{receipt}.EncodeIndex(0, &buf)
+	ret := hexutil.Bytes(buf.Bytes())
+	return &ret, nil
+}
+
+// Trace re-executes a mined transaction with one of the built-in tracers,
+// returning its output.
+func (t *Transaction) Trace(ctx context.Context, args struct{ Tracer *string }) (*JSON, error) {
+	if err := chargeCost(ctx, costTrace); err != nil {
+		return nil, err
+	}
+	if _, err := t.resolve(ctx); err != nil || t.block == nil {
+		return nil, err
+	}
+	backend, ok := t.backend.(tracers.Backend)
+	if !ok {
+		return nil, errTracingUnsupported
+	}
+	tracer := defaultTracer
+	if args.Tracer != nil {
+		tracer = *args.Tracer
+	}
+	if !tracers.IsBuiltin(tracer) {
+		return nil, fmt.Errorf("unknown tracer %q", tracer)
+	}
+	result, err := tracers.NewAPI(backend).TraceTransaction(ctx, t.hash, &tracers.TraceConfig{Tracer: &tracer})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &JSON{raw: raw}, nil
+}
+
 func (t *Transaction) R(ctx context.Context) (hexutil.Big, error) {
 	tx, err := t.resolve(ctx)
 	if err != nil || tx == nil {
@@ -475,6 +566,59 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+// Receipt represents the receipt of a mined transaction.
+type Receipt struct {
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) Long {
+	return Long(r.receipt.Status)
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) Long {
+	return Long(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) Long {
+	return Long(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) EffectiveGasPrice(ctx context.Context) (*hexutil.Big, error) {
+	return r.transaction.EffectiveGasPrice(ctx)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		backend:       r.transaction.backend,
+		address:       r.receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return r.receipt.Bloom.Bytes()
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     r.transaction.backend,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
 type BlockType int
 
 // Block represents an Etherdata block.
@@ -549,6 +693,142 @@ func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
 	return b.receipts, nil
 }
 
+func (b *Block) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return hexutil.Bytes{}, err
+	}
+	return rlp.EncodeToBytes(block)
+}
+
+func (b *Block) RawHeader(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return rlp.EncodeToBytes(header)
+}
+
+// TouchedAccounts re-executes the block, returning the accounts accessed or
+// modified by its transactions, along with the miners rewarded by it.
+func (b *Block) TouchedAccounts(ctx context.Context) ([]*Account, error) {
+	if err := chargeCost(ctx, costTouchedAccounts); err != nil {
+		return nil, err
+	}
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	backend, ok := b.backend.(tracers.Backend)
+	if !ok {
+		return nil, errTracingUnsupported
+	}
+	addresses, err := touchedAccounts(ctx, backend, block)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Account, 0, len(addresses))
+	for _, address := range addresses {
+		ret = append(ret, &Account{
+			backend:       b.backend,
+			address:       address,
+			blockNrOrHash: rpc.BlockNumberOrHashWithHash(block.Hash(), false),
+		})
+	}
+	return ret, nil
+}
+
+// StateDiff re-executes the block, returning the state changes of the accounts
+// modified by it.
+func (b *Block) StateDiff(ctx context.Context) ([]*AccountDiff, error) {
+	if err := chargeCost(ctx, costStateDiff); err != nil {
+		return nil, err
+	}
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	backend, ok := b.backend.(tracers.Backend)
+	if !ok {
+		return nil, errTracingUnsupported
+	}
+	diffs, err := stateDiff(ctx, backend, block)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*AccountDiff, 0, len(diffs))
+	for _, diff := range diffs {
+		ret = append(ret, &AccountDiff{
+			account: &Account{
+				backend:       b.backend,
+				address:       diff.address,
+				blockNrOrHash: rpc.BlockNumberOrHashWithHash(block.Hash(), false),
+			},
+			diff: diff,
+		})
+	}
+	return ret, nil
+}
+
+// AccountDiff represents the change of an account's state caused by a block.
+type AccountDiff struct {
+	account *Account
+	diff    *accountDiff
+}
+
+func (d *AccountDiff) Account(ctx context.Context) *Account {
+	return d.account
+}
+
+func (d *AccountDiff) BalanceBefore(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*d.diff.balanceBefore)
+}
+
+func (d *AccountDiff) BalanceAfter(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*d.diff.balanceAfter)
+}
+
+func (d *AccountDiff) NonceBefore(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(d.diff.nonceBefore)
+}
+
+func (d *AccountDiff) NonceAfter(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(d.diff.nonceAfter)
+}
+
+func (d *AccountDiff) CodeHashBefore(ctx context.Context) common.Hash {
+	return d.diff.codeHashBefore
+}
+
+func (d *AccountDiff) CodeHashAfter(ctx context.Context) common.Hash {
+	return d.diff.codeHashAfter
+}
+
+func (d *AccountDiff) Storage(ctx context.Context) []*StorageDiff {
+	ret := make([]*StorageDiff, 0, len(d.diff.storage))
+	for i := range d.diff.storage {
+		ret = append(ret, &StorageDiff{diff: d.diff.storage[i]})
+	}
+	return ret
+}
+
+// StorageDiff represents the change of a storage slot caused by a block.
+type StorageDiff struct {
+	diff storageDiff
+}
+
+func (d *StorageDiff) Key(ctx context.Context) common.Hash {
+	return d.diff.key
+}
+
+func (d *StorageDiff) Before(ctx context.Context) common.Hash {
+	return d.diff.before
+}
+
+func (d *StorageDiff) After(ctx context.Context) common.Hash {
+	return d.diff.after
+}
+
 func (b *Block) Number(ctx context.Context) (Long, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -799,6 +1079,35 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{
+			transaction: &Transaction{
+				backend: b.backend,
+				hash:    txs[i].Hash(),
+				tx:      txs[i],
+				block:   b,
+				index:   uint64(i),
+			},
+			receipt: receipt,
+		})
+	}
+	return &ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -862,6 +1171,9 @@ func runFilter(ctx context.Context, be etdapi.Backend, filter *filters.Filter) (
 	if err != nil || logs == nil {
 		return nil, err
 	}
+	if err := chargeCost(ctx, int64(len(logs))*costLog); err != nil {
+		return nil, err
+	}
 	ret := make([]*Log, 0, len(logs))
 	for _, log := range logs {
 		ret = append(ret, &Log{
@@ -948,6 +1260,9 @@ func (c *CallResult) Status() Long {
 func (b *Block) Call(ctx context.Context, args struct {
 	Data etdapi.TransactionArgs
 }) (*CallResult, error) {
+	if err := chargeCost(ctx, costCall); err != nil {
+		return nil, err
+	}
 	if b.numberOrHash == nil {
 		_, err := b.resolve(ctx)
 		if err != nil {
@@ -973,6 +1288,9 @@ func (b *Block) Call(ctx context.Context, args struct {
 func (b *Block) EstimateGas(ctx context.Context, args struct {
 	Data etdapi.TransactionArgs
 }) (Long, error) {
+	if err := chargeCost(ctx, costCall); err != nil {
+		return 0, err
+	}
 	if b.numberOrHash == nil {
 		_, err := b.resolveHeader(ctx)
 		if err != nil {
@@ -1023,6 +1341,9 @@ func (p *Pending) Account(ctx context.Context, args struct {
 func (p *Pending) Call(ctx context.Context, args struct {
 	Data etdapi.TransactionArgs
 }) (*CallResult, error) {
+	if err := chargeCost(ctx, costCall); err != nil {
+		return nil, err
+	}
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	result, err := etdapi.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, 5*time.Second, p.backend.RPCGasCap())
 	if err != nil {
@@ -1043,6 +1364,9 @@ func (p *Pending) Call(ctx context.Context, args struct {
 func (p *Pending) EstimateGas(ctx context.Context, args struct {
 	Data etdapi.TransactionArgs
 }) (Long, error) {
+	if err := chargeCost(ctx, costCall); err != nil {
+		return 0, err
+	}
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	gas, err := etdapi.DoEstimateGas(ctx, p.backend, args.Data, pendingBlockNr, p.backend.RPCGasCap())
 	return Long(gas), err
@@ -1051,6 +1375,8 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend etdapi.Backend
+
+	events *filters.EventSystem // Event system of the node feeding subscriptions
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -1108,6 +1434,9 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	if to < from {
 		return []*Block{}, nil
 	}
+	if err := chargeCost(ctx, int64(to-from+1)*costBlock); err != nil {
+		return nil, err
+	}
 	ret := make([]*Block, 0, to-from+1)
 	for i := from; i <= to; i++ {
 		numberOrHash := rpc.BlockNumberOrHashWithNumber(i)
@@ -1185,6 +1514,19 @@ func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria
 	if args.Filter.Topics != nil {
 		topics = *args.Filter.Topics
 	}
+	// Charge the scanned range up front, resolving the special block numbers
+	// the way the filter does
+	head := r.backend.CurrentHeader().Number.Int64()
+	first, last := begin, end
+	if first < 0 {
+		first = head
+	}
+	if last < 0 {
+		last = head
+	}
+	if err := chargeLogRange(ctx, uint64(first), uint64(last)); err != nil {
+		return nil, err
+	}
 	// Construct the range filter
 	filter := filters.NewRangeFilter(filters.Backend(r.backend), begin, end, addresses, topics)
 	return runFilter(ctx, r.backend, filter)
@@ -17,15 +17,22 @@
 package graphql
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/hexutil"
 	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
 	"github.com/crypyto-panel/go-etherdata/core"
 	"github.com/crypyto-panel/go-etherdata/core/types"
@@ -35,6 +42,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/etd/etdconfig"
 	"github.com/crypyto-panel/go-etherdata/node"
 	"github.com/crypyto-panel/go-etherdata/params"
+	"github.com/gorilla/websocket"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -52,7 +60,7 @@ func TestBuildSchema(t *testing.T) {
 		t.Fatalf("could not create new node: %v", err)
 	}
 	// Make sure the schema can be parsed and matched up to the object model.
-	if err := newHandler(stack, nil, []string{}, []string{}); err != nil {
+	if err := newHandler(stack, nil, nil, []string{}, []string{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -131,6 +139,41 @@ func TestGraphQLBlockSerialization(t *testing.T) {
 			want: `{"errors":[{"message":"Cannot query field \"bleh\" on type \"Query\".","locations":[{"line":1,"column":2}]}]}`,
 			code: 400,
 		},
+		{
+			body: `{"query": "{blockPage(from:2, first:3){blocks{number} pageInfo{hasNextPage endCursor}}}"}`,
+			want: `{"data":{"blockPage":{"blocks":[{"number":2},{"number":3},{"number":4}],"pageInfo":{"hasNextPage":true,"endCursor":"YmxvY2s6NA"}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{blockPage(first:3, after:\"YmxvY2s6NA\"){blocks{number} pageInfo{hasNextPage endCursor}}}"}`,
+			want: `{"data":{"blockPage":{"blocks":[{"number":5},{"number":6},{"number":7}],"pageInfo":{"hasNextPage":true,"endCursor":"YmxvY2s6Nw"}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{blockPage(from:9){blocks{number} pageInfo{hasNextPage endCursor}}}"}`,
+			want: `{"data":{"blockPage":{"blocks":[{"number":9},{"number":10}],"pageInfo":{"hasNextPage":false,"endCursor":"YmxvY2s6MTA"}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{logPage(filter:{fromBlock:\"0x0\"}, after:\"bogus\"){logs{index}}}"}`,
+			want: `{"errors":[{"message":"invalid cursor","path":["logPage"]}],"data":null}`,
+			code: 400,
+		},
+		{
+			body: `{"query": "{logPage(filter:{fromBlock:\"0x0\"}){logs{index} pageInfo{hasNextPage endCursor}}}"}`,
+			want: `{"data":{"logPage":{"logs":[],"pageInfo":{"hasNextPage":false,"endCursor":null}}}}`,
+			code: 200,
+		},
+		{ // Should refuse block ranges exceeding the query cost budget
+			body: `{"query": "{blocks(from:0, to:2000){number}}"}`,
+			want: `{"errors":[{"message":"query too complex","path":["blocks"]}],"data":null}`,
+			code: 400,
+		},
+		{ // Should refuse log ranges whose scan exceeds the query cost budget
+			body: `{"query": "{logs(filter:{fromBlock:0, toBlock:1000000}){index}}"}`,
+			want: `{"errors":[{"message":"query too complex","path":["logs"]}],"data":null}`,
+			code: 400,
+		},
 		// should return `estimateGas` as decimal
 		{
 			body: `{"query": "{block{ estimateGas(data:{}) }}"}`,
@@ -179,6 +222,26 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 			want: `{"data":{"block":{"number":1,"transactions":[{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x64","hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b","type":0,"accessList":[],"index":0},{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x32","hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","type":1,"accessList":[{"address":"0x0000000000000000000000000000000000000dad","storageKeys":["0x0000000000000000000000000000000000000000000000000000000000000000"]}],"index":1}]}}}`,
 			code: 200,
 		},
+		{
+			body: `{"query": "{block {receipts { transaction { hash index } status gasUsed cumulativeGasUsed createdContract { address } logs { index }}}}"}`,
+			want: `{"data":{"block":{"receipts":[{"transaction":{"hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b","index":0},"status":1,"gasUsed":25204,"cumulativeGasUsed":25204,"createdContract":null,"logs":[]},{"transaction":{"hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","index":1},"status":1,"gasUsed":27504,"cumulativeGasUsed":52708,"createdContract":null,"logs":[]}]}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block {transactions { raw rawReceipt trace(tracer:\"opcountTracer\") } touchedAccounts { address }}}"}`,
+			want: `{"data":{"block":{"transactions":[{"raw":"0xf86580843b9aca0082c350940000000000000000000000000000000000000dad6480820a96a0720de65ca14b0327d3162c31862405c8ad55dc8c6eb883d730b0dcbd8cea6e62a061156dce4d3691d2fd2f7fe0ad456c53ae7747da61381c734ee93e7c2679e8dc","rawReceipt":"0xf9010801826274b9010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c0","trace":5},{"raw":"0x01f8a082053901843b9aca00827530940000000000000000000000000000000000000dad3280f838f7940000000000000000000000000000000000000dade1a0000000000000000000000000000000000000000000000000000000000000000001a0c443574dc5f76c200c29736465afb9960a0dc855de5240018286ef3f55ce7431a053ca4689dcfb23d3caa4779d48b69102ffff2641f9f2148c2d3f0b64b5995b34","rawReceipt":"0x01f901080182cde4b9010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c0","trace":5}],"touchedAccounts":[{"address":"0x0000000000000000000000000000000000000dad"},{"address":"0x0100000000000000000000000000000000000000"},{"address":"0x71562b71999873db5b286df957af199ec94617f7"}]}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block {stateDiff { account { address } balanceBefore balanceAfter nonceBefore nonceAfter storage { key }}}}"}`,
+			want: `{"data":{"block":{"stateDiff":[{"account":{"address":"0x0000000000000000000000000000000000000dad"},"balanceBefore":"0x0","balanceAfter":"0x96","nonceBefore":"0x0","nonceAfter":"0x0","storage":[]},{"account":{"address":"0x0100000000000000000000000000000000000000"},"balanceBefore":"0x0","balanceAfter":"0x3231b9062410ebd00","nonceBefore":"0x0","nonceAfter":"0x0","storage":[]},{"account":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"balanceBefore":"0x38d7ea4c68000","balanceAfter":"0x35d8e9b28976a","nonceBefore":"0x0","nonceAfter":"0x2","storage":[]}]}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block {transactionAt(index:0) { trace(tracer:\"bogus\") }}}"}`,
+			want: `{"errors":[{"message":"unknown tracer \"bogus\"","path":["block","transactionAt","trace"]}],"data":{"block":{"transactionAt":{"trace":null}}}}`,
+			code: 400,
+		},
 	} {
 		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
 		if err != nil {
@@ -213,6 +276,197 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
+// Tests that clients authenticated with a token restricting their permissions
+// are only served if granted the graphql namespace.
+func TestGraphQLPermissions(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	path := filepath.Join(t.TempDir(), "jwtsecret")
+	if err := ioutil.WriteFile(path, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	stack, err := node.New(&node.Config{HTTPHost: "127.0.0.1", HTTPPort: 0, JWTSecret: path})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	token := func(permissions []string) string {
+		claims := map[string]interface{}{"iat": time.Now().Unix()}
+		if permissions != nil {
+			claims["permissions"] = permissions
+		}
+		header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signing))
+		return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	tests := []struct {
+		auth   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer " + token(nil), http.StatusOK},
+		{"Bearer " + token([]string{"*"}), http.StatusOK},
+		{"Bearer " + token([]string{"graphql"}), http.StatusOK},
+		{"Bearer " + token([]string{"etd"}), http.StatusForbidden},
+		{"Bearer " + token([]string{}), http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), strings.NewReader(`{"query": "{block{number}}","variables": null}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: could not post: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// Tests that new chain heads are streamed to GraphQL subscribers over WebSocket.
+func TestGraphQLSubscriptionNewHeads(t *testing.T) {
+	stack := createNode(t, false, false)
+	defer stack.Close()
+	backend := createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(stack.HTTPEndpoint(), "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not send %s: %v", msg, err)
+		}
+	}
+	expect := func(want string) {
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("could not read %s: %v", want, err)
+			}
+			have := strings.TrimSpace(string(msg))
+			if have == `{"type":"ka"}` {
+				continue
+			}
+			if have != want {
+				t.Fatalf("message mismatch:\nhave: %s\nwant: %s", have, want)
+			}
+			return
+		}
+	}
+	send(`{"type":"connection_init"}`)
+	expect(`{"type":"connection_ack"}`)
+
+	// Subscribe to new heads, and wait for a query to make sure the subscription
+	// is installed before importing the next block
+	send(`{"id":"1","type":"start","payload":{"query":"subscription { newHeads { number } }"}}`)
+	send(`{"id":"2","type":"start","payload":{"query":"{ block { number } }"}}`)
+	expect(`{"id":"2","type":"data","payload":{"data":{"block":{"number":10}}}}`)
+	expect(`{"id":"2","type":"complete"}`)
+
+	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, backend.BlockChain().Genesis(),
+		etdash.NewFaker(), backend.ChainDb(), 11, func(i int, gen *core.BlockGen) {})
+	if _, err := backend.BlockChain().InsertChain(chain[10:]); err != nil {
+		t.Fatalf("could not import block: %v", err)
+	}
+	expect(`{"id":"1","type":"data","payload":{"data":{"newHeads":{"number":11}}}}`)
+
+	send(`{"id":"1","type":"stop"}`)
+	send(`{"type":"connection_terminate"}`)
+}
+
+// Tests that a subscriber not consuming its results is dropped instead of
+// stalling the node's event system.
+func TestGraphQLSubscriptionSlowSubscriber(t *testing.T) {
+	stack := createNode(t, false, false)
+	defer stack.Close()
+	backend := createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	r := &Resolver{backend: backend.APIBackend, events: backend.APIBackend.EventSystem()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, err := r.NewHeads(ctx)
+	if err != nil {
+		t.Fatalf("could not subscribe: %v", err)
+	}
+	// Import blocks one by one without reading any results, each of them posting
+	// a chain head event that must not block
+	blocks := 2*subscriptionBufferSize + 10
+	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, backend.BlockChain().CurrentBlock(),
+		etdash.NewFaker(), backend.ChainDb(), blocks, func(i int, gen *core.BlockGen) {})
+	done := make(chan error, 1)
+	go func() {
+		for _, block := range chain {
+			if _, err := backend.BlockChain().InsertChain(types.Blocks{block}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("could not import block: %v", err)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatalf("block import stalled by slow subscriber")
+	}
+	// The buffered results must be followed by the end of the subscription
+	var received int
+	for range results {
+		received++
+	}
+	if received != subscriptionBufferSize {
+		t.Errorf("result count mismatch: have %d, want %d", received, subscriptionBufferSize)
+	}
+}
+
+// Tests that WebSocket upgrades are subject to the virtual host check too.
+func TestGraphQLSubscriptionVhosts(t *testing.T) {
+	stack := createNode(t, true, false)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	var (
+		dialer = websocket.Dialer{Subprotocols: []string{"graphql-ws"}}
+		url    = "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	)
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	conn.Close()
+
+	_, resp, err := dialer.Dial(url, http.Header{"Host": []string{"evil.example.com"}})
+	if err == nil {
+		t.Fatalf("upgrade with unknown virtual host succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("unexpected response to unknown virtual host: %v", resp)
+	}
+}
+
 func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost: "127.0.0.1",
@@ -234,7 +488,7 @@ func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	return stack
 }
 
-func createGQLService(t *testing.T, stack *node.Node) {
+func createGQLService(t *testing.T, stack *node.Node) *etd.Etherdata {
 	// create backend
 	etdConf := &etdconfig.Config{
 		Genesis: &core.Genesis{
@@ -269,6 +523,7 @@ func createGQLService(t *testing.T, stack *node.Node) {
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
+	return etdBackend
 }
 
 func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
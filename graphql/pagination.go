@@ -0,0 +1,271 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/etd/filters"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+const (
+	defaultPageSize  = 100  // Number of results in a page if none is requested
+	maxBlockPageSize = 100  // Maximum number of blocks in a page
+	maxLogPageSize   = 500  // Maximum number of logs in a page
+	logPageWindow    = 2048 // Number of blocks filtered at once when filling a log page
+)
+
+// errInvalidCursor is returned if a pagination cursor cannot be decoded.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// PageInfo describes the position of a page within a paginated result.
+type PageInfo struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p *PageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *PageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+// BlockPage is a page of a block range.
+type BlockPage struct {
+	blocks   []*Block
+	pageInfo *PageInfo
+}
+
+func (p *BlockPage) Blocks() []*Block {
+	return p.blocks
+}
+
+func (p *BlockPage) PageInfo() *PageInfo {
+	return p.pageInfo
+}
+
+// LogPage is a page of the logs matching a filter.
+type LogPage struct {
+	logs     []*Log
+	pageInfo *PageInfo
+}
+
+func (p *LogPage) Logs() []*Log {
+	return p.logs
+}
+
+func (p *LogPage) PageInfo() *PageInfo {
+	return p.pageInfo
+}
+
+// pageSize validates the requested number of results in a page.
+func pageSize(first *int32, limit int) (int, error) {
+	if first == nil {
+		if defaultPageSize > limit {
+			return limit, nil
+		}
+		return defaultPageSize, nil
+	}
+	if *first < 0 || int(*first) > limit {
+		return 0, fmt.Errorf("page size %d out of range [0, %d]", *first, limit)
+	}
+	return int(*first), nil
+}
+
+// encodeCursor packs the position of a result into an opaque cursor.
+func encodeCursor(kind string, position ...uint64) *string {
+	fields := []string{kind}
+	for _, n := range position {
+		fields = append(fields, strconv.FormatUint(n, 10))
+	}
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(strings.Join(fields, ":")))
+	return &cursor
+}
+
+// decodeCursor unpacks the position of a result from a cursor of the given kind.
+func decodeCursor(cursor string, kind string, fields int) ([]uint64, error) {
+	blob, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(blob), ":")
+	if len(parts) != fields+1 || parts[0] != kind {
+		return nil, errInvalidCursor
+	}
+	position := make([]uint64, fields)
+	for i := range position {
+		if position[i], err = strconv.ParseUint(parts[i+1], 10, 64); err != nil {
+			return nil, errInvalidCursor
+		}
+	}
+	return position, nil
+}
+
+// BlockPage returns a page of the canonical blocks between from and to,
+// following the block identified by the after cursor.
+func (r *Resolver) BlockPage(ctx context.Context, args struct {
+	From  *Long
+	To    *Long
+	First *int32
+	After *string
+}) (*BlockPage, error) {
+	first, err := pageSize(args.First, maxBlockPageSize)
+	if err != nil {
+		return nil, err
+	}
+	var from uint64
+	if args.From != nil {
+		if *args.From < 0 {
+			return nil, fmt.Errorf("invalid block number %d", *args.From)
+		}
+		from = uint64(*args.From)
+	}
+	to := r.backend.CurrentBlock().NumberU64()
+	if args.To != nil && *args.To >= 0 && uint64(*args.To) < to {
+		to = uint64(*args.To)
+	}
+	if args.After != nil {
+		position, err := decodeCursor(*args.After, "block", 1)
+		if err != nil {
+			return nil, err
+		}
+		if position[0]+1 > from {
+			from = position[0] + 1
+		}
+	}
+	page := &BlockPage{blocks: []*Block{}, pageInfo: new(PageInfo)}
+	if from > to || first == 0 {
+		page.pageInfo.hasNextPage = from <= to
+		return page, nil
+	}
+	last := to
+	if to-from >= uint64(first) {
+		last = from + uint64(first) - 1
+	}
+	if err := chargeCost(ctx, int64(last-from+1)*costBlock); err != nil {
+		return nil, err
+	}
+	for n := from; n <= last; n++ {
+		numberOrHash := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(n))
+		page.blocks = append(page.blocks, &Block{
+			backend:      r.backend,
+			numberOrHash: &numberOrHash,
+		})
+	}
+	page.pageInfo.hasNextPage = last < to
+	page.pageInfo.endCursor = encodeCursor("block", last)
+	return page, nil
+}
+
+// LogPage returns a page of the logs matching the filter criteria, following
+// the log identified by the after cursor.
+func (r *Resolver) LogPage(ctx context.Context, args struct {
+	Filter FilterCriteria
+	First  *int32
+	After  *string
+}) (*LogPage, error) {
+	first, err := pageSize(args.First, maxLogPageSize)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the block range to filter, continuing from the cursor if given
+	head := r.backend.CurrentHeader().Number.Uint64()
+	begin, end := head, head
+	if args.Filter.FromBlock != nil {
+		begin = uint64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil && uint64(*args.Filter.ToBlock) < end {
+		end = uint64(*args.Filter.ToBlock)
+	}
+	var after []uint64
+	if args.After != nil {
+		if after, err = decodeCursor(*args.After, "log", 2); err != nil {
+			return nil, err
+		}
+		if after[0] > begin {
+			begin = after[0]
+		}
+	}
+	var addresses []common.Address
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	var topics [][]common.Hash
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	// Filter the range window by window until the page is full, collecting one
+	// extra log to know whether there's a next page. Every window scanned is
+	// charged, so sparse ranges can't be scanned for free.
+	var logs []*types.Log
+	for start := begin; start <= end && len(logs) <= first; start += logPageWindow {
+		stop := end
+		if end-start >= logPageWindow {
+			stop = start + logPageWindow - 1
+		}
+		if err := chargeLogRange(ctx, start, stop); err != nil {
+			return nil, err
+		}
+		filter := filters.NewRangeFilter(filters.Backend(r.backend), int64(start), int64(stop), addresses, topics)
+		found, err := filter.Logs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range found {
+			if after != nil && (log.BlockNumber < after[0] || (log.BlockNumber == after[0] && uint64(log.Index) <= after[1])) {
+				continue
+			}
+			logs = append(logs, log)
+			if len(logs) > first {
+				break
+			}
+		}
+		if stop == end {
+			break
+		}
+	}
+	page := &LogPage{logs: []*Log{}, pageInfo: new(PageInfo)}
+	if len(logs) > first {
+		page.pageInfo.hasNextPage = true
+		logs = logs[:first]
+	}
+	if err := chargeCost(ctx, int64(len(logs))*costLog); err != nil {
+		return nil, err
+	}
+	for _, log := range logs {
+		page.logs = append(page.logs, &Log{
+			backend:     r.backend,
+			transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+			log:         log,
+		})
+	}
+	if len(logs) > 0 {
+		last := logs[len(logs)-1]
+		page.pageInfo.endCursor = encodeCursor("log", last.BlockNumber, uint64(last.Index))
+	}
+	return page, nil
+}
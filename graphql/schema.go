@@ -30,10 +30,13 @@ const schema string = `
     scalar BigInt
     # Long is a 64 bit unsigned integer.
     scalar Long
+    # JSON is an arbitrary JSON value.
+    scalar JSON
 
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Etherdata account at a particular block.
@@ -67,6 +70,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was undone by a chain reorganisation. It
+        # can only be set on logs emitted by the newLogs subscription.
+        removed: Boolean!
     }
 
     #EIP-2718 
@@ -138,6 +144,39 @@ const schema string = `
         #Envelope transaction support
         type: Int
         accessList: [AccessTuple!]
+        # Raw is the canonical encoding of the transaction.
+        raw: Bytes!
+        # RawReceipt is the canonical encoding of the receipt. If the transaction
+        # has not yet been mined, this field will be null.
+        rawReceipt: Bytes
+        # Trace re-executes the transaction with one of the built-in tracers,
+        # callTracer by default, returning its output. If the transaction has
+        # not yet been mined, this field will be null.
+        trace(tracer: String): JSON
+    }
+
+    # Receipt is the outcome of executing a mined transaction.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed.
+        status: Long!
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # EffectiveGasPrice is actual value per gas deducted from the sender's
+        # account.
+        effectiveGasPrice: BigInt
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, or null otherwise.
+        createdContract(block: Long): Account
+        # LogsBloom is a bloom filter over the logs emitted by the transaction.
+        logsBloom: Bytes!
+        # Logs is a list of log entries emitted by the transaction.
+        logs: [Log!]!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -222,6 +261,10 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
+        # Receipts is the list of receipts of all transactions in this block,
+        # loaded in one go. If receipts are unavailable for this block, this
+        # field will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Etherdata account at the current block's state.
@@ -231,6 +274,60 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # Raw is the canonical encoding of the block.
+        raw: Bytes!
+        # RawHeader is the canonical encoding of the block header.
+        rawHeader: Bytes!
+        # TouchedAccounts re-executes the block, returning the accounts accessed
+        # or modified by its transactions, along with the miners of the block
+        # and its ommers. Accounts are returned at this block's state.
+        touchedAccounts: [Account!]!
+        # StateDiff re-executes the block, returning the changes it made to the
+        # state of the accounts it touched. Accounts are returned at this
+        # block's state.
+        stateDiff: [AccountDiff!]!
+    }
+
+    # AccountDiff is the change of an account's state caused by a block.
+    type AccountDiff {
+        account: Account!
+        balanceBefore: BigInt!
+        balanceAfter: BigInt!
+        nonceBefore: Long!
+        nonceAfter: Long!
+        codeHashBefore: Bytes32!
+        codeHashAfter: Bytes32!
+        # Storage lists the changed storage slots of the account.
+        storage: [StorageDiff!]!
+    }
+
+    # StorageDiff is the change of a single storage slot caused by a block.
+    type StorageDiff {
+        key: Bytes32!
+        before: Bytes32!
+        after: Bytes32!
+    }
+
+    # PageInfo describes the position of a page within a paginated result.
+    type PageInfo {
+        # HasNextPage is true if there are more results after this page.
+        hasNextPage: Boolean!
+        # EndCursor identifies the last result of this page, and is passed as
+        # the after argument to fetch the next page. If the page is empty, this
+        # field will be null.
+        endCursor: String
+    }
+
+    # BlockPage is a page of a block range.
+    type BlockPage {
+        blocks: [Block!]!
+        pageInfo: PageInfo!
+    }
+
+    # LogPage is a page of the logs matching a filter.
+    type LogPage {
+        logs: [Log!]!
+        pageInfo: PageInfo!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -327,12 +424,22 @@ const schema string = `
         # Blocks returns all the blocks between two numbers, inclusive. If
         # to is not supplied, it defaults to the most recent known block.
         blocks(from: Long, to: Long): [Block!]!
+        # BlockPage returns a page of the blocks between two numbers, inclusive,
+        # following the block identified by the after cursor. If from is not
+        # supplied, it defaults to the genesis block, and to defaults to the most
+        # recent known block. Pages hold 100 blocks by default, which is also
+        # the maximum.
+        blockPage(from: Long, to: Long, first: Int, after: String): BlockPage!
         # Pending returns the current pending state.
         pending: Pending!
         # Transaction returns a transaction specified by its hash.
         transaction(hash: Bytes32!): Transaction
         # Logs returns log entries matching the provided filter.
         logs(filter: FilterCriteria!): [Log!]!
+        # LogPage returns a page of the log entries matching the provided filter,
+        # following the log identified by the after cursor. Pages hold 100 logs
+        # by default, and at most 500.
+        logPage(filter: FilterCriteria!, first: Int, after: String): LogPage!
         # GasPrice returns the node's estimate of a gas price sufficient to
         # ensure a transaction is mined in a timely fashion.
         gasPrice: BigInt!
@@ -349,4 +456,18 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription streams chain and transaction pool events. Subscriptions are
+    # served over WebSocket connections using the graphql-ws protocol.
+    type Subscription {
+        # NewHeads emits every block appended to the canonical chain.
+        newHeads: Block!
+        # NewLogs emits the logs matching the filter criteria as the blocks
+        # containing them are imported, and again with removed set if they
+        # are undone by a chain reorganisation.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # NewPendingTransactions emits the transactions entering the
+        # transaction pool.
+        newPendingTransactions: Transaction!
+    }
 `
@@ -18,10 +18,14 @@ package graphql
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/crypyto-panel/go-etherdata/etd/filters"
 	"github.com/crypyto-panel/go-etherdata/internal/etdapi"
 	"github.com/crypyto-panel/go-etherdata/node"
+	"github.com/crypyto-panel/go-etherdata/rpc"
 	"github.com/graph-gophers/graphql-go"
 )
 
@@ -40,7 +44,8 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := h.Schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+	ctx := withCostBudget(r.Context(), maxQueryCost)
+	response := h.Schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -55,26 +60,62 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// New constructs a new GraphQL service instance.
+// dispatchHandler routes WebSocket upgrade requests to the subscription
+// handler and everything else to the plain HTTP handler. Clients whose token
+// restricts the RPC namespaces they may access are only served if granted the
+// "graphql" namespace.
+type dispatchHandler struct {
+	plain http.Handler
+	ws    http.Handler
+}
+
+func (h *dispatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !rpc.PermissionsFromContext(r.Context()).Allows("graphql") {
+		http.Error(w, "graphql access denied", http.StatusForbidden)
+		return
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.ws.ServeHTTP(w, r)
+		return
+	}
+	h.plain.ServeHTTP(w, r)
+}
+
+// eventBackend is implemented by backends sharing the event system of the node
+// with the services built on top of them.
+type eventBackend interface {
+	EventSystem() *filters.EventSystem
+}
+
+// New constructs a new GraphQL service instance. Subscriptions are fed by the
+// event system of the backend, shared with the filter API.
 func New(stack *node.Node, backend etdapi.Backend, cors, vhosts []string) error {
 	if backend == nil {
 		panic("missing backend")
 	}
+	events, ok := backend.(eventBackend)
+	if !ok {
+		return errors.New("graphql: backend provides no event system")
+	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, events.EventSystem(), cors, vhosts)
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
+// newHandler returns a new `http.Handler` that will answer GraphQL queries, and
+// subscriptions over WebSocket connections using the graphql-ws protocol.
 // It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend etdapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
+func newHandler(stack *node.Node, backend etdapi.Backend, events *filters.EventSystem, cors, vhosts []string) error {
+	q := Resolver{backend: backend, events: events}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	s, err := graphql.ParseSchema(schema, &q, graphql.MaxDepth(maxQueryDepth))
 	if err != nil {
 		return err
 	}
 	h := handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
+	handler := stack.HTTPHandlerStack(&dispatchHandler{
+		plain: h,
+		ws:    newWSHandler(s, cors),
+	}, cors, vhosts)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
@@ -0,0 +1,165 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+
+	etherdata "github.com/crypyto-panel/go-etherdata"
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+// subscriptionBufferSize is the number of events and results buffered for a
+// subscriber. The node's event system delivers events with blocking sends, so
+// subscribers not keeping up with their buffer are dropped instead of stalling
+// every other consumer of the node's events.
+const subscriptionBufferSize = 256
+
+// NewHeads streams every block appended to the canonical chain. The stream
+// ends when the subscription context is cancelled, or when the subscriber falls
+// more than subscriptionBufferSize results behind.
+func (r *Resolver) NewHeads(ctx context.Context) (<-chan *Block, error) {
+	var (
+		headers = make(chan *types.Header, subscriptionBufferSize)
+		sub     = r.events.SubscribeNewHeads(headers)
+		results = make(chan *Block, subscriptionBufferSize)
+	)
+	go func() {
+		defer close(results)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				hash := header.Hash()
+				blockNrOrHash := rpc.BlockNumberOrHashWithHash(hash, false)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &blockNrOrHash,
+					hash:         hash,
+					header:       header,
+				}
+				refillCost(ctx)
+				select {
+				case results <- block:
+				default:
+					log.Debug("Dropping slow GraphQL subscriber", "subscription", "newHeads")
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results, nil
+}
+
+// NewLogs streams the logs matching the filter criteria as the blocks containing
+// them are imported. Logs undone by a chain reorganisation are streamed again
+// with their removed flag set.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit etherdata.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	logs := make(chan []*types.Log, subscriptionBufferSize)
+	sub, err := r.events.SubscribeLogs(crit, logs)
+	if err != nil {
+		return nil, err
+	}
+	results := make(chan *Log, subscriptionBufferSize)
+	go func() {
+		defer close(results)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-logs:
+				for _, l := range batch {
+					blockNrOrHash := rpc.BlockNumberOrHashWithHash(l.BlockHash, false)
+					result := &Log{
+						backend: r.backend,
+						transaction: &Transaction{
+							backend: r.backend,
+							hash:    l.TxHash,
+							block: &Block{
+								backend:      r.backend,
+								numberOrHash: &blockNrOrHash,
+								hash:         l.BlockHash,
+							},
+							index: uint64(l.TxIndex),
+						},
+						log: l,
+					}
+					refillCost(ctx)
+					select {
+					case results <- result:
+					default:
+						log.Debug("Dropping slow GraphQL subscriber", "subscription", "newLogs")
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results, nil
+}
+
+// NewPendingTransactions streams the transactions entering the transaction pool.
+func (r *Resolver) NewPendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	var (
+		hashes  = make(chan []common.Hash, subscriptionBufferSize)
+		sub     = r.events.SubscribePendingTxs(hashes)
+		results = make(chan *Transaction, subscriptionBufferSize)
+	)
+	go func() {
+		defer close(results)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					// Resolve the transaction right away, it might be gone from
+					// the pool by the time the fields are evaluated.
+					tx := r.backend.GetPoolTransaction(hash)
+					if tx == nil {
+						continue
+					}
+					refillCost(ctx)
+					select {
+					case results <- &Transaction{backend: r.backend, hash: hash, tx: tx}:
+					default:
+						log.Debug("Dropping slow GraphQL subscriber", "subscription", "newPendingTransactions")
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results, nil
+}
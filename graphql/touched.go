@@ -0,0 +1,251 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core"
+	"github.com/crypyto-panel/go-etherdata/core/state"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/core/vm"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/etd/tracers"
+)
+
+// touchedReexec is the number of blocks to re-execute if the state of a block's
+// parent is not available anymore.
+const touchedReexec = 128
+
+// touchTracer is a vm.Tracer collecting the addresses of all accounts accessed
+// during execution, along with the storage slots written.
+type touchTracer struct {
+	touched map[common.Address]struct{}
+	written map[common.Address]map[common.Hash]struct{}
+}
+
+func newTouchTracer() *touchTracer {
+	return &touchTracer{
+		touched: make(map[common.Address]struct{}),
+		written: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+func (t *touchTracer) touch(addr common.Address) {
+	t.touched[addr] = struct{}{}
+}
+
+func (t *touchTracer) write(addr common.Address, slot common.Hash) {
+	if t.written[addr] == nil {
+		t.written[addr] = make(map[common.Hash]struct{})
+	}
+	t.written[addr][slot] = struct{}{}
+}
+
+// addresses returns the accessed accounts, sorted by address.
+func (t *touchTracer) addresses() []common.Address {
+	addresses := make([]common.Address, 0, len(t.touched))
+	for addr := range t.touched {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+	return addresses
+}
+
+// slots returns the storage slots written in an account, sorted by key.
+func (t *touchTracer) slots(addr common.Address) []common.Hash {
+	slots := make([]common.Hash, 0, len(t.written[addr]))
+	for slot := range t.written[addr] {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return bytes.Compare(slots[i][:], slots[j][:]) < 0
+	})
+	return slots
+}
+
+// CaptureStart implements vm.Tracer, recording the sender and recipient, or
+// created contract, of a transaction.
+func (t *touchTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+}
+
+// CaptureState implements vm.Tracer, recording the accounts accessed by the
+// opcode about to be executed.
+func (t *touchTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	stack := scope.Stack
+	switch op {
+	case vm.SLOAD:
+		t.touch(scope.Contract.Address())
+
+	case vm.SSTORE:
+		t.touch(scope.Contract.Address())
+		if len(stack.Data()) >= 1 {
+			t.write(scope.Contract.Address(), common.Hash(stack.Back(0).Bytes32()))
+		}
+
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH, vm.SELFDESTRUCT:
+		if len(stack.Data()) >= 1 {
+			t.touch(common.Address(stack.Back(0).Bytes20()))
+		}
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		if len(stack.Data()) >= 2 {
+			t.touch(common.Address(stack.Back(1).Bytes20()))
+		}
+	case vm.CREATE:
+		caller := scope.Contract.Address()
+		t.touch(crypto.CreateAddress(caller, env.StateDB.GetNonce(caller)))
+
+	case vm.CREATE2:
+		if len(stack.Data()) >= 4 {
+			// Memory is already expanded to cover the init code when tracing
+			offset, size := stack.Back(1), stack.Back(2)
+			code := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+			salt := stack.Back(3).Bytes32()
+			t.touch(crypto.CreateAddress2(scope.Contract.Address(), salt, crypto.Keccak256(code)))
+		}
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *touchTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *touchTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {}
+
+// traceTouched re-executes a block on top of its parent state, returning the
+// tracer having collected the accounts accessed by its transactions, along with
+// the miners of the block and its uncles, and the parent state of the block.
+func traceTouched(ctx context.Context, backend tracers.Backend, block *types.Block) (*touchTracer, *state.StateDB, error) {
+	tracer := newTouchTracer()
+	if block.NumberU64() == 0 {
+		return tracer, nil, nil
+	}
+	tracer.touch(block.Coinbase())
+	for _, uncle := range block.Uncles() {
+		tracer.touch(uncle.Coinbase)
+	}
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		parent, err := backend.BlockByHash(ctx, block.ParentHash())
+		if err != nil {
+			return nil, nil, err
+		}
+		parentState, err := backend.StateAtBlock(ctx, parent, touchedReexec, nil, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tracer, parentState, nil
+	}
+	_, vmctx, statedb, err := backend.StateAtTransaction(ctx, block, 0, touchedReexec)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		config      = backend.ChainConfig()
+		signer      = types.MakeSigner(config, block.Number())
+		parentState = statedb.Copy()
+	)
+	for i, tx := range txs {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		msg, err := tx.AsMessage(signer, block.BaseFee())
+		if err != nil {
+			return nil, nil, err
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, config, vm.Config{Debug: true, Tracer: tracer})
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+			return nil, nil, err
+		}
+		statedb.Finalise(config.IsEIP158(block.Number()))
+	}
+	return tracer, parentState, nil
+}
+
+// touchedAccounts re-executes a block on top of its parent state, returning the
+// sorted addresses of all accounts accessed by its transactions, along with
+// the miners of the block and its uncles.
+func touchedAccounts(ctx context.Context, backend tracers.Backend, block *types.Block) ([]common.Address, error) {
+	tracer, _, err := traceTouched(ctx, backend, block)
+	if err != nil {
+		return nil, err
+	}
+	return tracer.addresses(), nil
+}
+
+// storageDiff is the change of a single storage slot of an account.
+type storageDiff struct {
+	key, before, after common.Hash
+}
+
+// accountDiff is the change of an account's state caused by a block.
+type accountDiff struct {
+	address                       common.Address
+	balanceBefore, balanceAfter   *big.Int
+	nonceBefore, nonceAfter       uint64
+	codeHashBefore, codeHashAfter common.Hash
+	storage                       []storageDiff
+}
+
+// stateDiff re-executes a block on top of its parent state, returning the
+// changes of all accounts whose state differs between the parent state and
+// the state of the block, sorted by address. Storage changes are tracked for
+// the slots written by the transactions of the block.
+func stateDiff(ctx context.Context, backend tracers.Backend, block *types.Block) ([]*accountDiff, error) {
+	tracer, parentState, err := traceTouched(ctx, backend, block)
+	if err != nil || parentState == nil {
+		return nil, err
+	}
+	blockState, err := backend.StateAtBlock(ctx, block, touchedReexec, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []*accountDiff
+	for _, addr := range tracer.addresses() {
+		diff := &accountDiff{
+			address:        addr,
+			balanceBefore:  parentState.GetBalance(addr),
+			balanceAfter:   blockState.GetBalance(addr),
+			nonceBefore:    parentState.GetNonce(addr),
+			nonceAfter:     blockState.GetNonce(addr),
+			codeHashBefore: parentState.GetCodeHash(addr),
+			codeHashAfter:  blockState.GetCodeHash(addr),
+		}
+		for _, slot := range tracer.slots(addr) {
+			before, after := parentState.GetState(addr, slot), blockState.GetState(addr, slot)
+			if before != after {
+				diff.storage = append(diff.storage, storageDiff{key: slot, before: before, after: after})
+			}
+		}
+		if diff.balanceBefore.Cmp(diff.balanceAfter) != 0 || diff.nonceBefore != diff.nonceAfter ||
+			diff.codeHashBefore != diff.codeHashAfter || len(diff.storage) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Message types of the graphql-ws protocol, as spoken by the Apollo
+// subscriptions-transport-ws client.
+const (
+	gqlConnectionInit      = "connection_init"
+	gqlConnectionAck       = "connection_ack"
+	gqlConnectionError     = "connection_error"
+	gqlConnectionKeepAlive = "ka"
+	gqlConnectionTerminate = "connection_terminate"
+	gqlStart               = "start"
+	gqlStop                = "stop"
+	gqlData                = "data"
+	gqlError               = "error"
+	gqlComplete            = "complete"
+)
+
+const (
+	wsSubprotocol       = "graphql-ws"
+	wsKeepAliveInterval = 30 * time.Second
+	wsWriteTimeout      = 10 * time.Second
+	wsMessageSizeLimit  = 1024 * 1024
+	wsMaxOperations     = 100 // Maximum number of concurrent operations per connection
+)
+
+// wsMessage is a graphql-ws protocol frame.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsStartPayload is the payload of a start message.
+type wsStartPayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsErrorPayload is the payload of the error and connection_error messages.
+type wsErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// wsHandler serves GraphQL subscriptions over WebSocket connections using the
+// graphql-ws protocol. Queries and mutations are accepted too, yielding a
+// single result each.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+// newWSHandler creates a graphql-ws handler accepting connections from the given
+// origins. If no origins are configured, only same-origin browser connections
+// are accepted.
+func newWSHandler(schema *graphql.Schema, origins []string) *wsHandler {
+	h := &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsSubprotocol},
+		},
+	}
+	if len(origins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range origins {
+				if allowed == "*" || strings.EqualFold(allowed, origin) {
+					return true
+				}
+			}
+			log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+			return false
+		}
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler, upgrading the request to a WebSocket.
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	if conn.Subprotocol() != wsSubprotocol {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseProtocolError, "unsupported subprotocol"), time.Now().Add(wsWriteTimeout))
+		conn.Close()
+		return
+	}
+	c := &wsConn{
+		conn:   conn,
+		schema: h.schema,
+		ops:    make(map[string]*wsOperation),
+	}
+	c.serve(r.Context())
+}
+
+// wsConn tracks the operations running on a single graphql-ws connection.
+type wsConn struct {
+	conn   *websocket.Conn
+	schema *graphql.Schema
+
+	writeLock sync.Mutex // Serialises frames written by concurrent operations
+
+	opsLock sync.Mutex
+	ops     map[string]*wsOperation // Running operations by client chosen id
+	wg      sync.WaitGroup
+}
+
+// wsOperation is a query, mutation or subscription running on a connection.
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// serve runs the read loop of the connection until the client disconnects or
+// terminates the session, then stops all operations still running.
+func (c *wsConn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(wsMessageSizeLimit)
+
+	var initialised bool
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Debug("GraphQL WebSocket read failed", "err", err)
+			}
+			return
+		}
+		switch msg.Type {
+		case gqlConnectionInit:
+			if initialised {
+				continue
+			}
+			initialised = true
+			c.write(wsMessage{Type: gqlConnectionAck})
+			c.write(wsMessage{Type: gqlConnectionKeepAlive})
+
+			c.wg.Add(1)
+			go c.keepAlive(ctx)
+
+		case gqlStart:
+			if !initialised {
+				c.write(wsMessage{Type: gqlConnectionError, Payload: errorPayload("connection not initialised")})
+				return
+			}
+			c.start(ctx, msg)
+
+		case gqlStop:
+			c.stop(msg.ID)
+
+		case gqlConnectionTerminate:
+			return
+
+		default:
+			c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: errorPayload("unknown message type " + msg.Type)})
+		}
+	}
+}
+
+// start launches a new operation, streaming its results until it completes or
+// gets stopped.
+func (c *wsConn) start(ctx context.Context, msg wsMessage) {
+	var payload wsStartPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: errorPayload(err.Error())})
+		return
+	}
+	c.opsLock.Lock()
+	if _, ok := c.ops[msg.ID]; ok {
+		c.opsLock.Unlock()
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: errorPayload("duplicate operation id")})
+		return
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.opsLock.Unlock()
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: errorPayload("too many operations")})
+		return
+	}
+	opCtx, cancel := context.WithCancel(withCostBudget(ctx, maxQueryCost))
+	op := &wsOperation{cancel: cancel}
+	c.ops[msg.ID] = op
+	c.opsLock.Unlock()
+
+	results, err := c.schema.Subscribe(opCtx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		c.finish(msg.ID, op)
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: errorPayload(err.Error())})
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for result := range results {
+			data, err := json.Marshal(result)
+			if err != nil {
+				log.Warn("Failed to encode GraphQL result", "err", err)
+				continue
+			}
+			c.write(wsMessage{ID: msg.ID, Type: gqlData, Payload: data})
+		}
+		// Only report completion if the operation ended on its own
+		if opCtx.Err() == nil {
+			c.write(wsMessage{ID: msg.ID, Type: gqlComplete})
+		}
+		c.finish(msg.ID, op)
+	}()
+}
+
+// stop cancels the operation with the given id, if it's running.
+func (c *wsConn) stop(id string) {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+
+	if op, ok := c.ops[id]; ok {
+		op.cancel()
+		delete(c.ops, id)
+	}
+}
+
+// finish releases a terminated operation, unless the client already reused its
+// id for a new one.
+func (c *wsConn) finish(id string, op *wsOperation) {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+
+	op.cancel()
+	if c.ops[id] == op {
+		delete(c.ops, id)
+	}
+}
+
+// keepAlive periodically sends keep-alive messages until the connection closes.
+func (c *wsConn) keepAlive(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.write(wsMessage{Type: gqlConnectionKeepAlive})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends a single protocol message to the client.
+func (c *wsConn) write(msg wsMessage) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("GraphQL WebSocket write failed", "err", err)
+	}
+}
+
+// errorPayload encodes an error message as a protocol payload.
+func errorPayload(msg string) json.RawMessage {
+	payload, _ := json.Marshal(wsErrorPayload{Message: msg})
+	return payload
+}
@@ -35,6 +35,7 @@ import (
 	"time"
 
 	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
 // Handler is the global debugging handler.
@@ -51,6 +52,33 @@ type HandlerT struct {
 	traceFile string
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (h *HandlerT) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"verbosity":               {Summary: "Sets the log verbosity ceiling.", Params: []string{"level"}},
+		"vmodule":                 {Summary: "Sets the log verbosity pattern of individual packages and files.", Params: []string{"pattern"}},
+		"backtraceAt":             {Summary: "Sets the log location printing a stack trace.", Params: []string{"location"}},
+		"memStats":                {Summary: "Returns the memory allocator statistics."},
+		"gcStats":                 {Summary: "Returns the garbage collector statistics."},
+		"cpuProfile":              {Summary: "Records a CPU profile into a file for a number of seconds.", Params: []string{"file", "nsec"}},
+		"startCPUProfile":         {Summary: "Starts recording a CPU profile into a file.", Params: []string{"file"}},
+		"stopCPUProfile":          {Summary: "Stops the running CPU profile."},
+		"goTrace":                 {Summary: "Records an execution trace into a file for a number of seconds.", Params: []string{"file", "nsec"}},
+		"startGoTrace":            {Summary: "Starts recording an execution trace into a file.", Params: []string{"file"}},
+		"stopGoTrace":             {Summary: "Stops the running execution trace."},
+		"blockProfile":            {Summary: "Records a goroutine blocking profile into a file for a number of seconds.", Params: []string{"file", "nsec"}},
+		"setBlockProfileRate":     {Summary: "Sets the rate of goroutine blocking events sampled.", Params: []string{"rate"}},
+		"writeBlockProfile":       {Summary: "Writes the goroutine blocking profile into a file.", Params: []string{"file"}},
+		"mutexProfile":            {Summary: "Records a mutex contention profile into a file for a number of seconds.", Params: []string{"file", "nsec"}},
+		"setMutexProfileFraction": {Summary: "Sets the rate of mutex contention events sampled.", Params: []string{"rate"}},
+		"writeMutexProfile":       {Summary: "Writes the mutex contention profile into a file.", Params: []string{"file"}},
+		"writeMemProfile":         {Summary: "Writes the allocation profile into a file.", Params: []string{"file"}},
+		"stacks":                  {Summary: "Returns the stack traces of all goroutines."},
+		"freeOSMemory":            {Summary: "Returns as much memory as possible to the operating system."},
+		"setGCPercent":            {Summary: "Sets the garbage collection target percentage, returning the previous one.", Params: []string{"v"}},
+	}
+}
+
 // Verbosity sets the log verbosity ceiling. The verbosity of individual packages
 // and source files can be raised using Vmodule.
 func (*HandlerT) Verbosity(level int) {
@@ -59,6 +59,16 @@ func NewPublicEtherdataAPI(b Backend) *PublicEtherdataAPI {
 	return &PublicEtherdataAPI{b}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *PublicEtherdataAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"gasPrice":             {Summary: "Returns a suggestion for a gas price for legacy transactions."},
+		"maxPriorityFeePerGas": {Summary: "Returns a suggestion for a gas tip cap for dynamic fee transactions."},
+		"feeHistory":           {Summary: "Returns the fee market history of a range of blocks.", Params: []string{"blockCount", "lastBlock", "rewardPercentiles"}},
+		"syncing":              {Summary: "Returns the synchronisation progress, or false if the node is not syncing."},
+	}
+}
+
 // GasPrice returns a suggestion for a gas price for legacy transactions.
 func (s *PublicEtherdataAPI) GasPrice(ctx context.Context) (*hexutil.Big, error) {
 	tipcap, err := s.b.SuggestGasTipCap(ctx)
@@ -80,6 +90,42 @@ func (s *PublicEtherdataAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil
 	return (*hexutil.Big)(tipcap), err
 }
 
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the base fees, gas used ratios and the requested percentiles
+// of the effective priority fees paid in a range of blocks ending at lastBlock.
+func (s *PublicEtherdataAPI) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, reward, baseFee, gasUsed, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	results := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsed,
+	}
+	if reward != nil {
+		results.Reward = make([][]*hexutil.Big, len(reward))
+		for i, w := range reward {
+			results.Reward[i] = make([]*hexutil.Big, len(w))
+			for j, v := range w {
+				results.Reward[i][j] = (*hexutil.Big)(v)
+			}
+		}
+	}
+	if baseFee != nil {
+		results.BaseFee = make([]*hexutil.Big, len(baseFee))
+		for i, v := range baseFee {
+			results.BaseFee[i] = (*hexutil.Big)(v)
+		}
+	}
+	return results, nil
+}
+
 // Syncing returns false in case the node is currently not syncing with the network. It can be up to date or has not
 // yet received the latest block headers from its pears. In case it is synchronizing:
 // - startingBlock: block number this node started to synchronise from
@@ -91,16 +137,28 @@ func (s *PublicEtherdataAPI) Syncing() (interface{}, error) {
 	progress := s.b.Downloader().Progress()
 
 	// Return not syncing if the synchronisation already completed
-	if progress.CurrentBlock >= progress.HighestBlock {
+	if progress.CurrentBlock >= progress.HighestBlock && progress.HealingStates == 0 {
 		return false, nil
 	}
 	// Otherwise gather the block sync stats
 	return map[string]interface{}{
-		"startingBlock": hexutil.Uint64(progress.StartingBlock),
-		"currentBlock":  hexutil.Uint64(progress.CurrentBlock),
-		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
-		"pulledStates":  hexutil.Uint64(progress.PulledStates),
-		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		"startingBlock":       hexutil.Uint64(progress.StartingBlock),
+		"currentBlock":        hexutil.Uint64(progress.CurrentBlock),
+		"highestBlock":        hexutil.Uint64(progress.HighestBlock),
+		"pulledStates":        hexutil.Uint64(progress.PulledStates),
+		"knownStates":         hexutil.Uint64(progress.KnownStates),
+		"syncedAccounts":      hexutil.Uint64(progress.SyncedAccounts),
+		"syncedAccountBytes":  hexutil.Uint64(progress.SyncedAccountBytes),
+		"syncedBytecodes":     hexutil.Uint64(progress.SyncedBytecodes),
+		"syncedBytecodeBytes": hexutil.Uint64(progress.SyncedBytecodeBytes),
+		"syncedStorage":       hexutil.Uint64(progress.SyncedStorage),
+		"syncedStorageBytes":  hexutil.Uint64(progress.SyncedStorageBytes),
+		"healedTrienodes":     hexutil.Uint64(progress.HealedTrienodes),
+		"healedTrienodeBytes": hexutil.Uint64(progress.HealedTrienodeBytes),
+		"healedBytecodes":     hexutil.Uint64(progress.HealedBytecodes),
+		"healedBytecodeBytes": hexutil.Uint64(progress.HealedBytecodeBytes),
+		"healingStates":       hexutil.Uint64(progress.HealingStates),
+		"stateEta":            hexutil.Uint64(progress.StateETA),
 	}, nil
 }
 
@@ -114,6 +172,15 @@ func NewPublicTxPoolAPI(b Backend) *PublicTxPoolAPI {
 	return &PublicTxPoolAPI{b}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *PublicTxPoolAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"content": {Summary: "Returns the transactions contained within the transaction pool."},
+		"status":  {Summary: "Returns the number of pending and queued transactions in the pool."},
+		"inspect": {Summary: "Returns a textual summary of the transactions in the pool."},
+	}
+}
+
 // Content returns the transactions contained within the transaction pool.
 func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
 	content := map[string]map[string]map[string]*RPCTransaction{
@@ -196,6 +263,13 @@ func NewPublicAccountAPI(am *accounts.Manager) *PublicAccountAPI {
 	return &PublicAccountAPI{am: am}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *PublicAccountAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"accounts": {Summary: "Returns the addresses of the accounts this node manages."},
+	}
+}
+
 // Accounts returns the collection of accounts this node manages
 func (s *PublicAccountAPI) Accounts() []common.Address {
 	return s.am.Accounts()
@@ -219,6 +293,27 @@ func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
 	}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *PrivateAccountAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"listAccounts":           {Summary: "Returns the addresses of all accounts in all wallets."},
+		"listWallets":            {Summary: "Returns the wallets this node manages, with their accounts."},
+		"openWallet":             {Summary: "Initiates a hardware wallet opening procedure.", Params: []string{"url", "passphrase"}},
+		"deriveAccount":          {Summary: "Requests a hardware wallet to derive a new account.", Params: []string{"url", "path", "pin"}},
+		"newAccount":             {Summary: "Creates a new account in the keystore, encrypted with the passphrase.", Params: []string{"passphrase"}},
+		"importRawKey":           {Summary: "Imports a hex encoded private key into the keystore.", Params: []string{"privkey", "passphrase"}},
+		"unlockAccount":          {Summary: "Unlocks an account for the given duration in seconds.", Params: []string{"address", "passphrase", "duration"}},
+		"lockAccount":            {Summary: "Locks an unlocked account.", Params: []string{"address"}},
+		"sendTransaction":        {Summary: "Signs a transaction with the passphrase of its sender and submits it.", Params: []string{"args", "passphrase"}},
+		"signTransaction":        {Summary: "Signs a transaction with the passphrase of its sender without submitting it.", Params: []string{"args", "passphrase"}},
+		"sign":                   {Summary: "Calculates an Etherdata specific signature of the data.", Params: []string{"data", "address", "passphrase"}},
+		"ecRecover":              {Summary: "Returns the address of the account that signed the data.", Params: []string{"data", "signature"}},
+		"signAndSendTransaction": {Summary: "Deprecated alias of sendTransaction.", Params: []string{"args", "passphrase"}},
+		"initializeWallet":       {Summary: "Initializes a new hardware wallet.", Params: []string{"url"}},
+		"unpair":                 {Summary: "Deletes a pairing between a smartcard wallet and this node.", Params: []string{"url", "pin"}},
+	}
+}
+
 // listAccounts will return a list of addresses for accounts this node manages.
 func (s *PrivateAccountAPI) ListAccounts() []common.Address {
 	return s.am.Accounts()
@@ -548,6 +643,30 @@ func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
 	return &PublicBlockChainAPI{b}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicBlockChainAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"chainId":                       {Summary: "Returns the chain ID used for transaction replay protection."},
+		"blockNumber":                   {Summary: "Returns the number of the most recent block."},
+		"getBalance":                    {Summary: "Returns the balance of an account at the given block.", Params: []string{"address", "block"}},
+		"getProof":                      {Summary: "Returns the Merkle proof of an account and some of its storage slots.", Params: []string{"address", "storageKeys", "block"}},
+		"getHeaderByNumber":             {Summary: "Returns the header of the block with the given number.", Params: []string{"number"}},
+		"getHeaderByHash":               {Summary: "Returns the header of the block with the given hash.", Params: []string{"hash"}},
+		"getBlockByNumber":              {Summary: "Returns the block with the given number.", Params: []string{"number", "fullTx"}},
+		"getBlockByHash":                {Summary: "Returns the block with the given hash.", Params: []string{"hash", "fullTx"}},
+		"getBlockReceipts":              {Summary: "Returns the receipts of all transactions in a block.", Params: []string{"block"}},
+		"getUncleByBlockNumberAndIndex": {Summary: "Returns an uncle of the block with the given number.", Params: []string{"number", "index"}},
+		"getUncleByBlockHashAndIndex":   {Summary: "Returns an uncle of the block with the given hash.", Params: []string{"hash", "index"}},
+		"getUncleCountByBlockNumber":    {Summary: "Returns the number of uncles of the block with the given number.", Params: []string{"number"}},
+		"getUncleCountByBlockHash":      {Summary: "Returns the number of uncles of the block with the given hash.", Params: []string{"hash"}},
+		"getCode":                       {Summary: "Returns the code of an account at the given block.", Params: []string{"address", "block"}},
+		"getStorageAt":                  {Summary: "Returns a storage slot of an account at the given block.", Params: []string{"address", "key", "block"}},
+		"call":                          {Summary: "Executes a message call without creating a transaction.", Params: []string{"args", "block", "overrides"}},
+		"estimateGas":                   {Summary: "Returns an estimate of the gas a transaction needs to complete.", Params: []string{"args", "block"}},
+		"createAccessList":              {Summary: "Returns the access list and gas used of executing a transaction.", Params: []string{"args", "block"}},
+	}
+}
+
 // ChainId is the EIP-155 replay-protection chain id for the current etherdata chain config.
 func (api *PublicBlockChainAPI) ChainId() (*hexutil.Big, error) {
 	// if current block is at or past the EIP-155 replay-protection fork block, return chainID from config
@@ -698,6 +817,44 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Ha
 	return nil, err
 }
 
+// GetBlockReceipts returns the receipts of all transactions in the given block.
+// The receipts are loaded and their metadata derived in one pass, which is much
+// cheaper than retrieving them one by one through etd_getTransactionReceipt.
+func (s *PublicBlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	var (
+		block    *types.Block
+		receipts types.Receipts
+		err      error
+	)
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		// Pending receipts are only known by the miner, not stored in the database
+		block, receipts = s.b.PendingBlockAndReceipts()
+		if block == nil {
+			return nil, nil
+		}
+	} else {
+		block, err = s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+		if block == nil || err != nil {
+			return nil, err
+		}
+		receipts, err = s.b.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	signer := types.MakeSigner(s.b.ChainConfig(), block.Number())
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i, block.BaseFee())
+	}
+	return result, nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
@@ -1418,6 +1575,29 @@ func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransa
 	return &PublicTransactionPoolAPI{b, nonceLock, signer}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *PublicTransactionPoolAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"getBlockTransactionCountByNumber":       {Summary: "Returns the number of transactions in the block with the given number.", Params: []string{"number"}},
+		"getBlockTransactionCountByHash":         {Summary: "Returns the number of transactions in the block with the given hash.", Params: []string{"hash"}},
+		"getTransactionByBlockNumberAndIndex":    {Summary: "Returns a transaction of the block with the given number.", Params: []string{"number", "index"}},
+		"getTransactionByBlockHashAndIndex":      {Summary: "Returns a transaction of the block with the given hash.", Params: []string{"hash", "index"}},
+		"getRawTransactionByBlockNumberAndIndex": {Summary: "Returns the encoding of a transaction of the block with the given number.", Params: []string{"number", "index"}},
+		"getRawTransactionByBlockHashAndIndex":   {Summary: "Returns the encoding of a transaction of the block with the given hash.", Params: []string{"hash", "index"}},
+		"getTransactionCount":                    {Summary: "Returns the number of transactions sent from an account at the given block.", Params: []string{"address", "block"}},
+		"getTransactionByHash":                   {Summary: "Returns the transaction with the given hash.", Params: []string{"hash"}},
+		"getRawTransactionByHash":                {Summary: "Returns the encoding of the transaction with the given hash.", Params: []string{"hash"}},
+		"getTransactionReceipt":                  {Summary: "Returns the receipt of the transaction with the given hash.", Params: []string{"hash"}},
+		"sendTransaction":                        {Summary: "Signs a transaction with a local account and submits it.", Params: []string{"args"}},
+		"fillTransaction":                        {Summary: "Fills in the defaults of a transaction and returns it unsigned.", Params: []string{"args"}},
+		"sendRawTransaction":                     {Summary: "Submits a signed, encoded transaction.", Params: []string{"input"}},
+		"sign":                                   {Summary: "Calculates an Etherdata specific signature of the data with a local account.", Params: []string{"address", "data"}},
+		"signTransaction":                        {Summary: "Signs a transaction with a local account without submitting it.", Params: []string{"args"}},
+		"pendingTransactions":                    {Summary: "Returns the pooled transactions sent from local accounts."},
+		"resend":                                 {Summary: "Replaces a pooled transaction with new gas settings.", Params: []string{"args", "gasPrice", "gasLimit"}},
+	}
+}
+
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
 func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr rpc.BlockNumber) *hexutil.Uint {
 	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
@@ -1545,13 +1725,29 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+
+	// Retrieve the base fee needed for the effective gas price
+	var baseFee *big.Int
+	if s.b.ChainConfig().IsLondon(bigblock) {
+		header, err := s.b.HeaderByHash(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		baseFee = header.BaseFee
+	}
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index), baseFee), nil
+}
+
+// marshalReceipt marshals a transaction receipt into a JSON object. The base
+// fee is nil for blocks before the London fork.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int, baseFee *big.Int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
@@ -1562,14 +1758,10 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"type":              hexutil.Uint(tx.Type()),
 	}
 	// Assign the effective gas price paid
-	if !s.b.ChainConfig().IsLondon(bigblock) {
+	if baseFee == nil {
 		fields["effectiveGasPrice"] = hexutil.Uint64(tx.GasPrice().Uint64())
 	} else {
-		header, err := s.b.HeaderByHash(ctx, blockHash)
-		if err != nil {
-			return nil, err
-		}
-		gasPrice := new(big.Int).Add(header.BaseFee, tx.EffectiveGasTipValue(header.BaseFee))
+		gasPrice := new(big.Int).Add(baseFee, tx.EffectiveGasTipValue(baseFee))
 		fields["effectiveGasPrice"] = hexutil.Uint64(gasPrice.Uint64())
 	}
 	// Assign receipt status or post state.
@@ -1585,7 +1777,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1839,6 +2031,16 @@ func NewPublicDebugAPI(b Backend) *PublicDebugAPI {
 	return &PublicDebugAPI{b: b}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PublicDebugAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"getBlockRlp":         {Summary: "Returns the RLP encoding of a block.", Params: []string{"number"}},
+		"testSignCliqueBlock": {Summary: "Signs a clique block with an account and returns the recovered signer.", Params: []string{"address", "number"}},
+		"printBlock":          {Summary: "Returns a human readable dump of a block.", Params: []string{"number"}},
+		"seedHash":            {Summary: "Returns the proof-of-work seed hash of a block.", Params: []string{"number"}},
+	}
+}
+
 // GetBlockRlp retrieves the RLP encoded for of a single block.
 func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
@@ -1921,6 +2123,15 @@ func NewPrivateDebugAPI(b Backend) *PrivateDebugAPI {
 	return &PrivateDebugAPI{b: b}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateDebugAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"chaindbProperty": {Summary: "Returns a property of the chain database.", Params: []string{"property"}},
+		"chaindbCompact":  {Summary: "Compacts the chain database."},
+		"setHead":         {Summary: "Rewinds the chain to a block.", Params: []string{"number"}},
+	}
+}
+
 // ChaindbProperty returns leveldb properties of the key-value database.
 func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
 	if property == "" {
@@ -1960,6 +2171,15 @@ func NewPublicNetAPI(net *p2p.Server, networkVersion uint64) *PublicNetAPI {
 	return &PublicNetAPI{net, networkVersion}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *PublicNetAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"listening": {Summary: "Returns whether the node is listening for network connections."},
+		"peerCount": {Summary: "Returns the number of connected peers."},
+		"version":   {Summary: "Returns the network ID."},
+	}
+}
+
 // Listening returns an indication if the node is listening for network connections.
 func (s *PublicNetAPI) Listening() bool {
 	return true // always listening
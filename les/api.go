@@ -25,6 +25,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/common/mclock"
 	vfs "github.com/crypyto-panel/go-etherdata/les/vflux/server"
 	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
 var (
@@ -48,6 +49,20 @@ func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
 	}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateLightServerAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"serverInfo":         {Summary: "Returns the capacity and client limits of the server."},
+		"clientInfo":         {Summary: "Returns the information of connected or known clients.", Params: []string{"nodes"}},
+		"priorityClientInfo": {Summary: "Returns the information of the clients with a positive balance in an id range.", Params: []string{"start", "stop", "maxCount"}},
+		"setClientParams":    {Summary: "Sets the pricing parameters of clients.", Params: []string{"nodes", "params"}},
+		"setDefaultParams":   {Summary: "Sets the default pricing parameters of clients.", Params: []string{"params"}},
+		"setConnectedBias":   {Summary: "Sets the bias protecting connected clients from being kicked out.", Params: []string{"bias"}},
+		"addBalance":         {Summary: "Adds to the balance of a client, returning the old and new balance.", Params: []string{"node", "amount"}},
+		"benchmark":          {Summary: "Runs request serving benchmarks.", Params: []string{"setups", "passCount", "length"}},
+	}
+}
+
 // parseNode parses either an enode address a raw hex node id
 func parseNode(node string) (enode.ID, error) {
 	if id, err := enode.ParseID(node); err == nil {
@@ -336,6 +351,13 @@ func NewPrivateDebugAPI(server *LesServer) *PrivateDebugAPI {
 	}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateDebugAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"freezeClient": {Summary: "Forces a client to freeze, for testing.", Params: []string{"node"}},
+	}
+}
+
 // FreezeClient forces a temporary client freeze which normally happens when the server is overloaded
 func (api *PrivateDebugAPI) FreezeClient(node string) error {
 	var (
@@ -363,6 +385,15 @@ func NewPrivateLightAPI(backend *lesCommons) *PrivateLightAPI {
 	return &PrivateLightAPI{backend: backend}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateLightAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"latestCheckpoint":             {Summary: "Returns the latest local checkpoint."},
+		"getCheckpoint":                {Summary: "Returns a local checkpoint by its index.", Params: []string{"index"}},
+		"getCheckpointContractAddress": {Summary: "Returns the address of the checkpoint oracle contract."},
+	}
+}
+
 // LatestCheckpoint returns the latest local checkpoint package.
 //
 // The checkpoint package consists of 4 strings:
@@ -31,6 +31,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/core/types"
 	"github.com/crypyto-panel/go-etherdata/core/vm"
 	"github.com/crypyto-panel/go-etherdata/etd/downloader"
+	"github.com/crypyto-panel/go-etherdata/etd/filters"
 	"github.com/crypyto-panel/go-etherdata/etd/gasprice"
 	"github.com/crypyto-panel/go-etherdata/etddb"
 	"github.com/crypyto-panel/go-etherdata/event"
@@ -255,10 +256,25 @@ func (b *LesApiBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 	return b.gpo.SuggestTipCap(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
+	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
+// PendingBlockAndReceipts returns nil, as the light client doesn't track a pending block.
+func (b *LesApiBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	return nil, nil
+}
+
 func (b *LesApiBackend) ChainDb() etddb.Database {
 	return b.etd.chainDb
 }
 
+// EventSystem returns the event system shared by the filter API and any other
+// service subscribing to chain events, such as GraphQL.
+func (b *LesApiBackend) EventSystem() *filters.EventSystem {
+	return b.etd.eventSystem
+}
+
 func (b *LesApiBackend) AccountManager() *accounts.Manager {
 	return b.etd.accountManager
 }
@@ -68,6 +68,7 @@ type LightEtherdata struct {
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
 	ApiBackend     *LesApiBackend
+	eventSystem    *filters.EventSystem // Chain and pool events shared by all log and head subscribers
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
@@ -168,6 +169,7 @@ func New(stack *node.Node, config *etdconfig.Config) (*LightEtherdata, error) {
 		gpoParams.Default = config.Miner.GasPrice
 	}
 	letd.ApiBackend.gpo = gasprice.NewOracle(letd.ApiBackend, gpoParams)
+	letd.eventSystem = filters.NewEventSystem(letd.ApiBackend, true)
 
 	letd.handler = newClientHandler(config.UltraLightServers, config.UltraLightFraction, checkpoint, letd)
 	if letd.handler.ulc != nil {
@@ -267,6 +269,16 @@ func (s *LightDummyAPI) Etherbase() (common.Address, error) {
 	return common.Address{}, fmt.Errorf("mining is not supported in light mode")
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *LightDummyAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"etherbase": {Summary: "Fails, mining is not supported by light clients."},
+		"coinbase":  {Summary: "Fails, mining is not supported by light clients."},
+		"hashrate":  {Summary: "Returns zero, mining is not supported by light clients."},
+		"mining":    {Summary: "Returns false, mining is not supported by light clients."},
+	}
+}
+
 // Coinbase is the address that mining rewards will be send to (alias for Etherbase)
 func (s *LightDummyAPI) Coinbase() (common.Address, error) {
 	return common.Address{}, fmt.Errorf("mining is not supported in light mode")
@@ -301,7 +313,7 @@ func (s *LightEtherdata) APIs() []rpc.API {
 		}, {
 			Namespace: "etd",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, 5*time.Minute),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, s.eventSystem, 5*time.Minute),
 			Public:    true,
 		}, {
 			Namespace: "net",
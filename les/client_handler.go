@@ -74,7 +74,11 @@ func newClientHandler(ulcServers []string, ulcFraction int, checkpoint *params.T
 		height = (checkpoint.SectionIndex+1)*params.CHTFrequency - 1
 	}
 	handler.fetcher = newLightFetcher(backend.blockchain, backend.engine, backend.peers, handler.ulc, backend.chainDb, backend.reqDist, handler.synchronise)
-	handler.downloader = downloader.New(height, backend.chainDb, nil, backend.eventMux, nil, backend.blockchain, handler.removePeer)
+	// Light servers are not scored, drop them regardless of the reason
+	dropper := func(id string, reason p2p.ReputationEvent) {
+		handler.removePeer(id)
+	}
+	handler.downloader = downloader.New(height, backend.chainDb, nil, backend.eventMux, nil, backend.blockchain, dropper)
 	handler.backend.peers.subscribe((*downloaderPeerNotify)(handler))
 	return handler
 }
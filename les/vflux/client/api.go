@@ -22,6 +22,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/common/mclock"
 	"github.com/crypyto-panel/go-etherdata/les/utils"
 	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/rpc"
 )
 
 // PrivateClientAPI implements the vflux client side API
@@ -34,6 +35,16 @@ func NewPrivateClientAPI(vt *ValueTracker) *PrivateClientAPI {
 	return &PrivateClientAPI{vt}
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *PrivateClientAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"requestStats": {Summary: "Returns the request statistics of the servers."},
+		"distribution": {Summary: "Returns the response time distribution of a server, or of all if none given.", Params: []string{"node", "normalized"}},
+		"timeout":      {Summary: "Returns the request timeout for a server at a failure rate.", Params: []string{"node", "failRate"}},
+		"value":        {Summary: "Returns the service value of a server at a timeout.", Params: []string{"node", "timeout"}},
+	}
+}
+
 // parseNodeStr converts either an enode address or a plain hex node id to enode.ID
 func parseNodeStr(nodeStr string) (enode.ID, error) {
 	if id, err := enode.ParseID(nodeStr); err == nil {
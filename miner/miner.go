@@ -51,6 +51,9 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in etdash).
+
+	Ordering       string `toml:",omitempty"` // Transaction ordering strategy used to fill blocks (default = price)
+	OrderingPlugin string `toml:",omitempty"` // Go plugin exporting a custom transaction ordering strategy
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -194,6 +197,11 @@ func (miner *Miner) PendingBlock() *types.Block {
 	return miner.worker.pendingBlock()
 }
 
+// PendingBlockAndReceipts returns the currently pending block and corresponding receipts.
+func (miner *Miner) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	return miner.worker.pendingBlockAndReceipts()
+}
+
 func (miner *Miner) SetEtherbase(addr common.Address) {
 	miner.coinbase = addr
 	miner.worker.setEtherbase(addr)
@@ -0,0 +1,257 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+)
+
+const (
+	// OrderingPrice fills blocks by effective miner tip, honouring account
+	// nonces, with transactions from local accounts committed first.
+	OrderingPrice = "price"
+
+	// OrderingFCFS fills blocks in the order transactions were first seen
+	// locally, honouring account nonces. Local accounts get no priority.
+	OrderingFCFS = "fcfs"
+)
+
+// errNoOrderingSymbol is returned if an ordering plugin doesn't export the
+// expected strategy symbol.
+var errNoOrderingSymbol = errors.New("plugin does not export an ordering strategy")
+
+// TransactionSet is a nonce-honouring iterator over the candidate transactions
+// of a block. The worker keeps committing the transaction returned by Peek,
+// calling Shift to move on to the next transaction of the same account if it
+// succeeded, or Pop to drop the remaining transactions of the account if it
+// did not. types.TransactionsByPriceAndNonce satisfies this interface.
+type TransactionSet interface {
+	// Peek returns the next transaction to commit, or nil if the set is exhausted.
+	Peek() *types.Transaction
+
+	// Shift replaces the current head with the next transaction of the same account.
+	Shift()
+
+	// Pop removes the current head without replacing it with the next one from
+	// the same account.
+	Pop()
+}
+
+// OrderingStrategy decides in which order pending transactions are offered to
+// the worker when filling a block.
+//
+// Note, the transaction maps are reowned by the strategy, which is free to
+// modify them.
+type OrderingStrategy interface {
+	// Order returns the transaction sets to commit, in sequence. Both maps hold
+	// the nonce-sorted pending transactions of each account, split by whether
+	// the account is tracked as local by the transaction pool. The base fee is
+	// nil for pre-London blocks.
+	Order(signer types.Signer, locals, remotes map[common.Address]types.Transactions, baseFee *big.Int) []TransactionSet
+}
+
+var (
+	orderingLock       sync.RWMutex
+	orderingStrategies = map[string]OrderingStrategy{
+		OrderingPrice: &priceOrdering{},
+		OrderingFCFS:  &fcfsOrdering{},
+	}
+)
+
+// RegisterOrdering makes an ordering strategy available under the given name,
+// so it can be selected through Config.Ordering. Registering a name twice, or
+// one of the built-in names, panics.
+func RegisterOrdering(name string, strategy OrderingStrategy) {
+	orderingLock.Lock()
+	defer orderingLock.Unlock()
+
+	if _, ok := orderingStrategies[name]; ok {
+		panic(fmt.Sprintf("miner: ordering strategy %q already registered", name))
+	}
+	orderingStrategies[name] = strategy
+}
+
+// Orderings returns the names of all available ordering strategies.
+func Orderings() []string {
+	orderingLock.RLock()
+	defer orderingLock.RUnlock()
+
+	names := make([]string, 0, len(orderingStrategies))
+	for name := range orderingStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadOrdering resolves the transaction ordering strategy selected by the
+// config. A strategy exported by a Go plugin takes precedence over a named
+// one, and the price ordering is used if neither is configured.
+func LoadOrdering(config *Config) (OrderingStrategy, error) {
+	if config.OrderingPlugin != "" {
+		return loadOrderingPlugin(config.OrderingPlugin)
+	}
+	name := config.Ordering
+	if name == "" {
+		name = OrderingPrice
+	}
+	orderingLock.RLock()
+	strategy, ok := orderingStrategies[name]
+	orderingLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown ordering strategy %q (available: %v)", name, Orderings())
+	}
+	return strategy, nil
+}
+
+// priceOrdering is the default ordering strategy, committing all transactions
+// from local accounts by price, followed by all remote ones by price.
+type priceOrdering struct{}
+
+// Order implements OrderingStrategy.
+func (o *priceOrdering) Order(signer types.Signer, locals, remotes map[common.Address]types.Transactions, baseFee *big.Int) []TransactionSet {
+	var sets []TransactionSet
+	if len(locals) > 0 {
+		sets = append(sets, types.NewTransactionsByPriceAndNonce(signer, locals, baseFee))
+	}
+	if len(remotes) > 0 {
+		sets = append(sets, types.NewTransactionsByPriceAndNonce(signer, remotes, baseFee))
+	}
+	return sets
+}
+
+// fcfsOrdering commits transactions in the order they arrived, regardless of
+// their price or origin.
+type fcfsOrdering struct{}
+
+// Order implements OrderingStrategy.
+func (o *fcfsOrdering) Order(signer types.Signer, locals, remotes map[common.Address]types.Transactions, baseFee *big.Int) []TransactionSet {
+	txs := make(map[common.Address]types.Transactions, len(locals)+len(remotes))
+	for from, accTxs := range remotes {
+		txs[from] = accTxs
+	}
+	for from, accTxs := range locals {
+		txs[from] = accTxs
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	return []TransactionSet{newTransactionsByTimeAndNonce(signer, txs, baseFee)}
+}
+
+// txsByTime implements the heap interface, ordering transactions by the time
+// they were first seen locally.
+type txsByTime types.Transactions
+
+func (s txsByTime) Len() int { return len(s) }
+func (s txsByTime) Less(i, j int) bool {
+	// If the arrival times are equal, use the hash for deterministic sorting
+	if ti, tj := s[i].Time(), s[j].Time(); !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	hi, hj := s[i].Hash(), s[j].Hash()
+	return bytes.Compare(hi[:], hj[:]) < 0
+}
+func (s txsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// transactionsByTimeAndNonce represents a set of transactions that can return
+// transactions in arrival order, while supporting removing entire batches of
+// transactions for non-executable accounts.
+type transactionsByTimeAndNonce struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads   txsByTime                             // Next transaction for each unique account (time heap)
+	signer  types.Signer                          // Signer for the set of transactions
+	baseFee *big.Int                              // Current base fee
+}
+
+// newTransactionsByTimeAndNonce creates a transaction set that can retrieve
+// arrival time sorted transactions in a nonce-honouring way. Transactions not
+// paying the base fee are dropped along with the rest of their account.
+func newTransactionsByTimeAndNonce(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) *transactionsByTimeAndNonce {
+	heads := make(txsByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		acc, _ := types.Sender(signer, accTxs[0])
+		if acc != from || !paysBaseFee(accTxs[0], baseFee) {
+			delete(txs, from)
+			continue
+		}
+		heads = append(heads, accTxs[0])
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &transactionsByTimeAndNonce{
+		txs:     txs,
+		heads:   heads,
+		signer:  signer,
+		baseFee: baseFee,
+	}
+}
+
+// Peek returns the earliest seen transaction.
+func (t *transactionsByTimeAndNonce) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0]
+}
+
+// Shift replaces the current head with the next one from the same account.
+func (t *transactionsByTimeAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 && paysBaseFee(txs[0], t.baseFee) {
+		t.heads[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+		return
+	}
+	heap.Pop(&t.heads)
+}
+
+// Pop removes the current head, *not* replacing it with the next one from the
+// same account.
+func (t *transactionsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// paysBaseFee reports whether the transaction's effective miner tip is
+// non-negative under the given base fee.
+func paysBaseFee(tx *types.Transaction, baseFee *big.Int) bool {
+	_, err := tx.EffectiveGasTip(baseFee)
+	return err == nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !linux,!darwin !cgo
+
+package miner
+
+import "errors"
+
+// loadOrderingPlugin reports that Go plugins are not supported on this platform.
+func loadOrderingPlugin(path string) (OrderingStrategy, error) {
+	return nil, errors.New("ordering plugins are not supported on this platform")
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux,cgo darwin,cgo
+
+package miner
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// orderingPluginSymbol is the name of the variable a Go plugin must export to
+// provide a custom ordering strategy, e.g.
+//
+//	var Ordering miner.OrderingStrategy = &myOrdering{}
+const orderingPluginSymbol = "Ordering"
+
+// loadOrderingPlugin opens the Go plugin at the given path and retrieves the
+// ordering strategy it exports.
+func loadOrderingPlugin(path string) (OrderingStrategy, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(orderingPluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch strategy := sym.(type) {
+	case *OrderingStrategy:
+		if *strategy == nil {
+			return nil, errNoOrderingSymbol
+		}
+		return *strategy, nil
+	case OrderingStrategy:
+		return strategy, nil
+	default:
+		return nil, fmt.Errorf("%w: symbol %s has type %T", errNoOrderingSymbol, orderingPluginSymbol, sym)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/core/types"
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/params"
+)
+
+// Tests that the first-come-first-served ordering returns transactions in
+// arrival order, while still honouring the nonces of each account.
+func TestFCFSOrdering(t *testing.T) {
+	var (
+		signer    = types.LatestSigner(params.TestChainConfig)
+		keyA, _   = crypto.GenerateKey()
+		keyB, _   = crypto.GenerateKey()
+		addrA     = crypto.PubkeyToAddress(keyA.PublicKey)
+		addrB     = crypto.PubkeyToAddress(keyB.PublicKey)
+		remotes   = make(map[common.Address]types.Transactions)
+		locals    = make(map[common.Address]types.Transactions)
+		arrivals  []*types.Transaction
+		nextNonce = make(map[common.Address]uint64)
+	)
+	// Interleave the arrivals of both accounts, pricing the later ones higher
+	// to ensure the price doesn't influence the ordering.
+	for i, key := range []*ecdsa.PrivateKey{keyA, keyB, keyA, keyB, keyA} {
+		from := crypto.PubkeyToAddress(key.PublicKey)
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nextNonce[from],
+			To:       &common.Address{},
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(int64(i + 1)),
+		})
+		nextNonce[from]++
+		arrivals = append(arrivals, tx)
+		if from == addrA {
+			locals[from] = append(locals[from], tx)
+		} else {
+			remotes[from] = append(remotes[from], tx)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sets := new(fcfsOrdering).Order(signer, locals, remotes, nil)
+	if len(sets) != 1 {
+		t.Fatalf("transaction set count mismatch: have %d, want %d", len(sets), 1)
+	}
+	var txs []*types.Transaction
+	for tx := sets[0].Peek(); tx != nil; tx = sets[0].Peek() {
+		txs = append(txs, tx)
+		sets[0].Shift()
+	}
+	if len(txs) != len(arrivals) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), len(arrivals))
+	}
+	for i, tx := range txs {
+		if tx.Hash() != arrivals[i].Hash() {
+			t.Errorf("transaction %d: order mismatch: have %x, want %x", i, tx.Hash(), arrivals[i].Hash())
+		}
+	}
+	// Popping an account must drop all its remaining transactions
+	sets = new(fcfsOrdering).Order(signer, map[common.Address]types.Transactions{
+		addrA: {arrivals[0], arrivals[2], arrivals[4]},
+		addrB: {arrivals[1], arrivals[3]},
+	}, nil, nil)
+	sets[0].Pop()
+	txs = txs[:0]
+	for tx := sets[0].Peek(); tx != nil; tx = sets[0].Peek() {
+		txs = append(txs, tx)
+		sets[0].Shift()
+	}
+	if len(txs) != 2 || txs[0] != arrivals[1] || txs[1] != arrivals[3] {
+		t.Errorf("popped account not dropped: have %d transactions", len(txs))
+	}
+}
+
+// Tests that ordering strategies are resolved from the config.
+func TestLoadOrdering(t *testing.T) {
+	if ordering, err := LoadOrdering(&Config{}); err != nil {
+		t.Fatalf("failed to load default ordering: %v", err)
+	} else if _, ok := ordering.(*priceOrdering); !ok {
+		t.Errorf("default ordering mismatch: have %T, want %T", ordering, new(priceOrdering))
+	}
+	if ordering, err := LoadOrdering(&Config{Ordering: OrderingFCFS}); err != nil {
+		t.Fatalf("failed to load fcfs ordering: %v", err)
+	} else if _, ok := ordering.(*fcfsOrdering); !ok {
+		t.Errorf("fcfs ordering mismatch: have %T, want %T", ordering, new(fcfsOrdering))
+	}
+	if _, err := LoadOrdering(&Config{Ordering: "nonexistent"}); err == nil {
+		t.Errorf("unknown ordering loaded")
+	}
+	custom := new(fcfsOrdering)
+	registerTestOrdering(t, "test-load", custom)
+	if ordering, err := LoadOrdering(&Config{Ordering: "test-load"}); err != nil {
+		t.Fatalf("failed to load registered ordering: %v", err)
+	} else if ordering != custom {
+		t.Errorf("registered ordering mismatch")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("duplicate registration didn't panic")
+		}
+	}()
+	RegisterOrdering(OrderingPrice, custom)
+}
+
+// registerTestOrdering registers an ordering strategy for the duration of a test.
+func registerTestOrdering(t *testing.T, name string, strategy OrderingStrategy) {
+	RegisterOrdering(name, strategy)
+	t.Cleanup(func() {
+		orderingLock.Lock()
+		defer orderingLock.Unlock()
+		delete(orderingStrategies, name)
+	})
+}
@@ -194,9 +194,13 @@ func (b *testWorkerBackend) newRandomTx(creation bool) *types.Transaction {
 }
 
 func newTestWorker(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, db etddb.Database, blocks int) (*worker, *testWorkerBackend) {
+	return newTestWorkerWithConfig(t, testConfig, chainConfig, engine, db, blocks)
+}
+
+func newTestWorkerWithConfig(t *testing.T, config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, db etddb.Database, blocks int) (*worker, *testWorkerBackend) {
 	backend := newTestWorkerBackend(t, chainConfig, engine, db, blocks)
 	backend.txPool.AddLocals(pendingTxs)
-	w := newWorker(testConfig, chainConfig, engine, backend, new(event.TypeMux), nil, false)
+	w := newWorker(config, chainConfig, engine, backend, new(event.TypeMux), nil, false)
 	w.setEtherbase(testBankAddress)
 	return w, backend
 }
@@ -520,3 +524,61 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+// countingOrdering is an ordering strategy which counts how many times it was
+// asked to order transactions, delegating the actual ordering.
+type countingOrdering struct {
+	OrderingStrategy
+	calls int32
+}
+
+func (o *countingOrdering) Order(signer types.Signer, locals, remotes map[common.Address]types.Transactions, baseFee *big.Int) []TransactionSet {
+	atomic.AddInt32(&o.calls, 1)
+	return o.OrderingStrategy.Order(signer, locals, remotes, baseFee)
+}
+
+func TestOrderingStrategyEthash(t *testing.T) {
+	testOrderingStrategy(t, etdashChainConfig, etdash.NewFaker())
+}
+
+func TestOrderingStrategyClique(t *testing.T) {
+	testOrderingStrategy(t, cliqueChainConfig, clique.New(cliqueChainConfig.Clique, rawdb.NewMemoryDatabase()))
+}
+
+func testOrderingStrategy(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine) {
+	defer engine.Close()
+
+	// Select the strategy through the config, as the worker starts using it
+	// right away
+	ordering := &countingOrdering{OrderingStrategy: new(fcfsOrdering)}
+	registerTestOrdering(t, "test-"+t.Name(), ordering)
+
+	config := *testConfig
+	config.Ordering = "test-" + t.Name()
+	w, _ := newTestWorkerWithConfig(t, &config, chainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var taskCh = make(chan *task, 2)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.receipts) > 0 {
+			taskCh <- task
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+	w.fullTaskHook = func() {
+		time.Sleep(100 * time.Millisecond)
+	}
+	w.start()
+
+	select {
+	case task := <-taskCh:
+		if len(task.receipts) != len(pendingTxs) {
+			t.Errorf("receipt number mismatch: have %d, want %d", len(task.receipts), len(pendingTxs))
+		}
+		if calls := atomic.LoadInt32(&ordering.calls); calls == 0 {
+			t.Errorf("ordering strategy not consulted")
+		}
+	case <-time.NewTimer(3 * time.Second).C:
+		t.Error("new task timeout")
+	}
+}
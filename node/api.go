@@ -19,6 +19,7 @@ package node
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/crypyto-panel/go-etherdata/common/hexutil"
@@ -26,6 +27,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/internal/debug"
 	"github.com/crypyto-panel/go-etherdata/log"
 	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/dnsdisc"
 	"github.com/crypyto-panel/go-etherdata/p2p/enode"
 	"github.com/crypyto-panel/go-etherdata/rpc"
 )
@@ -61,9 +63,31 @@ type privateAdminAPI struct {
 	node *Node // Node interfaced by this API
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *privateAdminAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"addPeer":            {Summary: "Requests connecting to a remote node.", Params: []string{"url", "persist"}},
+		"removePeer":         {Summary: "Disconnects from a remote node.", Params: []string{"url", "persist"}},
+		"listStaticPeers":    {Summary: "Returns the static peers added through the admin API."},
+		"addTrustedPeer":     {Summary: "Allows a remote node to always connect, even if slots are full.", Params: []string{"url", "persist"}},
+		"removeTrustedPeer":  {Summary: "Removes a remote node from the trusted peer set.", Params: []string{"url", "persist"}},
+		"addDNSDiscovery":    {Summary: "Adds a DNS discovery tree as a source of peers.", Params: []string{"url", "persist"}},
+		"removeDNSDiscovery": {Summary: "Removes a DNS discovery tree.", Params: []string{"url", "persist"}},
+		"listDNSDiscovery":   {Summary: "Returns the DNS discovery trees added through the admin API."},
+		"peerEvents":         {Summary: "Subscribes to the peer connection and message events."},
+		"startHTTP":          {Summary: "Starts the HTTP RPC API server.", Params: []string{"host", "port", "cors", "apis", "vhosts"}},
+		"startRPC":           {Summary: "Deprecated alias of startHTTP.", Params: []string{"host", "port", "cors", "apis", "vhosts"}},
+		"stopHTTP":           {Summary: "Stops the HTTP RPC API server."},
+		"stopRPC":            {Summary: "Deprecated alias of stopHTTP."},
+		"startWS":            {Summary: "Starts the WebSocket RPC API server.", Params: []string{"host", "port", "allowedOrigins", "apis"}},
+		"stopWS":             {Summary: "Stops the WebSocket RPC API server."},
+	}
+}
+
 // AddPeer requests connecting to a remote node, and also maintaining the new
-// connection at all times, even reconnecting if it is lost.
-func (api *privateAdminAPI) AddPeer(url string) (bool, error) {
+// connection at all times, even reconnecting if it is lost. If persist is set,
+// the peer is also restored when the node restarts.
+func (api *privateAdminAPI) AddPeer(url string, persist *bool) (bool, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
 	if server == nil {
@@ -74,12 +98,18 @@ func (api *privateAdminAPI) AddPeer(url string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
+	if persist != nil && *persist {
+		if err := api.node.peers.addNode(staticPeerList, node); err != nil {
+			return false, err
+		}
+	}
 	server.AddPeer(node)
 	return true, nil
 }
 
-// RemovePeer disconnects from a remote node if the connection exists
-func (api *privateAdminAPI) RemovePeer(url string) (bool, error) {
+// RemovePeer disconnects from a remote node if the connection exists. If persist
+// is set, the peer is also removed from the persisted static peers.
+func (api *privateAdminAPI) RemovePeer(url string, persist *bool) (bool, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
 	if server == nil {
@@ -90,12 +120,35 @@ func (api *privateAdminAPI) RemovePeer(url string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
+	if persist != nil && *persist {
+		if err := api.node.peers.removeNode(staticPeerList, node); err != nil {
+			return false, err
+		}
+	}
 	server.RemovePeer(node)
 	return true, nil
 }
 
-// AddTrustedPeer allows a remote node to always connect, even if slots are full
-func (api *privateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+// ListStaticPeers returns the nodes the server keeps connected at all times,
+// whether they are currently connected or not.
+func (api *privateAdminAPI) ListStaticPeers() ([]string, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	nodes := server.StaticPeers()
+	urls := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		urls = append(urls, node.String())
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// AddTrustedPeer allows a remote node to always connect, even if slots are full.
+// If persist is set, the peer is also trusted after the node restarts.
+func (api *privateAdminAPI) AddTrustedPeer(url string, persist *bool) (bool, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
 	if server == nil {
@@ -105,13 +158,19 @@ func (api *privateAdminAPI) AddTrustedPeer(url string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
+	if persist != nil && *persist {
+		if err := api.node.peers.addNode(trustedPeerList, node); err != nil {
+			return false, err
+		}
+	}
 	server.AddTrustedPeer(node)
 	return true, nil
 }
 
 // RemoveTrustedPeer removes a remote node from the trusted peer set, but it
-// does not disconnect it automatically.
-func (api *privateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+// does not disconnect it automatically. If persist is set, the peer is also
+// removed from the persisted trusted peers.
+func (api *privateAdminAPI) RemoveTrustedPeer(url string, persist *bool) (bool, error) {
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
 	if server == nil {
@@ -121,10 +180,62 @@ func (api *privateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("invalid enode: %v", err)
 	}
+	if persist != nil && *persist {
+		if err := api.node.peers.removeNode(trustedPeerList, node); err != nil {
+			return false, err
+		}
+	}
 	server.RemoveTrustedPeer(node)
 	return true, nil
 }
 
+// AddDNSDiscovery starts using the nodes of an ENR tree (enrtree:// URL) as dial
+// candidates. If persist is set, the tree is also used after the node restarts.
+func (api *privateAdminAPI) AddDNSDiscovery(url string, persist *bool) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	if server := api.node.Server(); server == nil {
+		return false, ErrNodeStopped
+	}
+	if _, _, err := dnsdisc.ParseURL(url); err != nil {
+		return false, fmt.Errorf("invalid ENR tree: %v", err)
+	}
+	if err := api.node.addDNSTree(url); err != nil {
+		return false, err
+	}
+	// Only persist trees which could actually be used, dropping the tree again
+	// if it can't be persisted to keep the two in sync
+	if persist != nil && *persist {
+		if err := api.node.peers.addURL(dnsTreeList, url); err != nil {
+			api.node.removeDNSTree(url)
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// RemoveDNSDiscovery stops using an ENR tree as a source of dial candidates.
+// If persist is set, the tree is also removed from the persisted trees.
+func (api *privateAdminAPI) RemoveDNSDiscovery(url string, persist *bool) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	if server := api.node.Server(); server == nil {
+		return false, ErrNodeStopped
+	}
+	if persist != nil && *persist {
+		if err := api.node.peers.removeURL(dnsTreeList, url); err != nil {
+			return false, err
+		}
+	}
+	if err := api.node.removeDNSTree(url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListDNSDiscovery returns the ENR trees added through the admin API.
+func (api *privateAdminAPI) ListDNSDiscovery() []string {
+	return api.node.dnsTreeURLs()
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *privateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -185,6 +296,8 @@ func (api *privateAdminAPI) StartHTTP(host *string, port *int, cors *string, api
 		CorsAllowedOrigins: api.node.config.HTTPCors,
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
+		jwtSecret:          api.node.jwtSecret,
+		limiter:            api.node.rpcLimiter,
 	}
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
@@ -256,8 +369,10 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 
 	// Determine config.
 	config := wsConfig{
-		Modules: api.node.config.WSModules,
-		Origins: api.node.config.WSOrigins,
+		Modules:   api.node.config.WSModules,
+		Origins:   api.node.config.WSOrigins,
+		jwtSecret: api.node.jwtSecret,
+		limiter:   api.node.rpcLimiter,
 		// ExposeAll: api.node.config.WSExposeAll,
 	}
 	if apis != nil {
@@ -301,6 +416,15 @@ type publicAdminAPI struct {
 	node *Node // Node interfaced by this API
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (api *publicAdminAPI) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"peers":    {Summary: "Returns information about the connected peers."},
+		"nodeInfo": {Summary: "Returns information about the running node."},
+		"datadir":  {Summary: "Returns the data directory of the node."},
+	}
+}
+
 // Peers retrieves all the information we know about each individual peer at the
 // protocol granularity.
 func (api *publicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
@@ -331,6 +455,14 @@ type publicWeb3API struct {
 	stack *Node
 }
 
+// RPCDescriptions implements rpc.Describer.
+func (s *publicWeb3API) RPCDescriptions() map[string]rpc.MethodDoc {
+	return map[string]rpc.MethodDoc{
+		"clientVersion": {Summary: "Returns the version of the node software."},
+		"sha3":          {Summary: "Returns the Keccak-256 hash of the input.", Params: []string{"input"}},
+	}
+}
+
 // ClientVersion returns the node name
 func (s *publicWeb3API) ClientVersion() string {
 	return s.stack.Server().Name
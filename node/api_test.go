@@ -18,6 +18,7 @@ package node
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"io"
 	"net"
 	"net/http"
@@ -25,6 +26,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/crypyto-panel/go-etherdata/crypto"
+	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
 	"github.com/crypyto-panel/go-etherdata/rpc"
 	"github.com/stretchr/testify/assert"
 )
@@ -296,6 +300,159 @@ func TestStartRPC(t *testing.T) {
 	}
 }
 
+// Tests that peers and DNS discovery trees added through the admin API with
+// persistence requested are restored when the node restarts.
+func TestPersistPeers(t *testing.T) {
+	var (
+		datadir = t.TempDir()
+		static  = enode.NewV4(&newTestKey(t).PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+		trusted = enode.NewV4(&newTestKey(t).PublicKey, net.IP{10, 0, 0, 2}, 30303, 30303)
+		other   = enode.NewV4(&newTestKey(t).PublicKey, net.IP{10, 0, 0, 3}, 30303, 30303)
+		tree    = "enrtree://AKA3AM6LPBYEUDMVNU3BSVQJ5AD45Y7YPOHJLEF6W26QOE4VTUDPE@nodes.example.org"
+		yes     = true
+	)
+	startNode := func() (*Node, *privateAdminAPI) {
+		stack, err := New(&Config{DataDir: datadir, P2P: p2p.Config{NoDiscovery: true, MaxPeers: 1}})
+		if err != nil {
+			t.Fatalf("can't create node: %v", err)
+		}
+		if err := stack.Start(); err != nil {
+			t.Fatalf("can't start node: %v", err)
+		}
+		return stack, &privateAdminAPI{stack}
+	}
+	stack, api := startNode()
+
+	_, err := api.AddPeer(static.String(), &yes)
+	assert.NoError(t, err)
+	_, err = api.AddPeer(other.String(), nil)
+	assert.NoError(t, err)
+	_, err = api.AddTrustedPeer(trusted.String(), &yes)
+	assert.NoError(t, err)
+	_, err = api.AddDNSDiscovery(tree, &yes)
+	assert.NoError(t, err)
+	_, err = api.AddDNSDiscovery(tree, nil)
+	assert.Equal(t, errDNSTreeKnown, err)
+
+	peers, err := api.ListStaticPeers()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{static.String(), other.String()}, peers)
+	assert.Equal(t, []string{tree}, api.ListDNSDiscovery())
+	stack.Close()
+
+	// Only the persisted peers should be restored after a restart
+	stack, api = startNode()
+
+	assert.Equal(t, []*enode.Node{static}, stack.server.Config.StaticNodes)
+	assert.Equal(t, []*enode.Node{trusted}, stack.server.Config.TrustedNodes)
+	assert.Equal(t, []string{tree}, api.ListDNSDiscovery())
+
+	_, err = api.RemovePeer(static.String(), &yes)
+	assert.NoError(t, err)
+	_, err = api.RemoveTrustedPeer(trusted.String(), &yes)
+	assert.NoError(t, err)
+	_, err = api.RemoveDNSDiscovery(tree, &yes)
+	assert.NoError(t, err)
+
+	peers, err = api.ListStaticPeers()
+	assert.NoError(t, err)
+	assert.Empty(t, peers)
+	assert.Empty(t, api.ListDNSDiscovery())
+	stack.Close()
+
+	stack, _ = startNode()
+	defer stack.Close()
+
+	assert.Empty(t, stack.server.Config.StaticNodes)
+	assert.Empty(t, stack.server.Config.TrustedNodes)
+	assert.Empty(t, stack.dnsTreeURLs())
+}
+
+// Tests that DNS discovery trees are only persisted if they could be added.
+func TestPersistDNSTreeFailure(t *testing.T) {
+	stack, err := New(&Config{DataDir: t.TempDir(), P2P: p2p.Config{NoDiscovery: true}})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	defer stack.Close()
+
+	var (
+		api  = &privateAdminAPI{stack}
+		tree = "enrtree://AKA3AM6LPBYEUDMVNU3BSVQJ5AD45Y7YPOHJLEF6W26QOE4VTUDPE@nodes.example.org"
+		yes  = true
+	)
+	_, err = api.AddDNSDiscovery(tree, nil)
+	assert.NoError(t, err)
+	_, err = api.AddDNSDiscovery(tree, &yes)
+	assert.Equal(t, errDNSTreeKnown, err)
+	assert.Empty(t, stack.peers.urls(dnsTreeList))
+}
+
+// Tests that persistence is refused for nodes without a data directory.
+func TestPersistPeersEphemeral(t *testing.T) {
+	stack, err := New(&Config{P2P: p2p.Config{NoDiscovery: true}})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	defer stack.Close()
+
+	var (
+		api  = &privateAdminAPI{stack}
+		node = enode.NewV4(&newTestKey(t).PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+		yes  = true
+	)
+	_, err = api.AddPeer(node.String(), &yes)
+	assert.Equal(t, errNoPeerStore, err)
+
+	peers, err := api.ListStaticPeers()
+	assert.NoError(t, err)
+	assert.Empty(t, peers)
+}
+
+// Tests that the methods of the node's own namespaces are documented in the
+// OpenRPC document.
+func TestDiscoverDescriptions(t *testing.T) {
+	stack, err := New(&Config{P2P: p2p.Config{NoDiscovery: true}})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	defer stack.Close()
+
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("can't attach to node: %v", err)
+	}
+	defer client.Close()
+
+	var doc rpc.OpenRPCDocument
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatalf("can't discover methods: %v", err)
+	}
+	methods := append(doc.Methods, doc.Subscriptions...)
+	for _, method := range methods {
+		if (strings.HasPrefix(method.Name, "admin_") || strings.HasPrefix(method.Name, "web3_")) && method.Summary == "" {
+			t.Errorf("method %s is undocumented", method.Name)
+		}
+	}
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
 // checkReachable checks if the TCP endpoint in rawurl is open.
 func checkReachable(rawurl string) bool {
 	u, err := url.Parse(rawurl)
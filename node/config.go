@@ -45,6 +45,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirAdminPeers      = "admin-peers.json"   // Path within the datadir to the peers persisted through the admin API
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -171,6 +172,18 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// JWTSecret is the path to a file holding the hex encoded HS256 secret which
+	// authenticates HTTP and WebSocket RPC requests. Requests must carry a bearer
+	// token signed with it and issued within the last minute, whose claims may
+	// restrict the callable namespaces and methods. A new secret is generated if
+	// the file doesn't exist.
+	JWTSecret string `toml:",omitempty"`
+
+	// RPCLimits configures the batch size, response size and per-client rate
+	// limits imposed on requests served over HTTP and WebSocket. A client's
+	// requests over both transports are charged to the same rate budget.
+	RPCLimits rpc.Limits `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
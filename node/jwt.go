@@ -0,0 +1,197 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/hexutil"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+const (
+	// jwtSecretLength is the length of the HS256 secret in bytes.
+	jwtSecretLength = 32
+
+	// jwtClockSkew is the tolerated drift between the clocks of the token issuer
+	// and the node.
+	jwtClockSkew = 5 * time.Second
+
+	// jwtMaxAge is the maximum time since a token was issued for it to be
+	// accepted, limiting the damage of leaked tokens.
+	jwtMaxAge = time.Minute
+)
+
+var (
+	errMissingToken       = errors.New("missing bearer token")
+	errMalformedToken     = errors.New("malformed token")
+	errUnsupportedAlg     = errors.New("unsupported signing algorithm")
+	errInvalidSignature   = errors.New("invalid token signature")
+	errMissingIssuedAt    = errors.New("missing issuance time")
+	errTokenExpired       = errors.New("token expired")
+	errTokenFromTheFuture = errors.New("token issued in the future")
+)
+
+// jwtHeader is the JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jwtClaims are the token claims understood by the node. Tokens must carry their
+// issuance time and are only accepted for jwtMaxAge after it, or until their
+// expiry if earlier. Tokens without a permission claim may call any method
+// exposed on the endpoint, otherwise the claim lists the namespaces (e.g. "etd")
+// and methods (e.g. "admin_peers") granted. GraphQL is only served to tokens
+// granted the "graphql" namespace. The subject, if set, identifies the client
+// for rate limiting.
+type jwtClaims struct {
+	Subject     string   `json:"sub,omitempty"`
+	IssuedAt    *int64   `json:"iat,omitempty"`
+	Expiry      *int64   `json:"exp,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// obtainJWTSecret loads the hex encoded HS256 secret from the given file,
+// generating a new random one if the file doesn't exist yet.
+func obtainJWTSecret(path string) ([]byte, error) {
+	if data, err := ioutil.ReadFile(path); err == nil {
+		secret := common.FromHex(strings.TrimSpace(string(data)))
+		if len(secret) != jwtSecretLength {
+			return nil, fmt.Errorf("invalid JWT secret in %s: want %d bytes, have %d", path, jwtSecretLength, len(secret))
+		}
+		log.Info("Loaded JWT secret file", "path", path)
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	secret := make([]byte, jwtSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
+// jwtHandler authenticates HTTP requests carrying an HS256 signed bearer token,
+// attaching the RPC permissions granted by the token to the request context.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// newJWTHandler wraps the given handler with token authentication.
+func newJWTHandler(secret []byte, next http.Handler) http.Handler {
+	return &jwtHandler{secret: secret, next: next}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		http.Error(w, errMissingToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	claims, err := verifyJWT(h.secret, strings.TrimPrefix(auth, "Bearer "), time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid token: %v", err), http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	if claims.Permissions != nil {
+		ctx = rpc.WithPermissions(ctx, rpc.NewPermissions(claims.Permissions))
+	}
+	if claims.Subject != "" {
+		ctx = rpc.WithClientIdentity(ctx, claims.Subject)
+	}
+	h.next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// verifyJWT checks the signature and validity period of a compact serialized
+// token, returning its claims.
+func verifyJWT(secret []byte, token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "HS256" {
+		return nil, errUnsupportedAlg
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidSignature
+	}
+	claims := new(jwtClaims)
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt == nil {
+		return nil, errMissingIssuedAt
+	}
+	issued := time.Unix(*claims.IssuedAt, 0)
+	if issued.After(now.Add(jwtClockSkew)) {
+		return nil, errTokenFromTheFuture
+	}
+	if !now.Before(issued.Add(jwtMaxAge + jwtClockSkew)) {
+		return nil, errTokenExpired
+	}
+	if claims.Expiry != nil && !now.Before(time.Unix(*claims.Expiry, 0).Add(jwtClockSkew)) {
+		return nil, errTokenExpired
+	}
+	return claims, nil
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment of a token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errMalformedToken
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/internal/testlog"
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// jwtTestService is exposed under multiple namespaces to check permissions.
+type jwtTestService struct{}
+
+func (s *jwtTestService) Echo(str string) string { return str }
+
+// makeJWT creates an HS256 token with the given claims.
+func makeJWT(t *testing.T, secret []byte, claims interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signing))
+	return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func createAndStartJWTServer(t *testing.T) *httpServer {
+	t.Helper()
+
+	apis := []rpc.API{
+		{Namespace: "test", Service: new(jwtTestService), Public: true},
+		{Namespace: "other", Service: new(jwtTestService), Public: true},
+	}
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	assert.NoError(t, srv.enableRPC(apis, httpConfig{jwtSecret: testJWTSecret}))
+	assert.NoError(t, srv.enableWS(apis, wsConfig{Origins: []string{"*"}, jwtSecret: testJWTSecret}))
+	assert.NoError(t, srv.setListenAddr("localhost", 0))
+	assert.NoError(t, srv.start())
+	return srv
+}
+
+// TestJWTAuthentication checks that requests without a valid token are rejected.
+func TestJWTAuthentication(t *testing.T) {
+	srv := createAndStartJWTServer(t)
+	defer srv.stop()
+	url := "http://" + srv.listenAddr()
+
+	var (
+		now     = time.Now().Unix()
+		past    = now - 3600
+		future  = now + 3600
+		stale   = now - int64((jwtMaxAge+jwtClockSkew)/time.Second) - 1
+		valid   = makeJWT(t, testJWTSecret, jwtClaims{IssuedAt: &now})
+		expired = makeJWT(t, testJWTSecret, jwtClaims{IssuedAt: &now, Expiry: &past})
+		early   = makeJWT(t, testJWTSecret, jwtClaims{IssuedAt: &future})
+		old     = makeJWT(t, testJWTSecret, jwtClaims{IssuedAt: &stale, Expiry: &future})
+		undated = makeJWT(t, testJWTSecret, jwtClaims{Expiry: &future})
+		forged  = makeJWT(t, []byte("bad secret"), jwtClaims{IssuedAt: &now})
+	)
+	tests := []struct {
+		auth   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer ", http.StatusUnauthorized},
+		{"Bearer not.a.token", http.StatusUnauthorized},
+		{"Bearer " + forged, http.StatusUnauthorized},
+		{"Bearer " + expired, http.StatusUnauthorized},
+		{"Bearer " + early, http.StatusUnauthorized},
+		{"Bearer " + old, http.StatusUnauthorized},
+		{"Bearer " + undated, http.StatusUnauthorized},
+		{"Basic " + valid, http.StatusUnauthorized},
+		{"Bearer " + valid, http.StatusOK},
+	}
+	for i, tt := range tests {
+		var resp *http.Response
+		if tt.auth == "" {
+			resp = rpcRequest(t, url)
+		} else {
+			resp = rpcRequest(t, url, "Authorization", tt.auth)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+	}
+	// WebSocket connections are authenticated during the handshake
+	wsURL := "ws://" + srv.listenAddr()
+	if _, err := rpc.DialWebsocketWithHeader(context.Background(), wsURL, "", http.Header{"Authorization": {"Bearer " + forged}}); err == nil {
+		t.Errorf("websocket dial succeeded with forged token")
+	}
+	client, err := rpc.DialWebsocketWithHeader(context.Background(), wsURL, "", http.Header{"Authorization": {"Bearer " + valid}})
+	if err != nil {
+		t.Fatalf("websocket dial failed with valid token: %v", err)
+	}
+	client.Close()
+}
+
+// TestJWTPreflight checks that CORS preflight requests, which never carry
+// credentials, are answered without a token.
+func TestJWTPreflight(t *testing.T) {
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	assert.NoError(t, srv.enableRPC(nil, httpConfig{CorsAllowedOrigins: []string{"*"}, jwtSecret: testJWTSecret}))
+	srv.mux.Handle("/mounted", NewHTTPHandlerStack(http.NotFoundHandler(), []string{"*"}, nil, testJWTSecret))
+	assert.NoError(t, srv.setListenAddr("localhost", 0))
+	assert.NoError(t, srv.start())
+	defer srv.stop()
+
+	for _, path := range []string{"/", "/mounted"} {
+		url := "http://" + srv.listenAddr() + path
+		req, _ := http.NewRequest(http.MethodOptions, url, nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Authorization")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: preflight request failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: preflight status mismatch: have %d, want %d", path, resp.StatusCode, http.StatusOK)
+		}
+		if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin == "" {
+			t.Errorf("%s: preflight not answered by CORS handler", path)
+		}
+		// Actual requests still need a token
+		resp, err = http.Post(url, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: unauthenticated status mismatch: have %d, want %d", path, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+}
+
+// TestJWTPermissions checks that the permission claim restricts the callable
+// namespaces and methods.
+func TestJWTPermissions(t *testing.T) {
+	srv := createAndStartJWTServer(t)
+	defer srv.stop()
+
+	tests := []struct {
+		permissions []string
+		allowed     []string
+		denied      []string
+	}{
+		{nil, []string{"test_echo", "other_echo", "rpc_modules"}, nil},
+		{[]string{}, nil, []string{"test_echo", "other_echo", "rpc_modules"}},
+		{[]string{"*"}, []string{"test_echo", "other_echo"}, nil},
+		{[]string{"test"}, []string{"test_echo"}, []string{"other_echo", "rpc_modules"}},
+		{[]string{"other_echo", "rpc"}, []string{"other_echo", "rpc_modules"}, []string{"test_echo"}},
+	}
+	for i, tt := range tests {
+		claims := map[string]interface{}{"iat": time.Now().Unix()}
+		if tt.permissions != nil {
+			claims["permissions"] = tt.permissions
+		}
+		token := makeJWT(t, testJWTSecret, claims)
+		header := http.Header{"Authorization": {"Bearer " + token}}
+
+		for _, url := range []string{"http://" + srv.listenAddr(), "ws://" + srv.listenAddr()} {
+			var (
+				client *rpc.Client
+				err    error
+			)
+			if strings.HasPrefix(url, "ws") {
+				client, err = rpc.DialWebsocketWithHeader(context.Background(), url, "", header)
+			} else {
+				if client, err = rpc.DialHTTP(url); err == nil {
+					client.SetHeader("Authorization", header.Get("Authorization"))
+				}
+			}
+			if err != nil {
+				t.Fatalf("test %d: can't dial %s: %v", i, url, err)
+			}
+			for _, method := range tt.allowed {
+				var res interface{}
+				if err := client.Call(&res, method, "hello"); err != nil && !strings.Contains(err.Error(), "argument") {
+					t.Errorf("test %d: %s call of %s failed: %v", i, url, method, err)
+				}
+			}
+			for _, method := range tt.denied {
+				var res interface{}
+				err := client.Call(&res, method, "hello")
+				if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32001 {
+					t.Errorf("test %d: %s call of %s not denied: %v", i, url, method, err)
+				}
+			}
+			client.Close()
+		}
+	}
+}
+
+// TestObtainJWTSecret checks that secrets are generated and loaded from disk.
+func TestObtainJWTSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt", "secret")
+
+	generated, err := obtainJWTSecret(path)
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	loaded, err := obtainJWTSecret(path)
+	if err != nil {
+		t.Fatalf("failed to load secret: %v", err)
+	}
+	assert.Equal(t, generated, loaded)
+
+	if err := ioutil.WriteFile(path, []byte("0xdeadbeef"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obtainJWTSecret(path); err == nil {
+		t.Fatalf("short secret accepted")
+	}
+}
@@ -32,6 +32,8 @@ import (
 	"github.com/crypyto-panel/go-etherdata/event"
 	"github.com/crypyto-panel/go-etherdata/log"
 	"github.com/crypyto-panel/go-etherdata/p2p"
+	"github.com/crypyto-panel/go-etherdata/p2p/dnsdisc"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
 	"github.com/crypyto-panel/go-etherdata/rpc"
 	"github.com/prometheus/tsdb/fileutil"
 )
@@ -50,14 +52,22 @@ type Node struct {
 	state         int               // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle  // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API    // List of APIs currently provided by the node
+	http          *httpServer  //
+	ws            *httpServer  //
+	ipc           *ipcServer   // Stores information about the ipc http server
+	inprocHandler *rpc.Server  // In-process RPC request handler to process the API requests
+	jwtSecret     []byte       // Secret authenticating HTTP and WebSocket requests, if enabled
+	rpcLimiter    *rpc.Limiter // Request limits shared by the HTTP and WebSocket servers
 
 	databases map[*closeTrackingDB]struct{} // All open databases
+
+	peers     *peerStore                // Peers and discovery trees persisted through the admin API
+	dnsLock   sync.Mutex                // Protects the runtime DNS discovery fields
+	dnsClient *dnsdisc.Client           // DNS discovery client, created on first use
+	dnsTrees  map[string]enode.Iterator // DNS discovery trees added at runtime
+	dnsFilter func(*enode.Node) bool    // Filter applied to the nodes of runtime DNS trees
 }
 
 const (
@@ -103,6 +113,7 @@ func New(conf *Config) (*Node, error) {
 		stop:          make(chan struct{}),
 		server:        &p2p.Server{Config: conf.P2P},
 		databases:     make(map[*closeTrackingDB]struct{}),
+		dnsTrees:      make(map[string]enode.Iterator),
 	}
 
 	// Register built-in APIs.
@@ -131,6 +142,16 @@ func New(conf *Config) (*Node, error) {
 	if node.server.Config.TrustedNodes == nil {
 		node.server.Config.TrustedNodes = node.config.TrustedNodes()
 	}
+	// Restore the peers persisted through the admin API on top of the configured ones.
+	if node.peers, err = newPeerStore(node.config.ResolvePath(datadirAdminPeers)); err != nil {
+		return nil, err
+	}
+	if static := node.peers.nodes(staticPeerList); len(static) > 0 {
+		node.server.Config.StaticNodes = append(append([]*enode.Node{}, node.server.Config.StaticNodes...), static...)
+	}
+	if trusted := node.peers.nodes(trustedPeerList); len(trusted) > 0 {
+		node.server.Config.TrustedNodes = append(append([]*enode.Node{}, node.server.Config.TrustedNodes...), trusted...)
+	}
 	if node.server.Config.NodeDatabase == "" {
 		node.server.Config.NodeDatabase = node.config.NodeDB()
 	}
@@ -142,8 +163,17 @@ func New(conf *Config) (*Node, error) {
 	if err := validatePrefix("WebSocket", conf.WSPathPrefix); err != nil {
 		return nil, err
 	}
+	// Load the secret authenticating RPC requests, if configured.
+	if conf.JWTSecret != "" {
+		secret, err := obtainJWTSecret(conf.JWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		node.jwtSecret = secret
+	}
 
-	// Configure RPC servers.
+	// Configure RPC servers, sharing the request limits across transports.
+	node.rpcLimiter = rpc.NewLimiter(conf.RPCLimits)
 	node.http = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
@@ -263,6 +293,11 @@ func (n *Node) openEndpoints() error {
 	if err := n.server.Start(); err != nil {
 		return convertFileLockError(err)
 	}
+	for _, url := range n.peers.urls(dnsTreeList) {
+		if err := n.addDNSTree(url); err != nil {
+			n.log.Warn("Failed to restore DNS discovery tree", "url", url, "err", err)
+		}
+	}
 	// start RPC endpoints
 	err := n.startRPC()
 	if err != nil {
@@ -355,6 +390,8 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			jwtSecret:          n.jwtSecret,
+			limiter:            n.rpcLimiter,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -368,9 +405,11 @@ func (n *Node) startRPC() error {
 	if n.config.WSHost != "" {
 		server := n.wsServerForPort(n.config.WSPort)
 		config := wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:   n.config.WSModules,
+			Origins:   n.config.WSOrigins,
+			prefix:    n.config.WSPathPrefix,
+			jwtSecret: n.jwtSecret,
+			limiter:   n.rpcLimiter,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -445,6 +484,18 @@ func (n *Node) RegisterProtocols(protocols []p2p.Protocol) {
 	n.server.Protocols = append(n.server.Protocols, protocols...)
 }
 
+// RegisterDNSFilter sets the filter deciding which nodes of the DNS discovery
+// trees added at runtime are used as dial candidates.
+func (n *Node) RegisterDNSFilter(filter func(*enode.Node) bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.state != initializingState {
+		panic("can't register DNS filter on running/stopped node")
+	}
+	n.dnsFilter = filter
+}
+
 // RegisterAPIs registers the APIs a service provides on the node.
 func (n *Node) RegisterAPIs(apis []rpc.API) {
 	n.lock.Lock()
@@ -460,6 +511,8 @@ func (n *Node) RegisterAPIs(apis []rpc.API) {
 //
 // The name of the handler is shown in a log message when the HTTP server starts
 // and should be a descriptive term for the service provided by the handler.
+// The handler is served as is, use HTTPHandlerStack to apply the node's request
+// authentication to it.
 func (n *Node) RegisterHandler(name, path string, handler http.Handler) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
@@ -472,6 +525,13 @@ func (n *Node) RegisterHandler(name, path string, handler http.Handler) {
 	n.http.handlerNames[path] = name
 }
 
+// HTTPHandlerStack wraps a handler to be mounted via RegisterHandler with the
+// virtual host and CORS checks, as well as the bearer token authentication of
+// the node's HTTP and WebSocket RPC endpoints, if enabled.
+func (n *Node) HTTPHandlerStack(handler http.Handler, cors, vhosts []string) http.Handler {
+	return NewHTTPHandlerStack(handler, cors, vhosts, n.jwtSecret)
+}
+
 // Attach creates an RPC client attached to an in-process API handler.
 func (n *Node) Attach() (*rpc.Client, error) {
 	return rpc.DialInProc(n.inprocHandler), nil
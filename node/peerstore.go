@@ -0,0 +1,246 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/p2p/dnsdisc"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+)
+
+var (
+	errNoPeerStore       = errors.New("persistence requires a data directory")
+	errDNSTreeKnown      = errors.New("DNS discovery tree already added")
+	errDNSTreeNotRunning = errors.New("DNS discovery tree not added")
+)
+
+// peerList selects one of the lists maintained by the peer store.
+type peerList int
+
+const (
+	staticPeerList peerList = iota
+	trustedPeerList
+	dnsTreeList
+)
+
+// storedPeers is the on-disk format of the peer store.
+type storedPeers struct {
+	Static  []string `json:"static,omitempty"`  // Static peers, as enode URLs or ENRs
+	Trusted []string `json:"trusted,omitempty"` // Trusted peers, as enode URLs or ENRs
+	DNS     []string `json:"dns,omitempty"`     // DNS discovery trees, as enrtree:// URLs
+}
+
+// list returns a pointer to the selected list.
+func (s *storedPeers) list(kind peerList) *[]string {
+	switch kind {
+	case staticPeerList:
+		return &s.Static
+	case trustedPeerList:
+		return &s.Trusted
+	default:
+		return &s.DNS
+	}
+}
+
+// peerStore persists the static peers, trusted peers and DNS discovery trees
+// added through the admin API, so they are restored when the node restarts
+// without the operator having to edit the node list files by hand.
+type peerStore struct {
+	path  string // File the peers are persisted to, empty for ephemeral nodes
+	lock  sync.Mutex
+	peers storedPeers
+}
+
+// newPeerStore loads the peer store from the given file, if it exists.
+func newPeerStore(path string) (*peerStore, error) {
+	store := &peerStore{path: path}
+	if path == "" {
+		return store, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return store, nil
+	}
+	if err := common.LoadJSON(path, &store.peers); err != nil {
+		return nil, fmt.Errorf("can't load persisted peers: %v", err)
+	}
+	return store, nil
+}
+
+// nodes returns the valid nodes of a persisted node list.
+func (s *peerStore) nodes(kind peerList) []*enode.Node {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var nodes []*enode.Node
+	for _, url := range *s.peers.list(kind) {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// urls returns a copy of a persisted list.
+func (s *peerStore) urls(kind peerList) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, *s.peers.list(kind)...)
+}
+
+// addNode persists a node into the given list, replacing any previous entry
+// of the same node.
+func (s *peerStore) addNode(kind peerList, node *enode.Node) error {
+	return s.update(kind, func(list []string) []string {
+		return append(removeNode(list, node.ID()), node.String())
+	})
+}
+
+// removeNode deletes a node from the given persisted list.
+func (s *peerStore) removeNode(kind peerList, node *enode.Node) error {
+	return s.update(kind, func(list []string) []string {
+		return removeNode(list, node.ID())
+	})
+}
+
+// addURL persists a URL into the given list, unless already present.
+func (s *peerStore) addURL(kind peerList, url string) error {
+	return s.update(kind, func(list []string) []string {
+		for _, have := range list {
+			if have == url {
+				return list
+			}
+		}
+		return append(list, url)
+	})
+}
+
+// removeURL deletes a URL from the given persisted list.
+func (s *peerStore) removeURL(kind peerList, url string) error {
+	return s.update(kind, func(list []string) []string {
+		kept := list[:0]
+		for _, have := range list {
+			if have != url {
+				kept = append(kept, have)
+			}
+		}
+		return kept
+	})
+}
+
+// update modifies a persisted list and writes the store back to disk.
+func (s *peerStore) update(kind peerList, modify func([]string) []string) error {
+	if s.path == "" {
+		return errNoPeerStore
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list := s.peers.list(kind)
+	*list = modify(*list)
+	if len(*list) == 0 {
+		*list = nil
+	}
+	return s.save()
+}
+
+// save writes the store to disk, replacing the previous file atomically.
+func (s *peerStore) save() error {
+	blob, err := json.MarshalIndent(&s.peers, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// removeNode filters all entries of a node out of a node list. Entries that
+// can't be parsed are kept, so hand edits aren't lost.
+func removeNode(list []string, id enode.ID) []string {
+	kept := list[:0]
+	for _, url := range list {
+		if node, err := enode.Parse(enode.ValidSchemes, url); err == nil && node.ID() == id {
+			continue
+		}
+		kept = append(kept, url)
+	}
+	return kept
+}
+
+// addDNSTree starts feeding the nodes of an ENR tree into the dial candidates
+// of the p2p server, skipping those rejected by the registered DNS filter.
+func (n *Node) addDNSTree(url string) error {
+	n.dnsLock.Lock()
+	defer n.dnsLock.Unlock()
+
+	if _, ok := n.dnsTrees[url]; ok {
+		return errDNSTreeKnown
+	}
+	if n.dnsClient == nil {
+		n.dnsClient = dnsdisc.NewClient(dnsdisc.Config{})
+	}
+	it, err := n.dnsClient.NewIterator(url)
+	if err != nil {
+		return err
+	}
+	if n.dnsFilter != nil {
+		it = enode.Filter(it, n.dnsFilter)
+	}
+	n.server.AddDialSource(it)
+	n.dnsTrees[url] = it
+	return nil
+}
+
+// removeDNSTree stops using an ENR tree added at runtime as a dial source.
+func (n *Node) removeDNSTree(url string) error {
+	n.dnsLock.Lock()
+	defer n.dnsLock.Unlock()
+
+	it, ok := n.dnsTrees[url]
+	if !ok {
+		return errDNSTreeNotRunning
+	}
+	it.Close()
+	delete(n.dnsTrees, url)
+	return nil
+}
+
+// dnsTreeURLs returns the ENR trees added at runtime, sorted.
+func (n *Node) dnsTreeURLs() []string {
+	n.dnsLock.Lock()
+	defer n.dnsLock.Unlock()
+
+	urls := make([]string, 0, len(n.dnsTrees))
+	for url := range n.dnsTrees {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
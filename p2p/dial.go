@@ -79,6 +79,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errBadReputation    = errors.New("bad reputation")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -101,6 +102,7 @@ type dialScheduler struct {
 	doneCh      chan *dialTask
 	addStaticCh chan *enode.Node
 	remStaticCh chan *enode.Node
+	staticReqCh chan chan []*enode.Node
 	addPeerCh   chan *conn
 	remPeerCh   chan *conn
 
@@ -130,10 +132,11 @@ type dialScheduler struct {
 type dialSetupFunc func(net.Conn, connFlag, *enode.Node) error
 
 type dialConfig struct {
-	self           enode.ID         // our own ID
-	maxDialPeers   int              // maximum number of dialed peers
-	maxActiveDials int              // maximum number of active dials
-	netRestrict    *netutil.Netlist // IP whitelist, disabled if nil
+	self           enode.ID            // our own ID
+	maxDialPeers   int                 // maximum number of dialed peers
+	maxActiveDials int                 // maximum number of active dials
+	netRestrict    *netutil.Netlist    // IP whitelist, disabled if nil
+	banned         func(enode.ID) bool // reports nodes not to dial dynamically, disabled if nil
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -171,6 +174,7 @@ func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupF
 		nodesIn:     make(chan *enode.Node),
 		addStaticCh: make(chan *enode.Node),
 		remStaticCh: make(chan *enode.Node),
+		staticReqCh: make(chan chan []*enode.Node),
 		addPeerCh:   make(chan *conn),
 		remPeerCh:   make(chan *conn),
 	}
@@ -204,6 +208,17 @@ func (d *dialScheduler) removeStatic(n *enode.Node) {
 	}
 }
 
+// staticNodes returns the nodes of all static dial candidates.
+func (d *dialScheduler) staticNodes() []*enode.Node {
+	ch := make(chan []*enode.Node, 1)
+	select {
+	case d.staticReqCh <- ch:
+		return <-ch
+	case <-d.ctx.Done():
+		return nil
+	}
+}
+
 // peerAdded updates the peer set.
 func (d *dialScheduler) peerAdded(c *conn) {
 	select {
@@ -242,7 +257,11 @@ loop:
 
 		select {
 		case node := <-nodesCh:
-			if err := d.checkDial(node); err != nil {
+			err := d.checkDial(node)
+			if err == nil && d.banned != nil && d.banned(node.ID()) {
+				err = errBadReputation
+			}
+			if err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
 			} else {
 				d.startDial(newDialTask(node, dynDialedConn))
@@ -287,6 +306,13 @@ loop:
 				d.addToStaticPool(task)
 			}
 
+		case ch := <-d.staticReqCh:
+			nodes := make([]*enode.Node, 0, len(d.static))
+			for _, task := range d.static {
+				nodes = append(nodes, task.dest)
+			}
+			ch <- nodes
+
 		case node := <-d.remStaticCh:
 			id := node.ID()
 			task := d.static[id]
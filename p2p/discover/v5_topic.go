@@ -0,0 +1,476 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common/mclock"
+	"github.com/crypyto-panel/go-etherdata/p2p/discover/v5wire"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+const (
+	topicQueueLimit     = 64               // max registrations per topic
+	topicTableLimit     = 1024             // max registrations across all topics
+	topicRegLifetime    = 15 * time.Minute // how long a registration is kept
+	topicBaseWait       = 10 * time.Second // wait time when a topic queue is almost full
+	topicRegWindow      = 10 * time.Second // how long a ticket can be used after its wait time
+	topicRegAttempts    = 3                // tickets requested per registrar before giving up
+	topicRegistrars     = 5                // nodes closest to the topic used as registrars
+	topicRetryInterval  = 30 * time.Second // retry delay when no registration succeeded
+	topicSearchInterval = 10 * time.Second // min time between two searches of an iterator
+)
+
+var (
+	errInvalidTicket = errors.New("invalid ticket")
+	errTopicRefused  = errors.New("topic registration refused")
+)
+
+// Topic identifies a discovery topic. It is the SHA256 hash of the topic name,
+// which also determines the location of the topic in the DHT.
+type Topic [32]byte
+
+// NewTopic creates the topic with the given name.
+func NewTopic(name string) Topic {
+	return Topic(sha256.Sum256([]byte(name)))
+}
+
+// String returns the topic hash in hex.
+func (t Topic) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// topicTicket is the content of a TICKET response. Tickets are authenticated by
+// the registrar, but the requester may decode them to learn the wait time.
+type topicTicket struct {
+	Topic  Topic
+	Node   enode.ID
+	IP     net.IP
+	Issued uint64 // registrar clock
+	Wait   uint64 // nanoseconds
+	MAC    []byte
+}
+
+// sign computes the ticket MAC using the registrar's ticket key.
+func (tk *topicTicket) sign(key []byte) []byte {
+	enc, _ := rlp.EncodeToBytes([]interface{}{tk.Topic, tk.Node, tk.IP, tk.Issued, tk.Wait})
+	mac := hmac.New(sha256.New, key)
+	mac.Write(enc)
+	return mac.Sum(nil)
+}
+
+// topicReg is a registration in a topic queue.
+type topicReg struct {
+	node    *enode.Node
+	expires mclock.AbsTime
+}
+
+// topicTable holds the topic registrations made at the local node.
+type topicTable struct {
+	mu     sync.Mutex
+	queues map[Topic][]topicReg
+	count  int
+}
+
+func newTopicTable() *topicTable {
+	return &topicTable{queues: make(map[Topic][]topicReg)}
+}
+
+// expire removes registrations which have outlived their lifetime.
+func (tab *topicTable) expire(now mclock.AbsTime) {
+	for topic, queue := range tab.queues {
+		live := queue[:0]
+		for _, reg := range queue {
+			if reg.expires > now {
+				live = append(live, reg)
+			}
+		}
+		tab.count -= len(queue) - len(live)
+		if len(live) == 0 {
+			delete(tab.queues, topic)
+		} else {
+			tab.queues[topic] = live
+		}
+	}
+}
+
+// waitTime returns how long a registrant has to wait before it may register
+// for the topic. The wait time grows with the occupancy of the topic queue. When
+// the queue or the table is full, it is the time until a slot becomes free.
+func (tab *topicTable) waitTime(topic Topic, now mclock.AbsTime) time.Duration {
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+
+	tab.expire(now)
+	queue := tab.queues[topic]
+	if len(queue) < topicQueueLimit && tab.count < topicTableLimit {
+		return topicBaseWait * time.Duration(len(queue)) / topicQueueLimit
+	}
+	// Queues are ordered by expiry, so the first entry is freed first.
+	next := now + mclock.AbsTime(topicRegLifetime)
+	if len(queue) >= topicQueueLimit {
+		next = queue[0].expires
+	} else {
+		for _, q := range tab.queues {
+			if q[0].expires < next {
+				next = q[0].expires
+			}
+		}
+	}
+	return time.Duration(next - now)
+}
+
+// register adds n to the topic queue. It returns false if there is no space.
+func (tab *topicTable) register(topic Topic, n *enode.Node, now mclock.AbsTime) bool {
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+
+	tab.expire(now)
+	queue := tab.queues[topic]
+	for i, reg := range queue {
+		if reg.node.ID() == n.ID() {
+			// Refresh existing registration, moving it to the end of the queue.
+			queue = append(queue[:i], queue[i+1:]...)
+			tab.queues[topic] = append(queue, topicReg{n, now + mclock.AbsTime(topicRegLifetime)})
+			return true
+		}
+	}
+	if len(queue) >= topicQueueLimit || tab.count >= topicTableLimit {
+		return false
+	}
+	tab.queues[topic] = append(queue, topicReg{n, now + mclock.AbsTime(topicRegLifetime)})
+	tab.count++
+	return true
+}
+
+// nodes returns up to limit registered nodes of the topic, most recent first.
+func (tab *topicTable) nodes(topic Topic, limit int, now mclock.AbsTime) []*enode.Node {
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+
+	tab.expire(now)
+	queue := tab.queues[topic]
+	var nodes []*enode.Node
+	for i := len(queue) - 1; i >= 0 && len(nodes) < limit; i-- {
+		nodes = append(nodes, queue[i].node)
+	}
+	return nodes
+}
+
+// RegisterTopic starts advertising the local node under the given topic. The
+// node registers with the nodes closest to the topic and keeps refreshing its
+// registrations until UnregisterTopic is called or the transport is closed.
+func (t *UDPv5) RegisterTopic(topic Topic) {
+	t.adlock.Lock()
+	defer t.adlock.Unlock()
+
+	if _, ok := t.ads[topic]; ok || t.closeCtx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	t.ads[topic] = cancel
+	t.wg.Add(1)
+	go t.advertiseTopic(ctx, topic)
+}
+
+// UnregisterTopic stops advertising the local node under the given topic.
+// Existing registrations expire on their own.
+func (t *UDPv5) UnregisterTopic(topic Topic) {
+	t.adlock.Lock()
+	defer t.adlock.Unlock()
+
+	if cancel, ok := t.ads[topic]; ok {
+		cancel()
+		delete(t.ads, topic)
+	}
+}
+
+// TopicQuery asks n for the nodes registered under the topic.
+func (t *UDPv5) TopicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.call(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic[:]})
+	return t.waitForNodes(resp, nil)
+}
+
+// TopicNodes returns an iterator that finds nodes advertising the topic. Searches
+// are repeated periodically, so the iterator may return the same node again.
+func (t *UDPv5) TopicNodes(topic Topic) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{t: t, topic: topic, ctx: ctx, cancel: cancel}
+}
+
+// advertiseTopic runs in its own goroutine and keeps the topic registered with
+// the nodes closest to it.
+func (t *UDPv5) advertiseTopic(ctx context.Context, topic Topic) {
+	defer t.wg.Done()
+
+	for {
+		var (
+			registrars = t.topicRegistrars(ctx, topic)
+			results    = make(chan error, len(registrars))
+			registered int
+		)
+		for _, n := range registrars {
+			go func(n *enode.Node) { results <- t.registerTopicAt(ctx, n, topic) }(n)
+		}
+		for _, n := range registrars {
+			if err := <-results; err != nil {
+				t.log.Debug("Topic registration failed", "topic", topic, "id", n.ID(), "err", err)
+			} else {
+				registered++
+			}
+		}
+		t.log.Trace("Topic registration round done", "topic", topic, "registrars", len(registrars), "registered", registered)
+
+		next := topicRegLifetime / 2
+		if registered == 0 {
+			next = topicRetryInterval
+		}
+		select {
+		case <-t.clock.After(next):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// topicRegistrars finds the nodes closest to the topic.
+func (t *UDPv5) topicRegistrars(ctx context.Context, topic Topic) []*enode.Node {
+	nodes := t.newLookup(ctx, enode.ID(topic)).run()
+	if len(nodes) > topicRegistrars {
+		nodes = nodes[:topicRegistrars]
+	}
+	return nodes
+}
+
+// registerTopicAt obtains a ticket from n, waits for the ticket's wait time and then
+// registers the topic. The ticket is renewed if the registrar refuses the registration.
+func (t *UDPv5) registerTopicAt(ctx context.Context, n *enode.Node, topic Topic) error {
+	for i := 0; i < topicRegAttempts; i++ {
+		ticket, wait, err := t.requestTicket(n, topic)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-t.clock.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		ok, err := t.regtopic(n, ticket)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return errTopicRefused
+}
+
+// requestTicket calls REQUESTTICKET on a node and returns the ticket and its wait time.
+func (t *UDPv5) requestTicket(n *enode.Node, topic Topic) ([]byte, time.Duration, error) {
+	resp := t.call(n, v5wire.TicketMsg, &v5wire.RequestTicket{Topic: topic[:]})
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		enc := p.(*v5wire.Ticket).Ticket
+		var ticket topicTicket
+		if err := rlp.DecodeBytes(enc, &ticket); err != nil || ticket.Topic != topic {
+			return nil, 0, errInvalidTicket
+		}
+		if time.Duration(ticket.Wait) > topicRegLifetime {
+			return nil, 0, errInvalidTicket
+		}
+		return enc, time.Duration(ticket.Wait), nil
+	case err := <-resp.err:
+		return nil, 0, err
+	}
+}
+
+// regtopic calls REGTOPIC on a node and waits for the confirmation.
+func (t *UDPv5) regtopic(n *enode.Node, ticket []byte) (bool, error) {
+	req := &v5wire.Regtopic{Ticket: ticket, ENR: t.Self().Record()}
+	resp := t.call(n, v5wire.RegconfirmationMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		return p.(*v5wire.Regconfirmation).Registered, nil
+	case err := <-resp.err:
+		return false, err
+	}
+}
+
+// searchTopic queries the nodes closest to the topic for registrations, including
+// the registrations held by the local node.
+func (t *UDPv5) searchTopic(ctx context.Context, topic Topic) []*enode.Node {
+	var (
+		self   = t.Self().ID()
+		seen   = make(map[enode.ID]struct{})
+		result []*enode.Node
+	)
+	add := func(nodes []*enode.Node) {
+		for _, n := range nodes {
+			if _, ok := seen[n.ID()]; !ok && n.ID() != self {
+				seen[n.ID()] = struct{}{}
+				result = append(result, n)
+			}
+		}
+	}
+	add(t.topics.nodes(topic, findnodeResultLimit, t.clock.Now()))
+	for _, n := range t.topicRegistrars(ctx, topic) {
+		if ctx.Err() != nil {
+			break
+		}
+		nodes, err := t.TopicQuery(n, topic)
+		if err != nil {
+			t.log.Debug("Topic query failed", "topic", topic, "id", n.ID(), "err", err)
+		}
+		add(nodes)
+	}
+	return result
+}
+
+// handleRequestTicket issues a ticket for the requested topic.
+func (t *UDPv5) handleRequestTicket(p *v5wire.RequestTicket, fromID enode.ID, fromAddr *net.UDPAddr) {
+	resp := &v5wire.Ticket{ReqID: p.ReqID}
+	if len(p.Topic) == len(Topic{}) {
+		var topic Topic
+		copy(topic[:], p.Topic)
+		now := t.clock.Now()
+		ticket := &topicTicket{
+			Topic:  topic,
+			Node:   fromID,
+			IP:     fromAddr.IP,
+			Issued: uint64(now),
+			Wait:   uint64(t.topics.waitTime(topic, now)),
+		}
+		ticket.MAC = ticket.sign(t.ticketKey)
+		resp.Ticket, _ = rlp.EncodeToBytes(ticket)
+	}
+	t.sendResponse(fromID, fromAddr, resp)
+}
+
+// handleRegtopic registers the sender if it presents a valid ticket.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	resp := &v5wire.Regconfirmation{ReqID: p.ReqID}
+	topic, n, err := t.verifyRegtopic(p, fromID, fromAddr)
+	if err != nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+	} else {
+		resp.Registered = t.topics.register(topic, n, t.clock.Now())
+	}
+	t.sendResponse(fromID, fromAddr, resp)
+}
+
+// verifyRegtopic checks the ticket and record of a REGTOPIC request.
+func (t *UDPv5) verifyRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) (Topic, *enode.Node, error) {
+	var ticket topicTicket
+	if err := rlp.DecodeBytes(p.Ticket, &ticket); err != nil {
+		return Topic{}, nil, errInvalidTicket
+	}
+	if !hmac.Equal(ticket.MAC, ticket.sign(t.ticketKey)) {
+		return Topic{}, nil, errInvalidTicket
+	}
+	if ticket.Node != fromID || !ticket.IP.Equal(fromAddr.IP) {
+		return Topic{}, nil, errors.New("ticket issued to different node")
+	}
+	var (
+		now   = t.clock.Now()
+		start = mclock.AbsTime(ticket.Issued).Add(time.Duration(ticket.Wait))
+	)
+	if now < start {
+		return Topic{}, nil, errors.New("ticket used before wait time")
+	}
+	if now > start.Add(topicRegWindow) {
+		return Topic{}, nil, errors.New("ticket expired")
+	}
+	if p.ENR == nil {
+		return Topic{}, nil, errors.New("missing record")
+	}
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil {
+		return Topic{}, nil, err
+	}
+	if n.ID() != fromID {
+		return Topic{}, nil, errors.New("record of different node")
+	}
+	return ticket.Topic, n, nil
+}
+
+// handleTopicQuery returns the nodes registered for a topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	var nodes []*enode.Node
+	if len(p.Topic) == len(Topic{}) {
+		var topic Topic
+		copy(topic[:], p.Topic)
+		nodes = t.topics.nodes(topic, findnodeResultLimit, t.clock.Now())
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// topicIterator repeatedly searches the DHT for nodes advertising a topic.
+type topicIterator struct {
+	t      *UDPv5
+	topic  Topic
+	ctx    context.Context
+	cancel context.CancelFunc
+	buf    []*enode.Node
+	cur    *enode.Node
+
+	searched bool           // whether a search has been performed
+	last     mclock.AbsTime // time of the last search
+}
+
+func (it *topicIterator) Next() bool {
+	it.cur = nil
+	for len(it.buf) == 0 {
+		if it.searched {
+			wait := time.Duration(it.last.Add(topicSearchInterval) - it.t.clock.Now())
+			select {
+			case <-it.t.clock.After(wait):
+			case <-it.ctx.Done():
+			}
+		}
+		if it.ctx.Err() != nil {
+			return false
+		}
+		it.searched, it.last = true, it.t.clock.Now()
+		it.buf = it.t.searchTopic(it.ctx, it.topic)
+	}
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+func (it *topicIterator) Node() *enode.Node {
+	return it.cur
+}
+
+func (it *topicIterator) Close() {
+	it.cancel()
+}
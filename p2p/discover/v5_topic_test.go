@@ -0,0 +1,182 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/common"
+	"github.com/crypyto-panel/go-etherdata/common/mclock"
+	"github.com/crypyto-panel/go-etherdata/p2p/discover/v5wire"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+	"github.com/crypyto-panel/go-etherdata/p2p/enr"
+	"github.com/crypyto-panel/go-etherdata/rlp"
+)
+
+func TestTopicTable(t *testing.T) {
+	var (
+		tab   = newTopicTable()
+		topic = NewTopic("foo")
+		now   = mclock.AbsTime(0)
+		nodes []*enode.Node
+	)
+	if wait := tab.waitTime(topic, now); wait != 0 {
+		t.Fatalf("wrong wait time for empty queue: %v", wait)
+	}
+	for i := 0; i < topicQueueLimit; i++ {
+		n := enode.SignNull(new(enr.Record), enode.ID{byte(i), 1})
+		nodes = append(nodes, n)
+		if !tab.register(topic, n, now) {
+			t.Fatalf("registration %d refused", i)
+		}
+		now += mclock.AbsTime(time.Second)
+	}
+	// The queue is full, the wait time is the time until the first entry expires.
+	extra := enode.SignNull(new(enr.Record), enode.ID{0xff})
+	if tab.register(topic, extra, now) {
+		t.Fatal("registration accepted in full queue")
+	}
+	want := topicRegLifetime - topicQueueLimit*time.Second
+	if wait := tab.waitTime(topic, now); wait != want {
+		t.Fatalf("wrong wait time for full queue: got %v, want %v", wait, want)
+	}
+	// Other topics are not affected.
+	if wait := tab.waitTime(NewTopic("bar"), now); wait != 0 {
+		t.Fatalf("wrong wait time for other topic: %v", wait)
+	}
+	// Results are returned most recent first.
+	result := tab.nodes(topic, 3, now)
+	if len(result) != 3 || result[0] != nodes[len(nodes)-1] {
+		t.Fatalf("wrong query result: %v", result)
+	}
+	// After expiry of the first entry, there is space again.
+	now += mclock.AbsTime(want)
+	if !tab.register(topic, extra, now) {
+		t.Fatal("registration refused after expiry")
+	}
+	if tab.count != topicQueueLimit {
+		t.Fatalf("wrong registration count %d", tab.count)
+	}
+}
+
+// This test checks that incoming ticket, registration and topic query requests are
+// handled correctly.
+func TestUDPv5_topicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = NewTopic("foo")
+		remote = test.getNode(test.remotekey, test.remoteaddr).Node()
+		ticket []byte
+	)
+	test.packetIn(&v5wire.RequestTicket{ReqID: []byte("1"), Topic: topic[:]})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		var tk topicTicket
+		if err := rlp.DecodeBytes(p.Ticket, &tk); err != nil {
+			t.Fatal("can't decode ticket:", err)
+		}
+		if tk.Topic != topic || tk.Node != remote.ID() || tk.Wait != 0 {
+			t.Errorf("wrong ticket content: %+v", tk)
+		}
+		ticket = p.Ticket
+	})
+
+	// Registration with a modified ticket is refused.
+	bad := common.CopyBytes(ticket)
+	bad[len(bad)-1]++
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte("2"), Ticket: bad, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Registered {
+			t.Error("registration with invalid ticket accepted")
+		}
+	})
+
+	// Registration with the real ticket works.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte("3"), Ticket: ticket, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte("3")) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if !p.Registered {
+			t.Error("registration refused")
+		}
+	})
+
+	// The next ticket has a non-zero wait time.
+	test.packetIn(&v5wire.RequestTicket{ReqID: []byte("4"), Topic: topic[:]})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		var tk topicTicket
+		rlp.DecodeBytes(p.Ticket, &tk)
+		if tk.Wait == 0 {
+			t.Error("zero wait time for occupied queue")
+		}
+	})
+
+	// The registered node is returned by TOPICQUERY.
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte("5"), Topic: topic[:]})
+	test.expectNodes([]byte("5"), 1, []*enode.Node{remote})
+}
+
+// Real sockets, real crypto: this test checks that a topic registered by one node
+// can be found by another one.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 3
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			cfg.Bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	topic := NewTopic("foo")
+	advertiser, searcher := nodes[1], nodes[2]
+	advertiser.RegisterTopic(topic)
+	defer advertiser.UnregisterTopic(topic)
+
+	// Wait for the registration to become visible.
+	var found bool
+	for deadline := time.Now().Add(10 * time.Second); !found && time.Now().Before(deadline); {
+		for _, n := range searcher.searchTopic(searcher.closeCtx, topic) {
+			if n.ID() == advertiser.Self().ID() {
+				found = true
+			}
+		}
+		if !found {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	if !found {
+		t.Fatal("advertiser not found by topic search")
+	}
+
+	// Check the iterator.
+	it := searcher.TopicNodes(topic)
+	defer it.Close()
+	if !it.Next() || it.Node().ID() != advertiser.Self().ID() {
+		t.Fatal("iterator did not return advertiser")
+	}
+}
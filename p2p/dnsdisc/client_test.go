@@ -19,8 +19,11 @@ package dnsdisc
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -71,6 +74,29 @@ func TestClientSyncTree(t *testing.T) {
 	}
 }
 
+// This test checks that trees can be synced from a record document served over HTTP.
+func TestClientSyncTreeHTTP(t *testing.T) {
+	nodes := testNodes(nodesSeed1, 10)
+	tree, url := makeTestTree("nodes.example.org", nodes, nil)
+	doc, _ := json.Marshal(tree.ToTXT("nodes.example.org"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(doc)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Resolver: NewHTTPResolver(srv.URL, nil), Logger: testlog.Logger(t, log.LvlTrace)})
+	stree, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(sortByID(stree.Nodes()), sortByID(nodes)) {
+		t.Errorf("wrong nodes in synced tree:\nhave %v\nwant %v", spew.Sdump(stree.Nodes()), spew.Sdump(nodes))
+	}
+	if _, err := NewHTTPResolver(srv.URL, nil).LookupTXT(context.Background(), "unknown.example.org"); err == nil {
+		t.Error("no error for unknown name")
+	}
+}
+
 // In this test, syncing the tree fails because it contains an invalid ENR entry.
 func TestClientSyncTreeBadNode(t *testing.T) {
 	// var b strings.Builder
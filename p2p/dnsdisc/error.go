@@ -39,6 +39,7 @@ var (
 	errHashMismatch  = errors.New("hash mismatch")
 	errENRInLinkTree = errors.New("enr entry in link tree")
 	errLinkInENRTree = errors.New("link entry in ENR tree")
+	errNoRecord      = errors.New("no such record")
 )
 
 type nameError struct {
@@ -0,0 +1,99 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	httpResolverTTL   = time.Minute // how long fetched records are cached
+	httpResolverLimit = 16 << 20    // max size of the record document
+)
+
+// HTTPResolver is a Resolver which fetches TXT records from a JSON document served
+// over HTTPS instead of querying DNS. The document is an object mapping DNS names to
+// record content, as written by 'devp2p dns to-txt'. It may contain the records of
+// multiple trees.
+type HTTPResolver struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	records map[string]string
+	fetched time.Time
+}
+
+// NewHTTPResolver creates a resolver for the record document at the given URL.
+// If client is nil, http.DefaultClient is used.
+func NewHTTPResolver(url string, client *http.Client) *HTTPResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPResolver{url: url, client: client}
+}
+
+// LookupTXT returns the TXT record of the given name.
+func (r *HTTPResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.records == nil || time.Since(r.fetched) > httpResolverTTL {
+		records, err := r.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.records, r.fetched = records, time.Now()
+	}
+	txt, ok := r.records[strings.ToLower(strings.TrimSuffix(domain, "."))]
+	if !ok {
+		return nil, nameError{domain, errNoRecord}
+	}
+	return []string{txt}, nil
+}
+
+// fetch downloads the record document.
+func (r *HTTPResolver) fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't fetch %s: %s", r.url, resp.Status)
+	}
+	var doc map[string]string
+	if err := json.NewDecoder(io.LimitReader(resp.Body, httpResolverLimit)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid record document at %s: %v", r.url, err)
+	}
+	records := make(map[string]string, len(doc))
+	for name, txt := range doc {
+		records[strings.ToLower(strings.TrimSuffix(name, "."))] = txt
+	}
+	return records, nil
+}
@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"sync"
@@ -49,6 +50,7 @@ const (
 	dbNodePing      = "lastping"
 	dbNodePong      = "lastpong"
 	dbNodeSeq       = "seq"
+	dbNodeRep       = "reputation"
 
 	// Local information is keyed by ID only, the full key is "local:<ID>:seq".
 	// Use localItemKey to create those keys.
@@ -271,6 +273,26 @@ func (db *DB) NodeSeq(id ID) uint64 {
 	return db.fetchUint64(nodeItemKey(id, zeroIP, dbNodeSeq))
 }
 
+// Reputation retrieves the last persisted reputation score of a node, along
+// with the time it was recorded at. A zero time is returned if the node has no
+// stored score.
+func (db *DB) Reputation(id ID) (float64, time.Time) {
+	blob, err := db.lvl.Get(nodeItemKey(id, zeroIP, dbNodeRep), nil)
+	if err != nil || len(blob) != 16 {
+		return 0, time.Time{}
+	}
+	score := math.Float64frombits(binary.BigEndian.Uint64(blob[:8]))
+	return score, time.Unix(0, int64(binary.BigEndian.Uint64(blob[8:])))
+}
+
+// UpdateReputation stores the reputation score of a node as of the given time.
+func (db *DB) UpdateReputation(id ID, score float64, at time.Time) error {
+	blob := make([]byte, 16)
+	binary.BigEndian.PutUint64(blob[:8], math.Float64bits(score))
+	binary.BigEndian.PutUint64(blob[8:], uint64(at.UnixNano()))
+	return db.lvl.Put(nodeItemKey(id, zeroIP, dbNodeRep), blob, nil)
+}
+
 // Resolve returns the stored record of the node if it has a larger sequence
 // number than n.
 func (db *DB) Resolve(n *Node) *Node {
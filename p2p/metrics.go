@@ -33,6 +33,9 @@ const (
 
 	// HandleHistName is the prefix of the per-packet serving time histograms.
 	HandleHistName = "p2p/handle"
+
+	// peerMeterName is the prefix of the per-peer traffic metrics.
+	peerMeterName = "p2p/peer"
 )
 
 var (
@@ -114,6 +114,12 @@ type Peer struct {
 	closed   chan struct{}
 	disc     chan DiscReason
 
+	// rep tracks the reputation of the peer, nil if scoring is disabled
+	rep *reputation
+
+	// traffic counts the traffic exchanged with the peer
+	traffic *trafficCounter
+
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
@@ -205,6 +211,47 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// Report records a behavioural event against the peer's reputation. Peers whose
+// score drops too low are disconnected, unless they are trusted or static.
+func (p *Peer) Report(ev ReputationEvent) {
+	if p.rep == nil {
+		return
+	}
+	p.log.Trace("Peer reputation event", "event", ev)
+	p.adjustReputation(ev.weight())
+}
+
+// ReportDelivery records a useful response from the peer, rewarding it more if
+// it responded faster than the target round trip time and less if slower.
+func (p *Peer) ReportDelivery(elapsed, target time.Duration) {
+	if p.rep == nil {
+		return
+	}
+	p.adjustReputation(deliveryReward(elapsed, target))
+}
+
+// Reputation returns the current reputation score of the peer.
+func (p *Peer) Reputation() float64 {
+	if p.rep == nil {
+		return 0
+	}
+	return p.rep.score(p.ID())
+}
+
+// adjustReputation changes the peer's score, disconnecting it if it went below
+// the drop threshold.
+func (p *Peer) adjustReputation(delta float64) {
+	score := p.rep.add(p.ID(), delta)
+	if delta >= 0 || score >= reputationDropThreshold {
+		return
+	}
+	if p.rw.is(trustedConn) || p.rw.is(staticDialedConn) {
+		return
+	}
+	p.log.Debug("Dropping peer with bad reputation", "score", score)
+	go p.Disconnect(DiscUselessPeer)
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	id := p.ID()
@@ -226,6 +273,10 @@ func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 		protoErr: make(chan error, len(protomap)+1), // protocols + pingLoop
 		closed:   make(chan struct{}),
 		log:      log.New("id", conn.node.ID(), "conn", conn.flags),
+		traffic:  conn.traffic,
+	}
+	if p.traffic == nil {
+		p.traffic = newTrafficCounter()
 	}
 	return p
 }
@@ -236,23 +287,41 @@ func (p *Peer) Log() log.Logger {
 
 func (p *Peer) run() (remoteRequested bool, err error) {
 	var (
-		writeStart = make(chan struct{}, 1)
-		writeErr   = make(chan error, 1)
-		readErr    = make(chan error, 1)
-		reason     DiscReason // sent to the peer
+		writeReq = make(chan *writeRequest)
+		writeErr = make(chan error, 1)
+		readErr  = make(chan error, 1)
+		reason   DiscReason // sent to the peer
+
+		writes  = newWriteQueue()
+		writing bool // whether a protocol currently holds the write slot
 	)
+	// grant hands the write slot to the next queued protocol write, if free.
+	grant := func() {
+		if !writing {
+			if req := writes.pop(); req != nil {
+				writing = true
+				close(req.start)
+			}
+		}
+	}
+	p.traffic.startMetrics(p.ID())
+	defer p.traffic.stopMetrics()
+
 	p.wg.Add(2)
 	go p.readLoop(readErr)
 	go p.pingLoop()
 
 	// Start all protocol handlers.
-	writeStart <- struct{}{}
-	p.startProtocols(writeStart, writeErr)
+	p.startProtocols(writeReq, writeErr)
 
 	// Wait for an error or disconnect.
 loop:
 	for {
 		select {
+		case req := <-writeReq:
+			// A protocol wants to write, queue it up fairly.
+			writes.push(req)
+			grant()
 		case err = <-writeErr:
 			// A write finished. Allow the next write to start if
 			// there was no error.
@@ -260,7 +329,8 @@ loop:
 				reason = DiscNetworkError
 				break loop
 			}
-			writeStart <- struct{}{}
+			writing = false
+			grant()
 		case err = <-readErr:
 			if r, ok := err.(DiscReason); ok {
 				remoteRequested = true
@@ -271,6 +341,9 @@ loop:
 			break loop
 		case err = <-p.protoErr:
 			reason = discReasonForError(err)
+			if isMisbehaviour(err) {
+				p.Report(ProtocolError)
+			}
 			break loop
 		case err = <-p.disc:
 			reason = discReasonForError(err)
@@ -319,6 +392,9 @@ func (p *Peer) readLoop(errc chan<- error) {
 }
 
 func (p *Peer) handle(msg Msg) error {
+	if msg.Code < baseProtocolLength {
+		p.traffic.record(true, Cap{}, msg.Code, msg.meterSize)
+	}
 	switch {
 	case msg.Code == pingMsg:
 		msg.Discard()
@@ -338,6 +414,7 @@ func (p *Peer) handle(msg Msg) error {
 		if err != nil {
 			return fmt.Errorf("msg code out of range: %v", msg.Code)
 		}
+		p.traffic.record(true, proto.cap(), msg.Code-proto.offset, msg.meterSize)
 		if metrics.Enabled {
 			m := fmt.Sprintf("%s/%s/%d/%#02x", ingressMeterName, proto.Name, proto.Version, msg.Code-proto.offset)
 			metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
@@ -390,13 +467,16 @@ outer:
 	return result
 }
 
-func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error) {
+func (p *Peer) startProtocols(writeReq chan<- *writeRequest, writeErr chan<- error) {
 	p.wg.Add(len(p.running))
 	for _, proto := range p.running {
 		proto := proto
 		proto.closed = p.closed
-		proto.wstart = writeStart
+		proto.wreq = writeReq
 		proto.werr = writeErr
+		if t, ok := p.rw.transport.(shapedTransport); ok {
+			proto.throttle = t.throttleWrite
+		}
 		var rw MsgReadWriter = proto
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name, p.Info().Network.RemoteAddress, p.Info().Network.LocalAddress)
@@ -429,12 +509,14 @@ func (p *Peer) getProto(code uint64) (*protoRW, error) {
 
 type protoRW struct {
 	Protocol
-	in     chan Msg        // receives read messages
-	closed <-chan struct{} // receives when peer is shutting down
-	wstart <-chan struct{} // receives when write may start
-	werr   chan<- error    // for write results
+	in     chan Msg             // receives read messages
+	closed <-chan struct{}      // receives when peer is shutting down
+	wreq   chan<- *writeRequest // queues a write, which may start once granted
+	werr   chan<- error         // for write results
 	offset uint64
 	w      MsgWriter
+
+	throttle func(code uint64, size uint32) // waits for the egress limiter, nil if unlimited
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
@@ -446,8 +528,19 @@ func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 
 	msg.Code += rw.offset
 
+	// Wait for the bandwidth limiter before queueing the write, so throttled
+	// messages hold up neither the connection nor the writes of other protocols.
+	if rw.throttle != nil {
+		rw.throttle(msg.Code, msg.Size)
+	}
+	req := &writeRequest{proto: rw.Name, size: msg.Size, start: make(chan struct{})}
+	select {
+	case rw.wreq <- req:
+	case <-rw.closed:
+		return ErrShuttingDown
+	}
 	select {
-	case <-rw.wstart:
+	case <-req.start:
 		err = rw.w.WriteMsg(msg)
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
@@ -486,7 +579,9 @@ type PeerInfo struct {
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Reputation float64                `json:"reputation"` // Reputation score of the peer, zero if scoring is disabled
+	Traffic    *PeerTraffic           `json:"traffic"`    // Traffic exchanged with the peer
+	Protocols  map[string]interface{} `json:"protocols"`  // Sub-protocol specific metadata fields
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -498,11 +593,13 @@ func (p *Peer) Info() *PeerInfo {
 	}
 	// Assemble the generic peer metadata
 	info := &PeerInfo{
-		Enode:     p.Node().URLv4(),
-		ID:        p.ID().String(),
-		Name:      p.Fullname(),
-		Caps:      caps,
-		Protocols: make(map[string]interface{}),
+		Enode:      p.Node().URLv4(),
+		ID:         p.ID().String(),
+		Name:       p.Fullname(),
+		Caps:       caps,
+		Reputation: p.Reputation(),
+		Traffic:    p.traffic.stats(),
+		Protocols:  make(map[string]interface{}),
 	}
 	if p.Node().Seq() > 0 {
 		info.ENR = p.Node().String()
@@ -19,6 +19,8 @@ package p2p
 import (
 	"errors"
 	"fmt"
+	"io"
+	"net"
 )
 
 const (
@@ -117,3 +119,16 @@ func discReasonForError(err error) DiscReason {
 	}
 	return DiscSubprotocolError
 }
+
+// isMisbehaviour reports whether a protocol handler error was caused by the
+// remote peer misbehaving, as opposed to a network failure or orderly shutdown.
+func isMisbehaviour(err error) bool {
+	if _, ok := err.(DiscReason); ok {
+		return false
+	}
+	if err == errProtocolReturned || errors.Is(err, ErrShuttingDown) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+		return false
+	}
+	var netErr net.Error
+	return !errors.As(err, &netErr)
+}
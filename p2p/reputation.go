@@ -0,0 +1,212 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+)
+
+const (
+	// reputationMax and reputationMin bound the score a peer can accumulate, so
+	// a long history of good behaviour can't shield a peer that turns malicious
+	// and a single bad session doesn't make a peer unreachable forever.
+	reputationMax = 100
+	reputationMin = -100
+
+	// reputationHalfLife is the time it takes for a score to decay half way back
+	// towards neutral if the peer doesn't do anything notable.
+	reputationHalfLife = time.Hour
+
+	// reputationDropThreshold is the score below which a connected peer is
+	// disconnected and new connections to or from it are refused. Trusted and
+	// static peers are exempt.
+	reputationDropThreshold = -50
+
+	// deliveryBonusMin and deliveryBonusMax bound the latency multiplier applied
+	// to the reward of a useful delivery.
+	deliveryBonusMin = 0.5
+	deliveryBonusMax = 2
+)
+
+// ReputationEvent is a notable behaviour of a remote peer that affects its
+// reputation score.
+type ReputationEvent int
+
+const (
+	UsefulDelivery ReputationEvent = iota // Peer answered a request with useful data
+	RequestTimeout                        // Peer failed to answer a request in time
+	InvalidData                           // Peer delivered data failing validation
+	ProtocolError                         // Peer violated the wire protocol
+	UselessPeer                           // Peer has nothing to offer, but did not misbehave
+)
+
+// weight returns the score change caused by a single event.
+func (ev ReputationEvent) weight() float64 {
+	switch ev {
+	case UsefulDelivery:
+		return 1
+	case RequestTimeout:
+		return -5
+	case InvalidData:
+		return -20
+	case ProtocolError:
+		return -30
+	default:
+		return 0
+	}
+}
+
+// String implements fmt.Stringer.
+func (ev ReputationEvent) String() string {
+	switch ev {
+	case UsefulDelivery:
+		return "useful delivery"
+	case RequestTimeout:
+		return "request timeout"
+	case InvalidData:
+		return "invalid data"
+	case ProtocolError:
+		return "protocol error"
+	case UselessPeer:
+		return "useless peer"
+	default:
+		return "unknown event"
+	}
+}
+
+// ReputationReporter is implemented by peers tracking a reputation score. The
+// sub-protocol handlers use it to report the quality of the remote side, without
+// having to know whether scoring is enabled on the underlying connection.
+type ReputationReporter interface {
+	// Report records a behavioural event against the peer.
+	Report(ev ReputationEvent)
+
+	// ReportDelivery records a useful response, rewarding the peer according to
+	// how its response time compares to the target round trip time.
+	ReportDelivery(elapsed, target time.Duration)
+}
+
+// peerScore is the cached reputation of a single peer.
+type peerScore struct {
+	value   float64   // Score as of the last update
+	updated time.Time // Time of the last update, used for decaying
+}
+
+// reputation tracks the scores of the connected peers, persisting them into the
+// node database when they disconnect, so bad behaviour is remembered across
+// reconnects and restarts.
+type reputation struct {
+	db     *enode.DB
+	now    func() time.Time
+	lock   sync.Mutex
+	scores map[enode.ID]*peerScore
+}
+
+func newReputation(db *enode.DB) *reputation {
+	return &reputation{
+		db:     db,
+		now:    time.Now,
+		scores: make(map[enode.ID]*peerScore),
+	}
+}
+
+// decay returns the value of a score after the given amount of time passed.
+func decay(value float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return value
+	}
+	return value * math.Pow(0.5, float64(elapsed)/float64(reputationHalfLife))
+}
+
+// load retrieves the score of a peer, falling back to the database if it's not
+// cached. The caller must hold the lock.
+func (r *reputation) load(id enode.ID) (float64, time.Time) {
+	if score, ok := r.scores[id]; ok {
+		return score.value, score.updated
+	}
+	return r.db.Reputation(id)
+}
+
+// score returns the current, decayed score of a peer.
+func (r *reputation) score(id enode.ID) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	value, updated := r.load(id)
+	if updated.IsZero() {
+		return 0
+	}
+	return decay(value, r.now().Sub(updated))
+}
+
+// banned reports whether the score of a peer is too low to keep a connection.
+func (r *reputation) banned(id enode.ID) bool {
+	return r.score(id) < reputationDropThreshold
+}
+
+// add changes the score of a peer by the given amount, returning the new value.
+func (r *reputation) add(id enode.ID, delta float64) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		now            = r.now()
+		value, updated = r.load(id)
+	)
+	if !updated.IsZero() {
+		value = decay(value, now.Sub(updated))
+	}
+	value = math.Max(reputationMin, math.Min(reputationMax, value+delta))
+	r.scores[id] = &peerScore{value: value, updated: now}
+	return value
+}
+
+// flush persists the cached score of a peer and evicts it from the cache.
+func (r *reputation) flush(id enode.ID) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if score, ok := r.scores[id]; ok {
+		r.db.UpdateReputation(id, score.value, score.updated)
+		delete(r.scores, id)
+	}
+}
+
+// flushAll persists all cached scores.
+func (r *reputation) flushAll() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for id, score := range r.scores {
+		r.db.UpdateReputation(id, score.value, score.updated)
+	}
+	r.scores = make(map[enode.ID]*peerScore)
+}
+
+// deliveryReward returns the score increase for a useful delivery, scaling the
+// base reward up for fast peers and down for slow ones.
+func deliveryReward(elapsed, target time.Duration) float64 {
+	factor := 1.0
+	if elapsed > 0 && target > 0 {
+		factor = math.Max(deliveryBonusMin, math.Min(deliveryBonusMax, float64(target)/float64(elapsed)))
+	}
+	return UsefulDelivery.weight() * factor
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+)
+
+// Tests that reputation scores are bounded and decay towards neutral over time.
+func TestReputationDecay(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		now = time.Unix(1000000, 0)
+		rep = newReputation(db)
+		id  = uintID(1)
+	)
+	rep.now = func() time.Time { return now }
+
+	if score := rep.score(id); score != 0 {
+		t.Fatalf("unknown peer score mismatch: have %v, want 0", score)
+	}
+	for i := 0; i < 10; i++ {
+		rep.add(id, ProtocolError.weight())
+	}
+	if score := rep.score(id); score != reputationMin {
+		t.Fatalf("score not clamped: have %v, want %v", score, reputationMin)
+	}
+	if !rep.banned(id) {
+		t.Fatalf("peer with minimal score not banned")
+	}
+	now = now.Add(reputationHalfLife)
+	if score := rep.score(id); math.Abs(score-reputationMin/2) > 1e-9 {
+		t.Fatalf("decayed score mismatch: have %v, want %v", score, reputationMin/2)
+	}
+	if rep.banned(id) {
+		t.Fatalf("peer still banned after decay")
+	}
+}
+
+// Tests that delivery rewards scale with the response latency.
+func TestReputationDeliveryReward(t *testing.T) {
+	tests := []struct {
+		elapsed, target time.Duration
+		reward          float64
+	}{
+		{time.Second, time.Second, 1},
+		{time.Second, 0, 1},
+		{500 * time.Millisecond, time.Second, 2},
+		{100 * time.Millisecond, time.Second, deliveryBonusMax},
+		{4 * time.Second, time.Second, deliveryBonusMin},
+	}
+	for i, tt := range tests {
+		if reward := deliveryReward(tt.elapsed, tt.target); reward != tt.reward {
+			t.Errorf("test %d: reward mismatch: have %v, want %v", i, reward, tt.reward)
+		}
+	}
+}
+
+// Tests that scores survive being flushed into the node database.
+func TestReputationPersistence(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	now := time.Unix(1000000, 0)
+	clock := func() time.Time { return now }
+
+	rep := newReputation(db)
+	rep.now = clock
+	rep.add(uintID(1), InvalidData.weight())
+	rep.add(uintID(2), UsefulDelivery.weight())
+	rep.flush(uintID(1))
+	rep.flushAll()
+
+	if len(rep.scores) != 0 {
+		t.Fatalf("cache not emptied after flush: %d entries", len(rep.scores))
+	}
+	reloaded := newReputation(db)
+	reloaded.now = clock
+	if score := reloaded.score(uintID(1)); score != InvalidData.weight() {
+		t.Errorf("peer 1 score mismatch: have %v, want %v", score, InvalidData.weight())
+	}
+	if score := reloaded.score(uintID(2)); score != UsefulDelivery.weight() {
+		t.Errorf("peer 2 score mismatch: have %v, want %v", score, UsefulDelivery.weight())
+	}
+	// Updates should continue from the persisted score
+	if score := reloaded.add(uintID(1), InvalidData.weight()); score != 2*InvalidData.weight() {
+		t.Errorf("updated score mismatch: have %v, want %v", score, 2*InvalidData.weight())
+	}
+}
+
+// Tests that peers are disconnected once their score drops below the threshold.
+func TestPeerReputationDrop(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	closer, _, peer, errc := testPeer(nil)
+	defer closer()
+	peer.rep = newReputation(db)
+	now := time.Unix(1000000, 0)
+	peer.rep.now = func() time.Time { return now }
+
+	peer.Report(ProtocolError)
+	select {
+	case err := <-errc:
+		t.Fatalf("peer dropped above threshold: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if info := peer.Info(); info.Reputation != ProtocolError.weight() {
+		t.Fatalf("reported reputation mismatch: have %v, want %v", info.Reputation, ProtocolError.weight())
+	}
+	peer.Report(ProtocolError)
+	select {
+	case err := <-errc:
+		if err != DiscUselessPeer {
+			t.Fatalf("peer dropped with wrong reason: have %v, want %v", err, DiscUselessPeer)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("peer not dropped below threshold")
+	}
+}
+
+// Tests that the server refuses connections from peers with a bad reputation,
+// unless they are trusted.
+func TestServerRejectBadReputation(t *testing.T) {
+	srv := &Server{Config: Config{MaxPeers: 10, PrivateKey: newkey()}}
+	if err := srv.setupLocalNode(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.nodedb.Close()
+
+	id := uintID(1)
+	srv.reputation.add(id, reputationMin)
+
+	c := &conn{node: newNode(id, ""), flags: inboundConn}
+	if err := srv.postHandshakeChecks(nil, 0, c); err != DiscUselessPeer {
+		t.Errorf("inbound peer check mismatch: have %v, want %v", err, DiscUselessPeer)
+	}
+	c.flags |= trustedConn
+	if err := srv.postHandshakeChecks(nil, 0, c); err != nil {
+		t.Errorf("trusted peer rejected: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlpx
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket capping the throughput of one or more connections.
+// It is safe for concurrent use. Callers reserve their bandwidth in the order
+// they ask for it, and frames larger than the bucket may borrow from the future,
+// so no transfer is blocked indefinitely.
+type Limiter struct {
+	rate  float64 // Allowed throughput in bytes per second
+	burst float64 // Maximum number of bytes that can accumulate while idle
+
+	lock   sync.Mutex
+	tokens float64   // Bytes available for transfer, negative if borrowed
+	last   time.Time // Last time the bucket was refilled
+
+	now   func() time.Time    // Overridable for testing
+	sleep func(time.Duration) // Overridable for testing
+}
+
+// NewLimiter creates a limiter allowing the given number of bytes per second,
+// with bursts of up to one second worth of traffic.
+func NewLimiter(rate int) *Limiter {
+	return &Limiter{
+		rate:   float64(rate),
+		burst:  float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// Rate returns the throughput allowed by the limiter, in bytes per second.
+func (l *Limiter) Rate() int {
+	return int(l.rate)
+}
+
+// Wait blocks until n bytes may be transferred, returning the time spent
+// waiting. Calling Wait on a nil limiter returns immediately.
+func (l *Limiter) Wait(n int) time.Duration {
+	if l == nil || n <= 0 {
+		return 0
+	}
+	delay := l.reserve(n)
+	if delay > 0 {
+		l.sleep(delay)
+	}
+	return delay
+}
+
+// reserve takes n bytes out of the bucket, returning how long the caller has to
+// wait until the reservation is covered.
+func (l *Limiter) reserve(n int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlpx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	var (
+		now   = time.Unix(1000000, 0)
+		slept time.Duration
+		l     = NewLimiter(1000)
+	)
+	l.last = now
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) { slept += d }
+
+	// The initial burst should be served without waiting
+	if wait := l.Wait(1000); wait != 0 {
+		t.Fatalf("burst delayed: %v", wait)
+	}
+	// Further traffic has to wait for the bucket to refill, with oversized
+	// transfers borrowing from the future
+	if wait := l.Wait(500); wait != 500*time.Millisecond {
+		t.Fatalf("wait mismatch: have %v, want %v", wait, 500*time.Millisecond)
+	}
+	if wait := l.Wait(2000); wait != 2500*time.Millisecond {
+		t.Fatalf("wait mismatch: have %v, want %v", wait, 2500*time.Millisecond)
+	}
+	if slept != 3*time.Second {
+		t.Fatalf("sleep mismatch: have %v, want %v", slept, 3*time.Second)
+	}
+	// Idle time should refill the bucket, but no more than the burst
+	now = now.Add(time.Hour)
+	if wait := l.Wait(1000); wait != 0 {
+		t.Fatalf("refilled burst delayed: %v", wait)
+	}
+	if wait := l.Wait(100); wait != 100*time.Millisecond {
+		t.Fatalf("wait mismatch after burst: have %v, want %v", wait, 100*time.Millisecond)
+	}
+	// Nil limiters should never block
+	var nilLimiter *Limiter
+	if wait := nilLimiter.Wait(1 << 20); wait != 0 {
+		t.Fatalf("nil limiter delayed: %v", wait)
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/crypyto-panel/go-etherdata/p2p/enr"
 	"github.com/crypyto-panel/go-etherdata/p2p/nat"
 	"github.com/crypyto-panel/go-etherdata/p2p/netutil"
+	"github.com/crypyto-panel/go-etherdata/p2p/rlpx"
 )
 
 const (
@@ -155,6 +156,13 @@ type Config struct {
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
+	// MaxUploadRate and MaxDownloadRate cap the combined bandwidth of all peer
+	// connections, in bytes of message payload per second. Zero means unlimited.
+	// Messages of the base protocol (handshake, disconnect and keepalives) are
+	// not limited.
+	MaxUploadRate   int `toml:",omitempty"`
+	MaxDownloadRate int `toml:",omitempty"`
+
 	clock mclock.Clock
 }
 
@@ -178,7 +186,12 @@ type Server struct {
 	peerFeed     event.Feed
 	log          log.Logger
 
-	nodedb    *enode.DB
+	nodedb     *enode.DB
+	reputation *reputation
+
+	uploadLimit   *rlpx.Limiter // Caps the egress bandwidth of all peers, nil if unlimited
+	downloadLimit *rlpx.Limiter // Caps the ingress bandwidth of all peers, nil if unlimited
+
 	localnode *enode.LocalNode
 	ntab      *discover.UDPv4
 	DiscV5    *discover.UDPv5
@@ -226,6 +239,8 @@ type conn struct {
 	cont  chan error // The run loop uses cont to signal errors to SetupConn.
 	caps  []Cap      // valid after the protocol handshake
 	name  string     // valid after the protocol handshake
+
+	traffic *trafficCounter // traffic exchanged over the connection
 }
 
 type transport interface {
@@ -323,6 +338,17 @@ func (srv *Server) AddPeer(node *enode.Node) {
 	srv.dialsched.addStatic(node)
 }
 
+// StaticPeers returns the nodes in the static node set, whether connected or not.
+func (srv *Server) StaticPeers() []*enode.Node {
+	return srv.dialsched.staticNodes()
+}
+
+// AddDialSource adds an iterator of dial candidates to the running server, e.g. a
+// DNS discovery tree added at runtime. The iterator is closed when the server stops.
+func (srv *Server) AddDialSource(it enode.Iterator) {
+	srv.discmix.AddSource(it)
+}
+
 // RemovePeer removes a node from the static node set. It also disconnects from the given
 // node if it is currently connected as a peer.
 //
@@ -461,6 +487,12 @@ func (srv *Server) Start() (err error) {
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
 	}
+	if srv.MaxUploadRate > 0 {
+		srv.uploadLimit = rlpx.NewLimiter(srv.MaxUploadRate)
+	}
+	if srv.MaxDownloadRate > 0 {
+		srv.downloadLimit = rlpx.NewLimiter(srv.MaxDownloadRate)
+	}
 	srv.quit = make(chan struct{})
 	srv.delpeer = make(chan peerDrop)
 	srv.checkpointPostHandshake = make(chan *conn)
@@ -503,6 +535,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.reputation = newReputation(db)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -623,6 +656,7 @@ func (srv *Server) setupDialScheduler() {
 		netRestrict:    srv.NetRestrict,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
+		banned:         srv.reputation.banned,
 	}
 	if srv.ntab != nil {
 		config.resolver = srv.ntab
@@ -769,6 +803,7 @@ running:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			delete(peers, pd.ID())
+			srv.reputation.flush(pd.ID())
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
 			if pd.Inbound() {
@@ -798,6 +833,7 @@ running:
 		p.log.Trace("<-delpeer (spindown)")
 		delete(peers, p.ID())
 	}
+	srv.reputation.flushAll()
 }
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
@@ -810,6 +846,8 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn) && !c.is(staticDialedConn) && srv.reputation.banned(c.node.ID()):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -919,12 +957,15 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 // as a peer. It returns when the connection has been added as a peer
 // or the handshakes have failed.
 func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *enode.Node) error {
-	c := &conn{fd: fd, flags: flags, cont: make(chan error)}
+	c := &conn{fd: fd, flags: flags, cont: make(chan error), traffic: newTrafficCounter()}
 	if dialDest == nil {
 		c.transport = srv.newTransport(fd, nil)
 	} else {
 		c.transport = srv.newTransport(fd, dialDest.Pubkey())
 	}
+	if t, ok := c.transport.(shapedTransport); ok {
+		t.setShaping(c.traffic, srv.downloadLimit, srv.uploadLimit)
+	}
 
 	err := srv.setupConn(c, flags, dialDest)
 	if err != nil {
@@ -1014,6 +1055,7 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.rep = srv.reputation
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
@@ -0,0 +1,255 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/metrics"
+	"github.com/crypyto-panel/go-etherdata/p2p/enode"
+)
+
+// writeQuantum is the number of bytes a subprotocol may send per round of the
+// fair write queue before the other protocols get their turn.
+const writeQuantum = 64 * 1024
+
+// TrafficStats counts the traffic of a connection in one direction.
+type TrafficStats struct {
+	Bytes    uint64 `json:"bytes"`    // Bytes transferred on the wire, after compression
+	Messages uint64 `json:"messages"` // Number of messages transferred
+}
+
+func (s *TrafficStats) add(size uint32) {
+	s.Bytes += uint64(size)
+	s.Messages++
+}
+
+// MsgTraffic is the traffic of a single subprotocol message type.
+type MsgTraffic struct {
+	Ingress TrafficStats `json:"ingress"`
+	Egress  TrafficStats `json:"egress"`
+}
+
+// PeerTraffic is the traffic exchanged with a peer, in total and broken down
+// by subprotocol message type. Messages are keyed by protocol name and version
+// (e.g. "etd/66") and then by the hex message code within the protocol.
+type PeerTraffic struct {
+	Ingress  TrafficStats                      `json:"ingress"`
+	Egress   TrafficStats                      `json:"egress"`
+	Messages map[string]map[string]*MsgTraffic `json:"messages"`
+}
+
+// trafficKey identifies a subprotocol message type.
+type trafficKey struct {
+	cap  Cap
+	code uint64
+}
+
+// peerMeters are the metrics of a single peer's traffic, registered while the
+// peer is running.
+type peerMeters struct {
+	prefix          string
+	ingress         metrics.Meter // Bytes received from the peer
+	egress          metrics.Meter // Bytes sent to the peer
+	ingressThrottle metrics.Timer // Time reads were held back by the bandwidth cap
+	egressThrottle  metrics.Timer // Time writes were held back by the bandwidth cap
+}
+
+func newPeerMeters(id enode.ID) *peerMeters {
+	prefix := fmt.Sprintf("%s/%x", peerMeterName, id[:8])
+	return &peerMeters{
+		prefix:          prefix,
+		ingress:         metrics.NewRegisteredMeter(prefix+"/ingress", nil),
+		egress:          metrics.NewRegisteredMeter(prefix+"/egress", nil),
+		ingressThrottle: metrics.NewRegisteredTimer(prefix+"/ingress/throttle", nil),
+		egressThrottle:  metrics.NewRegisteredTimer(prefix+"/egress/throttle", nil),
+	}
+}
+
+// unregister removes the metrics of a disconnected peer.
+func (m *peerMeters) unregister() {
+	for _, name := range []string{"/ingress", "/egress", "/ingress/throttle", "/egress/throttle"} {
+		metrics.Unregister(m.prefix + name)
+	}
+}
+
+// trafficCounter tracks the traffic of a single connection.
+type trafficCounter struct {
+	lock    sync.Mutex
+	ingress TrafficStats
+	egress  TrafficStats
+	msgs    map[trafficKey]*MsgTraffic
+	meters  *peerMeters // Per-peer metrics, nil if disabled
+}
+
+func newTrafficCounter() *trafficCounter {
+	return &trafficCounter{msgs: make(map[trafficKey]*MsgTraffic)}
+}
+
+// startMetrics starts reporting the traffic of the connection as the metrics of
+// the given peer, if metrics collection is enabled.
+func (t *trafficCounter) startMetrics(id enode.ID) {
+	if !metrics.Enabled {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.meters = newPeerMeters(id)
+}
+
+// stopMetrics stops reporting the traffic of the connection and removes the
+// metrics of the peer.
+func (t *trafficCounter) stopMetrics() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.meters != nil {
+		t.meters.unregister()
+		t.meters = nil
+	}
+}
+
+// throttled accounts time spent waiting for the bandwidth limiter.
+func (t *trafficCounter) throttled(ingress bool, wait time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.meters == nil {
+		return
+	}
+	if ingress {
+		t.meters.ingressThrottle.Update(wait)
+	} else {
+		t.meters.egressThrottle.Update(wait)
+	}
+}
+
+// record accounts a message transferred over the connection. Messages of the
+// base protocol, for which cap is empty, only count towards the totals.
+func (t *trafficCounter) record(ingress bool, cap Cap, code uint64, size uint32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var msg *MsgTraffic
+	if cap.Name != "" {
+		key := trafficKey{cap, code}
+		if msg = t.msgs[key]; msg == nil {
+			msg = new(MsgTraffic)
+			t.msgs[key] = msg
+		}
+	}
+	if ingress {
+		t.ingress.add(size)
+		if msg != nil {
+			msg.Ingress.add(size)
+		}
+		if t.meters != nil {
+			t.meters.ingress.Mark(int64(size))
+		}
+	} else {
+		t.egress.add(size)
+		if msg != nil {
+			msg.Egress.add(size)
+		}
+		if t.meters != nil {
+			t.meters.egress.Mark(int64(size))
+		}
+	}
+}
+
+// stats returns a snapshot of the counted traffic.
+func (t *trafficCounter) stats() *PeerTraffic {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := &PeerTraffic{
+		Ingress:  t.ingress,
+		Egress:   t.egress,
+		Messages: make(map[string]map[string]*MsgTraffic),
+	}
+	for key, msg := range t.msgs {
+		proto := key.cap.String()
+		if stats.Messages[proto] == nil {
+			stats.Messages[proto] = make(map[string]*MsgTraffic)
+		}
+		snapshot := *msg
+		stats.Messages[proto][fmt.Sprintf("%#02x", key.code)] = &snapshot
+	}
+	return stats
+}
+
+// writeRequest is a subprotocol asking for the connection's write slot.
+type writeRequest struct {
+	proto string        // Name of the requesting protocol
+	size  uint32        // Payload size of the message to write
+	start chan struct{} // Closed when the write may start
+}
+
+// writeQueue hands out the single write slot of a connection to the waiting
+// subprotocols using deficit round robin, so a protocol transferring bulk data
+// can't starve the others, especially when the upload bandwidth is capped.
+type writeQueue struct {
+	queues  map[string][]*writeRequest // Pending requests per protocol
+	deficit map[string]int             // Bytes each protocol may still send in the current round
+	active  []string                   // Protocols with pending requests, in round robin order
+	next    int                        // Index of the protocol to serve next
+}
+
+func newWriteQueue() *writeQueue {
+	return &writeQueue{
+		queues:  make(map[string][]*writeRequest),
+		deficit: make(map[string]int),
+	}
+}
+
+// push queues a write request.
+func (q *writeQueue) push(req *writeRequest) {
+	if len(q.queues[req.proto]) == 0 {
+		q.active = append(q.active, req.proto)
+	}
+	q.queues[req.proto] = append(q.queues[req.proto], req)
+}
+
+// pop returns the next request to grant the write slot to, or nil if there are
+// no pending requests.
+func (q *writeQueue) pop() *writeRequest {
+	for len(q.active) > 0 {
+		if q.next >= len(q.active) {
+			q.next = 0
+		}
+		proto := q.active[q.next]
+		req := q.queues[proto][0]
+		if int(req.size) > q.deficit[proto] {
+			// Not enough credit left, top it up and move on to the next protocol
+			q.deficit[proto] += writeQuantum
+			q.next++
+			continue
+		}
+		q.deficit[proto] -= int(req.size)
+		if q.queues[proto] = q.queues[proto][1:]; len(q.queues[proto]) == 0 {
+			// Idle protocols don't accumulate credit
+			delete(q.queues, proto)
+			delete(q.deficit, proto)
+			q.active = append(q.active[:q.next], q.active[q.next+1:]...)
+		}
+		return req
+	}
+	return nil
+}
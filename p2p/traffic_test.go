@@ -0,0 +1,228 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crypyto-panel/go-etherdata/log"
+	"github.com/crypyto-panel/go-etherdata/metrics"
+	"github.com/crypyto-panel/go-etherdata/p2p/rlpx"
+)
+
+// Tests that the write queue shares the write slot between protocols in
+// proportion to the bytes they send, regardless of how many writes they queue.
+func TestWriteQueueFairness(t *testing.T) {
+	q := newWriteQueue()
+	for i := 0; i < 16; i++ {
+		q.push(&writeRequest{proto: "bulk", size: writeQuantum})
+	}
+	for i := 0; i < 64; i++ {
+		q.push(&writeRequest{proto: "small", size: writeQuantum / 4})
+	}
+	// While both protocols have pending writes, neither should get ahead by
+	// more than a quantum
+	var sent = make(map[string]int)
+	for i := 0; i < 16; i++ {
+		req := q.pop()
+		sent[req.proto] += int(req.size)
+
+		if diff := sent["bulk"] - sent["small"]; diff > writeQuantum || diff < -writeQuantum {
+			t.Fatalf("write %d: unfair share: bulk %d bytes, small %d bytes", i, sent["bulk"], sent["small"])
+		}
+	}
+	// Drain the queue and make sure every request is served exactly once
+	served := 16
+	for q.pop() != nil {
+		served++
+	}
+	if served != 80 {
+		t.Fatalf("served requests mismatch: have %d, want %d", served, 80)
+	}
+	if len(q.active) != 0 || len(q.queues) != 0 || len(q.deficit) != 0 {
+		t.Fatalf("queue not empty after draining: %d active", len(q.active))
+	}
+}
+
+// Tests that the traffic exchanged with a peer is counted per message type and
+// reported in the peer info.
+func TestPeerTraffic(t *testing.T) {
+	var (
+		fd1, fd2   = net.Pipe()
+		key1, key2 = newkey(), newkey()
+		t1         = newTestTransport(&key2.PublicKey, fd1, nil)
+		t2         = newTestTransport(&key1.PublicKey, fd2, &key1.PublicKey)
+		proto      = Protocol{
+			Name:   "echo",
+			Length: 2,
+			Run: func(p *Peer, rw MsgReadWriter) error {
+				for {
+					msg, err := rw.ReadMsg()
+					if err != nil {
+						return err
+					}
+					msg.Discard()
+					if err := SendItems(rw, 1, "pong"); err != nil {
+						return err
+					}
+				}
+			},
+		}
+	)
+	c1 := &conn{fd: fd1, node: newNode(uintID(1), ""), transport: t1, caps: []Cap{proto.cap()}, traffic: newTrafficCounter()}
+	c2 := &conn{fd: fd2, node: newNode(uintID(2), ""), transport: t2}
+	t1.(shapedTransport).setShaping(c1.traffic, nil, nil)
+	defer c2.close(errProtocolReturned)
+
+	peer := newPeer(log.Root(), c1, []Protocol{proto})
+	go peer.run()
+
+	for i := 0; i < 3; i++ {
+		if err := SendItems(c2, baseProtocolLength, "ping"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c2.ReadMsg(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Wait for the peer to process the last message before checking the stats
+	time.Sleep(50 * time.Millisecond)
+
+	stats := peer.Info().Traffic
+	msgs := stats.Messages["echo/0"]
+	if msgs == nil {
+		t.Fatalf("no traffic recorded for protocol: %v", stats.Messages)
+	}
+	if have := msgs["0x00"].Ingress.Messages; have != 3 {
+		t.Errorf("ingress message count mismatch: have %d, want 3", have)
+	}
+	if have := msgs["0x01"].Egress.Messages; have != 3 {
+		t.Errorf("egress message count mismatch: have %d, want 3", have)
+	}
+	if stats.Ingress.Bytes != msgs["0x00"].Ingress.Bytes || stats.Ingress.Bytes == 0 {
+		t.Errorf("ingress bytes mismatch: total %d, message %d", stats.Ingress.Bytes, msgs["0x00"].Ingress.Bytes)
+	}
+	if stats.Egress.Bytes != msgs["0x01"].Egress.Bytes || stats.Egress.Bytes == 0 {
+		t.Errorf("egress bytes mismatch: total %d, message %d", stats.Egress.Bytes, msgs["0x01"].Egress.Bytes)
+	}
+	if !reflect.DeepEqual(msgs["0x00"].Egress, TrafficStats{}) {
+		t.Errorf("unexpected egress for inbound message type: %+v", msgs["0x00"].Egress)
+	}
+}
+
+// Tests that the bandwidth limiter delays subprotocol messages, but lets the
+// messages of the base protocol through without waiting, even while a
+// subprotocol write is throttled.
+func TestTransportThrottle(t *testing.T) {
+	var (
+		fd1, fd2   = net.Pipe()
+		key1, key2 = newkey(), newkey()
+		t1         = newTestTransport(&key2.PublicKey, fd1, nil)
+		t2         = newTestTransport(&key1.PublicKey, fd2, &key1.PublicKey)
+		throttled  = make(chan time.Duration, 1)
+		writing    = make(chan struct{})
+		proto      = Protocol{
+			Name:   "bulk",
+			Length: 1,
+			Run: func(p *Peer, rw MsgReadWriter) error {
+				// Exceed the burst of the limiter, making the next message wait
+				start := time.Now()
+				if err := rw.WriteMsg(Msg{Code: 0, Size: 1500, Payload: bytes.NewReader(make([]byte, 1500))}); err != nil {
+					return err
+				}
+				throttled <- time.Since(start)
+				close(writing)
+				if err := rw.WriteMsg(Msg{Code: 0, Size: 1500, Payload: bytes.NewReader(make([]byte, 1500))}); err != nil {
+					return err
+				}
+				<-p.closed
+				return nil
+			},
+		}
+	)
+	c1 := &conn{fd: fd1, node: newNode(uintID(1), ""), transport: t1, caps: []Cap{proto.cap()}, traffic: newTrafficCounter()}
+	c2 := &conn{fd: fd2, node: newNode(uintID(2), ""), transport: t2}
+	t1.(shapedTransport).setShaping(c1.traffic, nil, rlpx.NewLimiter(1000))
+	defer c2.close(errProtocolReturned)
+
+	go func() {
+		for {
+			msg, err := c2.ReadMsg()
+			if err != nil {
+				return
+			}
+			msg.Discard()
+		}
+	}()
+	peer := newPeer(log.Root(), c1, []Protocol{proto})
+	go peer.run()
+	defer peer.Disconnect(DiscQuitting)
+
+	if elapsed := <-throttled; elapsed < 400*time.Millisecond {
+		t.Fatalf("subprotocol message not throttled: %v", elapsed)
+	}
+	// Give the second subprotocol write time to start waiting for the limiter
+	<-writing
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err := SendItems(c1, pingMsg); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("base protocol message throttled: %v", elapsed)
+	}
+}
+
+// Tests that the traffic of a running peer is reported as per-peer metrics,
+// which are removed once the peer disconnects.
+func TestPeerMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	var (
+		id      = uintID(1)
+		prefix  = fmt.Sprintf("%s/%x", peerMeterName, id[:8])
+		traffic = newTrafficCounter()
+	)
+	traffic.startMetrics(id)
+	traffic.record(true, Cap{Name: "etd", Version: 66}, 0x01, 100)
+	traffic.record(false, Cap{}, pingMsg, 10)
+	traffic.throttled(false, time.Second)
+
+	if meter, ok := metrics.Get(prefix + "/ingress").(metrics.Meter); !ok || meter.Count() != 100 {
+		t.Errorf("ingress meter mismatch: %v", metrics.Get(prefix+"/ingress"))
+	}
+	if meter, ok := metrics.Get(prefix + "/egress").(metrics.Meter); !ok || meter.Count() != 10 {
+		t.Errorf("egress meter mismatch: %v", metrics.Get(prefix+"/egress"))
+	}
+	if timer, ok := metrics.Get(prefix + "/egress/throttle").(metrics.Timer); !ok || timer.Sum() != int64(time.Second) {
+		t.Errorf("egress throttle timer mismatch: %v", metrics.Get(prefix+"/egress/throttle"))
+	}
+	traffic.stopMetrics()
+	for _, name := range []string{"/ingress", "/egress", "/ingress/throttle", "/egress/throttle"} {
+		if metric := metrics.Get(prefix + name); metric != nil {
+			t.Errorf("metric %s not removed", name)
+		}
+	}
+}
@@ -49,12 +49,48 @@ type rlpxTransport struct {
 	rmu, wmu sync.Mutex
 	wbuf     bytes.Buffer
 	conn     *rlpx.Conn
+
+	traffic    *trafficCounter // counts egress traffic, nil if disabled
+	readLimit  *rlpx.Limiter   // caps the ingress bandwidth, nil if unlimited
+	writeLimit *rlpx.Limiter   // caps the egress bandwidth, nil if unlimited
+}
+
+// shapedTransport is implemented by transports supporting per-connection
+// traffic accounting and bandwidth limits.
+type shapedTransport interface {
+	setShaping(traffic *trafficCounter, read, write *rlpx.Limiter)
+	throttleWrite(code uint64, size uint32)
 }
 
 func newRLPX(conn net.Conn, dialDest *ecdsa.PublicKey) transport {
 	return &rlpxTransport{conn: rlpx.NewConn(conn, dialDest)}
 }
 
+// setShaping implements shapedTransport. The limiters only apply to subprotocol
+// messages, the handshake and other base protocol messages are never charged.
+func (t *rlpxTransport) setShaping(traffic *trafficCounter, read, write *rlpx.Limiter) {
+	t.traffic = traffic
+	t.readLimit, t.writeLimit = read, write
+}
+
+// throttleWrite implements shapedTransport. It waits for the egress limiter to
+// allow a message through. Writers call it before taking the write lock, so
+// base protocol messages are never delayed by throttled subprotocol writes.
+func (t *rlpxTransport) throttleWrite(code uint64, size uint32) {
+	t.throttle(false, t.writeLimit, code, size)
+}
+
+// throttle waits for the bandwidth limiter to allow a message of the given code
+// and size through, accounting the time spent waiting to the connection.
+func (t *rlpxTransport) throttle(ingress bool, limit *rlpx.Limiter, code uint64, size uint32) {
+	if code < baseProtocolLength {
+		return
+	}
+	if wait := limit.Wait(int(size)); wait > 0 && t.traffic != nil {
+		t.traffic.throttled(ingress, wait)
+	}
+}
+
 func (t *rlpxTransport) ReadMsg() (Msg, error) {
 	t.rmu.Lock()
 	defer t.rmu.Unlock()
@@ -74,6 +110,9 @@ func (t *rlpxTransport) ReadMsg() (Msg, error) {
 			meterSize:  uint32(wireSize),
 			Payload:    bytes.NewReader(data),
 		}
+		// Charge the message to the bandwidth limiter after it arrived, holding
+		// back the next read until the cap allows for it.
+		t.throttle(true, t.readLimit, msg.Code, msg.Size)
 	}
 	return msg, err
 }
@@ -97,6 +136,9 @@ func (t *rlpxTransport) WriteMsg(msg Msg) error {
 
 	// Set metrics.
 	msg.meterSize = size
+	if t.traffic != nil {
+		t.traffic.record(false, msg.meterCap, msg.meterCode, msg.meterSize)
+	}
 	if metrics.Enabled && msg.meterCap.Name != "" { // don't meter non-subprotocol messages
 		m := fmt.Sprintf("%s/%s/%d/%#02x", egressMeterName, msg.meterCap.Name, msg.meterCap.Version, msg.meterCode)
 		metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
@@ -145,8 +187,8 @@ func (t *rlpxTransport) doProtoHandshake(our *protoHandshake) (their *protoHands
 	if err := <-werr; err != nil {
 		return nil, fmt.Errorf("write error: %v", err)
 	}
-	// If the protocol version supports Snappy encoding, upgrade immediately
-	t.conn.SetSnappy(their.Version >= snappyProtocolVersion)
+	// If both protocol versions support Snappy encoding, upgrade immediately
+	t.conn.SetSnappy(our.Version >= snappyProtocolVersion && their.Version >= snappyProtocolVersion)
 
 	return their, nil
 }
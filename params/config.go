@@ -246,16 +246,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Etherdata core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -341,6 +341,7 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash *EthashConfig `json:"etdash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+	IBFT   *IBFTConfig   `json:"ibft,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -355,6 +356,18 @@ func (c *EthashConfig) String() string {
 type CliqueConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	Transitions []CliqueTransition `json:"transitions,omitempty"` // Scheduled parameter changes, in ascending block order
+}
+
+// CliqueTransition is a scheduled change of the clique consensus parameters,
+// taking effect from the given block onwards. Unset fields retain the value
+// active before the transition.
+type CliqueTransition struct {
+	Block      *big.Int `json:"block"`                // Block number the new parameters apply from
+	Period     *uint64  `json:"period,omitempty"`     // Number of seconds between blocks to enforce
+	Epoch      *uint64  `json:"epoch,omitempty"`      // Epoch length to reset votes and checkpoint, counted from the transition block
+	StrictTurn *bool    `json:"strictTurn,omitempty"` // Whether only the in-turn signer may seal blocks
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -362,6 +375,137 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// PeriodAt returns the minimum number of seconds between the given block and
+// its parent.
+func (c *CliqueConfig) PeriodAt(number uint64) uint64 {
+	period := c.Period
+	for _, t := range c.Transitions {
+		if !isForked(t.Block, new(big.Int).SetUint64(number)) {
+			break
+		}
+		if t.Period != nil {
+			period = *t.Period
+		}
+	}
+	return period
+}
+
+// IsCheckpoint returns whether the given block is an epoch transition, where
+// pending votes are reset and the list of signers is checkpointed. Epochs are
+// counted from the last transition changing the epoch length, making every such
+// transition block a checkpoint.
+func (c *CliqueConfig) IsCheckpoint(number uint64) bool {
+	start, epoch := uint64(0), c.Epoch
+	for _, t := range c.Transitions {
+		if !isForked(t.Block, new(big.Int).SetUint64(number)) {
+			break
+		}
+		if t.Epoch != nil {
+			start, epoch = t.Block.Uint64(), *t.Epoch
+		}
+	}
+	return (number-start)%epoch == 0
+}
+
+// IsStrictTurn returns whether only the in-turn signer is allowed to seal the
+// given block.
+func (c *CliqueConfig) IsStrictTurn(number uint64) bool {
+	strict := false
+	for _, t := range c.Transitions {
+		if !isForked(t.Block, new(big.Int).SetUint64(number)) {
+			break
+		}
+		if t.StrictTurn != nil {
+			strict = *t.StrictTurn
+		}
+	}
+	return strict
+}
+
+// CheckTransitions verifies that the scheduled parameter changes are sane.
+func (c *CliqueConfig) CheckTransitions() error {
+	var last *big.Int
+	for i, t := range c.Transitions {
+		if t.Block == nil || t.Block.Sign() <= 0 {
+			return fmt.Errorf("invalid clique transition %d: block must be positive", i)
+		}
+		if last != nil && last.Cmp(t.Block) >= 0 {
+			return fmt.Errorf("unsupported clique transition ordering: transition at %v follows transition at %v", t.Block, last)
+		}
+		if t.Epoch != nil && *t.Epoch == 0 {
+			return fmt.Errorf("invalid clique transition at %v: zero epoch length", t.Block)
+		}
+		last = t.Block
+	}
+	return nil
+}
+
+// checkCompatible checks whether the scheduled transitions of two clique
+// configs agree on all blocks up to and including head.
+func (c *CliqueConfig) checkCompatible(newcfg *CliqueConfig, head *big.Int) *ConfigCompatError {
+	for i := 0; i < len(c.Transitions) || i < len(newcfg.Transitions); i++ {
+		var stored, next *CliqueTransition
+		if i < len(c.Transitions) {
+			stored = &c.Transitions[i]
+		}
+		if i < len(newcfg.Transitions) {
+			next = &newcfg.Transitions[i]
+		}
+		switch {
+		case stored == nil && next == nil:
+			return nil
+		case stored == nil:
+			if isForked(next.Block, head) {
+				return newCompatError("Clique transition block", nil, next.Block)
+			}
+			return nil
+		case next == nil:
+			if isForked(stored.Block, head) {
+				return newCompatError("Clique transition block", stored.Block, nil)
+			}
+			return nil
+		}
+		if isForkIncompatible(stored.Block, next.Block, head) {
+			return newCompatError("Clique transition block", stored.Block, next.Block)
+		}
+		if isForked(stored.Block, head) && !stored.equal(next) {
+			return newCompatError("Clique transition parameters", stored.Block, next.Block)
+		}
+	}
+	return nil
+}
+
+// equal returns whether two transitions schedule the same parameter changes.
+func (t *CliqueTransition) equal(other *CliqueTransition) bool {
+	uint64Equal := func(x, y *uint64) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return *x == *y
+	}
+	boolEqual := func(x, y *bool) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return *x == *y
+	}
+	return configNumEqual(t.Block, other.Block) && uint64Equal(t.Period, other.Period) &&
+		uint64Equal(t.Epoch, other.Epoch) && boolEqual(t.StrictTurn, other.StrictTurn)
+}
+
+// IBFTConfig is the consensus engine configs for Byzantine fault tolerant
+// proof-of-authority sealing with immediate finality.
+type IBFTConfig struct {
+	Period         uint64 `json:"period"`         // Number of seconds between blocks to enforce
+	Epoch          uint64 `json:"epoch"`          // Epoch length to reset votes
+	RequestTimeout uint64 `json:"requestTimeout"` // Timeout of the first round in milliseconds, doubling each round
+}
+
+// String implements the stringer interface, returning the consensus engine details.
+func (c *IBFTConfig) String() string {
+	return "ibft"
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -370,6 +514,8 @@ func (c *ChainConfig) String() string {
 		engine = c.Ethash
 	case c.Clique != nil:
 		engine = c.Clique
+	case c.IBFT != nil:
+		engine = c.IBFT
 	default:
 		engine = "unknown"
 	}
@@ -523,6 +669,9 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if c.Clique != nil {
+		return c.Clique.CheckTransitions()
+	}
 	return nil
 }
 
@@ -576,6 +725,11 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if c.Clique != nil && newcfg.Clique != nil {
+		if err := c.Clique.checkCompatible(newcfg.Clique, head); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -28,6 +28,7 @@ func TestCheckCompatible(t *testing.T) {
 		head        uint64
 		wantErr     *ConfigCompatError
 	}
+	period1, period2 := uint64(1), uint64(2)
 	tests := []test{
 		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, head: 0, wantErr: nil},
 		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, head: 100, wantErr: nil},
@@ -76,6 +77,34 @@ func TestCheckCompatible(t *testing.T) {
 			head:    40,
 			wantErr: nil,
 		},
+		{
+			stored:  &ChainConfig{Clique: &CliqueConfig{Period: 5, Transitions: []CliqueTransition{{Block: big.NewInt(10), Period: &period1}}}},
+			new:     &ChainConfig{Clique: &CliqueConfig{Period: 5, Transitions: []CliqueTransition{{Block: big.NewInt(20), Period: &period2}}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Clique: &CliqueConfig{Period: 5, Transitions: []CliqueTransition{{Block: big.NewInt(10), Period: &period1}}}},
+			new:    &ChainConfig{Clique: &CliqueConfig{Period: 5, Transitions: []CliqueTransition{{Block: big.NewInt(10), Period: &period2}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Clique transition parameters",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Clique: &CliqueConfig{Period: 5}},
+			new:    &ChainConfig{Clique: &CliqueConfig{Period: 5, Transitions: []CliqueTransition{{Block: big.NewInt(10), Period: &period2}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Clique transition block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 		{
 			stored: &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
 			new:    &ChainConfig{ConstantinopleBlock: big.NewInt(30), PetersburgBlock: big.NewInt(31)},
@@ -96,3 +125,62 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCliqueTransitions(t *testing.T) {
+	var (
+		period2, epoch10, strict = uint64(2), uint64(10), true
+		config                   = &CliqueConfig{
+			Period: 15,
+			Epoch:  100,
+			Transitions: []CliqueTransition{
+				{Block: big.NewInt(50), Period: &period2},
+				{Block: big.NewInt(205), Epoch: &epoch10},
+				{Block: big.NewInt(300), StrictTurn: &strict},
+			},
+		}
+	)
+	if err := config.CheckTransitions(); err != nil {
+		t.Fatalf("valid transitions rejected: %v", err)
+	}
+	for _, tt := range []struct {
+		number     uint64
+		period     uint64
+		checkpoint bool
+		strict     bool
+	}{
+		{0, 15, true, false},
+		{49, 15, false, false},
+		{50, 2, false, false},
+		{100, 2, true, false},
+		{200, 2, true, false},
+		{204, 2, false, false},
+		{205, 2, true, false},
+		{210, 2, false, false},
+		{215, 2, true, false},
+		{300, 2, false, true},
+		{305, 2, true, true},
+	} {
+		if period := config.PeriodAt(tt.number); period != tt.period {
+			t.Errorf("block %d: period mismatch: have %d, want %d", tt.number, period, tt.period)
+		}
+		if checkpoint := config.IsCheckpoint(tt.number); checkpoint != tt.checkpoint {
+			t.Errorf("block %d: checkpoint mismatch: have %v, want %v", tt.number, checkpoint, tt.checkpoint)
+		}
+		if strict := config.IsStrictTurn(tt.number); strict != tt.strict {
+			t.Errorf("block %d: strict turn mismatch: have %v, want %v", tt.number, strict, tt.strict)
+		}
+	}
+	// Ensure invalid schedules are rejected
+	zero := uint64(0)
+	for i, transitions := range [][]CliqueTransition{
+		{{Block: nil}},
+		{{Block: big.NewInt(0)}},
+		{{Block: big.NewInt(10)}, {Block: big.NewInt(10)}},
+		{{Block: big.NewInt(20)}, {Block: big.NewInt(10)}},
+		{{Block: big.NewInt(10), Epoch: &zero}},
+	} {
+		if err := (&CliqueConfig{Period: 15, Epoch: 100, Transitions: transitions}).CheckTransitions(); err == nil {
+			t.Errorf("test %d: invalid transitions accepted", i)
+		}
+	}
+}
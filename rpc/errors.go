@@ -54,6 +54,13 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(unauthorizedError)
+	_ Error = new(limitExceededError)
+)
+
+var (
+	errRateLimited      = &limitExceededError{"request rate limit exceeded"}
+	errResponseTooLarge = &limitExceededError{"response size limit exceeded"}
 )
 
 const defaultErrorCode = -32000
@@ -74,6 +81,21 @@ func (e *subscriptionNotFoundError) Error() string {
 	return fmt.Sprintf("no %q subscription in %s namespace", e.subscription, e.namespace)
 }
 
+type unauthorizedError struct{ method string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("the method %s is not permitted", e.method)
+}
+
+// a configured request limit was exceeded
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
 // Invalid JSON was received by the server.
 type parseError struct{ message string }
 
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -61,6 +62,9 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	permissions    *Permissions // methods the connection may call, nil if unrestricted
+	limiter        *Limiter     // request limits of the server, nil if unlimited
+	client         string       // identity the connection is rate limited by
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -71,7 +75,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, limiter *Limiter) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -84,6 +88,9 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		permissions:    PermissionsFromContext(connCtx),
+		limiter:        limiter,
+		client:         clientIdentity(connCtx, conn),
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -101,6 +108,14 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches exceeding the size limit as a whole:
+	if limit := h.limiter.batchItems(); limit > 0 && len(msgs) > limit {
+		rpcBatchLimitMeter.Mark(1)
+		h.startCallProc(func(cp *callProc) {
+			h.conn.writeJSON(cp.ctx, errorMessage(&limitExceededError{fmt.Sprintf("batch of %d requests exceeds limit of %d", len(msgs), limit)}))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -114,10 +129,23 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
 		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+			// Don't execute any more calls once the response limit was reached
+			limit := h.limiter.responseBytes()
+			if limit > 0 && size >= limit {
+				rpcResponseLimitMeter.Mark(1)
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(errResponseTooLarge))
+				}
+				continue
+			}
+			if answer := h.handleLimitedCallMsg(cp, msg, limit-size); answer != nil {
+				size += len(answer.Result)
+				answers = append(answers, h.limitResponse(answer, size))
 			}
 		}
 		h.addSubscriptions(cp.notifiers)
@@ -136,7 +164,10 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 		return
 	}
 	h.startCallProc(func(cp *callProc) {
-		answer := h.handleCallMsg(cp, msg)
+		answer := h.handleLimitedCallMsg(cp, msg, h.limiter.responseBytes())
+		if answer != nil {
+			answer = h.limitResponse(answer, len(answer.Result))
+		}
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			h.conn.writeJSON(cp.ctx, answer)
@@ -147,6 +178,29 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	})
 }
 
+// handleLimitedCallMsg executes a call message, exposing the response budget
+// left to the method through its context if responses are limited.
+func (h *handler) handleLimitedCallMsg(cp *callProc, msg *jsonrpcMessage, budget int) *jsonrpcMessage {
+	if h.limiter.responseBytes() == 0 {
+		return h.handleCallMsg(cp, msg)
+	}
+	ctx := cp.ctx
+	cp.ctx = withResponseBudget(ctx, budget)
+	defer func() { cp.ctx = ctx }()
+
+	return h.handleCallMsg(cp, msg)
+}
+
+// limitResponse replaces a successful answer with an error if the total size of
+// the response it belongs to exceeds the configured limit.
+func (h *handler) limitResponse(answer *jsonrpcMessage, size int) *jsonrpcMessage {
+	if limit := h.limiter.responseBytes(); limit == 0 || size <= limit || answer.Error != nil {
+		return answer
+	}
+	rpcResponseLimitMeter.Mark(1)
+	return answer.errorResponse(errResponseTooLarge)
+}
+
 // close cancels all requests except for inflightReq and waits for
 // call goroutines to shut down.
 func (h *handler) close(err error, inflightReq *requestOp) {
@@ -317,6 +371,13 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if !h.permissions.Allows(msg.Method) {
+		return msg.errorResponse(&unauthorizedError{method: msg.Method})
+	}
+	if err := h.limiter.allow(h.client, msg.Method); err != nil {
+		rpcRateLimitMeter.Mark(1)
+		return msg.errorResponse(err)
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -0,0 +1,191 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterCleanupInterval is the interval at which idle client buckets are dropped.
+const limiterCleanupInterval = time.Minute
+
+// Limits configures the request limits enforced by a Server. Zero values
+// disable the respective limit.
+type Limits struct {
+	BatchItems    int            // Maximum number of requests in a batch
+	ResponseBytes int            // Maximum size of the results returned for a request or batch
+	Rate          float64        // Request cost units refilled per second for each client
+	Burst         int            // Maximum request cost units a client may spend at once
+	MethodCosts   map[string]int // Cost units of individual methods, defaulting to one
+}
+
+// Limiter enforces the configured limits, tracking a token bucket for every
+// client identity seen. A single limiter may be shared by several servers.
+type Limiter struct {
+	limits Limits
+
+	buckets map[string]*clientBucket
+	cleaned time.Time
+	lock    sync.Mutex
+}
+
+// clientBucket is the token bucket of a single client.
+type clientBucket struct {
+	limiter *rate.Limiter
+	used    time.Time // last time the bucket was drawn from
+}
+
+// NewLimiter creates a limiter enforcing the given limits.
+func NewLimiter(limits Limits) *Limiter {
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+	return &Limiter{
+		limits:  limits,
+		buckets: make(map[string]*clientBucket),
+		cleaned: time.Now(),
+	}
+}
+
+// batchItems returns the maximum number of requests allowed in a batch, or 0 if
+// batches are unlimited.
+func (l *Limiter) batchItems() int {
+	if l == nil {
+		return 0
+	}
+	return l.limits.BatchItems
+}
+
+// responseBytes returns the maximum size of responses, or 0 if unlimited.
+func (l *Limiter) responseBytes() int {
+	if l == nil {
+		return 0
+	}
+	return l.limits.ResponseBytes
+}
+
+// allow charges the cost of a method to the bucket of a client, returning an
+// error if the client doesn't have enough budget left to call it. Methods more
+// expensive than the burst can never be afforded and are always rejected.
+// Clients without an identity are never limited.
+func (l *Limiter) allow(client, method string) error {
+	if l == nil || l.limits.Rate <= 0 || client == "" {
+		return nil
+	}
+	cost := 1
+	if c, ok := l.limits.MethodCosts[method]; ok {
+		cost = c
+	}
+	if cost <= 0 {
+		return nil
+	}
+	if cost > l.limits.Burst {
+		return &limitExceededError{fmt.Sprintf("method cost %d exceeds rate limit burst %d", cost, l.limits.Burst)}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.cleaned) > limiterCleanupInterval {
+		l.cleanup(now)
+	}
+	bucket := l.buckets[client]
+	if bucket == nil {
+		bucket = &clientBucket{limiter: rate.NewLimiter(rate.Limit(l.limits.Rate), l.limits.Burst)}
+		l.buckets[client] = bucket
+	}
+	bucket.used = now
+	if !bucket.limiter.AllowN(now, cost) {
+		return errRateLimited
+	}
+	rpcCostMeter.Mark(int64(cost))
+	return nil
+}
+
+// cleanup drops the buckets idle for long enough to have been refilled, as new
+// buckets start out full anyway.
+func (l *Limiter) cleanup(now time.Time) {
+	idle := time.Duration(float64(l.limits.Burst) / l.limits.Rate * float64(time.Second))
+	if idle < limiterCleanupInterval {
+		idle = limiterCleanupInterval
+	}
+	for client, bucket := range l.buckets {
+		if now.Sub(bucket.used) > idle {
+			delete(l.buckets, client)
+		}
+	}
+	l.cleaned = now
+}
+
+type budgetContextKey struct{}
+
+// withResponseBudget returns a context carrying the number of bytes left for
+// the result of the method called with it.
+func withResponseBudget(ctx context.Context, budget int) context.Context {
+	return context.WithValue(ctx, budgetContextKey{}, budget)
+}
+
+// ResponseBudget returns the number of bytes the JSON encoded result of the
+// method called with ctx may take up before the response limit of the server is
+// hit. Methods producing large results may use it to give up early instead of
+// assembling a result that is going to be rejected anyway.
+func ResponseBudget(ctx context.Context) (int, bool) {
+	budget, ok := ctx.Value(budgetContextKey{}).(int)
+	return budget, ok
+}
+
+type identityContextKey struct{}
+
+// WithClientIdentity returns a context identifying the client of the requests
+// served with it, such as the subject of an authentication token. Rate limits
+// are tracked per identity, falling back to the remote address if unset.
+func WithClientIdentity(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, id)
+}
+
+// clientIdentity returns the identity rate limits are tracked by for the given
+// connection.
+func clientIdentity(ctx context.Context, conn jsonWriter) string {
+	if id, _ := ctx.Value(identityContextKey{}).(string); id != "" {
+		return id
+	}
+	remote := conn.remoteAddr()
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// detachRequestContext copies the values set by the HTTP middleware from the
+// context of a request into a new context outliving the request.
+func detachRequestContext(ctx context.Context) context.Context {
+	detached := context.Background()
+	if perms := PermissionsFromContext(ctx); perms != nil {
+		detached = WithPermissions(detached, perms)
+	}
+	if id, _ := ctx.Value(identityContextKey{}).(string); id != "" {
+		detached = WithClientIdentity(detached, id)
+	}
+	return detached
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// limitsTestRequest posts a raw JSON-RPC request to the server, returning the
+// decoded responses.
+func limitsTestRequest(t *testing.T, url, body string) []*jsonrpcMessage {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	var msgs []*jsonrpcMessage
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &msgs)
+	} else {
+		msg := new(jsonrpcMessage)
+		err = json.Unmarshal(data, msg)
+		msgs = append(msgs, msg)
+	}
+	if err != nil {
+		t.Fatalf("invalid response %q: %v", data, err)
+	}
+	return msgs
+}
+
+// checkLimitErrors checks which responses were rejected by a limit.
+func checkLimitErrors(t *testing.T, msgs []*jsonrpcMessage, want []bool) {
+	t.Helper()
+
+	if len(msgs) != len(want) {
+		t.Fatalf("response count mismatch: have %d, want %d", len(msgs), len(want))
+	}
+	for i, msg := range msgs {
+		limited := msg.Error != nil && msg.Error.Code == -32005
+		if limited != want[i] {
+			t.Errorf("response %d: limited mismatch: have %v, want %v (%+v)", i, limited, want[i], msg.Error)
+		}
+	}
+}
+
+func TestBatchLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimiter(NewLimiter(Limits{BatchItems: 2}))
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, "["+call+","+call+"]"), []bool{false, false})
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, "["+call+","+call+","+call+"]"), []bool{true})
+}
+
+func TestResponseLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimiter(NewLimiter(Limits{ResponseBytes: 64}))
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	var (
+		small = `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`
+		large = `{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["` + strings.Repeat("x", 64) + `",1]}`
+	)
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, small), []bool{false})
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, large), []bool{true})
+
+	// Calls are rejected once the results of a batch add up over the limit
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, "["+small+","+small+","+small+"]"), []bool{false, true, true})
+}
+
+func TestRateLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimiter(NewLimiter(Limits{Rate: 0.001, Burst: 3, MethodCosts: map[string]int{"test_echo": 2, "test_rets": 0, "test_returnError": 4}}))
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	var (
+		echo = `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`
+		noop = `{"jsonrpc":"2.0","id":2,"method":"test_noArgsRets"}`
+		free = `{"jsonrpc":"2.0","id":3,"method":"test_rets"}`
+		huge = `{"jsonrpc":"2.0","id":4,"method":"test_returnError"}`
+	)
+	// Methods costing more than the burst are rejected without touching the budget
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, huge), []bool{true})
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, echo), []bool{false})
+	checkLimitErrors(t, limitsTestRequest(t, httpsrv.URL, "["+echo+","+noop+","+noop+","+free+"]"), []bool{true, false, true, false})
+}
+
+// Tests that servers sharing a limiter charge a client's requests to the same
+// budget, regardless of the server they were issued through.
+func TestRateLimitShared(t *testing.T) {
+	var (
+		limiter = NewLimiter(Limits{Rate: 0.001, Burst: 2})
+		first   = newTestServer()
+		second  = newTestServer()
+	)
+	first.SetLimiter(limiter)
+	second.SetLimiter(limiter)
+
+	firstsrv := httptest.NewServer(first)
+	defer firstsrv.Close()
+	secondsrv := httptest.NewServer(second)
+	defer secondsrv.Close()
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`
+	checkLimitErrors(t, limitsTestRequest(t, firstsrv.URL, call), []bool{false})
+	checkLimitErrors(t, limitsTestRequest(t, secondsrv.URL, call), []bool{false})
+	checkLimitErrors(t, limitsTestRequest(t, firstsrv.URL, call), []bool{true})
+	checkLimitErrors(t, limitsTestRequest(t, secondsrv.URL, call), []bool{true})
+}
+
+// budgetService reports the response budget methods are called with.
+type budgetService struct{}
+
+func (budgetService) Budget(ctx context.Context) int {
+	budget, ok := ResponseBudget(ctx)
+	if !ok {
+		return -1
+	}
+	return budget
+}
+
+func (budgetService) Pad(n int) string { return strings.Repeat("x", n) }
+
+// Tests that methods are told the response budget left before they run.
+func TestResponseBudget(t *testing.T) {
+	newServer := func(limiter *Limiter) *httptest.Server {
+		server := newTestServer()
+		if err := server.RegisterName("budget", budgetService{}); err != nil {
+			t.Fatal(err)
+		}
+		server.SetLimiter(limiter)
+		return httptest.NewServer(server)
+	}
+	var (
+		budget = `{"jsonrpc":"2.0","id":1,"method":"budget_budget"}`
+		pad    = `{"jsonrpc":"2.0","id":2,"method":"budget_pad","params":[8]}`
+	)
+	// Unlimited servers don't report a budget
+	unlimited := newServer(nil)
+	defer unlimited.Close()
+
+	if msgs := limitsTestRequest(t, unlimited.URL, budget); string(msgs[0].Result) != "-1" {
+		t.Fatalf("unlimited budget mismatch: have %s, want -1", msgs[0].Result)
+	}
+	limited := newServer(NewLimiter(Limits{ResponseBytes: 64}))
+	defer limited.Close()
+
+	msgs := limitsTestRequest(t, limited.URL, "["+budget+","+pad+","+budget+"]")
+	if len(msgs) != 3 {
+		t.Fatalf("response count mismatch: have %d, want 3", len(msgs))
+	}
+	if have := string(msgs[0].Result); have != "64" {
+		t.Errorf("first budget mismatch: have %s, want 64", have)
+	}
+	// The first result takes 2 bytes, the padded string 10 more
+	if have := string(msgs[2].Result); have != "52" {
+		t.Errorf("second budget mismatch: have %s, want 52", have)
+	}
+}
+
+func TestRateLimitIdentity(t *testing.T) {
+	l := NewLimiter(Limits{Rate: 0.001, Burst: 1})
+	if err := l.allow("alice", "test_echo"); err != nil {
+		t.Fatalf("first request of alice rejected: %v", err)
+	}
+	if err := l.allow("alice", "test_echo"); err == nil {
+		t.Fatalf("second request of alice allowed")
+	}
+	if err := l.allow("bob", "test_echo"); err != nil {
+		t.Fatalf("first request of bob rejected: %v", err)
+	}
+	// Connections without an identity aren't rate limited
+	for i := 0; i < 3; i++ {
+		if err := l.allow("", "test_echo"); err != nil {
+			t.Fatalf("anonymous request %d rejected: %v", i, err)
+		}
+	}
+}
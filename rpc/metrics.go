@@ -27,6 +27,11 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	rpcCostMeter          = metrics.NewRegisteredMeter("rpc/limits/cost", nil)
+	rpcRateLimitMeter     = metrics.NewRegisteredMeter("rpc/limits/ratelimited", nil)
+	rpcBatchLimitMeter    = metrics.NewRegisteredMeter("rpc/limits/batch", nil)
+	rpcResponseLimitMeter = metrics.NewRegisteredMeter("rpc/limits/response", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
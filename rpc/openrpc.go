@@ -0,0 +1,344 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// openrpcVersion is the version of the OpenRPC specification the documents
+// returned by rpc_discover conform to.
+const openrpcVersion = "1.2.6"
+
+var (
+	bigIntType            = reflect.TypeOf(big.Int{})
+	blockNumberType       = reflect.TypeOf(BlockNumber(0))
+	blockNumberOrHashType = reflect.TypeOf(BlockNumberOrHash{})
+	jsonMarshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	describerType         = reflect.TypeOf((*Describer)(nil)).Elem()
+)
+
+// Describer can be implemented by RPC services to document their methods in the
+// OpenRPC document returned by rpc_discover. The RPCDescriptions method itself
+// is not exposed over RPC.
+type Describer interface {
+	// RPCDescriptions returns the documentation of the methods and subscriptions
+	// of the service, keyed by their RPC name without namespace (e.g. "modules").
+	RPCDescriptions() map[string]MethodDoc
+}
+
+// MethodDoc documents an RPC method or subscription.
+type MethodDoc struct {
+	Summary     string   // Short summary of what the method does
+	Description string   // Longer description of the method, if needed
+	Params      []string // Names of the parameters, in order
+}
+
+// OpenRPCDocument is an OpenRPC service description, as specified by
+// https://spec.open-rpc.org.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []OpenRPCMethod   `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+
+	// Subscriptions lists the subscriptions which can be created via the
+	// <namespace>_subscribe method of a service. OpenRPC has no notion of
+	// subscriptions, so they are carried in a specification extension, which
+	// the spec permits as any field prefixed with "x-". Each entry is shaped
+	// like a method named <namespace>_subscribe, whose first parameter is the
+	// subscription name and whose result is the subscription ID.
+	Subscriptions []OpenRPCMethod `json:"x-subscriptions,omitempty"`
+}
+
+// openrpcDescription documents the non-standard parts of the OpenRPC document.
+const openrpcDescription = "Subscriptions created via the <namespace>_subscribe methods " +
+	"are listed in the x-subscriptions specification extension, using the method object " +
+	"format with the subscription name as first parameter."
+
+// OpenRPCInfo is the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenRPCMethod describes a single RPC method.
+type OpenRPCMethod struct {
+	Name        string           `json:"name"`
+	Summary     string           `json:"summary,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Params      []OpenRPCContent `json:"params"`
+	Result      OpenRPCContent   `json:"result"`
+}
+
+// OpenRPCContent describes a parameter or the result of a method.
+type OpenRPCContent struct {
+	Name     string                 `json:"name"`
+	Required bool                   `json:"required,omitempty"`
+	Schema   map[string]interface{} `json:"schema"`
+}
+
+// OpenRPCComponents holds the reusable schemas referenced by the methods.
+type OpenRPCComponents struct {
+	Schemas map[string]interface{} `json:"schemas"`
+}
+
+// Discover returns an OpenRPC document describing the methods and subscriptions
+// served by this endpoint which the caller is permitted to use.
+func (s *RPCService) Discover(ctx context.Context) *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	var (
+		perms = PermissionsFromContext(ctx)
+		gen   = newSchemaGenerator()
+		doc   = &OpenRPCDocument{
+			OpenRPC: openrpcVersion,
+			Info: OpenRPCInfo{
+				Title:       "Etherdata JSON-RPC API",
+				Description: openrpcDescription,
+				Version:     "1.0",
+			},
+			Methods: []OpenRPCMethod{},
+		}
+	)
+	for name, svc := range s.server.services.services {
+		for method, cb := range svc.callbacks {
+			if !perms.Allows(name + serviceMethodSeparator + method) {
+				continue
+			}
+			doc.Methods = append(doc.Methods, gen.method(name+serviceMethodSeparator+method, cb, svc.docs[method]))
+		}
+		if !perms.Allows(name + subscribeMethodSuffix) {
+			continue
+		}
+		for subscription, cb := range svc.subscriptions {
+			m := gen.method(name+subscribeMethodSuffix, cb, svc.docs[subscription])
+			m.Params = append([]OpenRPCContent{{
+				Name:     "subscription",
+				Required: true,
+				Schema:   map[string]interface{}{"type": "string", "enum": []string{subscription}},
+			}}, m.Params...)
+			m.Result = OpenRPCContent{Name: "subscriptionID", Schema: map[string]interface{}{"type": "string"}}
+			doc.Subscriptions = append(doc.Subscriptions, m)
+		}
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool {
+		return doc.Methods[i].Name < doc.Methods[j].Name
+	})
+	sort.Slice(doc.Subscriptions, func(i, j int) bool {
+		a, b := doc.Subscriptions[i], doc.Subscriptions[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Params[0].Schema["enum"].([]string)[0] < b.Params[0].Schema["enum"].([]string)[0]
+	})
+	doc.Components.Schemas = gen.schemas
+	return doc
+}
+
+// RPCDescriptions implements Describer.
+func (s *RPCService) RPCDescriptions() map[string]MethodDoc {
+	return map[string]MethodDoc{
+		"modules":  {Summary: "Returns the RPC namespaces enabled on this endpoint, with their versions."},
+		"discover": {Summary: "Returns an OpenRPC document describing the methods enabled on this endpoint."},
+	}
+}
+
+// schemaGenerator derives JSON schemas from Go types, collecting the schemas
+// of named struct types as reusable components.
+type schemaGenerator struct {
+	schemas map[string]interface{}  // Component schemas by name
+	names   map[reflect.Type]string // Component names of the already seen types
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// method describes an RPC callback.
+func (g *schemaGenerator) method(name string, cb *callback, doc MethodDoc) OpenRPCMethod {
+	m := OpenRPCMethod{
+		Name:        name,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Params:      []OpenRPCContent{},
+	}
+	// Describe the parameters, trailing pointers being optional
+	seen := make(map[string]int)
+	for i, typ := range cb.argTypes {
+		param := OpenRPCContent{
+			Name:     paramName(typ, i),
+			Required: typ.Kind() != reflect.Ptr,
+			Schema:   g.schema(typ),
+		}
+		if i < len(doc.Params) {
+			param.Name = doc.Params[i]
+		}
+		if n := seen[param.Name]; n > 0 {
+			param.Name = fmt.Sprintf("%s%d", param.Name, n)
+		}
+		seen[param.Name]++
+		m.Params = append(m.Params, param)
+	}
+	// Describe the result, if any
+	m.Result = OpenRPCContent{Name: "result", Schema: map[string]interface{}{"type": "null"}}
+	if fntype := cb.fn.Type(); fntype.NumOut() > 0 && cb.errPos != 0 {
+		m.Result.Schema = g.schema(fntype.Out(0))
+	}
+	return m
+}
+
+// paramName derives a parameter name from its type.
+func paramName(typ reflect.Type, index int) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Name() == "" || typ.PkgPath() == "" {
+		return fmt.Sprintf("param%d", index)
+	}
+	return formatName(typ.Name())
+}
+
+// schema returns the JSON schema of the JSON encoding of a Go type.
+func (g *schemaGenerator) schema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ == bigIntType:
+		return map[string]interface{}{"type": "integer"}
+	case typ == blockNumberType:
+		return map[string]interface{}{
+			"title":       "BlockNumber",
+			"type":        "string",
+			"description": "Hex encoded block number, or one of \"earliest\", \"latest\" and \"pending\"",
+		}
+	case typ == blockNumberOrHashType:
+		return map[string]interface{}{
+			"title":       "BlockNumberOrHash",
+			"description": "Block number, block hash or an object with either a blockNumber or a blockHash field",
+		}
+	case implements(typ, textMarshalerType) || implements(typ, textUnmarshalerType):
+		return map[string]interface{}{"title": typ.Name(), "type": "string"}
+	case typ.Kind() != reflect.Struct && (implements(typ, jsonMarshalerType) || implements(typ, jsonUnmarshalerType)):
+		// Custom encodings of non-structs are strings in all our APIs
+		return map[string]interface{}{"title": typ.Name(), "type": "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + g.component(typ)}
+	}
+	// Interfaces and anything else can be any value
+	return map[string]interface{}{}
+}
+
+// component returns the name of the reusable schema of a named struct type,
+// generating it on first use.
+func (g *schemaGenerator) component(typ reflect.Type) string {
+	if name, ok := g.names[typ]; ok {
+		return name
+	}
+	// Disambiguate equally named types from different packages
+	name := typ.Name()
+	if _, ok := g.schemas[name]; ok {
+		name = strings.Title(path.Base(typ.PkgPath())) + typ.Name()
+	}
+	g.names[typ] = name
+	g.schemas[name] = nil // reserve the name for recursive types
+	g.schemas[name] = g.structSchema(typ)
+	return name
+}
+
+// structSchema returns the object schema of a struct, following the encoding
+// rules of encoding/json.
+func (g *schemaGenerator) structSchema(typ reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.addFields(typ, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// addFields adds the JSON fields of a struct to an object schema, flattening the
+// untagged embedded structs.
+func (g *schemaGenerator) addFields(typ reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			ftyp := field.Type
+			if ftyp.Kind() == reflect.Ptr {
+				ftyp = ftyp.Elem()
+			}
+			if ftyp.Kind() == reflect.Struct {
+				g.addFields(ftyp, properties)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := properties[name]; !ok {
+			properties[name] = g.schema(field.Type)
+		}
+	}
+}
+
+// implements reports whether a type or a pointer to it implements an interface.
+func implements(typ, iface reflect.Type) bool {
+	return typ.Implements(iface) || reflect.PtrTo(typ).Implements(iface)
+}
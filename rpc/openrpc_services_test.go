@@ -0,0 +1,63 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc_test
+
+import (
+	"testing"
+
+	"github.com/crypyto-panel/go-etherdata/consensus/clique"
+	"github.com/crypyto-panel/go-etherdata/consensus/etdash"
+	"github.com/crypyto-panel/go-etherdata/consensus/ibft"
+	"github.com/crypyto-panel/go-etherdata/etd/downloader"
+	"github.com/crypyto-panel/go-etherdata/etd/filters"
+	"github.com/crypyto-panel/go-etherdata/etd/tracers"
+	"github.com/crypyto-panel/go-etherdata/internal/etdapi"
+	"github.com/crypyto-panel/go-etherdata/les/vflux/client"
+	"github.com/crypyto-panel/go-etherdata/rpc"
+)
+
+// Tests that the documented methods of the RPC services exist. The services of
+// packages linking the node (such as the node's own admin and debug APIs) are
+// checked when registered by the tests starting a node.
+func TestServiceDescriptions(t *testing.T) {
+	services := []struct {
+		namespace string
+		service   rpc.Describer
+	}{
+		{"etd", new(etdapi.PublicEtherdataAPI)},
+		{"etd", new(etdapi.PublicBlockChainAPI)},
+		{"etd", new(etdapi.PublicTransactionPoolAPI)},
+		{"etd", new(etdapi.PublicAccountAPI)},
+		{"txpool", new(etdapi.PublicTxPoolAPI)},
+		{"debug", new(etdapi.PublicDebugAPI)},
+		{"debug", new(etdapi.PrivateDebugAPI)},
+		{"personal", new(etdapi.PrivateAccountAPI)},
+		{"net", new(etdapi.PublicNetAPI)},
+		{"etd", new(filters.PublicFilterAPI)},
+		{"etd", new(downloader.PublicDownloaderAPI)},
+		{"debug", new(tracers.API)},
+		{"clique", new(clique.API)},
+		{"ibft", new(ibft.API)},
+		{"etdash", new(etdash.API)},
+		{"vflux", new(client.PrivateClientAPI)},
+	}
+	for _, s := range services {
+		if err := rpc.NewServer().RegisterName(s.namespace, s.service); err != nil {
+			t.Errorf("%s: %v", s.namespace, err)
+		}
+	}
+}
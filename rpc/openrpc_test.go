@@ -0,0 +1,147 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatal(err)
+	}
+	methods := make(map[string]OpenRPCMethod)
+	for _, method := range doc.Methods {
+		methods[method.Name] = method
+	}
+	// Documentation hooks are not methods themselves
+	if _, ok := methods["rpc_rPCDescriptions"]; ok {
+		t.Errorf("documentation hook exposed as method")
+	}
+	if have := methods["rpc_discover"].Summary; have == "" {
+		t.Errorf("missing rpc_discover summary")
+	}
+	// Check the parameter and result schemas derived from the Go types
+	echo, ok := methods["test_echo"]
+	if !ok {
+		t.Fatalf("test_echo missing from %v", doc.Methods)
+	}
+	wantParams := []OpenRPCContent{
+		{Name: "param0", Required: true, Schema: map[string]interface{}{"type": "string"}},
+		{Name: "param1", Required: true, Schema: map[string]interface{}{"type": "integer"}},
+		{Name: "echoArgs", Schema: map[string]interface{}{"$ref": "#/components/schemas/echoArgs"}},
+	}
+	if !reflect.DeepEqual(echo.Params, wantParams) {
+		t.Errorf("test_echo params mismatch:\nhave %+v\nwant %+v", echo.Params, wantParams)
+	}
+	wantResult := map[string]interface{}{"$ref": "#/components/schemas/echoResult"}
+	if !reflect.DeepEqual(echo.Result.Schema, wantResult) {
+		t.Errorf("test_echo result mismatch: have %+v, want %+v", echo.Result.Schema, wantResult)
+	}
+	wantComponent := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"String": map[string]interface{}{"type": "string"},
+			"Int":    map[string]interface{}{"type": "integer"},
+			"Args":   map[string]interface{}{"$ref": "#/components/schemas/echoArgs"},
+		},
+	}
+	if have := doc.Components.Schemas["echoResult"]; !reflect.DeepEqual(have, wantComponent) {
+		t.Errorf("echoResult schema mismatch:\nhave %+v\nwant %+v", have, wantComponent)
+	}
+	if have := methods["test_noArgsRets"].Result.Schema; !reflect.DeepEqual(have, map[string]interface{}{"type": "null"}) {
+		t.Errorf("test_noArgsRets result mismatch: have %+v", have)
+	}
+	// Subscriptions are listed separately with their names
+	var found bool
+	for _, sub := range doc.Subscriptions {
+		if sub.Name == "nftest_subscribe" && reflect.DeepEqual(sub.Params[0].Schema["enum"], []interface{}{"someSubscription"}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("nftest someSubscription missing from %+v", doc.Subscriptions)
+	}
+}
+
+// Tests that the OpenRPC document only lists what the caller may use.
+func TestDiscoverPermissions(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	ctx := WithPermissions(context.Background(), NewPermissions([]string{"test_echo", "nftest"}))
+	doc := (&RPCService{server: server}).Discover(ctx)
+
+	var methods []string
+	for _, method := range doc.Methods {
+		if method.Name == "test_echo" || strings.HasPrefix(method.Name, "nftest_") {
+			continue
+		}
+		methods = append(methods, method.Name)
+	}
+	if len(methods) > 0 {
+		t.Errorf("forbidden methods listed: %v", methods)
+	}
+	if len(doc.Subscriptions) == 0 {
+		t.Errorf("permitted subscriptions missing")
+	}
+	// Subscriptions of namespaces only granted single methods are hidden
+	ctx = WithPermissions(context.Background(), NewPermissions([]string{"nftest_echo"}))
+	if doc := (&RPCService{server: server}).Discover(ctx); len(doc.Subscriptions) > 0 {
+		t.Errorf("forbidden subscriptions listed: %+v", doc.Subscriptions)
+	}
+}
+
+type describedService struct{ docs map[string]MethodDoc }
+
+func (s *describedService) Echo(str string) string { return str }
+
+func (s *describedService) RPCDescriptions() map[string]MethodDoc { return s.docs }
+
+// Tests that services can't be registered with documentation of methods they
+// don't expose, so renamed or removed methods don't leave stale descriptions.
+func TestRegisterDescriptions(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+
+	// The built-in rpc service is registered by NewServer
+	if len(server.services.services["rpc"].docs) == 0 {
+		t.Errorf("rpc service undocumented")
+	}
+	if err := server.RegisterName("test", &describedService{docs: map[string]MethodDoc{"echo": {Summary: "Echoes the string."}}}); err != nil {
+		t.Fatalf("failed to register documented service: %v", err)
+	}
+	for _, name := range []string{"Echo", "ech", "rpcDescriptions"} {
+		svc := &describedService{docs: map[string]MethodDoc{name: {Summary: "Echoes the string."}}}
+		if err := server.RegisterName("test2", svc); err == nil {
+			t.Errorf("registered service documenting unknown method %q", name)
+		}
+	}
+	if _, ok := server.services.services["test2"]; ok {
+		t.Errorf("service with stale documentation registered")
+	}
+}
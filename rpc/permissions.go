@@ -0,0 +1,85 @@
+// Copyright 2021 The go-etherdata Authors
+// This file is part of the go-etherdata library.
+//
+// The go-etherdata library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-etherdata library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-etherdata library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"strings"
+)
+
+// Permissions restricts the methods a client may call. Each entry is either a
+// full method name such as "etd_blockNumber", a namespace such as "etd" granting
+// all of its methods, or "*" granting everything.
+//
+// A nil set of permissions allows all methods.
+type Permissions struct {
+	all        bool
+	namespaces map[string]struct{}
+	methods    map[string]struct{}
+}
+
+// NewPermissions creates a permission set granting the listed namespaces and
+// methods.
+func NewPermissions(allowed []string) *Permissions {
+	p := &Permissions{
+		namespaces: make(map[string]struct{}),
+		methods:    make(map[string]struct{}),
+	}
+	for _, entry := range allowed {
+		switch {
+		case entry == "*":
+			p.all = true
+		case strings.Contains(entry, serviceMethodSeparator):
+			p.methods[entry] = struct{}{}
+		default:
+			p.namespaces[entry] = struct{}{}
+		}
+	}
+	return p
+}
+
+// Allows reports whether the given method may be called.
+func (p *Permissions) Allows(method string) bool {
+	if p == nil || p.all {
+		return true
+	}
+	if _, ok := p.methods[method]; ok {
+		return true
+	}
+	namespace := method
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		namespace = method[:i]
+	}
+	_, ok := p.namespaces[namespace]
+	return ok
+}
+
+type permissionsContextKey struct{}
+
+// WithPermissions returns a context restricting the RPC methods callable by the
+// requests served with it. HTTP and WebSocket handlers pick the permissions up
+// from the request context.
+func WithPermissions(ctx context.Context, perms *Permissions) context.Context {
+	return context.WithValue(ctx, permissionsContextKey{}, perms)
+}
+
+// PermissionsFromContext retrieves the permissions attached to the context, or
+// nil if there are none.
+func PermissionsFromContext(ctx context.Context) *Permissions {
+	perms, _ := ctx.Value(permissionsContextKey{}).(*Permissions)
+	return perms
+}
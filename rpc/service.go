@@ -46,6 +46,7 @@ type service struct {
 	name          string               // name for service
 	callbacks     map[string]*callback // registered handlers
 	subscriptions map[string]*callback // available subscriptions/notifications
+	docs          map[string]MethodDoc // documentation of the handlers, if provided
 }
 
 // callback is a method callback which was registered in the server
@@ -67,6 +68,15 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 	if len(callbacks) == 0 {
 		return fmt.Errorf("service %T doesn't have any suitable methods/subscriptions to expose", rcvr)
 	}
+	var docs map[string]MethodDoc
+	if describer, ok := rcvr.(Describer); ok {
+		docs = describer.RPCDescriptions()
+		for name := range docs {
+			if callbacks[name] == nil {
+				return fmt.Errorf("service %T documents method %q, which it doesn't expose", rcvr, name)
+			}
+		}
+	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -79,6 +89,7 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 			name:          name,
 			callbacks:     make(map[string]*callback),
 			subscriptions: make(map[string]*callback),
+			docs:          make(map[string]MethodDoc),
 		}
 		r.services[name] = svc
 	}
@@ -89,6 +100,9 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 			svc.callbacks[name] = cb
 		}
 	}
+	for name, doc := range docs {
+		svc.docs[name] = doc
+	}
 	return nil
 }
 
@@ -121,6 +135,9 @@ func suitableCallbacks(receiver reflect.Value) map[string]*callback {
 		if method.PkgPath != "" {
 			continue // method not exported
 		}
+		if method.Name == "RPCDescriptions" && typ.Implements(describerType) {
+			continue // documentation, not an RPC method
+		}
 		cb := newCallback(receiver, method.Func)
 		if cb == nil {
 			continue // function invalid
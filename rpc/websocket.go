@@ -60,6 +60,9 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
+		wc := codec.(*websocketCodec)
+		wc.remote = r.RemoteAddr
+		wc.connCtx = detachRequestContext(r.Context())
 		s.ServeCodec(codec, 0)
 	})
 }
@@ -183,10 +186,31 @@ func parseOriginURL(origin string) (string, string, string, error) {
 // DialWebsocketWithDialer creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint using the provided dialer.
 func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, dialer websocket.Dialer) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, nil, dialer)
+}
+
+// DialWebsocketWithHeader creates a new RPC client that communicates with a JSON-RPC
+// server that is listening on the given endpoint, sending the extra headers (such as
+// an authorization token) along with the handshake.
+func DialWebsocketWithHeader(ctx context.Context, endpoint, origin string, extra http.Header) (*Client, error) {
+	dialer := websocket.Dialer{
+		ReadBufferSize:  wsReadBuffer,
+		WriteBufferSize: wsWriteBuffer,
+		WriteBufferPool: wsBufferPool,
+	}
+	return dialWebsocket(ctx, endpoint, origin, extra, dialer)
+}
+
+func dialWebsocket(ctx context.Context, endpoint, origin string, extra http.Header, dialer websocket.Dialer) (*Client, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
+	for key, values := range extra {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
@@ -235,6 +259,8 @@ type websocketCodec struct {
 	*jsonCodec
 	conn *websocket.Conn
 
+	connCtx context.Context // values of the handshake request, set on the server side
+
 	wg        sync.WaitGroup
 	pingReset chan struct{}
 }
@@ -333,6 +333,20 @@ func (s *Sync) Pending() int {
 	return len(s.nodeReqs) + len(s.codeReqs)
 }
 
+// Retrieved returns the trie nodes that were already downloaded, but are still
+// waiting for some of their children before they can be committed. Feeding them
+// back into a new scheduler for the same trie via Process avoids having to fetch
+// them again, e.g. after a restart.
+func (s *Sync) Retrieved() [][]byte {
+	var blobs [][]byte
+	for _, req := range s.nodeReqs {
+		if req.data != nil {
+			blobs = append(blobs, req.data)
+		}
+	}
+	return blobs
+}
+
 // schedule inserts a new state retrieval request into the fetch queue. If there
 // is already a pending request for this node, the new request will be discarded
 // and only a parent reference added to the old one.
@@ -476,3 +476,85 @@ func TestSyncOrdering(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the retrieved but uncommitted nodes of an interrupted sync can be
+// fed into a new scheduler, avoiding having to retrieve them again.
+func TestSyncRetrievedResume(t *testing.T) {
+	// Create a random trie to copy
+	srcDb, srcTrie, srcData := makeTestTrie()
+
+	// Sync a few rounds of the trie, then abandon the scheduler
+	diskdb := memorydb.New()
+	triedb := NewDatabase(diskdb)
+	sched := NewSync(srcTrie.Hash(), diskdb, nil, NewSyncBloom(1, diskdb))
+
+	for i := 0; i < 3; i++ {
+		nodes, _, _ := sched.Missing(10)
+		for _, hash := range nodes {
+			data, err := srcDb.Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for hash %x: %v", hash, err)
+			}
+			if err := sched.Process(SyncResult{hash, data}); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+		batch := diskdb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+	}
+	retrieved := make(map[common.Hash][]byte)
+	for _, blob := range sched.Retrieved() {
+		retrieved[crypto.Keccak256Hash(blob)] = blob
+	}
+	if len(retrieved) == 0 {
+		t.Fatalf("no retrieved nodes after partial sync")
+	}
+	// Create a new scheduler and replay the retrieved nodes into it
+	sched = NewSync(srcTrie.Hash(), diskdb, nil, NewSyncBloom(1, diskdb))
+
+	var queue []common.Hash
+	for {
+		nodes, _, _ := sched.Missing(0)
+		replayed := false
+		for _, hash := range nodes {
+			if blob, ok := retrieved[hash]; ok {
+				delete(retrieved, hash)
+				if err := sched.Process(SyncResult{hash, blob}); err != nil {
+					t.Fatalf("failed to replay retrieved node %x: %v", hash, err)
+				}
+				replayed = true
+				continue
+			}
+			queue = append(queue, hash)
+		}
+		if !replayed {
+			break
+		}
+	}
+	if len(retrieved) != 0 {
+		t.Fatalf("retrieved nodes not replayed: %d", len(retrieved))
+	}
+	// Finish the sync and cross check that the two tries are in sync
+	for len(queue) > 0 {
+		for _, hash := range queue {
+			data, err := srcDb.Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for hash %x: %v", hash, err)
+			}
+			if err := sched.Process(SyncResult{hash, data}); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+		batch := diskdb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+
+		queue, _, _ = sched.Missing(0)
+	}
+	checkTrieContents(t, triedb, srcTrie.Hash().Bytes(), srcData)
+}